@@ -5,11 +5,23 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
+	"userservice/internal/auth"
+	"userservice/internal/components/elevation"
+	"userservice/internal/components/invitation"
+	"userservice/internal/components/jwks"
 	"userservice/internal/components/role"
+	"userservice/internal/components/scim"
 	"userservice/internal/components/service"
+	"userservice/internal/components/serviceaccount"
+	"userservice/internal/components/team"
 	"userservice/internal/components/user"
 	"userservice/internal/configs"
 	"userservice/internal/middleware"
+	"userservice/internal/misc"
+	"userservice/internal/notify"
+	"userservice/internal/oidc"
+	"userservice/internal/policy"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -18,6 +30,7 @@ import (
 
 const (
 	V1apiRoutePrefix = "/api/v1"
+	SCIMRoutePrefix  = "/scim/v2"
 )
 
 // APIServer contains listener info and entities which will be used by underlying route endpoints
@@ -28,15 +41,17 @@ type APIServer struct {
 	db        *gorm.DB
 	wg        *sync.WaitGroup
 	logger    *zap.SugaredLogger
+	policy    *policy.Enforcer
 	errorChan chan error
 
 	// exported for main module to use it
 	Runtime *http.Server
 }
 
-// NewAPIServer initializes entities for server and endpoint runtime
-func NewAPIServer(ctx context.Context, wg *sync.WaitGroup,
-	config *configs.Config, db *gorm.DB, logger *zap.SugaredLogger, serverErr chan error) *APIServer {
+// NewAPIServer initializes entities for server and endpoint runtime.
+// Authorization policy is optional, nil authorizes requests by permissions of the role alone.
+func NewAPIServer(ctx context.Context, wg *sync.WaitGroup, config *configs.Config, db *gorm.DB,
+	logger *zap.SugaredLogger, policyEnforcer *policy.Enforcer, serverErr chan error) *APIServer {
 
 	return &APIServer{
 		addr:      fmt.Sprintf(":%s", config.ServerPort),
@@ -45,6 +60,7 @@ func NewAPIServer(ctx context.Context, wg *sync.WaitGroup,
 		wg:        wg,
 		db:        db,
 		logger:    logger,
+		policy:    policyEnforcer,
 		errorChan: serverErr,
 	}
 }
@@ -54,20 +70,113 @@ func (s *APIServer) StartAPIServer() {
 
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
+	// Client IP drives login lockout and is recorded against sessions, hence forwarding headers are honoured
+	// only from the configured proxies, and never by default.
+	if err := router.SetTrustedProxies(s.config.TrustedProxies); err != nil {
+		s.errorChan <- err
+		return
+	}
 	v1Apis := router.Group(V1apiRoutePrefix)
 
+	signingKeys, err := auth.LoadKeySet(s.config.JWTSigningAlgorithm, []byte(s.config.JWTSecret),
+		s.config.JWTSigningKeyFile, s.config.JWTVerificationKeyFiles)
+	if err != nil {
+		s.errorChan <- err
+		return
+	}
+
+	// Verification keys are public, hence served outside the authenticated api group.
+	jwksHandler := jwks.NewHandler(signingKeys)
+	jwksHandler.RegisterRoutes(router.Group("/"))
+
+	tokens := auth.NewTokenIssuer(signingKeys, s.config.JWTIssuer, s.config.JWTAudience,
+		s.config.JWTExpirationInSeconds, s.config.JWTLeewayInSeconds)
+
+	// Revoked tokens are retained until the longest lived access token expires, leeway included.
+	revocationStore := auth.NewRevocationStore(s.ctx, s.db, s.logger,
+		time.Second*time.Duration(s.config.JWTExpirationInSeconds),
+		time.Second*time.Duration(s.config.JWTLeewayInSeconds))
+	if err := revocationStore.Load(); err != nil {
+		s.errorChan <- err
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		revocationStore.SyncPeriodically(time.Second * time.Duration(s.config.TokenRevocationSyncIntervalInSeconds))
+	}()
+
 	// Use global middleware to validate JWT token
-	v1Apis.Use(middleware.Authenticate(V1apiRoutePrefix, s.logger, []byte(s.config.JWTSecret)))
+	v1Apis.Use(middleware.Authenticate(V1apiRoutePrefix, s.logger, tokens, revocationStore,
+		auth.NewAccessTokenStore(s.db, s.logger)))
+	// Requests made while impersonating are audited ahead of being authorized, such that denied ones are recorded too
+	v1Apis.Use(middleware.AuditImpersonation(auth.NewImpersonationAuditStore(s.db, s.logger)))
+	if s.policy != nil {
+		v1Apis.Use(middleware.EnforcePolicy(V1apiRoutePrefix, s.logger, s.policy))
+	}
 
-	userHandler := user.NewHandler(s.logger, s.config, s.db)
+	passwordPolicy, err := auth.LoadPasswordPolicy(int(s.config.PasswordMinLength), int(s.config.PasswordMaxLength),
+		s.config.PasswordRequiredCharacterClasses)
+	if err != nil {
+		s.errorChan <- err
+		return
+	}
+	passwordHasher, err := auth.LoadPasswordHasher(s.config.PasswordHashAlgorithm, int(s.config.BcryptCost),
+		auth.Argon2idParams{Memory: uint32(s.config.Argon2MemoryInKiB), Iterations: uint32(s.config.Argon2Iterations),
+			Parallelism: uint8(s.config.Argon2Parallelism)})
+	if err != nil {
+		s.errorChan <- err
+		return
+	}
+	notifier, err := notify.LoadNotifier(s.config.NotifierDriver, s.config.NotifierFrom, s.config.NotifierOutboxDir,
+		notify.SMTPConfig{Host: s.config.SMTPHost, Port: s.config.SMTPPort, Username: s.config.SMTPUsername,
+			Password: s.config.SMTPPassword, TLSMode: s.config.SMTPTLSMode}, s.logger)
+	if err != nil {
+		s.errorChan <- err
+		return
+	}
+	oidcProvider, err := s.loadOIDCProvider()
+	if err != nil {
+		s.errorChan <- err
+		return
+	}
+	userHandler := user.NewHandler(s.wg, s.logger, s.config, s.db, tokens, revocationStore, passwordPolicy, passwordHasher,
+		notifier, oidcProvider)
 	userHandler.RegisterRoutes(v1Apis)
 
+	invitationHandler := invitation.NewHandler(s.logger, s.config, s.db, passwordPolicy, passwordHasher, notifier)
+	invitationHandler.RegisterRoutes(v1Apis)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		invitationHandler.StartCleanup(s.ctx)
+	}()
+
+	serviceAccountHandler := serviceaccount.NewHandler(s.logger, s.config, s.db, tokens, revocationStore)
+	serviceAccountHandler.RegisterRoutes(v1Apis)
+
 	roleHandler := role.NewHandler(s.logger, s.db)
 	roleHandler.RegisterRoutes(v1Apis)
 
+	elevationHandler := elevation.NewHandler(s.logger, s.config, s.db, revocationStore)
+	elevationHandler.RegisterRoutes(v1Apis)
+
+	teamHandler := team.NewHandler(s.logger, s.db)
+	teamHandler.RegisterRoutes(v1Apis)
+
 	serviceHandler := service.NewHandler(s.ctx, s.wg, s.logger, s.config, s.db)
 	serviceHandler.RegisterRoutes(v1Apis)
 
+	// SCIM provisioning is authenticated with its own bearer token, hence served outside the api group.
+	if s.config.SCIMBearerToken != "" {
+		if !misc.IsRoleConfigured(s.config.SCIMDefaultRole) {
+			s.errorChan <- fmt.Errorf("SCIM default role %s doesn't exist", s.config.SCIMDefaultRole)
+			return
+		}
+		scimHandler := scim.NewHandler(s.logger, s.config, s.db, revocationStore)
+		scimHandler.RegisterRoutes(router.Group(SCIMRoutePrefix))
+	}
+
 	s.Runtime = &http.Server{Addr: ":8080", Handler: router}
 
 	s.wg.Add(1)
@@ -78,3 +187,27 @@ func (s *APIServer) StartAPIServer() {
 		}
 	}()
 }
+
+// loadOIDCProvider builds identity provider of single sign-on, nil if single sign-on is not configured.
+// Roles which groups are mapped to have to be the pre-configured user roles.
+func (s *APIServer) loadOIDCProvider() (*oidc.Provider, error) {
+	if s.config.OIDCIssuerURL == "" {
+		return nil, nil
+	}
+	if s.config.OIDCClientID == "" || s.config.OIDCRedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required for single sign-on")
+	}
+	roleMapping, err := oidc.ParseRoleMapping(s.config.OIDCRoleMapping, s.config.OIDCDefaultRole)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roleMapping.Roles() {
+		if !misc.IsRoleConfigured(role) {
+			return nil, fmt.Errorf("single sign-on role mapping refers to unknown role %s", role)
+		}
+	}
+	return oidc.NewProvider(oidc.Config{IssuerURL: s.config.OIDCIssuerURL, ClientID: s.config.OIDCClientID,
+		ClientSecret: s.config.OIDCClientSecret, RedirectURL: s.config.OIDCRedirectURL, Scopes: s.config.OIDCScopes,
+		GroupsClaim: s.config.OIDCGroupsClaim, RoleMapping: roleMapping,
+		Leeway: time.Second * time.Duration(s.config.JWTLeewayInSeconds)}, s.logger), nil
+}
@@ -13,6 +13,7 @@ import (
 	"userservice/cmd/migration"
 	"userservice/internal/configs"
 	"userservice/internal/misc"
+	"userservice/internal/policy"
 
 	"github.com/sabariarunkumar/go-logger"
 
@@ -36,6 +37,11 @@ func main() {
 		"",
 		"migrate DB entities and exit",
 	)
+	checkPolicy := flag.Bool(
+		"check-policy",
+		false,
+		"validate authorization policy file configured by POLICY_FILE and exit",
+	)
 	flag.Parse()
 
 	// Runtime config.
@@ -46,6 +52,21 @@ func main() {
 	// Custom logger used by application.
 	logger := logger.NewLogger(config.LogLevel)
 
+	// policy is checked ahead of connecting to DB, such that it can be checked anywhere before rolling it out
+	if *checkPolicy {
+		if config.PolicyFile == "" {
+			logger.Fatal("POLICY_FILE is not configured")
+		}
+		checkedPolicy, err := policy.LoadFile(config.PolicyFile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		// roles are managed in DB, hence they are listed to be checked against the configured ones
+		logger.Infof("Policy file %s is valid with %d rules, referring to roles: %s", config.PolicyFile,
+			checkedPolicy.Rules(), strings.Join(checkedPolicy.Roles(), ", "))
+		os.Exit(0)
+	}
+
 	// let us set gorm log level to silent by default
 	// In case of app logger set to DEBUG,
 	// we shall set gorm accepted detailed Logging level `INFO`
@@ -89,6 +110,15 @@ func main() {
 	}
 	misc.InitPayloadValidator()
 
+	var policyEnforcer *policy.Enforcer
+	if config.PolicyFile != "" {
+		policyEnforcer, err = policy.NewEnforcer(config.PolicyFile, policy.NewOwnershipStore(db, logger),
+			misc.IsRoleConfigured, logger)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
 	var (
 		// runtimeContext controls the runtime of goroutine which periodically refresh db materialized view.
 		runtimeContext, runtimeContextCancel = context.WithCancel(context.Background())
@@ -99,9 +129,33 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
+	// policy is reloaded on SIGHUP, whereas invalid policy is reported and the one in effect is retained
+	if policyEnforcer != nil {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer signal.Stop(reload)
+			for {
+				select {
+				case <-runtimeContext.Done():
+					return
+				case <-reload:
+					rules, err := policyEnforcer.Reload()
+					if err != nil {
+						logger.Errorf("Failed to reload policy, retaining the policy in effect: %v", err)
+						continue
+					}
+					logger.Infof("Reloaded policy file %s with %d rules", config.PolicyFile, rules)
+				}
+			}
+		}()
+	}
+
 	serverError := make(chan error, 1)
 
-	apiServer := api.NewAPIServer(runtimeContext, &wg, config, db, logger, serverError)
+	apiServer := api.NewAPIServer(runtimeContext, &wg, config, db, logger, policyEnforcer, serverError)
 	apiServer.StartAPIServer()
 
 	select {
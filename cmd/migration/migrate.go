@@ -24,6 +24,29 @@ var defaultRoles = func() map[string]string {
 	}
 }
 
+// defaultRolePermissions grants default roles the permissions equivalent to what they were authorized for,
+// ahead of permissions being configurable. Roles are granted only the permissions beyond the ones
+// inherited from their parent.
+var defaultRolePermissions = func() map[string][]string {
+	return map[string][]string{
+		"basic":    {models.PermissionServiceRead, models.PermissionRoleRead},
+		"advanced": {models.PermissionServiceWrite, models.PermissionUserRead},
+		"admin": {models.PermissionUserWrite, models.PermissionRoleWrite, models.PermissionServiceAccountRead,
+			models.PermissionServiceAccountWrite, models.PermissionInvitationRead, models.PermissionInvitationWrite,
+			models.PermissionServiceAdmin},
+	}
+}
+
+// defaultRoleParents makes every default role inherit the one below, such that admin can do everything
+// advanced and basic can.
+var defaultRoleParents = func() map[string]string {
+	return map[string]string{
+		"basic":    "",
+		"advanced": "basic",
+		"admin":    "advanced",
+	}
+}
+
 // MigrateDBEntities migrate db tables, initializes admin users, roles and views
 func MigrateDBEntities(log *zap.SugaredLogger, db *gorm.DB) error {
 	if err := db.AutoMigrate(&models.User{}); err != nil {
@@ -38,9 +61,90 @@ func MigrateDBEntities(log *zap.SugaredLogger, db *gorm.DB) error {
 		return fmt.Errorf("failed to migrate Service Version table: %+v", err)
 	}
 	log.Info("Successfully Migrated Service Version table")
+	roleInheritanceMigrated := db.Migrator().HasColumn(&models.UserRole{}, "parent")
 	if err := db.AutoMigrate(&models.UserRole{}); err != nil {
 		return fmt.Errorf("failed to migrate UserRole table: %+v", err)
 	}
+	if !roleInheritanceMigrated {
+		// default roles configured by earlier releases inherit the same way as freshly seeded ones
+		for role, parent := range defaultRoleParents() {
+			if err := db.Model(&models.UserRole{}).Where("name = ?", role).
+				Update("parent", parent).Error; err != nil {
+				return fmt.Errorf("failed to set parent of role %s: %+v", role, err)
+			}
+		}
+		log.Info("Successfully Migrated UserRole inheritance")
+	}
+	if err := db.AutoMigrate(&models.RefreshToken{}); err != nil {
+		return fmt.Errorf("failed to migrate RefreshToken table: %+v", err)
+	}
+	log.Info("Successfully Migrated RefreshToken table")
+	if err := db.AutoMigrate(&models.RevokedToken{}, &models.SubjectRevocation{}); err != nil {
+		return fmt.Errorf("failed to migrate token revocation tables: %+v", err)
+	}
+	log.Info("Successfully Migrated token revocation tables")
+	if err := db.AutoMigrate(&models.PersonalAccessToken{}); err != nil {
+		return fmt.Errorf("failed to migrate PersonalAccessToken table: %+v", err)
+	}
+	log.Info("Successfully Migrated PersonalAccessToken table")
+	if err := db.AutoMigrate(&models.ServiceAccount{}); err != nil {
+		return fmt.Errorf("failed to migrate ServiceAccount table: %+v", err)
+	}
+	log.Info("Successfully Migrated ServiceAccount table")
+	if err := db.AutoMigrate(&models.LoginFailure{}); err != nil {
+		return fmt.Errorf("failed to migrate LoginFailure table: %+v", err)
+	}
+	log.Info("Successfully Migrated LoginFailure table")
+	if err := db.AutoMigrate(&models.PasswordHistory{}); err != nil {
+		return fmt.Errorf("failed to migrate PasswordHistory table: %+v", err)
+	}
+	log.Info("Successfully Migrated PasswordHistory table")
+	if err := db.AutoMigrate(&models.MFARecoveryCode{}); err != nil {
+		return fmt.Errorf("failed to migrate MFARecoveryCode table: %+v", err)
+	}
+	log.Info("Successfully Migrated MFARecoveryCode table")
+	if err := db.AutoMigrate(&models.PasswordResetToken{}); err != nil {
+		return fmt.Errorf("failed to migrate PasswordResetToken table: %+v", err)
+	}
+	log.Info("Successfully Migrated PasswordResetToken table")
+	if err := db.AutoMigrate(&models.Invitation{}); err != nil {
+		return fmt.Errorf("failed to migrate Invitation table: %+v", err)
+	}
+	log.Info("Successfully Migrated Invitation table")
+	if err := db.AutoMigrate(&models.OIDCAuthorization{}); err != nil {
+		return fmt.Errorf("failed to migrate OIDCAuthorization table: %+v", err)
+	}
+	log.Info("Successfully Migrated OIDCAuthorization table")
+	if err := db.AutoMigrate(&models.Session{}); err != nil {
+		return fmt.Errorf("failed to migrate Session table: %+v", err)
+	}
+	log.Info("Successfully Migrated Session table")
+	if err := db.AutoMigrate(&models.ImpersonationAudit{}); err != nil {
+		return fmt.Errorf("failed to migrate ImpersonationAudit table: %+v", err)
+	}
+	log.Info("Successfully Migrated ImpersonationAudit table")
+	if err := db.AutoMigrate(&models.Team{}, &models.TeamMember{}); err != nil {
+		return fmt.Errorf("failed to migrate team tables: %+v", err)
+	}
+	log.Info("Successfully Migrated team tables")
+	if err := db.AutoMigrate(&models.RoleElevation{}); err != nil {
+		return fmt.Errorf("failed to migrate RoleElevation table: %+v", err)
+	}
+	log.Info("Successfully Migrated RoleElevation table")
+	// view created by earlier releases lacks owner of the service, as its columns are fixed upon creation
+	var viewsLackingOwner int64
+	if err := db.Raw(`SELECT count(*) FROM pg_matviews v WHERE v.matviewname = ? AND NOT EXISTS (
+		SELECT 1 FROM pg_attribute a JOIN pg_class c ON a.attrelid = c.oid
+		WHERE c.relname = v.matviewname AND a.attname = 'owner_team_id' AND NOT a.attisdropped)`,
+		models.NameSortedServiceView).Scan(&viewsLackingOwner).Error; err != nil {
+		return fmt.Errorf("failed to inspect name_sorted_service materialized view: %v", err)
+	}
+	if viewsLackingOwner != 0 {
+		if err := db.Exec("DROP MATERIALIZED VIEW " + models.NameSortedServiceView).Error; err != nil {
+			return fmt.Errorf("failed to drop outdated name_sorted_service materialized view: %v", err)
+		}
+		log.Info("outdated materialized view name_sorted_service dropped to be recreated")
+	}
 	nameSortedServiceView := `
     CREATE MATERIALIZED VIEW IF NOT EXISTS name_sorted_service AS
     SELECT *
@@ -78,7 +182,8 @@ func InitDBEntities(log *zap.SugaredLogger, db *gorm.DB) error {
 	}
 
 	for role, description := range defaultRoles() {
-		userRole := models.UserRole{Name: role, Description: description}
+		userRole := models.UserRole{Name: role, Description: description, Permissions: defaultRolePermissions()[role],
+			Parent: defaultRoleParents()[role]}
 		var roleConfigured int64 = 0
 		if gormErr := db.Model(&models.UserRole{}).Where("name = ?", role).
 			Count(&roleConfigured).Error; gormErr != nil {
@@ -89,6 +194,16 @@ func InitDBEntities(log *zap.SugaredLogger, db *gorm.DB) error {
 				return fmt.Errorf("failed to create role %s: %v", role, gormErr)
 			}
 			log.Infof("Role %s configured successfully", role)
+			continue
+		}
+		// roles configured by earlier releases are granted the permissions they were authorized for
+		result := db.Model(&models.UserRole{}).Where("name = ? AND permissions IS NULL", role).
+			Select("permissions").Updates(&userRole)
+		if result.Error != nil {
+			return fmt.Errorf("failed to grant permissions to role %s: %v", role, result.Error)
+		}
+		if result.RowsAffected != 0 {
+			log.Infof("Role %s granted permissions %v", role, userRole.Permissions)
 		}
 	}
 	return nil
@@ -53,6 +53,17 @@ var _ = Describe("Initialize DB entities", func() {
 					"admin",
 					sqlmock.AnyArg(),
 					true,
+					nil,
+					0,
+					nil,
+					false,
+					"",
+					"",
+					0,
+					nil,
+					nil,
+					false,
+					false,
 				).WillReturnError(errors.New("connection is already closed"))
 			mock.ExpectRollback()
 			err := InitDBEntities(mockLog, db)
@@ -75,6 +86,17 @@ var _ = Describe("Initialize DB entities", func() {
 					"admin",
 					sqlmock.AnyArg(),
 					true,
+					nil,
+					0,
+					nil,
+					false,
+					"",
+					"",
+					0,
+					nil,
+					nil,
+					false,
+					false,
 				).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
 			mock.ExpectCommit()
 
@@ -100,6 +122,17 @@ var _ = Describe("Initialize DB entities", func() {
 				"admin",
 				sqlmock.AnyArg(),
 				true,
+				nil,
+				0,
+				nil,
+				false,
+				"",
+				"",
+				0,
+				nil,
+				nil,
+				false,
+				false,
 			).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
 		mock.ExpectCommit()
 
@@ -111,6 +144,8 @@ var _ = Describe("Initialize DB entities", func() {
 			WithArgs(
 				sqlmock.AnyArg(),
 				sqlmock.AnyArg(),
+				sqlmock.AnyArg(),
+				sqlmock.AnyArg(),
 			).WillReturnError(errors.New("connection is already closed"))
 		mock.ExpectRollback()
 
@@ -133,6 +168,17 @@ var _ = Describe("Initialize DB entities", func() {
 				"admin",
 				sqlmock.AnyArg(),
 				true,
+				nil,
+				0,
+				nil,
+				false,
+				"",
+				"",
+				0,
+				nil,
+				nil,
+				false,
+				false,
 			).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
 		mock.ExpectCommit()
 		var err error
@@ -146,11 +192,32 @@ var _ = Describe("Initialize DB entities", func() {
 				WithArgs(
 					sqlmock.AnyArg(),
 					sqlmock.AnyArg(),
+					sqlmock.AnyArg(),
+					sqlmock.AnyArg(),
 				).WillReturnResult(sqlmock.NewResult(1, 1))
 			mock.ExpectCommit()
 		}
 		err = InitDBEntities(mockLog, db)
 		Expect(err).To(BeNil())
+		Expect(mock.ExpectationsWereMet()).To(BeNil())
+	})
+	It("Default roles configured by earlier releases are granted permissions", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user"`)).WillReturnRows(sqlmock.NewRows([]string{"Count"}).AddRow(1))
+		for range defaultRoles() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role"`)).WillReturnRows(sqlmock.NewRows([]string{"Count"}).AddRow(1))
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user_role" SET "permissions"=$1 WHERE name = $2 AND permissions IS NULL`)).
+				WithArgs(
+					sqlmock.AnyArg(),
+					sqlmock.AnyArg(),
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+		err := InitDBEntities(mockLog, db)
+		Expect(err).To(BeNil())
+		Expect(mock.ExpectationsWereMet()).To(BeNil())
 	})
 
 })
@@ -13,6 +13,7 @@ require (
 	github.com/sabariarunkumar/go-postgresql-init v1.0.0
 	go.uber.org/zap v1.27.0
 	golang.org/x/crypto v0.26.0
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/postgres v1.5.9
 	gorm.io/gorm v1.25.11
 )
@@ -62,5 +63,4 @@ require (
 	golang.org/x/sys v0.23.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+	appErrors "userservice/internal/errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Access Token Tests", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		mockDb *sql.DB
+		store  *AccessTokenStore
+	)
+	tokenColumns := []string{"id", "user_id", "name", "prefix", "token_hash", "scopes", "expires_at", "last_used_at"}
+	BeforeEach(func() {
+		mockDb, mock, _ = sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ := gorm.Open(dialector)
+		store = NewAccessTokenStore(db, zap.NewExample().Sugar())
+	})
+
+	It("generate access token", func() {
+		token, displayPrefix, err := GenerateAccessToken()
+		Expect(err).To(BeNil())
+		Expect(IsAccessToken(token)).To(BeTrue())
+		Expect(token).To(HavePrefix(displayPrefix))
+		Expect(displayPrefix).To(HaveLen(12))
+	})
+	It("JWT is not an access token", func() {
+		Expect(IsAccessToken("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.e30.sig")).To(BeFalse())
+	})
+
+	Context("Authenticate access token", func() {
+		It("DB error while fetching token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token" WHERE ` +
+				`(token_hash = $1 AND expires_at > $2)`)).
+				WillReturnError(errors.New("connection is already closed"))
+			_, _, err := store.AuthenticateAccessToken("pat_xyz")
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Unknown or expired token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token"`)).
+				WithArgs(HashOpaqueToken("pat_xyz"), sqlmock.AnyArg(), 1).
+				WillReturnRows(sqlmock.NewRows(tokenColumns))
+			_, _, err := store.AuthenticateAccessToken("pat_xyz")
+			Expect(err).To(MatchError(gorm.ErrRecordNotFound))
+		})
+		It("Owner no longer exists", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token"`)).
+				WillReturnRows(sqlmock.NewRows(tokenColumns).
+					AddRow(1, 7, "ci", "pat_abcdefgh", "hash", `["service:read"]`, time.Now().Add(time.Hour), nil))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, _, err := store.AuthenticateAccessToken("pat_xyz")
+			Expect(err).To(MatchError(gorm.ErrRecordNotFound))
+		})
+		It("Owner is disabled", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token"`)).
+				WillReturnRows(sqlmock.NewRows(tokenColumns).
+					AddRow(1, 7, "ci", "pat_abcdefgh", "hash", `["service:read"]`, time.Now().Add(time.Hour), nil))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "disabled"}).AddRow(7, true))
+			_, _, err := store.AuthenticateAccessToken("pat_xyz")
+			Expect(err).To(MatchError(gorm.ErrRecordNotFound))
+		})
+		It("Valid token records its usage", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token"`)).
+				WillReturnRows(sqlmock.NewRows(tokenColumns).
+					AddRow(1, 7, "ci", "pat_abcdefgh", "hash", `["service:read"]`, time.Now().Add(time.Hour), nil))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(7, "ci@mgmtportal.com", "advanced"))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "personal_access_token" SET "last_used_at"=$1`)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			accessToken, owner, err := store.AuthenticateAccessToken("pat_xyz")
+			Expect(err).To(BeNil())
+			Expect(accessToken.Scopes).To(Equal([]string{"service:read"}))
+			Expect(owner.Role).To(Equal("advanced"))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Recent usage isn't recorded again", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token"`)).
+				WillReturnRows(sqlmock.NewRows(tokenColumns).
+					AddRow(1, 7, "ci", "pat_abcdefgh", "hash", `["service:read"]`, time.Now().Add(time.Hour), time.Now()))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(7, "ci@mgmtportal.com", "advanced"))
+			_, _, err := store.AuthenticateAccessToken("pat_xyz")
+			Expect(err).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ImpersonationAuditStore persists audit trail of requests made under impersonation.
+type ImpersonationAuditStore struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewImpersonationAuditStore initializes impersonation audit store.
+func NewImpersonationAuditStore(db *gorm.DB, log *zap.SugaredLogger) *ImpersonationAuditStore {
+	return &ImpersonationAuditStore{db: db, log: log}
+}
+
+// RecordImpersonatedRequest persists audit record of the request.
+func (s *ImpersonationAuditStore) RecordImpersonatedRequest(audit *models.ImpersonationAudit) error {
+	if err := s.db.Create(audit).Error; err != nil {
+		s.log.Errorf("Failed to audit request of user %d impersonating user %d: %v", audit.ActorID, audit.UserID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"errors"
+	"regexp"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Impersonation Audit Tests", func() {
+	var (
+		mock  sqlmock.Sqlmock
+		store *ImpersonationAuditStore
+	)
+	BeforeEach(func() {
+		mockDb, sqlMock, _ := sqlmock.New()
+		mock = sqlMock
+		db, _ := gorm.Open(postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		}))
+		store = NewImpersonationAuditStore(db, zap.NewExample().Sugar())
+	})
+
+	It("record impersonated request", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "impersonation_audit"`)).
+			WithArgs(sqlmock.AnyArg(), 1, 7, "jti", "GET", "/api/v1/services", "10.0.0.7").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+		audit := &models.ImpersonationAudit{ActorID: 1, UserID: 7, TokenID: "jti", Method: "GET",
+			Path: "/api/v1/services", IP: "10.0.0.7"}
+		Expect(store.RecordImpersonatedRequest(audit)).To(BeNil())
+		Expect(audit.ID).To(Equal(uint(3)))
+	})
+	It("DB error while recording", func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "impersonation_audit"`)).
+			WillReturnError(errors.New("connection is already closed"))
+		mock.ExpectRollback()
+		err := store.RecordImpersonatedRequest(&models.ImpersonationAudit{ActorID: 1, UserID: 7})
+		Expect(err).To(MatchError(appErrors.ErrInternal))
+	})
+})
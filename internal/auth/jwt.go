@@ -1,7 +1,11 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,33 +18,170 @@ const (
 	JWTClaimRole = "role"
 	// JWTClaimExpiresAt expiresAt
 	JWTClaimExpiresAt = "exp"
+	// JWTClaimIssuedAt issuedAt
+	JWTClaimIssuedAt = "iat"
+	// JWTClaimNotBefore notBefore
+	JWTClaimNotBefore = "nbf"
+	// JWTClaimID unique token identifier, facilitates revocation of individual token
+	JWTClaimID = "jti"
+	// JWTClaimSubject user ID, the identity token is issued to
+	JWTClaimSubject = "sub"
+	// JWTClaimIssuer issuer
+	JWTClaimIssuer = "iss"
+	// JWTClaimAudience audience
+	JWTClaimAudience = "aud"
+	// JWTClaimMFAEnrollmentRequired user has to enroll MFA, before accessing anything else
+	JWTClaimMFAEnrollmentRequired = "mfa_enrollment_required"
+	// JWTClaimPasswordChangeRequired user has to replace temporary password, before accessing anything else
+	JWTClaimPasswordChangeRequired = "password_change_required"
+	// JWTClaimSessionID session ID, the login token is issued within
+	JWTClaimSessionID = "sid"
+	// JWTClaimActor actor, the admin user impersonating the subject
+	JWTClaimActor = "act"
+
+	// ContextKeyServiceAccount holds the service account ID, for requests authenticated as service account
+	ContextKeyServiceAccount = "service_account"
+	// serviceAccountSubjectPrefix distinguishes service account subjects from user subjects
+	serviceAccountSubjectPrefix = "service-account:"
+	// sessionSubjectPrefix distinguishes pseudo subjects of sessions, tokens of which are revoked along with session
+	sessionSubjectPrefix = "session:"
+	// mfaChallengeAudienceSuffix distinguishes audience of MFA challenge tokens from the one of access tokens
+	mfaChallengeAudienceSuffix = "/mfa"
 )
 
-// As of now, we don't implement token refresh, if product really requires
-// and the security risks like Refresh Token Theft, Token Replay Attack
-// Extended Exposure needs to be evaluated
-
-// CreateJWT creates jwt with secret and  necessary claims
-func CreateJWT(secret []byte, expirationInSec int64, email string, userRole string) (*string, error) {
-	expiration := time.Second * time.Duration(expirationInSec)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		JWTClaimEmail:     email,
-		JWTClaimRole:      userRole,
-		JWTClaimExpiresAt: time.Now().Add(expiration).Unix(),
-	})
+// Access tokens are kept short-lived, longer sessions are facilitated by
+// opaque refresh tokens which are rotated on every use.
+
+// TokenIssuer issues and validates tokens on behalf of configured issuer for configured audience.
+type TokenIssuer struct {
+	keys       *KeySet
+	issuer     string
+	audience   string
+	expiration time.Duration
+	leeway     time.Duration
+}
+
+// TokenSubject represents the user or service account whom token is issued to.
+type TokenSubject struct {
+	UserID           uint
+	ServiceAccountID uint
+	Email            string
+	Role             string
+	// MFAEnrollmentRequired restricts the token to MFA enrollment, as user's role demands MFA
+	MFAEnrollmentRequired bool
+	// PasswordChangeRequired restricts the token to password change, as user is yet to replace temporary password
+	PasswordChangeRequired bool
+	// SessionID is the login of user the token is issued within, zero for service accounts
+	SessionID uint
+	// NotAfter caps the expiration of the token, e.g. to the end of the role elevation it carries; zero if uncapped
+	NotAfter time.Time
+}
+
+// subject formats identity of the token subject.
+func (s TokenSubject) subject() string {
+	if s.ServiceAccountID != 0 {
+		return SubjectFromServiceAccountID(s.ServiceAccountID)
+	}
+	return SubjectFromUserID(s.UserID)
+}
 
-	tokenString, err := token.SignedString(secret)
+// NewTokenIssuer initializes token issuer.
+// Leeway tolerates clock skew across the hosts while validating time based claims.
+func NewTokenIssuer(keys *KeySet, issuer, audience string, expirationInSec, leewayInSec int64) *TokenIssuer {
+	return &TokenIssuer{
+		keys:       keys,
+		issuer:     issuer,
+		audience:   audience,
+		expiration: time.Second * time.Duration(expirationInSec),
+		leeway:     time.Second * time.Duration(leewayInSec),
+	}
+}
+
+// Expiration is the lifetime of issued tokens.
+func (i *TokenIssuer) Expiration() time.Duration {
+	return i.expiration
+}
+
+// CreateJWT creates jwt signed with the signing key of key set and necessary claims
+func (i *TokenIssuer) CreateJWT(subject TokenSubject) (*string, error) {
+	claims, err := i.claims(subject, i.expiration)
+	if err != nil {
+		return nil, err
+	}
+	tokenString, err := i.keys.Sign(claims)
 	if err != nil {
 		return nil, err
 	}
 	return &tokenString, err
 }
 
-// ValidateJWT validates received token against secret
-func ValidateJWT(secret []byte, tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
+// CreateImpersonationJWT creates jwt for the subject, which the actor user impersonates. Actor is carried in act
+// claim as per RFC 8693. Expiration is capped by the lifetime of access tokens, since revocations are retained
+// only that long, and the effective expiration is returned along.
+func (i *TokenIssuer) CreateImpersonationJWT(subject TokenSubject, actorUserID uint,
+	expiration time.Duration) (string, time.Duration, error) {
+
+	expiration = min(expiration, i.expiration)
+	claims, err := i.claims(subject, expiration)
+	if err != nil {
+		return "", 0, err
+	}
+	claims[JWTClaimActor] = map[string]interface{}{JWTClaimSubject: SubjectFromUserID(actorUserID)}
+	tokenString, err := i.keys.Sign(claims)
+	if err != nil {
+		return "", 0, err
+	}
+	return tokenString, expiration, nil
+}
+
+// claims builds claims of access token for the subject.
+func (i *TokenIssuer) claims(subject TokenSubject, expiration time.Duration) (jwt.MapClaims, error) {
+	tokenID, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	issuedAt := time.Now()
+	expiresAt := issuedAt.Add(expiration)
+	if !subject.NotAfter.IsZero() && subject.NotAfter.Before(expiresAt) {
+		expiresAt = subject.NotAfter
+	}
+	claims := jwt.MapClaims{
+		JWTClaimIssuer:    i.issuer,
+		JWTClaimAudience:  i.audience,
+		JWTClaimSubject:   subject.subject(),
+		JWTClaimRole:      subject.Role,
+		JWTClaimID:        tokenID,
+		JWTClaimIssuedAt:  preciseNumericDate(issuedAt),
+		JWTClaimNotBefore: issuedAt.Unix(),
+		JWTClaimExpiresAt: expiresAt.Unix(),
+	}
+	// service accounts aren't associated with email
+	if subject.Email != "" {
+		claims[JWTClaimEmail] = subject.Email
+	}
+	if subject.MFAEnrollmentRequired {
+		claims[JWTClaimMFAEnrollmentRequired] = true
+	}
+	if subject.PasswordChangeRequired {
+		claims[JWTClaimPasswordChangeRequired] = true
+	}
+	if subject.SessionID != 0 {
+		claims[JWTClaimSessionID] = strconv.FormatUint(uint64(subject.SessionID), 10)
+	}
+	return claims, nil
+}
+
+// ValidateJWT validates received token against verification keys of key set.
+// Token is accepted only if it is signed with the algorithm bound to the key referred by its kid header,
+// is issued by configured issuer for configured audience and is within its validity window.
+func (i *TokenIssuer) ValidateJWT(tokenString string) (*jwt.Token, error) {
+	token, err := jwt.Parse(tokenString, i.keys.Keyfunc,
+		jwt.WithValidMethods(i.keys.ValidMethods()),
+		jwt.WithIssuer(i.issuer),
+		jwt.WithAudience(i.audience),
+		jwt.WithLeeway(i.leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired())
 	if err != nil {
 		return nil, err
 	}
@@ -50,3 +191,120 @@ func ValidateJWT(secret []byte, tokenString string) (*jwt.Token, error) {
 
 	return token, nil
 }
+
+// CreateMFAChallenge creates short-lived token, proving that the user has presented valid password.
+// It is only exchangeable for a token pair along with the second factor, and is issued for a distinct
+// audience such that it isn't accepted as access token.
+func (i *TokenIssuer) CreateMFAChallenge(userID uint, expiration time.Duration) (string, error) {
+	tokenID, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	issuedAt := time.Now()
+	return i.keys.Sign(jwt.MapClaims{
+		JWTClaimIssuer:    i.issuer,
+		JWTClaimAudience:  i.audience + mfaChallengeAudienceSuffix,
+		JWTClaimSubject:   SubjectFromUserID(userID),
+		JWTClaimID:        tokenID,
+		JWTClaimIssuedAt:  issuedAt.Unix(),
+		JWTClaimNotBefore: issuedAt.Unix(),
+		JWTClaimExpiresAt: issuedAt.Add(expiration).Unix(),
+	})
+}
+
+// ValidateMFAChallenge validates MFA challenge token, and returns ID of the user it is issued to.
+func (i *TokenIssuer) ValidateMFAChallenge(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, i.keys.Keyfunc,
+		jwt.WithValidMethods(i.keys.ValidMethods()),
+		jwt.WithIssuer(i.issuer),
+		jwt.WithAudience(i.audience+mfaChallengeAudienceSuffix),
+		jwt.WithLeeway(i.leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, err
+	}
+	subject, err := token.Claims.GetSubject()
+	if err != nil {
+		return 0, err
+	}
+	return UserIDFromSubject(subject)
+}
+
+// SubjectFromUserID formats user ID as token subject.
+func SubjectFromUserID(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// UserIDFromSubject parses user ID from token subject.
+func UserIDFromSubject(subject string) (uint, error) {
+	userID, err := strconv.ParseUint(subject, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, fmt.Errorf("invalid token subject %q", subject)
+	}
+	return uint(userID), nil
+}
+
+// SubjectFromServiceAccountID formats service account ID as token subject.
+func SubjectFromServiceAccountID(serviceAccountID uint) string {
+	return serviceAccountSubjectPrefix + strconv.FormatUint(uint64(serviceAccountID), 10)
+}
+
+// ServiceAccountIDFromSubject parses service account ID from token subject.
+func ServiceAccountIDFromSubject(subject string) (uint, error) {
+	if !strings.HasPrefix(subject, serviceAccountSubjectPrefix) {
+		return 0, fmt.Errorf("token subject %q isn't a service account", subject)
+	}
+	return UserIDFromSubject(strings.TrimPrefix(subject, serviceAccountSubjectPrefix))
+}
+
+// ActorUserIDFromClaim parses user ID of the actor from act claim.
+func ActorUserIDFromClaim(claim interface{}) (uint, error) {
+	actor, ok := claim.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("invalid actor claim %v", claim)
+	}
+	subject, _ := actor[JWTClaimSubject].(string)
+	return UserIDFromSubject(subject)
+}
+
+// SubjectFromSessionID formats session ID as pseudo subject, revoking which revokes the tokens of session.
+func SubjectFromSessionID(sessionID uint) string {
+	return sessionSubjectPrefix + strconv.FormatUint(uint64(sessionID), 10)
+}
+
+// preciseNumericDate represents the time as seconds since epoch along with the microseconds, such that access tokens
+// issued within the same second as a subject revocation are told apart from the revocation.
+func preciseNumericDate(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// IssuedAtFromClaims parses issued-at claim of access token along with its fraction of second, which
+// jwt.MapClaims.GetIssuedAt truncates to seconds.
+func IssuedAtFromClaims(claims jwt.MapClaims) (time.Time, error) {
+	var issuedAt float64
+	switch claim := claims[JWTClaimIssuedAt].(type) {
+	case float64:
+		issuedAt = claim
+	case json.Number:
+		var err error
+		if issuedAt, err = claim.Float64(); err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s claim: %v", JWTClaimIssuedAt, err)
+		}
+	default:
+		return time.Time{}, fmt.Errorf("%s claim is missing or invalid", JWTClaimIssuedAt)
+	}
+	if issuedAt <= 0 {
+		return time.Time{}, fmt.Errorf("%s claim is missing or invalid", JWTClaimIssuedAt)
+	}
+	return time.UnixMicro(int64(math.Round(issuedAt * 1e6))), nil
+}
+
+// SessionIDFromClaim parses session ID from session ID claim.
+func SessionIDFromClaim(claim string) (uint, error) {
+	sessionID, err := strconv.ParseUint(claim, 10, 64)
+	if err != nil || sessionID == 0 {
+		return 0, fmt.Errorf("invalid session ID %q", claim)
+	}
+	return uint(sessionID), nil
+}
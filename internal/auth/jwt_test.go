@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -8,25 +11,107 @@ import (
 var _ = Describe("JWT Tests", func() {
 
 	var (
-		token  *string
-		secret = []byte("secret")
+		token   *string
+		secret  = []byte("secret")
+		keys    = NewHMACKeySet(secret)
+		issuer  = NewTokenIssuer(keys, "userservice", "mgmtportal", 55, 5)
+		subject = TokenSubject{UserID: 7, Email: "test@gmail.com", Role: "basic"}
 	)
+	// signClaims signs registered claims which are valid unless overridden.
+	signClaims := func(overrides jwt.MapClaims) string {
+		now := time.Now()
+		claims := jwt.MapClaims{
+			JWTClaimIssuer:    "userservice",
+			JWTClaimAudience:  "mgmtportal",
+			JWTClaimSubject:   "7",
+			JWTClaimIssuedAt:  now.Unix(),
+			JWTClaimNotBefore: now.Unix(),
+			JWTClaimExpiresAt: now.Add(time.Minute).Unix(),
+		}
+		for claim, value := range overrides {
+			if value == nil {
+				delete(claims, claim)
+				continue
+			}
+			claims[claim] = value
+		}
+		tokenString, _ := keys.Sign(claims)
+		return tokenString
+	}
+
 	It("create JWT", func() {
 		var err error
-		token, err = CreateJWT(secret, 55, "test@gmail.com", "basic")
+		token, err = issuer.CreateJWT(subject)
 		Expect(err).To(BeNil())
 		Expect(*token).To(Not(BeEmpty()))
 	})
 
 	Context("validate JWT", func() {
 		It("validate against recently issued token ", func() {
-			recvToken, err := ValidateJWT(secret, *token)
+			recvToken, err := issuer.ValidateJWT(*token)
 			Expect(err).To(BeNil())
 			Expect(recvToken).To(Not(BeNil()))
 			Expect(recvToken.Valid).To(BeTrue())
+			claims := recvToken.Claims.(jwt.MapClaims)
+			Expect(claims[JWTClaimSubject]).To(Equal("7"))
+			Expect(claims[JWTClaimIssuer]).To(Equal("userservice"))
+			Expect(claims[JWTClaimAudience]).To(Equal("mgmtportal"))
+			Expect(claims[JWTClaimNotBefore]).To(Not(BeNil()))
 		})
 		It("validate against empty token ", func() {
-			_, err := ValidateJWT(secret, "")
+			_, err := issuer.ValidateJWT("")
+			Expect(err).To(Not(BeNil()))
+		})
+		It("validate against token of other issuer", func() {
+			_, err := issuer.ValidateJWT(signClaims(jwt.MapClaims{JWTClaimIssuer: "other"}))
+			Expect(err).To(MatchError(jwt.ErrTokenInvalidIssuer))
+		})
+		It("validate against token without issuer", func() {
+			_, err := issuer.ValidateJWT(signClaims(jwt.MapClaims{JWTClaimIssuer: nil}))
+			Expect(err).To(MatchError(jwt.ErrTokenRequiredClaimMissing))
+		})
+		It("validate against token for other audience", func() {
+			_, err := issuer.ValidateJWT(signClaims(jwt.MapClaims{JWTClaimAudience: "other"}))
+			Expect(err).To(MatchError(jwt.ErrTokenInvalidAudience))
+		})
+		It("validate against token without expiry", func() {
+			_, err := issuer.ValidateJWT(signClaims(jwt.MapClaims{JWTClaimExpiresAt: nil}))
+			Expect(err).To(MatchError(jwt.ErrTokenRequiredClaimMissing))
+		})
+		It("validate against token not valid yet", func() {
+			_, err := issuer.ValidateJWT(signClaims(jwt.MapClaims{JWTClaimNotBefore: time.Now().Add(time.Minute).Unix()}))
+			Expect(err).To(MatchError(jwt.ErrTokenNotValidYet))
+		})
+		It("validate against token issued in future", func() {
+			_, err := issuer.ValidateJWT(signClaims(jwt.MapClaims{JWTClaimIssuedAt: time.Now().Add(time.Minute).Unix()}))
+			Expect(err).To(MatchError(jwt.ErrTokenUsedBeforeIssued))
+		})
+		It("clock skew within leeway is tolerated", func() {
+			skewed := time.Now().Add(3 * time.Second).Unix()
+			_, err := issuer.ValidateJWT(signClaims(jwt.MapClaims{JWTClaimIssuedAt: skewed, JWTClaimNotBefore: skewed}))
+			Expect(err).To(BeNil())
+		})
+		It("validate against token signed with unexpected method", func() {
+			tokenString, _ := jwt.NewWithClaims(jwt.SigningMethodHS384, jwt.MapClaims{
+				JWTClaimIssuer: "userservice", JWTClaimAudience: "mgmtportal",
+				JWTClaimExpiresAt: time.Now().Add(time.Minute).Unix(),
+			}).SignedString(secret)
+			_, err := issuer.ValidateJWT(tokenString)
+			Expect(err).To(MatchError(jwt.ErrTokenSignatureInvalid))
+		})
+		It("issued-at claim keeps fraction of second", func() {
+			before := time.Now().Truncate(time.Microsecond)
+			token, err := issuer.CreateJWT(subject)
+			Expect(err).To(BeNil())
+			recvToken, err := issuer.ValidateJWT(*token)
+			Expect(err).To(BeNil())
+			issuedAt, err := IssuedAtFromClaims(recvToken.Claims.(jwt.MapClaims))
+			Expect(err).To(BeNil())
+			Expect(issuedAt).To(BeTemporally(">=", before))
+			Expect(issuedAt).To(BeTemporally("<=", time.Now()))
+		})
+		It("missing issued-at claim is rejected", func() {
+			_, err := IssuedAtFromClaims(jwt.MapClaims{})
 			Expect(err).To(Not(BeNil()))
 		})
 	})
@@ -35,8 +120,121 @@ var _ = Describe("JWT Tests", func() {
 		token := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJlbWFpbCI6ImFkbWluQGtvbmcuY" +
 			"29tIiwiZXhwIjoxNzIzNDY4NTgxLCJyb2xlIjoiYWRtaW4ifQ._FC85LCw0nGviDhK0EAmTWneTWUFQBJC41ivdpnH5b4"
 		It("validate against empty token ", func() {
-			_, err := ValidateJWT(secret, token)
+			_, err := issuer.ValidateJWT(token)
+			Expect(err).To(Not(BeNil()))
+		})
+	})
+	Context("token subject", func() {
+		It("user ID round trips through subject", func() {
+			userID, err := UserIDFromSubject(SubjectFromUserID(42))
+			Expect(err).To(BeNil())
+			Expect(userID).To(Equal(uint(42)))
+		})
+		It("non numerical subject is rejected", func() {
+			_, err := UserIDFromSubject("admin@mgmtportal.com")
+			Expect(err).To(Not(BeNil()))
+		})
+		It("service account ID round trips through subject", func() {
+			serviceAccountID, err := ServiceAccountIDFromSubject(SubjectFromServiceAccountID(3))
+			Expect(err).To(BeNil())
+			Expect(serviceAccountID).To(Equal(uint(3)))
+			_, err = UserIDFromSubject(SubjectFromServiceAccountID(3))
+			Expect(err).To(Not(BeNil()))
+		})
+		It("user subject isn't a service account", func() {
+			_, err := ServiceAccountIDFromSubject(SubjectFromUserID(3))
+			Expect(err).To(Not(BeNil()))
+		})
+		It("service account token carries no email", func() {
+			token, err := issuer.CreateJWT(TokenSubject{ServiceAccountID: 3, Role: "advanced"})
+			Expect(err).To(BeNil())
+			recvToken, err := issuer.ValidateJWT(*token)
+			Expect(err).To(BeNil())
+			claims := recvToken.Claims.(jwt.MapClaims)
+			Expect(claims[JWTClaimSubject]).To(Equal("service-account:3"))
+			Expect(claims).To(Not(HaveKey(JWTClaimEmail)))
+			Expect(claims).To(Not(HaveKey(JWTClaimSessionID)))
+		})
+		It("session ID round trips through claim", func() {
+			token, err := issuer.CreateJWT(TokenSubject{UserID: 7, Email: "test@gmail.com", Role: "admin",
+				SessionID: 12})
+			Expect(err).To(BeNil())
+			recvToken, err := issuer.ValidateJWT(*token)
+			Expect(err).To(BeNil())
+			sessionID, err := SessionIDFromClaim(recvToken.Claims.(jwt.MapClaims)[JWTClaimSessionID].(string))
+			Expect(err).To(BeNil())
+			Expect(sessionID).To(Equal(uint(12)))
+			Expect(SubjectFromSessionID(12)).To(Equal("session:12"))
+			_, err = UserIDFromSubject(SubjectFromSessionID(12))
+			Expect(err).To(Not(BeNil()))
+		})
+		It("token doesn't outlive the cap on its expiration", func() {
+			notAfter := time.Now().Add(30 * time.Second).Truncate(time.Second)
+			token, err := issuer.CreateJWT(TokenSubject{UserID: 7, Email: "test@gmail.com", Role: "admin",
+				NotAfter: notAfter})
+			Expect(err).To(BeNil())
+			recvToken, err := issuer.ValidateJWT(*token)
+			Expect(err).To(BeNil())
+			expiresAt, err := recvToken.Claims.GetExpirationTime()
+			Expect(err).To(BeNil())
+			Expect(expiresAt.Time).To(BeTemporally("==", notAfter))
+		})
+		It("impersonation token carries the actor, and doesn't outlive access tokens", func() {
+			token, expiration, err := issuer.CreateImpersonationJWT(subject, 1, time.Hour)
+			Expect(err).To(BeNil())
+			Expect(expiration).To(Equal(issuer.Expiration()))
+			recvToken, err := issuer.ValidateJWT(token)
+			Expect(err).To(BeNil())
+			claims := recvToken.Claims.(jwt.MapClaims)
+			Expect(claims[JWTClaimSubject]).To(Equal("7"))
+			actorUserID, err := ActorUserIDFromClaim(claims[JWTClaimActor])
+			Expect(err).To(BeNil())
+			Expect(actorUserID).To(Equal(uint(1)))
+			_, err = ActorUserIDFromClaim("1")
 			Expect(err).To(Not(BeNil()))
 		})
 	})
+	Context("MFA", func() {
+		It("token demanding MFA enrollment carries the claim", func() {
+			token, err := issuer.CreateJWT(TokenSubject{UserID: 7, Email: "test@gmail.com", Role: "admin",
+				MFAEnrollmentRequired: true})
+			Expect(err).To(BeNil())
+			recvToken, err := issuer.ValidateJWT(*token)
+			Expect(err).To(BeNil())
+			Expect(recvToken.Claims.(jwt.MapClaims)[JWTClaimMFAEnrollmentRequired]).To(Equal(true))
+		})
+		It("token demanding password change carries the claim", func() {
+			token, err := issuer.CreateJWT(TokenSubject{UserID: 7, Email: "test@gmail.com", Role: "admin",
+				PasswordChangeRequired: true})
+			Expect(err).To(BeNil())
+			recvToken, err := issuer.ValidateJWT(*token)
+			Expect(err).To(BeNil())
+			Expect(recvToken.Claims.(jwt.MapClaims)[JWTClaimPasswordChangeRequired]).To(Equal(true))
+
+			token, _ = issuer.CreateJWT(TokenSubject{UserID: 7, Email: "test@gmail.com", Role: "admin"})
+			recvToken, _ = issuer.ValidateJWT(*token)
+			Expect(recvToken.Claims.(jwt.MapClaims)).To(Not(HaveKey(JWTClaimPasswordChangeRequired)))
+		})
+		It("MFA challenge round trips user ID", func() {
+			challenge, err := issuer.CreateMFAChallenge(7, time.Minute)
+			Expect(err).To(BeNil())
+			userID, err := issuer.ValidateMFAChallenge(challenge)
+			Expect(err).To(BeNil())
+			Expect(userID).To(Equal(uint(7)))
+		})
+		It("MFA challenge isn't accepted as access token", func() {
+			challenge, _ := issuer.CreateMFAChallenge(7, time.Minute)
+			_, err := issuer.ValidateJWT(challenge)
+			Expect(err).To(MatchError(jwt.ErrTokenInvalidAudience))
+		})
+		It("access token isn't accepted as MFA challenge", func() {
+			_, err := issuer.ValidateMFAChallenge(*token)
+			Expect(err).To(MatchError(jwt.ErrTokenInvalidAudience))
+		})
+		It("expired MFA challenge is rejected", func() {
+			challenge, _ := issuer.CreateMFAChallenge(7, -time.Minute)
+			_, err := issuer.ValidateMFAChallenge(challenge)
+			Expect(err).To(MatchError(jwt.ErrTokenExpired))
+		})
+	})
 })
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// JWTHeaderKeyID identifies the key token is signed with
+	JWTHeaderKeyID = "kid"
+
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// verificationKey binds the key with the only signing method it is accepted for,
+// such that token can't choose how it gets verified.
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// KeySet holds the key tokens are signed with, and the keys accepted while verifying tokens.
+// During rotation, public keys of the former signing keys are kept as verification keys,
+// until the tokens signed by them expire.
+type KeySet struct {
+	signingKeyID     string
+	signingMethod    jwt.SigningMethod
+	signingKey       interface{}
+	verificationKeys map[string]verificationKey
+}
+
+// JWK represents public key in JSON Web Key format(RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKSet represents set of public keys in JSON Web Key Set format.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeySet initializes key set with shared secret.
+// Tokens are signed without key id, as the secret is never published.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{
+		signingMethod: jwt.SigningMethodHS256,
+		signingKey:    secret,
+		verificationKeys: map[string]verificationKey{
+			"": {method: jwt.SigningMethodHS256, key: secret},
+		},
+	}
+}
+
+// LoadKeySet initializes key set for the configured algorithm.
+// For HS256 shared secret is used, for asymmetric algorithms signing key is loaded from PEM encoded private key file
+// and additional verification keys are loaded from PEM encoded public key files.
+func LoadKeySet(algorithm string, secret []byte, signingKeyFile string,
+	verificationKeyFiles []string) (*KeySet, error) {
+
+	if algorithm == AlgorithmHS256 {
+		if len(verificationKeyFiles) != 0 {
+			return nil, fmt.Errorf("verification keys are not applicable for %s", AlgorithmHS256)
+		}
+		return NewHMACKeySet(secret), nil
+	}
+	if signingKeyFile == "" {
+		return nil, fmt.Errorf("signing key file is mandatory for %s", algorithm)
+	}
+
+	pemBytes, err := os.ReadFile(signingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signing key file: %+v", err)
+	}
+	method, privateKey, publicKey, err := parsePrivateKey(algorithm, pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key in %s: %+v", signingKeyFile, err)
+	}
+	signingKeyID, err := deriveKeyID(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	keySet := &KeySet{
+		signingKeyID:  signingKeyID,
+		signingMethod: method,
+		signingKey:    privateKey,
+		verificationKeys: map[string]verificationKey{
+			signingKeyID: {method: method, key: publicKey},
+		},
+	}
+
+	for _, verificationKeyFile := range verificationKeyFiles {
+		pemBytes, err := os.ReadFile(verificationKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read verification key file: %+v", err)
+		}
+		method, publicKey, err := parsePublicKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid verification key in %s: %+v", verificationKeyFile, err)
+		}
+		keyID, err := deriveKeyID(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		keySet.verificationKeys[keyID] = verificationKey{method: method, key: publicKey}
+	}
+	return keySet, nil
+}
+
+// parsePrivateKey parses private key expected by the algorithm along with its public key.
+func parsePrivateKey(algorithm string, pemBytes []byte) (jwt.SigningMethod, interface{}, crypto.PublicKey, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return jwt.SigningMethodRS256, privateKey, privateKey.Public(), nil
+	case AlgorithmES256:
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, nil, nil, fmt.Errorf("%s demands P-256 curve", AlgorithmES256)
+		}
+		return jwt.SigningMethodES256, privateKey, privateKey.Public(), nil
+	case AlgorithmEdDSA:
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		edPrivateKey := privateKey.(ed25519.PrivateKey)
+		return jwt.SigningMethodEdDSA, edPrivateKey, edPrivateKey.Public(), nil
+	}
+	return nil, nil, nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+}
+
+// parsePublicKey parses PEM encoded public key or certificate, and infers the algorithm from the key type.
+func parsePublicKey(pemBytes []byte) (jwt.SigningMethod, crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, nil, jwt.ErrKeyMustBePEMEncoded
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		cert, certErr := x509.ParseCertificate(block.Bytes)
+		if certErr != nil {
+			return nil, nil, err
+		}
+		publicKey = cert.PublicKey
+	}
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, key, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, nil, fmt.Errorf("%s demands P-256 curve", AlgorithmES256)
+		}
+		return jwt.SigningMethodES256, key, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, key, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported public key type %T", publicKey)
+}
+
+// deriveKeyID derives stable key id from the public key, such that no separate configuration is needed.
+func deriveKeyID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("unable to derive key id: %+v", err)
+	}
+	digest := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(digest[:])[:16], nil
+}
+
+// Sign signs token with signing key, and sets the key id in header.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	if k.signingKeyID != "" {
+		token.Header[JWTHeaderKeyID] = k.signingKeyID
+	}
+	return token.SignedString(k.signingKey)
+}
+
+// Keyfunc resolves verification key by the key id in header.
+// Token is rejected if its alg header doesn't match the algorithm bound to resolved key.
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header[JWTHeaderKeyID].(string)
+	verificationKey, ok := k.verificationKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	if token.Method.Alg() != verificationKey.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return verificationKey.key, nil
+}
+
+// ValidMethods lists algorithms of every verification key.
+func (k *KeySet) ValidMethods() []string {
+	var methods []string
+	seen := make(map[string]struct{})
+	for _, verificationKey := range k.verificationKeys {
+		if _, ok := seen[verificationKey.method.Alg()]; !ok {
+			seen[verificationKey.method.Alg()] = struct{}{}
+			methods = append(methods, verificationKey.method.Alg())
+		}
+	}
+	return methods
+}
+
+// JWKS publishes public verification keys. Shared secrets are never published.
+func (k *KeySet) JWKS() JWKSet {
+	jwks := JWKSet{Keys: []JWK{}}
+	for keyID, verificationKey := range k.verificationKeys {
+		jwk := JWK{KeyID: keyID, Use: "sig", Algorithm: verificationKey.method.Alg()}
+		switch key := verificationKey.key.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		case *ecdsa.PublicKey:
+			// coordinates are padded to the curve size as demanded by RFC 7518
+			byteLen := (key.Curve.Params().BitSize + 7) / 8
+			jwk.KeyType = "EC"
+			jwk.Curve = key.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, byteLen)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, byteLen)))
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID })
+	return jwks
+}
+
+// NewJWKSKeySet initializes key set verifying tokens of other issuer, with the public keys it publishes.
+// Keys of unsupported type or algorithm, or meant for encryption, are skipped.
+func NewJWKSKeySet(jwks JWKSet) *KeySet {
+	keySet := &KeySet{verificationKeys: make(map[string]verificationKey)}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		method, publicKey, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keySet.verificationKeys[jwk.KeyID] = verificationKey{method: method, key: publicKey}
+	}
+	return keySet
+}
+
+// PublicKey decodes the public key along with the signing method it is meant for.
+// Algorithm is inferred from the key type, if not specified.
+func (j JWK) PublicKey() (jwt.SigningMethod, crypto.PublicKey, error) {
+	var method jwt.SigningMethod
+	var publicKey crypto.PublicKey
+	switch j.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid RSA modulus: %+v", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, nil, fmt.Errorf("invalid RSA exponent")
+		}
+		method = jwt.SigningMethodRS256
+		publicKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		if j.Curve != elliptic.P256().Params().Name {
+			return nil, nil, fmt.Errorf("%s demands P-256 curve", AlgorithmES256)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(j.X)
+		y, errY := base64.RawURLEncoding.DecodeString(j.Y)
+		if errX != nil || errY != nil {
+			return nil, nil, fmt.Errorf("invalid EC coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, nil, fmt.Errorf("EC point is not on P-256 curve")
+		}
+		method = jwt.SigningMethodES256
+		publicKey = key
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if j.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		method = jwt.SigningMethodEdDSA
+		publicKey = ed25519.PublicKey(x)
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %s", j.KeyType)
+	}
+	if j.Algorithm != "" && j.Algorithm != method.Alg() {
+		return nil, nil, fmt.Errorf("unsupported algorithm %s for key type %s", j.Algorithm, j.KeyType)
+	}
+	return method, publicKey, nil
+}
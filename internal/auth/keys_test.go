@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writePrivateKey writes PKCS8 PEM encoded private key and returns the file path.
+func writePrivateKey(dir, name string, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).To(BeNil())
+	path := filepath.Join(dir, name)
+	Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+	return path
+}
+
+// writePublicKey writes PKIX PEM encoded public key and returns the file path.
+func writePublicKey(dir, name string, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).To(BeNil())
+	path := filepath.Join(dir, name)
+	Expect(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)).To(Succeed())
+	return path
+}
+
+var _ = Describe("Key Set Tests", func() {
+
+	var (
+		dir        string
+		rsaKey     *rsa.PrivateKey
+		ecKey      *ecdsa.PrivateKey
+		edKey      ed25519.PrivateKey
+		rsaKeyFile string
+		ecKeyFile  string
+		edKeyFile  string
+	)
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "keys")
+		Expect(err).To(BeNil())
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+		_, edKey, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+		rsaKeyFile = writePrivateKey(dir, "rsa.pem", rsaKey)
+		ecKeyFile = writePrivateKey(dir, "ec.pem", ecKey)
+		edKeyFile = writePrivateKey(dir, "ed.pem", edKey)
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("Load key set", func() {
+		It("HMAC key set", func() {
+			keys, err := LoadKeySet(AlgorithmHS256, []byte("secret"), "", nil)
+			Expect(err).To(BeNil())
+			Expect(keys.JWKS().Keys).To(BeEmpty())
+		})
+		It("HMAC key set doesn't take verification keys", func() {
+			_, err := LoadKeySet(AlgorithmHS256, []byte("secret"), "", []string{"public.pem"})
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Missing signing key file", func() {
+			_, err := LoadKeySet(AlgorithmRS256, nil, "", nil)
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Unreadable signing key file", func() {
+			_, err := LoadKeySet(AlgorithmRS256, nil, filepath.Join(dir, "missing.pem"), nil)
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Unsupported algorithm", func() {
+			_, err := LoadKeySet("PS256", nil, rsaKeyFile, nil)
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Signing key doesn't match algorithm", func() {
+			_, err := LoadKeySet(AlgorithmES256, nil, rsaKeyFile, nil)
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Unsupported curve", func() {
+			p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			_, err := LoadKeySet(AlgorithmES256, nil, writePrivateKey(dir, "p384.pem", p384Key), nil)
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Invalid verification key", func() {
+			invalidFile := filepath.Join(dir, "invalid.pem")
+			Expect(os.WriteFile(invalidFile, []byte("not a key"), 0600)).To(Succeed())
+			_, err := LoadKeySet(AlgorithmRS256, nil, rsaKeyFile, []string{invalidFile})
+			Expect(err).To(Not(BeNil()))
+		})
+	})
+
+	Context("Sign and validate", func() {
+		for _, tc := range []struct {
+			algorithm string
+			keyFile   *string
+		}{
+			{AlgorithmRS256, &rsaKeyFile},
+			{AlgorithmES256, &ecKeyFile},
+			{AlgorithmEdDSA, &edKeyFile},
+		} {
+			tc := tc
+			It(tc.algorithm+" signed token carries kid and is validated", func() {
+				keys, err := LoadKeySet(tc.algorithm, nil, *tc.keyFile, nil)
+				Expect(err).To(BeNil())
+				issuer := NewTokenIssuer(keys, "userservice", "mgmtportal", 55, 0)
+				token, err := issuer.CreateJWT(TokenSubject{UserID: 1, Email: "test@gmail.com", Role: "basic"})
+				Expect(err).To(BeNil())
+				recvToken, err := issuer.ValidateJWT(*token)
+				Expect(err).To(BeNil())
+				Expect(recvToken.Method.Alg()).To(Equal(tc.algorithm))
+				Expect(recvToken.Header[JWTHeaderKeyID]).To(Not(BeEmpty()))
+			})
+		}
+		It("Token signed with former key is validated during rotation", func() {
+			formerKeys, err := LoadKeySet(AlgorithmRS256, nil, rsaKeyFile, nil)
+			Expect(err).To(BeNil())
+			token, _ := formerKeys.Sign(jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+
+			rotatedKeys, err := LoadKeySet(AlgorithmEdDSA, nil, edKeyFile,
+				[]string{writePublicKey(dir, "rsa.pub", &rsaKey.PublicKey)})
+			Expect(err).To(BeNil())
+			_, err = jwt.Parse(token, rotatedKeys.Keyfunc)
+			Expect(err).To(BeNil())
+
+			keysWithoutFormer, _ := LoadKeySet(AlgorithmEdDSA, nil, edKeyFile, nil)
+			_, err = jwt.Parse(token, keysWithoutFormer.Keyfunc)
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Token with unknown kid is rejected", func() {
+			keys, _ := LoadKeySet(AlgorithmRS256, nil, rsaKeyFile, nil)
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+			token.Header[JWTHeaderKeyID] = "unknown"
+			tokenString, _ := token.SignedString(rsaKey)
+			_, err := jwt.Parse(tokenString, keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods()))
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Token using public key as HMAC secret is rejected", func() {
+			keys, _ := LoadKeySet(AlgorithmRS256, nil, rsaKeyFile, nil)
+			publicKeyPEM, _ := os.ReadFile(writePublicKey(dir, "rsa.pub", &rsaKey.PublicKey))
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+			token.Header[JWTHeaderKeyID] = keys.signingKeyID
+			tokenString, _ := token.SignedString(publicKeyPEM)
+			_, err := jwt.Parse(tokenString, keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods()))
+			Expect(err).To(Not(BeNil()))
+		})
+		It("Unsigned token is rejected", func() {
+			keys := NewHMACKeySet([]byte("secret"))
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+			tokenString, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			_, err := jwt.Parse(tokenString, keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods()))
+			Expect(err).To(Not(BeNil()))
+		})
+	})
+
+	Context("JWKS", func() {
+		It("Publishes every verification key", func() {
+			keys, err := LoadKeySet(AlgorithmES256, nil, ecKeyFile, []string{
+				writePublicKey(dir, "rsa.pub", &rsaKey.PublicKey),
+				writePublicKey(dir, "ed.pub", edKey.Public()),
+			})
+			Expect(err).To(BeNil())
+			jwks := keys.JWKS()
+			Expect(jwks.Keys).To(HaveLen(3))
+			keyTypes := map[string]JWK{}
+			for _, jwk := range jwks.Keys {
+				Expect(jwk.Use).To(Equal("sig"))
+				Expect(jwk.KeyID).To(Not(BeEmpty()))
+				keyTypes[jwk.KeyType] = jwk
+			}
+			Expect(keyTypes["RSA"].Algorithm).To(Equal(AlgorithmRS256))
+			Expect(keyTypes["RSA"].E).To(Equal("AQAB"))
+			Expect(keyTypes["EC"].Algorithm).To(Equal(AlgorithmES256))
+			Expect(keyTypes["EC"].Curve).To(Equal("P-256"))
+			Expect(keyTypes["EC"].X).To(HaveLen(43))
+			Expect(keyTypes["EC"].Y).To(HaveLen(43))
+			Expect(keyTypes["OKP"].Algorithm).To(Equal(AlgorithmEdDSA))
+			Expect(keyTypes["OKP"].Curve).To(Equal("Ed25519"))
+			Expect(keyTypes["EC"].KeyID).To(Equal(keys.signingKeyID))
+		})
+		It("Published keys verify tokens of the issuer", func() {
+			keys, _ := LoadKeySet(AlgorithmES256, nil, ecKeyFile, []string{
+				writePublicKey(dir, "rsa.pub", &rsaKey.PublicKey),
+				writePublicKey(dir, "ed.pub", edKey.Public()),
+			})
+			jwksKeys := NewJWKSKeySet(keys.JWKS())
+			Expect(jwksKeys.verificationKeys).To(HaveLen(3))
+			Expect(jwksKeys.ValidMethods()).To(ConsistOf(AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA))
+			token, _ := keys.Sign(jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix()})
+			_, err := jwt.Parse(token, jwksKeys.Keyfunc, jwt.WithValidMethods(jwksKeys.ValidMethods()))
+			Expect(err).To(BeNil())
+		})
+		It("Keys unfit for verification are skipped", func() {
+			keys, _ := LoadKeySet(AlgorithmRS256, nil, rsaKeyFile, nil)
+			jwk := keys.JWKS().Keys[0]
+			encryptionKey, unknownAlgorithm, invalidExponent := jwk, jwk, jwk
+			encryptionKey.KeyID, encryptionKey.Use = "enc", "enc"
+			unknownAlgorithm.KeyID, unknownAlgorithm.Algorithm = "alg", "PS256"
+			invalidExponent.KeyID, invalidExponent.E = "exp", "!"
+			jwksKeys := NewJWKSKeySet(JWKSet{Keys: []JWK{encryptionKey, unknownAlgorithm, invalidExponent,
+				{KeyType: "oct", KeyID: "oct"}, {KeyType: "EC", KeyID: "ec", Curve: "P-384"}}})
+			Expect(jwksKeys.verificationKeys).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,50 @@
+package auth
+
+import "time"
+
+// LockoutPolicy decides how failed login attempts are throttled. Failures are tracked per account
+// and per client IP, such that guessing passwords of a single account as well as spraying
+// common passwords across accounts are slowed down and eventually locked out.
+// Zero thresholds disable the respective lockout.
+type LockoutPolicy struct {
+	MaxFailedAttempts      int
+	MaxFailedAttemptsPerIP int
+	LockoutDuration        time.Duration
+	BaseDelay              time.Duration
+	MaxDelay               time.Duration
+}
+
+// Delay is the response delay for a failed attempt, doubling with every consecutive failure up to MaxDelay.
+func (p LockoutPolicy) Delay(failedAttempts int) time.Duration {
+	if failedAttempts <= 0 || p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < failedAttempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// AccountLockedUntil responds with the lockout expiry if the account has to be locked after
+// failedAttempts consecutive failures, nil otherwise.
+func (p LockoutPolicy) AccountLockedUntil(failedAttempts int, now time.Time) *time.Time {
+	if p.MaxFailedAttempts <= 0 || failedAttempts < p.MaxFailedAttempts {
+		return nil
+	}
+	lockedUntil := now.Add(p.LockoutDuration)
+	return &lockedUntil
+}
+
+// IPLockedUntil responds with the lockout expiry if the client IP has to be locked after failedAttempts
+// failures, nil otherwise.
+func (p LockoutPolicy) IPLockedUntil(failedAttempts int, now time.Time) *time.Time {
+	if p.MaxFailedAttemptsPerIP <= 0 || failedAttempts < p.MaxFailedAttemptsPerIP {
+		return nil
+	}
+	lockedUntil := now.Add(p.LockoutDuration)
+	return &lockedUntil
+}
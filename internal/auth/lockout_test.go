@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lockout Policy Tests", func() {
+	policy := LockoutPolicy{
+		MaxFailedAttempts:      3,
+		MaxFailedAttemptsPerIP: 5,
+		LockoutDuration:        time.Minute,
+		BaseDelay:              100 * time.Millisecond,
+		MaxDelay:               time.Second,
+	}
+	now := time.Now()
+
+	It("Delay doubles with consecutive failures up to max delay", func() {
+		Expect(policy.Delay(0)).To(Equal(time.Duration(0)))
+		Expect(policy.Delay(1)).To(Equal(100 * time.Millisecond))
+		Expect(policy.Delay(2)).To(Equal(200 * time.Millisecond))
+		Expect(policy.Delay(4)).To(Equal(800 * time.Millisecond))
+		Expect(policy.Delay(5)).To(Equal(time.Second))
+		Expect(policy.Delay(100)).To(Equal(time.Second))
+	})
+	It("Account is locked once failures reach threshold", func() {
+		Expect(policy.AccountLockedUntil(2, now)).To(BeNil())
+		lockedUntil := policy.AccountLockedUntil(3, now)
+		Expect(lockedUntil).To(Not(BeNil()))
+		Expect(*lockedUntil).To(Equal(now.Add(time.Minute)))
+	})
+	It("Zero threshold disables account lockout", func() {
+		Expect(LockoutPolicy{}.AccountLockedUntil(100, now)).To(BeNil())
+	})
+	It("Client IP is locked once failures reach threshold", func() {
+		Expect(policy.IPLockedUntil(4, now)).To(BeNil())
+		lockedUntil := policy.IPLockedUntil(5, now)
+		Expect(lockedUntil).To(Not(BeNil()))
+		Expect(*lockedUntil).To(Equal(now.Add(time.Minute)))
+		Expect(LockoutPolicy{}.IPLockedUntil(100, now)).To(BeNil())
+	})
+})
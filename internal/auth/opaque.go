@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+var (
+	opaqueTokenLength = 32
+)
+
+// GenerateOpaqueToken generates url safe random token which carries no information by itself.
+// Opaque tokens are only meaningful for the system which persisted them.
+func GenerateOpaqueToken() (string, error) {
+	randomBytes := make([]byte, opaqueTokenLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+// HashOpaqueToken hashes opaque token for persistence.
+// Tokens are high entropy random values, hence a fast hash is sufficient
+// and it allows the token to be looked up directly by its hash.
+func HashOpaqueToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Opaque Token Tests", func() {
+
+	It("Generate unique opaque tokens", func() {
+		firstToken, err := GenerateOpaqueToken()
+		Expect(err).To(BeNil())
+		Expect(firstToken).To(Not(BeEmpty()))
+		secondToken, err := GenerateOpaqueToken()
+		Expect(err).To(BeNil())
+		Expect(secondToken).To(Not(Equal(firstToken)))
+	})
+	It("Hash of opaque token is deterministic", func() {
+		token, _ := GenerateOpaqueToken()
+		Expect(HashOpaqueToken(token)).To(Equal(HashOpaqueToken(token)))
+		Expect(HashOpaqueToken(token)).To(HaveLen(64))
+		Expect(HashOpaqueToken(token)).To(Not(Equal(token)))
+	})
+})
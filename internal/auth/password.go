@@ -4,20 +4,12 @@ import (
 	"crypto/rand"
 	"math/big"
 	"strings"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	tempPassLength = 12
 )
 
-// CompareHashAndPassword...
-func CompareHashAndPassword(hashed string, plain []byte) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), plain)
-	return err == nil
-}
-
 // GeneratePassword generates password considering alphanumerics and selected symbols
 func GeneratePassword() (*string, error) {
 	var charsetBuilder strings.Builder
@@ -44,12 +36,3 @@ func GeneratePassword() (*string, error) {
 	generatedPass := string(password)
 	return &generatedPass, nil
 }
-
-// GeneratePasswordHash...
-func GeneratePasswordHash(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hash), nil
-}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordHashAlgorithmArgon2id = "argon2id"
+	PasswordHashAlgorithmBcrypt   = "bcrypt"
+)
+
+// Hashes are encoded with a prefix identifying the algorithm along with its version, such that hashes of
+// different algorithms co-exist in DB, and are verified by the algorithm they were generated with.
+const (
+	// argon2idPrefix follows the PHC string format, $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+	argon2idPrefix = "$argon2id$"
+	// bcryptSHA256Prefix precedes bcrypt hash of the base64 encoded SHA-256 digest of password.
+	// Password is pre-hashed since bcrypt ignores anything beyond 72 bytes.
+	bcryptSHA256Prefix = "$bcrypt-sha256"
+
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+// legacyBcryptPrefixes are of plain bcrypt hashes, generated before password hashing was versioned.
+var legacyBcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// argon2idEncoding is the unpadded base64 encoding of salt and key demanded by the PHC string format.
+var argon2idEncoding = base64.RawStdEncoding
+
+// DefaultArgon2idParams are the minimum parameters recommended by OWASP.
+var DefaultArgon2idParams = Argon2idParams{Memory: 19456, Iterations: 2, Parallelism: 1}
+
+// PasswordHasher hashes passwords with a particular algorithm and parameters.
+// Any of the hashes is verified through CompareHashAndPassword, irrespective of the hasher.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether the hash was generated with other algorithm or parameters than of the hasher,
+	// such that the password is rehashed once it is known.
+	NeedsRehash(hashed string) bool
+}
+
+// Argon2idParams are the cost parameters of argon2id, memory being in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Argon2idHasher hashes passwords with argon2id.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher...
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+// Hash...
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism,
+		argon2idKeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, h.params.Memory,
+		h.params.Iterations, h.params.Parallelism, argon2idEncoding.EncodeToString(salt),
+		argon2idEncoding.EncodeToString(key)), nil
+}
+
+// NeedsRehash...
+func (h *Argon2idHasher) NeedsRehash(hashed string) bool {
+	params, _, key, err := decodeArgon2idHash(hashed)
+	return err != nil || params != h.params || len(key) != argon2idKeyLength
+}
+
+// BcryptHasher hashes SHA-256 digest of passwords with bcrypt, such that passwords of any length are accepted.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher...
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+// Hash...
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(preHashPassword([]byte(password)), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return bcryptSHA256Prefix + string(hash), nil
+}
+
+// NeedsRehash...
+func (h *BcryptHasher) NeedsRehash(hashed string) bool {
+	if !strings.HasPrefix(hashed, bcryptSHA256Prefix) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(strings.TrimPrefix(hashed, bcryptSHA256Prefix)))
+	return err != nil || cost != h.cost
+}
+
+// LoadPasswordHasher builds hasher of the given algorithm, validating its parameters.
+func LoadPasswordHasher(algorithm string, bcryptCost int, argon2idParams Argon2idParams) (PasswordHasher, error) {
+	switch algorithm {
+	case PasswordHashAlgorithmArgon2id:
+		if argon2idParams.Iterations < 1 || argon2idParams.Parallelism < 1 ||
+			argon2idParams.Memory < 8*uint32(argon2idParams.Parallelism) {
+			return nil, fmt.Errorf("invalid argon2id parameters %+v; iterations and parallelism have to be "+
+				"at least 1, and memory at least 8 KiB per parallelism", argon2idParams)
+		}
+		return NewArgon2idHasher(argon2idParams), nil
+	case PasswordHashAlgorithmBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d; expected between %d and %d", bcryptCost,
+				bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return NewBcryptHasher(bcryptCost), nil
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %s", algorithm)
+	}
+}
+
+// CompareHashAndPassword verifies password against the hash, detecting the algorithm from the hash.
+func CompareHashAndPassword(hashed string, plain []byte) bool {
+	switch {
+	case strings.HasPrefix(hashed, argon2idPrefix):
+		params, salt, key, err := decodeArgon2idHash(hashed)
+		if err != nil {
+			return false
+		}
+		expected := argon2.IDKey(plain, salt, params.Iterations, params.Memory, params.Parallelism,
+			uint32(len(key)))
+		return subtle.ConstantTimeCompare(expected, key) == 1
+	case strings.HasPrefix(hashed, bcryptSHA256Prefix):
+		return bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(hashed, bcryptSHA256Prefix)),
+			preHashPassword(plain)) == nil
+	case isLegacyBcryptHash(hashed):
+		return bcrypt.CompareHashAndPassword([]byte(hashed), plain) == nil
+	default:
+		return false
+	}
+}
+
+// GeneratePasswordHash hashes password with argon2id of default parameters, for the hashes generated
+// outside of request handling. Such hashes are rehashed with the configured hasher upon login.
+func GeneratePasswordHash(password string) (string, error) {
+	return NewArgon2idHasher(DefaultArgon2idParams).Hash(password)
+}
+
+// decodeArgon2idHash parses parameters, salt and key of argon2id hash in PHC string format.
+func decodeArgon2idHash(hashed string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	parts := strings.Split(strings.TrimPrefix(hashed, argon2idPrefix), "$")
+	if !strings.HasPrefix(hashed, argon2idPrefix) || len(parts) != 4 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %s", parts[0])
+	}
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations,
+		&params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %v", err)
+	}
+	salt, err := argon2idEncoding.DecodeString(parts[2])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id salt: %v", err)
+	}
+	key, err := argon2idEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id key")
+	}
+	return params, salt, key, nil
+}
+
+// preHashPassword digests password to a fixed length within the limit of bcrypt. Digest is base64 encoded,
+// since bcrypt stops at NUL byte.
+func preHashPassword(password []byte) []byte {
+	digest := sha256.Sum256(password)
+	return []byte(base64.StdEncoding.EncodeToString(digest[:]))
+}
+
+// isLegacyBcryptHash...
+func isLegacyBcryptHash(hashed string) bool {
+	for _, prefix := range legacyBcryptPrefixes {
+		if strings.HasPrefix(hashed, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	appErrors "userservice/internal/errors"
+)
+
+const (
+	CharacterClassLower  = "lower"
+	CharacterClassUpper  = "upper"
+	CharacterClassDigit  = "digit"
+	CharacterClassSymbol = "symbol"
+)
+
+// commonPasswordList is a deny-list of frequently used passwords, one per line, which are the first
+// to be tried by attackers. List is embedded in the binary, such that no runtime file is demanded.
+//
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords is the lower cased set of commonPasswordList, built once during package init.
+var commonPasswords = buildCommonPasswords(commonPasswordList)
+
+// buildCommonPasswords...
+func buildCommonPasswords(list string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, password := range strings.Split(list, "\n") {
+		if password = strings.TrimSpace(password); password != "" {
+			passwords[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	return passwords
+}
+
+// PasswordRule validates password chosen by the user with given email,
+// responding with descriptive error if the password is rejected.
+type PasswordRule func(password string, email string) error
+
+// PasswordPolicy is a set of rules every newly chosen password has to satisfy.
+// Rules are evaluated in order, and the first violation is reported.
+type PasswordPolicy struct {
+	rules []PasswordRule
+}
+
+// NewPasswordPolicy...
+func NewPasswordPolicy(rules ...PasswordRule) *PasswordPolicy {
+	return &PasswordPolicy{rules: rules}
+}
+
+// Validate...
+func (p *PasswordPolicy) Validate(password string, email string) error {
+	for _, rule := range p.rules {
+		if err := rule(password, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinLength rejects password having fewer characters than minLength.
+func MinLength(minLength int) PasswordRule {
+	return func(password string, _ string) error {
+		if len([]rune(password)) < minLength {
+			return fmt.Errorf("%w; expected at least %d characters", appErrors.ErrPasswordTooShort, minLength)
+		}
+		return nil
+	}
+}
+
+// MaxLength rejects password having more characters than maxLength, bounding the work of hashing it.
+func MaxLength(maxLength int) PasswordRule {
+	return func(password string, _ string) error {
+		if len([]rune(password)) > maxLength {
+			return fmt.Errorf("%w; expected at most %d characters", appErrors.ErrPasswordTooLong, maxLength)
+		}
+		return nil
+	}
+}
+
+// RequireCharacterClasses rejects password lacking a character from any of the given classes.
+// Unknown classes are reported, such that misconfiguration doesn't go unnoticed.
+func RequireCharacterClasses(classes ...string) (PasswordRule, error) {
+	matchers := make(map[string]func(rune) bool, len(classes))
+	for _, class := range classes {
+		switch class {
+		case CharacterClassLower:
+			matchers[class] = unicode.IsLower
+		case CharacterClassUpper:
+			matchers[class] = unicode.IsUpper
+		case CharacterClassDigit:
+			matchers[class] = unicode.IsDigit
+		case CharacterClassSymbol:
+			matchers[class] = func(ch rune) bool { return unicode.IsPunct(ch) || unicode.IsSymbol(ch) }
+		default:
+			return nil, fmt.Errorf("unknown password character class %s", class)
+		}
+	}
+	return func(password string, _ string) error {
+		for _, class := range classes {
+			if !strings.ContainsFunc(password, matchers[class]) {
+				return fmt.Errorf("%w; expected at least one %s character", appErrors.ErrPasswordMissingCharacterClass, class)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// DenyCommonPasswords rejects password found in the embedded list of common passwords, regardless of case.
+func DenyCommonPasswords() PasswordRule {
+	return func(password string, _ string) error {
+		if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+			return appErrors.ErrPasswordTooCommon
+		}
+		return nil
+	}
+}
+
+// DenyEmail rejects password same as the email of the user or its local part, regardless of case.
+func DenyEmail() PasswordRule {
+	return func(password string, email string) error {
+		if email == "" {
+			return nil
+		}
+		localPart, _, _ := strings.Cut(email, "@")
+		if strings.EqualFold(password, email) || strings.EqualFold(password, localPart) {
+			return appErrors.ErrPasswordSameAsEmail
+		}
+		return nil
+	}
+}
+
+// IsPasswordReused reports whether the password matches any of the given password hashes.
+func IsPasswordReused(password string, passwordHashes []string) bool {
+	for _, passwordHash := range passwordHashes {
+		if CompareHashAndPassword(passwordHash, []byte(password)) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPasswordPolicy builds policy demanding minLength to maxLength characters from the given character classes,
+// and denying common passwords and the email of the user.
+func LoadPasswordPolicy(minLength int, maxLength int, characterClasses []string) (*PasswordPolicy, error) {
+	if maxLength < minLength {
+		return nil, fmt.Errorf("maximum password length %d is less than minimum length %d", maxLength, minLength)
+	}
+	characterClassRule, err := RequireCharacterClasses(characterClasses...)
+	if err != nil {
+		return nil, err
+	}
+	return NewPasswordPolicy(MinLength(minLength), MaxLength(maxLength), characterClassRule, DenyCommonPasswords(),
+		DenyEmail()), nil
+}
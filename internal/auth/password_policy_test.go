@@ -0,0 +1,66 @@
+package auth
+
+import (
+	appErrors "userservice/internal/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Password Policy Tests", func() {
+
+	It("Embedded common passwords are loaded", func() {
+		Expect(commonPasswords).To(HaveKey("password"))
+		Expect(commonPasswords).To(HaveKey("admin123"))
+	})
+	It("Password shorter than minimum length", func() {
+		err := MinLength(10)("Short1", "")
+		Expect(err).To(MatchError(appErrors.ErrPasswordTooShort))
+		Expect(err.Error()).To(ContainSubstring("at least 10 characters"))
+		Expect(MinLength(10)("LongEnough", "")).To(BeNil())
+	})
+	It("Password longer than maximum length", func() {
+		err := MaxLength(16)("Mgmt-Portal-2024-Mgmt-Portal", "")
+		Expect(err).To(MatchError(appErrors.ErrPasswordTooLong))
+		Expect(err.Error()).To(ContainSubstring("at most 16 characters"))
+		Expect(MaxLength(16)("Mgmt-Portal-2024", "")).To(BeNil())
+	})
+	It("Password lacking character class", func() {
+		rule, err := RequireCharacterClasses(CharacterClassLower, CharacterClassUpper, CharacterClassDigit,
+			CharacterClassSymbol)
+		Expect(err).To(BeNil())
+		err = rule("Password123", "")
+		Expect(err).To(MatchError(appErrors.ErrPasswordMissingCharacterClass))
+		Expect(err.Error()).To(ContainSubstring("symbol"))
+		Expect(rule("password#123", "")).To(MatchError(ContainSubstring("upper")))
+		Expect(rule("Pass#word123", "")).To(BeNil())
+	})
+	It("Unknown character class", func() {
+		_, err := RequireCharacterClasses("emoji")
+		Expect(err).To(Not(BeNil()))
+	})
+	It("Common password regardless of case", func() {
+		Expect(DenyCommonPasswords()("PassWord123", "")).To(MatchError(appErrors.ErrPasswordTooCommon))
+		Expect(DenyCommonPasswords()("Mgmt-Portal-2024", "")).To(BeNil())
+	})
+	It("Password same as email", func() {
+		Expect(DenyEmail()("Admin@MgmtPortal.com", "admin@mgmtportal.com")).To(MatchError(appErrors.ErrPasswordSameAsEmail))
+		Expect(DenyEmail()("ADMIN", "admin@mgmtportal.com")).To(MatchError(appErrors.ErrPasswordSameAsEmail))
+		Expect(DenyEmail()("admin-portal", "admin@mgmtportal.com")).To(BeNil())
+	})
+	It("Maximum length less than minimum length", func() {
+		_, err := LoadPasswordPolicy(12, 8, nil)
+		Expect(err).To(Not(BeNil()))
+	})
+	It("Policy reports first violation", func() {
+		policy, err := LoadPasswordPolicy(12, 64, []string{CharacterClassUpper})
+		Expect(err).To(BeNil())
+		Expect(policy.Validate("admin", "admin@mgmtportal.com")).To(MatchError(appErrors.ErrPasswordTooShort))
+		Expect(policy.Validate("Mgmt-Portal-2024", "admin@mgmtportal.com")).To(BeNil())
+	})
+	It("Password reuse", func() {
+		hash, _ := GeneratePasswordHash("Mgmt-Portal-2024")
+		Expect(IsPasswordReused("Mgmt-Portal-2024", []string{"", hash})).To(BeTrue())
+		Expect(IsPasswordReused("Mgmt-Portal-2025", []string{"", hash})).To(BeFalse())
+	})
+})
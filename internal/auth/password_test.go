@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"strings"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var _ = Describe("Password Tests", func() {
@@ -10,25 +13,119 @@ var _ = Describe("Password Tests", func() {
 	var (
 		password       = "password"
 		hashedPassword string
+		// cheap parameters to keep tests fast
+		argon2idParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}
 	)
 
 	It("Generate valid Password Hash", func() {
 		var err error
 		hashedPassword, err = GeneratePasswordHash(password)
 		Expect(err).To(BeNil())
-		Expect(hashedPassword).To(Not(BeEmpty()))
-	})
-	It("password of higher length not accepted by bcrypt", func() {
-		passwordOfHigherLength := "Lorem ipsum dolor sit amet," +
-			" consectetuer adipiscing elit. Aenean commodo ligula eget dolor. Aenean m"
-		hashedPasswordNotExpected, err := GeneratePasswordHash(passwordOfHigherLength)
-		Expect(err).ToNot(BeNil())
-		Expect(err.Error()).To(ContainSubstring("password length exceeds 72 bytes"))
-		Expect(hashedPasswordNotExpected).To(BeEmpty())
+		Expect(hashedPassword).To(HavePrefix("$argon2id$v=19$m=19456,t=2,p=1$"))
 	})
 	It("Compare Hash And Password", func() {
 		result := CompareHashAndPassword(hashedPassword, []byte(password))
 		Expect(result).To(BeTrue())
+		Expect(CompareHashAndPassword(hashedPassword, []byte("Password"))).To(BeFalse())
+	})
+	Context("argon2id", func() {
+		hasher := NewArgon2idHasher(argon2idParams)
+
+		It("hashes with random salt", func() {
+			hash, err := hasher.Hash(password)
+			Expect(err).To(BeNil())
+			Expect(hash).To(HavePrefix("$argon2id$v=19$m=64,t=1,p=1$"))
+			anotherHash, _ := hasher.Hash(password)
+			Expect(anotherHash).To(Not(Equal(hash)))
+			Expect(CompareHashAndPassword(hash, []byte(password))).To(BeTrue())
+			Expect(CompareHashAndPassword(anotherHash, []byte(password))).To(BeTrue())
+			Expect(CompareHashAndPassword(hash, []byte("wrongPass"))).To(BeFalse())
+		})
+		It("accepts password beyond 72 bytes", func() {
+			longPassword := strings.Repeat("a", 72)
+			hash, err := hasher.Hash(longPassword + "b")
+			Expect(err).To(BeNil())
+			Expect(CompareHashAndPassword(hash, []byte(longPassword+"b"))).To(BeTrue())
+			Expect(CompareHashAndPassword(hash, []byte(longPassword+"c"))).To(BeFalse())
+		})
+		It("demands rehash of other parameters or algorithm", func() {
+			hash, _ := hasher.Hash(password)
+			Expect(hasher.NeedsRehash(hash)).To(BeFalse())
+			Expect(NewArgon2idHasher(Argon2idParams{Memory: 128, Iterations: 1, Parallelism: 1}).NeedsRehash(hash)).
+				To(BeTrue())
+			bcryptHash, _ := NewBcryptHasher(bcrypt.MinCost).Hash(password)
+			Expect(hasher.NeedsRehash(bcryptHash)).To(BeTrue())
+		})
+		It("rejects malformed hash", func() {
+			hash, _ := hasher.Hash(password)
+			for _, malformed := range []string{
+				strings.Replace(hash, "v=19", "v=16", 1),
+				strings.Replace(hash, "m=64,t=1,p=1", "m=64,t=1", 1),
+				hash[:strings.LastIndex(hash, "$")],
+				hash[:strings.LastIndex(hash, "$")+1],
+				"$argon2id$",
+			} {
+				Expect(CompareHashAndPassword(malformed, []byte(password))).To(BeFalse())
+				Expect(hasher.NeedsRehash(malformed)).To(BeTrue())
+			}
+		})
+	})
+	Context("bcrypt", func() {
+		hasher := NewBcryptHasher(bcrypt.MinCost)
+
+		It("hashes pre-hashed password", func() {
+			hash, err := hasher.Hash(password)
+			Expect(err).To(BeNil())
+			Expect(hash).To(HavePrefix("$bcrypt-sha256$2a$04$"))
+			Expect(CompareHashAndPassword(hash, []byte(password))).To(BeTrue())
+			Expect(CompareHashAndPassword(hash, []byte("wrongPass"))).To(BeFalse())
+		})
+		It("accepts password beyond 72 bytes", func() {
+			longPassword := strings.Repeat("a", 72)
+			hash, err := hasher.Hash(longPassword + "b")
+			Expect(err).To(BeNil())
+			Expect(CompareHashAndPassword(hash, []byte(longPassword+"b"))).To(BeTrue())
+			Expect(CompareHashAndPassword(hash, []byte(longPassword+"c"))).To(BeFalse())
+		})
+		It("verifies legacy hash of plain bcrypt", func() {
+			legacyHash := "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"
+			Expect(CompareHashAndPassword(legacyHash, []byte("sabari123"))).To(BeTrue())
+			Expect(CompareHashAndPassword(legacyHash, []byte("wrongPass"))).To(BeFalse())
+			Expect(hasher.NeedsRehash(legacyHash)).To(BeTrue())
+		})
+		It("demands rehash of other cost or algorithm", func() {
+			hash, _ := hasher.Hash(password)
+			Expect(hasher.NeedsRehash(hash)).To(BeFalse())
+			Expect(NewBcryptHasher(bcrypt.MinCost + 1).NeedsRehash(hash)).To(BeTrue())
+			argon2idHash, _ := NewArgon2idHasher(argon2idParams).Hash(password)
+			Expect(hasher.NeedsRehash(argon2idHash)).To(BeTrue())
+		})
+	})
+	It("Unknown hash is never matched", func() {
+		Expect(CompareHashAndPassword("", []byte(""))).To(BeFalse())
+		Expect(CompareHashAndPassword("password", []byte("password"))).To(BeFalse())
+	})
+	Context("Load Password Hasher", func() {
+		It("loads hasher of the algorithm", func() {
+			hasher, err := LoadPasswordHasher(PasswordHashAlgorithmArgon2id, 0, argon2idParams)
+			Expect(err).To(BeNil())
+			Expect(hasher).To(BeAssignableToTypeOf(&Argon2idHasher{}))
+			hasher, err = LoadPasswordHasher(PasswordHashAlgorithmBcrypt, 12, Argon2idParams{})
+			Expect(err).To(BeNil())
+			Expect(hasher).To(BeAssignableToTypeOf(&BcryptHasher{}))
+		})
+		It("rejects invalid parameters", func() {
+			_, err := LoadPasswordHasher(PasswordHashAlgorithmArgon2id, 0, Argon2idParams{Memory: 64, Iterations: 0,
+				Parallelism: 1})
+			Expect(err).To(Not(BeNil()))
+			_, err = LoadPasswordHasher(PasswordHashAlgorithmArgon2id, 0, Argon2idParams{Memory: 4, Iterations: 1,
+				Parallelism: 1})
+			Expect(err).To(Not(BeNil()))
+			_, err = LoadPasswordHasher(PasswordHashAlgorithmBcrypt, 32, argon2idParams)
+			Expect(err).To(Not(BeNil()))
+			_, err = LoadPasswordHasher("md5", 12, argon2idParams)
+			Expect(err).To(MatchError(ContainSubstring("unsupported password hash algorithm md5")))
+		})
 	})
 	Context("Generate Password", func() {
 		It("check if generated password is of tempPassLength", func() {
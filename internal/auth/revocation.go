@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore tracks access tokens revoked ahead of their expiry.
+// PostgreSQL is the source of truth, and revocations are cached in memory, since every authenticated
+// request is checked against it. Cache is periodically re-synced, such that revocations done
+// by other instances of the application are eventually honoured.
+type RevocationStore struct {
+	ctx           context.Context
+	db            *gorm.DB
+	log           *zap.SugaredLogger
+	tokenLifetime time.Duration
+	leeway        time.Duration
+	mux           sync.RWMutex
+	// jti -> token expiry
+	revokedTokens map[string]time.Time
+	// subject -> tokens issued before this instant are revoked
+	revokedSubjects map[string]time.Time
+}
+
+// NewRevocationStore initializes revocation store, tokenLifetime decides how long subject revocations are retained.
+// Revocations are retained for the leeway tokens are validated with as well, as expired tokens are accepted until then.
+func NewRevocationStore(ctx context.Context, db *gorm.DB, log *zap.SugaredLogger,
+	tokenLifetime time.Duration, leeway time.Duration) *RevocationStore {
+	return &RevocationStore{
+		ctx:             ctx,
+		db:              db,
+		log:             log,
+		tokenLifetime:   tokenLifetime,
+		leeway:          leeway,
+		revokedTokens:   make(map[string]time.Time),
+		revokedSubjects: make(map[string]time.Time),
+	}
+}
+
+// Load purges revocations which are no longer relevant and records the rest in memory.
+func (s *RevocationStore) Load() error {
+	now := time.Now()
+	// Token is accepted until its expiry plus leeway, hence its revocation has to outlive it as long.
+	if err := s.db.Where("expires_at < ?", now.Add(-s.leeway)).Delete(&models.RevokedToken{}).Error; err != nil {
+		return fmt.Errorf("unable to purge expired token revocations: %+v", err)
+	}
+	// Every token issued before now-(tokenLifetime+leeway) is already rejected as expired, hence subject revocations
+	// older than it are stale.
+	if err := s.db.Where("revoked_before < ?", now.Add(-(s.tokenLifetime + s.leeway))).
+		Delete(&models.SubjectRevocation{}).Error; err != nil {
+		return fmt.Errorf("unable to purge stale subject revocations: %+v", err)
+	}
+
+	var revokedTokens []models.RevokedToken
+	if err := s.db.Find(&revokedTokens).Error; err != nil {
+		return fmt.Errorf("unable to fetch token revocations: %+v", err)
+	}
+	var revokedSubjects []models.SubjectRevocation
+	if err := s.db.Find(&revokedSubjects).Error; err != nil {
+		return fmt.Errorf("unable to fetch subject revocations: %+v", err)
+	}
+
+	tokens := make(map[string]time.Time, len(revokedTokens))
+	for _, revokedToken := range revokedTokens {
+		tokens[revokedToken.JTI] = revokedToken.ExpiresAt
+	}
+	subjects := make(map[string]time.Time, len(revokedSubjects))
+	for _, revokedSubject := range revokedSubjects {
+		subjects[revokedSubject.Subject] = revokedSubject.RevokedBefore
+	}
+	s.mux.Lock()
+	s.revokedTokens = tokens
+	s.revokedSubjects = subjects
+	s.mux.Unlock()
+	return nil
+}
+
+// SyncPeriodically reloads revocations until the context is done.
+func (s *RevocationStore) SyncPeriodically(interval time.Duration) {
+	syncTicker := time.NewTicker(interval)
+	defer syncTicker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-syncTicker.C:
+			if err := s.Load(); err != nil {
+				s.log.Errorf("failed to sync token revocations: %v", err)
+			}
+		}
+	}
+}
+
+// RevokeToken revokes single access token identified by jti until its expiry.
+func (s *RevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	revokedToken := models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revokedToken).Error; err != nil {
+		s.log.Errorf("Failed to revoke token %s: %v", jti, err)
+		return appErrors.ErrInternal
+	}
+	s.mux.Lock()
+	s.revokedTokens[jti] = expiresAt
+	s.mux.Unlock()
+	return nil
+}
+
+// RevokeSubjectTokens revokes every access token issued to the subject till now, including the ones issued
+// earlier within the same second. Issued-at of access tokens carries microseconds, as the cutoff does,
+// such that tokens issued right after revocation remain valid.
+func (s *RevocationStore) RevokeSubjectTokens(subject string) error {
+	revokedBefore := time.Now().Truncate(time.Microsecond)
+	revokedSubject := models.SubjectRevocation{Subject: subject, RevokedBefore: revokedBefore}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subject"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before"}),
+	}).Create(&revokedSubject).Error; err != nil {
+		s.log.Errorf("Failed to revoke tokens of subject %s: %v", subject, err)
+		return appErrors.ErrInternal
+	}
+	s.mux.Lock()
+	s.revokedSubjects[subject] = revokedBefore
+	s.mux.Unlock()
+	return nil
+}
+
+// IsRevoked checks if the token is revoked either by itself or as a part of subject revocation.
+func (s *RevocationStore) IsRevoked(jti string, subject string, issuedAt time.Time) bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	if _, ok := s.revokedTokens[jti]; ok {
+		return true
+	}
+	if revokedBefore, ok := s.revokedSubjects[subject]; ok && !issuedAt.After(revokedBefore) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"time"
+	appErrors "userservice/internal/errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// cutoff matches the instant which is the given duration before now, tolerating the time taken by the test.
+type cutoff struct {
+	before time.Duration
+}
+
+// Match...
+func (c cutoff) Match(v driver.Value) bool {
+	instant, ok := v.(time.Time)
+	if !ok {
+		return false
+	}
+	elapsed := time.Since(instant) - c.before
+	return elapsed >= 0 && elapsed < time.Second
+}
+
+var _ = Describe("Revocation Store Tests", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		mockDb *sql.DB
+		store  *RevocationStore
+	)
+	BeforeEach(func() {
+		mockDb, mock, _ = sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ := gorm.Open(dialector)
+		store = NewRevocationStore(context.Background(), db, zap.NewExample().Sugar(), time.Minute, 30*time.Second)
+	})
+
+	Context("Load revocations", func() {
+		It("DB error while purging expired revocations", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "revoked_token" WHERE expires_at < $1`)).
+				WillReturnError(errors.New("connection is already closed"))
+			mock.ExpectRollback()
+			err := store.Load()
+			Expect(err).To(Not(BeNil()))
+			Expect(err.Error()).To(ContainSubstring("unable to purge expired token revocations"))
+		})
+		It("Revocations are recorded in memory", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "revoked_token" WHERE expires_at < $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "subject_revocation" WHERE revoked_before < $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "revoked_token"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"jti", "expires_at"}).AddRow("xyz", time.Now().Add(time.Minute)))
+			revokedBefore := time.Now().Truncate(time.Microsecond)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "subject_revocation"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"subject", "revoked_before"}).
+					AddRow("admin@mgmtportal.com", revokedBefore))
+			err := store.Load()
+			Expect(err).To(BeNil())
+			Expect(store.IsRevoked("xyz", "basic@mgmtportal.com", time.Now())).To(BeTrue())
+			Expect(store.IsRevoked("abc", "admin@mgmtportal.com", revokedBefore.Add(-time.Millisecond))).To(BeTrue())
+			Expect(store.IsRevoked("abc", "admin@mgmtportal.com", revokedBefore)).To(BeTrue())
+			Expect(store.IsRevoked("abc", "admin@mgmtportal.com", revokedBefore.Add(time.Microsecond))).To(BeFalse())
+			Expect(store.IsRevoked("abc", "basic@mgmtportal.com", time.Now())).To(BeFalse())
+		})
+		It("Revocations are retained through the leeway of token validation", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "revoked_token" WHERE expires_at < $1`)).
+				WithArgs(cutoff{before: 30 * time.Second}).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "subject_revocation" WHERE revoked_before < $1`)).
+				WithArgs(cutoff{before: time.Minute + 30*time.Second}).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "revoked_token"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"jti", "expires_at"}))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "subject_revocation"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"subject", "revoked_before"}))
+			Expect(store.Load()).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("Revoke", func() {
+		It("DB error while revoking token", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "revoked_token"`)).
+				WillReturnError(errors.New("connection is already closed"))
+			mock.ExpectRollback()
+			err := store.RevokeToken("xyz", time.Now().Add(time.Minute))
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+			Expect(store.IsRevoked("xyz", "", time.Now())).To(BeFalse())
+		})
+		It("Revoke token", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "revoked_token" ("jti","expires_at") VALUES ($1,$2) ON CONFLICT DO NOTHING`)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			err := store.RevokeToken("xyz", time.Now().Add(time.Minute))
+			Expect(err).To(BeNil())
+			Expect(store.IsRevoked("xyz", "", time.Now())).To(BeTrue())
+		})
+		It("Revoke subject tokens", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "subject_revocation" ("subject","revoked_before") VALUES ($1,$2) ` +
+				`ON CONFLICT ("subject") DO UPDATE SET "revoked_before"="excluded"."revoked_before"`)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			err := store.RevokeSubjectTokens("admin@mgmtportal.com")
+			Expect(err).To(BeNil())
+			Expect(store.IsRevoked("xyz", "admin@mgmtportal.com", time.Now().Add(-time.Minute))).To(BeTrue())
+			Expect(store.IsRevoked("xyz", "admin@mgmtportal.com", time.Now().Add(time.Second))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters follow the defaults of authenticator apps, i.e. HMAC-SHA1 with
+// 6 digit codes changing every 30 seconds (RFC 6238).
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew tolerates codes of adjacent time steps, to accommodate clock drift of the device
+	totpSkew = 1
+	// totpSecretLength is the length of secret in bytes, as recommended by RFC 4226
+	totpSecretLength = 20
+
+	// recoveryCodeAlphabet excludes characters which are easily confused while typing
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
+)
+
+// totpEncoding is the base32 encoding of secrets expected by authenticator apps.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret generates base32 encoded random secret to be shared with user's authenticator app.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI formats otpauth URI of the secret, which authenticator apps enroll through a QR code.
+func TOTPURI(issuer string, account string, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPStep is the time step at the given time, codes are derived from it.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod.Seconds())
+}
+
+// GenerateTOTPCode generates the code of the secret at the given time.
+func GenerateTOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(TOTPStep(t)), totpDigits, sha1.New), nil
+}
+
+// ValidateTOTPCode checks the code against the secret, around the given time.
+// Time step of the matching code is returned, such that the caller can deny its replay.
+func ValidateTOTPCode(secret string, code string, t time.Time) (int64, bool) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	step := TOTPStep(t)
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		expected := hotp(key, uint64(step+offset), totpDigits, sha1.New)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step + offset, true
+		}
+	}
+	return 0, false
+}
+
+// decodeTOTPSecret decodes base32 secret, tolerating lower case and padding added while copying it around.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	return totpEncoding.DecodeString(strings.TrimRight(strings.ToUpper(secret), "="))
+}
+
+// hotp generates HMAC based one time password of the counter (RFC 4226).
+func hotp(key []byte, counter uint64, digits int, algorithm func() hash.Hash) string {
+	mac := hmac.New(algorithm, key)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}
+
+// GenerateRecoveryCodes generates single use codes, which let the user sign in without the authenticator app.
+// Codes are formatted as two hyphenated halves for readability.
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		code := make([]byte, 0, recoveryCodeLength+1)
+		for j := 0; j < recoveryCodeLength; j++ {
+			if j == recoveryCodeLength/2 {
+				code = append(code, '-')
+			}
+			index, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+			if err != nil {
+				return nil, err
+			}
+			code = append(code, recoveryCodeAlphabet[index.Int64()])
+		}
+		codes = append(codes, string(code))
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes recovery code for persistence, ignoring case and surrounding spaces typed by the user.
+func HashRecoveryCode(code string) string {
+	return HashOpaqueToken(strings.ToLower(strings.TrimSpace(code)))
+}
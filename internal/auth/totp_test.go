@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TOTP Tests", func() {
+	// seeds of RFC 6238 test vectors, for each of the algorithms
+	var (
+		sha1Seed   = []byte("12345678901234567890")
+		sha256Seed = []byte("12345678901234567890123456789012")
+		sha512Seed = []byte("1234567890123456789012345678901234567890123456789012345678901234")
+	)
+
+	It("RFC 6238 test vectors", func() {
+		vectors := []struct {
+			unixTime                         int64
+			sha1Code, sha256Code, sha512Code string
+		}{
+			{59, "94287082", "46119246", "90693936"},
+			{1111111109, "07081804", "68084774", "25091201"},
+			{1111111111, "14050471", "67062674", "99943326"},
+			{1234567890, "89005924", "91819424", "93441116"},
+			{2000000000, "69279037", "90698825", "38618901"},
+			{20000000000, "65353130", "77737706", "47863826"},
+		}
+		for _, vector := range vectors {
+			step := uint64(TOTPStep(time.Unix(vector.unixTime, 0)))
+			Expect(hotp(sha1Seed, step, 8, sha1.New)).To(Equal(vector.sha1Code))
+			Expect(hotp(sha256Seed, step, 8, sha256.New)).To(Equal(vector.sha256Code))
+			Expect(hotp(sha512Seed, step, 8, sha512.New)).To(Equal(vector.sha512Code))
+		}
+	})
+
+	It("RFC 4226 test vectors", func() {
+		expected := []string{"755224", "287082", "359152", "969429", "338314",
+			"254676", "287922", "162583", "399871", "520489"}
+		for counter, code := range expected {
+			Expect(hotp(sha1Seed, uint64(counter), 6, sha1.New)).To(Equal(code))
+		}
+	})
+
+	Context("validate code", func() {
+		secret := base32.StdEncoding.EncodeToString(sha1Seed)
+		now := time.Unix(1111111111, 0)
+
+		It("generates 6 digit code", func() {
+			code, err := GenerateTOTPCode(secret, now)
+			Expect(err).To(BeNil())
+			Expect(code).To(Equal("050471"))
+		})
+		It("accepts code of current time step", func() {
+			step, ok := ValidateTOTPCode(secret, "050471", now)
+			Expect(ok).To(BeTrue())
+			Expect(step).To(Equal(TOTPStep(now)))
+		})
+		It("accepts code of adjacent time steps", func() {
+			code, _ := GenerateTOTPCode(secret, now.Add(-totpPeriod))
+			step, ok := ValidateTOTPCode(secret, code, now)
+			Expect(ok).To(BeTrue())
+			Expect(step).To(Equal(TOTPStep(now) - 1))
+			code, _ = GenerateTOTPCode(secret, now.Add(totpPeriod))
+			_, ok = ValidateTOTPCode(secret, code, now)
+			Expect(ok).To(BeTrue())
+		})
+		It("rejects code outside the skew", func() {
+			code, _ := GenerateTOTPCode(secret, now.Add(-2*totpPeriod))
+			_, ok := ValidateTOTPCode(secret, code, now)
+			Expect(ok).To(BeFalse())
+		})
+		It("rejects malformed code", func() {
+			_, ok := ValidateTOTPCode(secret, "50471", now)
+			Expect(ok).To(BeFalse())
+			_, ok = ValidateTOTPCode(secret, "", now)
+			Expect(ok).To(BeFalse())
+		})
+		It("tolerates lower case secret", func() {
+			_, ok := ValidateTOTPCode(strings.ToLower(secret), "050471", now)
+			Expect(ok).To(BeTrue())
+		})
+		It("rejects invalid secret", func() {
+			_, ok := ValidateTOTPCode("not base32!", "050471", now)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	It("generates secret and otpauth URI", func() {
+		secret, err := GenerateTOTPSecret()
+		Expect(err).To(BeNil())
+		key, err := decodeTOTPSecret(secret)
+		Expect(err).To(BeNil())
+		Expect(key).To(HaveLen(totpSecretLength))
+		uri := TOTPURI("userservice", "admin@mgmtportal.com", secret)
+		Expect(uri).To(HavePrefix("otpauth://totp/userservice:admin@mgmtportal.com?"))
+		Expect(uri).To(ContainSubstring("secret=" + secret))
+		Expect(uri).To(ContainSubstring("issuer=userservice"))
+		Expect(uri).To(ContainSubstring("digits=6"))
+		Expect(uri).To(ContainSubstring("period=30"))
+	})
+
+	It("generates distinct recovery codes", func() {
+		codes, err := GenerateRecoveryCodes(10)
+		Expect(err).To(BeNil())
+		Expect(codes).To(HaveLen(10))
+		seen := map[string]bool{}
+		for _, code := range codes {
+			Expect(code).To(MatchRegexp(`^[a-z2-9]{5}-[a-z2-9]{5}$`))
+			Expect(seen[code]).To(BeFalse())
+			seen[code] = true
+		}
+		Expect(HashRecoveryCode(" " + strings.ToUpper(codes[0]) + " ")).To(Equal(HashRecoveryCode(codes[0])))
+	})
+})
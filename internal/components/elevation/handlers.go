@@ -0,0 +1,267 @@
+package elevation
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"userservice/internal/auth"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+	"userservice/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestElevation requests elevation of the user to another role for the duration, with the justification
+// to be reviewed. Once approved, tokens issued to the user through login or refresh carry the role elevated to,
+// till the requested duration passes. Role elevated to has to be granted every permission of the role assigned
+// to the user, and more, such that elevation never takes away permissions. Elevation not reviewed in time expires.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) requestElevation(c *gin.Context) {
+	var elevationToRequest map[string]interface{}
+	if err := c.BindJSON(&elevationToRequest); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Role elevation payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(elevationToRequest, models.RoleElevationPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Role elevation payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.RoleElevationPayloadTemplate))))
+		return
+	}
+	role := elevationToRequest[models.AttributeRole].(string)
+	if !misc.IsRoleConfigured(role) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", role)))
+		return
+	}
+	// role claim might be the one of an elevation already held, hence the role assigned to the user is the base
+	heldRole, err := h.operations.GetUserRole(c.GetUint(auth.JWTClaimSubject))
+	if err != nil {
+		if err == appErrors.ErrUserDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if !misc.CoversPermissions(role, heldRole) || misc.CoversPermissions(heldRole, role) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(fmt.Sprintf(
+			"Role elevation payload is invalid; role %s isn't granted permissions beyond the ones of role %s held",
+			role, heldRole)))
+		return
+	}
+	justification := strings.TrimSpace(elevationToRequest[models.AttributeJustification].(string))
+	if justification == "" {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Role elevation payload is invalid; justification is empty"))
+		return
+	}
+	durationInMinutes := elevationToRequest[models.AttributeDurationInMinutes].(float64)
+	if durationInMinutes != math.Trunc(durationInMinutes) || durationInMinutes < 1 ||
+		durationInMinutes > float64(h.runtimeConfig.RoleElevationMaxDurationInMinutes) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(fmt.Sprintf(
+			"Role elevation payload is invalid; %s should be whole number of minutes between 1 and %d",
+			models.AttributeDurationInMinutes, h.runtimeConfig.RoleElevationMaxDurationInMinutes)))
+		return
+	}
+
+	now := time.Now()
+	reviewExpiresAt := now.Add(time.Minute * time.Duration(h.runtimeConfig.RoleElevationPendingExpiryInMinutes))
+	elevation := models.RoleElevation{
+		CreatedAt:         now,
+		UserID:            c.GetUint(auth.JWTClaimSubject),
+		Role:              role,
+		Justification:     justification,
+		DurationInMinutes: int64(durationInMinutes),
+		Status:            models.ElevationStatusPending,
+		ExpiresAt:         &reviewExpiresAt,
+	}
+	if err := h.operations.CreateElevation(&elevation); err != nil {
+		if err == appErrors.ErrElevationAlreadyPending {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrElevationAlreadyPending.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, elevation)
+}
+
+// fetchOwnElevations responds with role elevations requested by the user, latest first.
+func (h *Handler) fetchOwnElevations(c *gin.Context) {
+	h.respondElevations(c, c.GetUint(auth.JWTClaimSubject))
+}
+
+// fetchElevations responds with the history of role elevations of every user, latest first.
+// History can be narrowed down to a user through user_id query param.
+func (h *Handler) fetchElevations(c *gin.Context) {
+	var userID uint
+	if userIDStr := c.Query(models.QueryParamUserID); userIDStr != "" {
+		if _, err := fmt.Sscanf(userIDStr, "%d", &userID); err != nil {
+			c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("User ID should be numerical"))
+			return
+		}
+	}
+	h.respondElevations(c, userID)
+}
+
+// respondElevations responds with the page of role elevations of the user, or of every user if userID is 0.
+// Elevations can be narrowed down to the ones with status query param.
+func (h *Handler) respondElevations(c *gin.Context, userID uint) {
+	status := c.Query(models.QueryParamStatus)
+	if status != "" && !models.IsElevationStatus(status) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Request Path contains invalid status, choose one of %v", models.ElevationStatuses)))
+		return
+	}
+	pageStr := c.DefaultQuery("page", "0")
+	page, paramErr := strconv.Atoi(pageStr)
+	if paramErr != nil || page < 0 {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Request Path contains invalid page number, choose positive numerical value"))
+		return
+	}
+	// page 0 or unset page parameter will represent the first page
+	if page == 0 {
+		page = 1
+	}
+
+	pageSizeStr := c.DefaultQuery("size", models.DefaultPageSize)
+	pageSize, paramErr := strconv.Atoi(pageSizeStr)
+	if paramErr != nil || pageSize < 0 {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Request Path contains invalid page size, choose positive numerical value"))
+		return
+	}
+
+	elevations, err := h.operations.FetchElevationsWithPagination(userID, status, time.Now(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, elevations)
+}
+
+// approveElevation approves pending role elevation, starting the window the requester holds the role for.
+func (h *Handler) approveElevation(c *gin.Context) {
+	h.reviewElevation(c, models.ElevationStatusApproved)
+}
+
+// denyElevation denies pending role elevation.
+func (h *Handler) denyElevation(c *gin.Context) {
+	h.reviewElevation(c, models.ElevationStatusDenied)
+}
+
+// reviewElevation records the review of pending role elevation by the user. Requester can't review own elevation,
+// and reviewer can't approve elevation to a role granted permissions beyond the ones of reviewer's role.
+func (h *Handler) reviewElevation(c *gin.Context, status string) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Role elevation ID should be numerical"))
+		return
+	}
+	elevation, err := h.operations.GetElevation(id)
+	if err != nil {
+		if err == appErrors.ErrElevationDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrElevationDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if elevation.Status != models.ElevationStatusPending {
+		c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrElevationAlreadyReviewed.Error()))
+		return
+	}
+	now := time.Now()
+	if !elevation.IsPending(now) {
+		c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrElevationExpired.Error()))
+		return
+	}
+	reviewerID := c.GetUint(auth.JWTClaimSubject)
+	if elevation.UserID == reviewerID {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrElevationSelfReview.Error()))
+		return
+	}
+
+	elevation.Status = status
+	elevation.ReviewedBy = &reviewerID
+	elevation.ReviewedAt = &now
+	if status == models.ElevationStatusApproved {
+		if !misc.IsRoleConfigured(elevation.Role) {
+			c.JSON(http.StatusBadRequest,
+				utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", elevation.Role)))
+			return
+		}
+		reviewerRole := c.GetString(auth.JWTClaimRole)
+		for _, permission := range misc.RolePermissions(elevation.Role) {
+			if !misc.HasPermission(reviewerRole, permission) {
+				c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrElevationBeyondReviewer.Error()))
+				return
+			}
+		}
+		expiresAt := now.Add(time.Minute * time.Duration(elevation.DurationInMinutes))
+		elevation.ExpiresAt = &expiresAt
+	} else {
+		// denied elevation is never held, hence it has no window
+		elevation.ExpiresAt = nil
+	}
+	if err := h.operations.ReviewElevation(elevation); err != nil {
+		if err == appErrors.ErrElevationAlreadyReviewed {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrElevationAlreadyReviewed.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, elevation)
+}
+
+// revokeElevation ends the window of active role elevation ahead of time. Access tokens issued to the requester
+// are revoked, such that the role elevated to isn't held any longer, whereas tokens issued afterwards through login
+// or refresh carry the role of the requester.
+func (h *Handler) revokeElevation(c *gin.Context) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Role elevation ID should be numerical"))
+		return
+	}
+	elevation, err := h.operations.GetElevation(id)
+	if err != nil {
+		if err == appErrors.ErrElevationDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrElevationDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	now := time.Now()
+	if !elevation.IsActive(now) {
+		c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrElevationNotActive.Error()))
+		return
+	}
+
+	revokerID := c.GetUint(auth.JWTClaimSubject)
+	elevation.Status = models.ElevationStatusRevoked
+	elevation.RevokedBy = &revokerID
+	elevation.RevokedAt = &now
+	elevation.ExpiresAt = &now
+	if err := h.operations.RevokeElevation(elevation); err != nil {
+		if err == appErrors.ErrElevationNotActive {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrElevationNotActive.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.revoker.RevokeSubjectTokens(auth.SubjectFromUserID(elevation.UserID)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, elevation)
+}
@@ -0,0 +1,331 @@
+package elevation
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+	"userservice/internal/configs"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func GetTestGinContext(w *httptest.ResponseRecorder) *gin.Context {
+	gin.SetMode(gin.TestMode)
+
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{},
+	}
+	return ctx
+}
+
+func MockJsonPostOrPut(c *gin.Context, content interface{}) {
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	jsonbytes, err := json.Marshal(content)
+	if err != nil {
+		panic(err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonbytes))
+}
+
+var _ = Describe("Role Elevation [Handler]", func() {
+
+	var (
+		ctx        *gin.Context
+		handler    *Handler
+		w          *httptest.ResponseRecorder
+		operations *ElevationMock
+		revoker    *RevocationMock
+	)
+	BeforeEach(func() {
+		operations = new(ElevationMock)
+		revoker = new(RevocationMock)
+		handler = &Handler{runtimeConfig: &configs.Config{RoleElevationMaxDurationInMinutes: 240,
+			RoleElevationPendingExpiryInMinutes: 1440}, operations: operations, revoker: revoker}
+		w = httptest.NewRecorder()
+		ctx = GetTestGinContext(w)
+		misc.SetRole(models.UserRole{Name: models.RoleAdvanced, Permissions: []string{models.PermissionServiceRead}})
+		misc.SetRole(models.UserRole{Name: models.RoleAdmin, Permissions: []string{models.PermissionUserWrite},
+			Parent: models.RoleAdvanced})
+	})
+	AfterEach(func() {
+		misc.RemoveRole(models.RoleAdmin)
+		misc.RemoveRole(models.RoleAdvanced)
+		misc.RemoveRole(models.RoleBasic)
+	})
+
+	Context("Request elevation", func() {
+		BeforeEach(func() {
+			ctx.Set("sub", uint(2))
+			ctx.Set("role", models.RoleAdvanced)
+			operations.UserRole = models.RoleAdvanced
+		})
+		It("Invalid payload", func() {
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Role elevation payload is invalid; Expected JSON payload"))
+		})
+		It("Role doesn't exist", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": "root", "justification": "incident",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("User Role root doesn't exist"))
+		})
+		It("Role isn't granted permissions beyond the ones held", func() {
+			misc.SetRole(models.UserRole{Name: models.RoleBasic, Permissions: []string{models.PermissionServiceRead}})
+			misc.SetRole(models.UserRole{Name: "auditor", Permissions: []string{models.PermissionUserRead}})
+			defer misc.RemoveRole("auditor")
+			for _, role := range []string{models.RoleAdvanced, models.RoleBasic, "auditor"} {
+				w = httptest.NewRecorder()
+				ctx = GetTestGinContext(w)
+				MockJsonPostOrPut(ctx, map[string]interface{}{"role": role, "justification": "incident",
+					"duration_in_minutes": 60})
+				handler.requestElevation(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("isn't granted permissions beyond the ones of role advanced"))
+			}
+		})
+		It("Role held through elevation isn't the base of another elevation", func() {
+			ctx.Set("role", models.RoleAdmin)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "incident",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(201))
+		})
+		It("User doesn't exist", func() {
+			operations.SetUserDoesntExist = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "incident",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(404))
+			Expect(operations.Elevations).To(BeEmpty())
+		})
+		It("Empty justification", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "  ",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("justification is empty"))
+		})
+		It("Duration beyond the longest allowed", func() {
+			for _, duration := range []float64{0, 30.5, 241} {
+				w = httptest.NewRecorder()
+				ctx = GetTestGinContext(w)
+				MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "incident",
+					"duration_in_minutes": duration})
+				handler.requestElevation(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("between 1 and 240"))
+			}
+		})
+		It("Elevation to the role already pending", func() {
+			reviewExpiresAt := time.Now().Add(time.Hour)
+			operations.Elevations = []models.RoleElevation{{ID: 1, UserID: 2, Role: models.RoleAdmin,
+				Status: models.ElevationStatusPending, ExpiresAt: &reviewExpiresAt}}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "incident",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(409))
+		})
+		It("Elevation to the role pending past its review window is requested again", func() {
+			reviewExpiredAt := time.Now().Add(-time.Minute)
+			operations.Elevations = []models.RoleElevation{{ID: 1, UserID: 2, Role: models.RoleAdmin,
+				Status: models.ElevationStatusPending, ExpiresAt: &reviewExpiredAt}}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "incident",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(201))
+		})
+		It("Facing DB errors", func() {
+			operations.SetInternalError = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "incident",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Elevation is pending review", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"role": models.RoleAdmin, "justification": "incident",
+				"duration_in_minutes": 60})
+			handler.requestElevation(ctx)
+			Expect(w.Code).To(Equal(201))
+			Expect(operations.Elevations).To(HaveLen(1))
+			Expect(operations.Elevations[0].UserID).To(Equal(uint(2)))
+			Expect(operations.Elevations[0].DurationInMinutes).To(Equal(int64(60)))
+			Expect(operations.Elevations[0].Status).To(Equal(models.ElevationStatusPending))
+			Expect(*operations.Elevations[0].ExpiresAt).To(BeTemporally("~", time.Now().Add(24*time.Hour), time.Second))
+		})
+	})
+
+	Context("Fetch elevations", func() {
+		It("Invalid status", func() {
+			ctx.Request.URL.RawQuery = "status=ended"
+			handler.fetchElevations(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Invalid user ID", func() {
+			ctx.Request.URL.RawQuery = "user_id=me"
+			handler.fetchElevations(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Facing DB errors", func() {
+			operations.SetInternalError = true
+			handler.fetchElevations(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("History of the user, with approved and pending elevations past their window reported expired", func() {
+			expiredAt := time.Now().Add(-time.Minute)
+			operations.Elevations = []models.RoleElevation{
+				{ID: 1, UserID: 2, Role: models.RoleAdmin, Status: models.ElevationStatusApproved, ExpiresAt: &expiredAt},
+				{ID: 2, UserID: 3, Role: models.RoleAdmin, Status: models.ElevationStatusDenied},
+				{ID: 3, UserID: 2, Role: models.RoleAdmin, Status: models.ElevationStatusPending, ExpiresAt: &expiredAt},
+			}
+			ctx.Request.URL.RawQuery = "user_id=2&status=expired"
+			handler.fetchElevations(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.FetchedUserID).To(Equal(uint(2)))
+			var elevations models.PaginatedRoleElevationList
+			Expect(json.Unmarshal(w.Body.Bytes(), &elevations)).To(Succeed())
+			Expect(elevations.Data).To(HaveLen(2))
+			Expect(elevations.Data[1].Status).To(Equal(models.ElevationStatusExpired))
+			Expect(elevations.Data[0].Status).To(Equal(models.ElevationStatusExpired))
+		})
+		It("Own elevations", func() {
+			ctx.Set("sub", uint(3))
+			handler.fetchOwnElevations(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.FetchedUserID).To(Equal(uint(3)))
+		})
+	})
+
+	Context("Review elevation", func() {
+		BeforeEach(func() {
+			reviewExpiresAt := time.Now().Add(time.Hour)
+			operations.Elevations = []models.RoleElevation{{ID: 1, UserID: 2, Role: models.RoleAdmin,
+				Justification: "incident", DurationInMinutes: 60, Status: models.ElevationStatusPending,
+				ExpiresAt: &reviewExpiresAt}}
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			ctx.Set("sub", uint(1))
+			ctx.Set("role", models.RoleAdmin)
+		})
+		It("Invalid ID", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "first"}}
+			handler.approveElevation(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Elevation doesn't exist", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "7"}}
+			handler.approveElevation(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Requester can't review own elevation", func() {
+			ctx.Set("sub", uint(2))
+			handler.approveElevation(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrElevationSelfReview.Error()))
+			Expect(operations.Elevations[0].Status).To(Equal(models.ElevationStatusPending))
+		})
+		It("Reviewer isn't granted every permission of the role", func() {
+			ctx.Set("role", models.RoleAdvanced)
+			handler.approveElevation(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrElevationBeyondReviewer.Error()))
+		})
+		It("Elevation already reviewed", func() {
+			operations.Elevations[0].Status = models.ElevationStatusDenied
+			handler.approveElevation(ctx)
+			Expect(w.Code).To(Equal(409))
+		})
+		It("Elevation not reviewed in time", func() {
+			reviewExpiredAt := time.Now().Add(-time.Minute)
+			operations.Elevations[0].ExpiresAt = &reviewExpiredAt
+			handler.approveElevation(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrElevationExpired.Error()))
+			Expect(operations.Elevations[0].Status).To(Equal(models.ElevationStatusPending))
+		})
+		It("Elevation reviewed concurrently", func() {
+			operations.SetReviewRace = true
+			handler.denyElevation(ctx)
+			Expect(w.Code).To(Equal(409))
+		})
+		It("Approval starts the window of the elevation", func() {
+			handler.approveElevation(ctx)
+			Expect(w.Code).To(Equal(200))
+			approved := operations.Elevations[0]
+			Expect(approved.Status).To(Equal(models.ElevationStatusApproved))
+			Expect(*approved.ReviewedBy).To(Equal(uint(1)))
+			Expect(*approved.ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+			Expect(approved.IsActive(time.Now())).To(BeTrue())
+		})
+		It("Denied elevation is never held", func() {
+			handler.denyElevation(ctx)
+			Expect(w.Code).To(Equal(200))
+			denied := operations.Elevations[0]
+			Expect(denied.Status).To(Equal(models.ElevationStatusDenied))
+			Expect(denied.ExpiresAt).To(BeNil())
+			Expect(denied.IsActive(time.Now())).To(BeFalse())
+		})
+	})
+
+	Context("Revoke elevation", func() {
+		BeforeEach(func() {
+			expiresAt := time.Now().Add(time.Hour)
+			operations.Elevations = []models.RoleElevation{{ID: 1, UserID: 2, Role: models.RoleAdmin,
+				Justification: "incident", DurationInMinutes: 60, Status: models.ElevationStatusApproved,
+				ExpiresAt: &expiresAt}}
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			ctx.Set("sub", uint(1))
+			ctx.Set("role", models.RoleAdmin)
+		})
+		It("Invalid ID", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "first"}}
+			handler.revokeElevation(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Elevation doesn't exist", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "7"}}
+			handler.revokeElevation(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Elevation isn't active", func() {
+			expiredAt := time.Now().Add(-time.Minute)
+			operations.Elevations[0].ExpiresAt = &expiredAt
+			handler.revokeElevation(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrElevationNotActive.Error()))
+			Expect(revoker.RevokedSubjects).To(BeEmpty())
+		})
+		It("Elevation revoked concurrently", func() {
+			operations.SetReviewRace = true
+			handler.revokeElevation(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(revoker.RevokedSubjects).To(BeEmpty())
+		})
+		It("Facing errors while revoking tokens", func() {
+			revoker.SetInternalError = true
+			handler.revokeElevation(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Revocation ends the window of the elevation and revokes tokens of the requester", func() {
+			handler.revokeElevation(ctx)
+			Expect(w.Code).To(Equal(200))
+			revoked := operations.Elevations[0]
+			Expect(revoked.Status).To(Equal(models.ElevationStatusRevoked))
+			Expect(*revoked.RevokedBy).To(Equal(uint(1)))
+			Expect(revoked.IsActive(time.Now())).To(BeFalse())
+			Expect(revoker.RevokedSubjects).To(Equal([]string{"2"}))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package elevation
+
+import (
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+)
+
+// ElevationMock...
+type ElevationMock struct {
+	SetInternalError   bool
+	SetReviewRace      bool
+	SetUserDoesntExist bool
+	Elevations         []models.RoleElevation
+	// UserRole is the role assigned to every user
+	UserRole string
+	// FetchedUserID and FetchedStatus are the filters elevations were last fetched with
+	FetchedUserID uint
+	FetchedStatus string
+}
+
+// CreateElevation...
+func (m *ElevationMock) CreateElevation(elevation *models.RoleElevation) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	for _, existing := range m.Elevations {
+		if existing.UserID == elevation.UserID && existing.Role == elevation.Role &&
+			existing.IsPending(elevation.CreatedAt) {
+			return appErrors.ErrElevationAlreadyPending
+		}
+	}
+	elevation.ID = uint(len(m.Elevations) + 1)
+	m.Elevations = append(m.Elevations, *elevation)
+	return nil
+}
+
+// GetElevation...
+func (m *ElevationMock) GetElevation(id uint) (*models.RoleElevation, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	for _, elevation := range m.Elevations {
+		if elevation.ID == id {
+			return &elevation, nil
+		}
+	}
+	return nil, appErrors.ErrElevationDoesNotExist
+}
+
+// FetchElevationsWithPagination...
+func (m *ElevationMock) FetchElevationsWithPagination(userID uint, status string, now time.Time, page int,
+	size int) (*models.PaginatedRoleElevationList, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	m.FetchedUserID, m.FetchedStatus = userID, status
+	elevations := []models.RoleElevation{}
+	for _, elevation := range m.Elevations {
+		elevation.MarkExpired(now)
+		if (userID == 0 || elevation.UserID == userID) && (status == "" || elevation.Status == status) {
+			elevations = append(elevations, elevation)
+		}
+	}
+	return &models.PaginatedRoleElevationList{Data: elevations, TotalItems: int64(len(elevations)),
+		CurrentPage: page, PageSize: size}, nil
+}
+
+// ReviewElevation...
+func (m *ElevationMock) ReviewElevation(elevation *models.RoleElevation) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetReviewRace {
+		return appErrors.ErrElevationAlreadyReviewed
+	}
+	m.updateElevation(elevation)
+	return nil
+}
+
+// RevokeElevation...
+func (m *ElevationMock) RevokeElevation(elevation *models.RoleElevation) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetReviewRace {
+		return appErrors.ErrElevationNotActive
+	}
+	m.updateElevation(elevation)
+	return nil
+}
+
+// GetUserRole...
+func (m *ElevationMock) GetUserRole(uint) (string, error) {
+	if m.SetInternalError {
+		return "", appErrors.ErrInternal
+	} else if m.SetUserDoesntExist {
+		return "", appErrors.ErrUserDoesNotExist
+	}
+	return m.UserRole, nil
+}
+
+// updateElevation...
+func (m *ElevationMock) updateElevation(elevation *models.RoleElevation) {
+	for i := range m.Elevations {
+		if m.Elevations[i].ID == elevation.ID {
+			m.Elevations[i] = *elevation
+		}
+	}
+}
+
+// RevocationMock...
+type RevocationMock struct {
+	SetInternalError bool
+	RevokedSubjects  []string
+}
+
+// RevokeToken...
+func (m *RevocationMock) RevokeToken(string, time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RevokeSubjectTokens...
+func (m *RevocationMock) RevokeSubjectTokens(subject string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RevokedSubjects = append(m.RevokedSubjects, subject)
+	return nil
+}
+
+// IsRevoked...
+func (m *RevocationMock) IsRevoked(_ string, subject string, _ time.Time) bool {
+	for _, revokedSubject := range m.RevokedSubjects {
+		if revokedSubject == subject {
+			return true
+		}
+	}
+	return false
+}
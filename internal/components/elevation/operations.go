@@ -0,0 +1,155 @@
+package elevation
+
+import (
+	"errors"
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// operations...
+type operations struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// newOperations initializes role elevation operation handler
+func newOperations(db *gorm.DB, log *zap.SugaredLogger) *operations {
+	return &operations{db: db, log: log}
+}
+
+// CreateElevation creates pending role elevation in DB, unless elevation of the user to the same role
+// is already pending, as of its creation.
+func (ops *operations) CreateElevation(elevation *models.RoleElevation) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		var pendingElevations int64
+		if err := tx.Model(&models.RoleElevation{}).Where("user_id = ? AND role = ? AND status = ? AND expires_at > ?",
+			elevation.UserID, elevation.Role, models.ElevationStatusPending, elevation.CreatedAt).
+			Count(&pendingElevations).Error; err != nil {
+			return err
+		}
+		if pendingElevations != 0 {
+			return appErrors.ErrElevationAlreadyPending
+		}
+		return tx.Create(elevation).Error
+	})
+	if err != nil {
+		if errors.Is(err, appErrors.ErrElevationAlreadyPending) {
+			return err
+		}
+		ops.log.Errorf("Failed to create elevation of user with id %d to role %s: %v", elevation.UserID,
+			elevation.Role, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// GetElevation fetches role elevation record in DB for the given id
+func (ops *operations) GetElevation(id uint) (*models.RoleElevation, error) {
+	elevation := new(models.RoleElevation)
+	if err := ops.db.Where("id = ?", id).First(elevation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrElevationDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch role elevation by id %d: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	return elevation, nil
+}
+
+// FetchElevationsWithPagination fetches role elevations, latest first, of the user and with the status if given.
+// Approved and pending elevations past their window, as of now, are reported expired.
+func (ops *operations) FetchElevationsWithPagination(userID uint, status string, now time.Time, currentPage int,
+	pageSize int) (*models.PaginatedRoleElevationList, error) {
+
+	matchingElevations := func() *gorm.DB {
+		query := ops.db.Model(&models.RoleElevation{})
+		if userID != 0 {
+			query = query.Where("user_id = ?", userID)
+		}
+		switch status {
+		case "":
+		case models.ElevationStatusApproved, models.ElevationStatusPending:
+			query = query.Where("status = ? AND expires_at > ?", status, now)
+		case models.ElevationStatusExpired:
+			query = query.Where("status IN ? AND expires_at <= ?",
+				[]string{models.ElevationStatusApproved, models.ElevationStatusPending}, now)
+		default:
+			query = query.Where("status = ?", status)
+		}
+		return query
+	}
+	var (
+		total      int64
+		elevations []models.RoleElevation
+	)
+	if err := matchingElevations().Count(&total).Error; err != nil {
+		ops.log.Errorf("Failed to get the total count of role elevations: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	offset := (currentPage - 1) * pageSize
+	if err := matchingElevations().Order("id DESC").Limit(pageSize).Offset(offset).
+		Find(&elevations).Error; err != nil {
+		ops.log.Errorf("Failed to fetch role elevations: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	for i := range elevations {
+		elevations[i].MarkExpired(now)
+	}
+	return &models.PaginatedRoleElevationList{
+		Data:        elevations,
+		TotalItems:  total,
+		CurrentPage: currentPage,
+		PageSize:    pageSize,
+	}, nil
+}
+
+// ReviewElevation records the review of pending role elevation. Elevation is updated only while pending,
+// as of the review, such that concurrent reviews don't override one another.
+func (ops *operations) ReviewElevation(elevation *models.RoleElevation) error {
+	result := ops.db.Model(&models.RoleElevation{}).
+		Where("id = ? AND status = ? AND expires_at > ?", elevation.ID, models.ElevationStatusPending,
+			elevation.ReviewedAt).
+		Select("status", "reviewed_by", "reviewed_at", "expires_at").Updates(elevation)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to review role elevation with id %d: %v", elevation.ID, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrElevationAlreadyReviewed
+	}
+	return nil
+}
+
+// RevokeElevation records the revocation of active role elevation, ending its window ahead of time.
+// Elevation is updated only while its window hasn't ended, such that it isn't revoked twice.
+func (ops *operations) RevokeElevation(elevation *models.RoleElevation) error {
+	result := ops.db.Model(&models.RoleElevation{}).
+		Where("id = ? AND status = ? AND expires_at > ?", elevation.ID, models.ElevationStatusApproved,
+			elevation.RevokedAt).
+		Select("status", "revoked_by", "revoked_at", "expires_at").Updates(elevation)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to revoke role elevation with id %d: %v", elevation.ID, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrElevationNotActive
+	}
+	return nil
+}
+
+// GetUserRole fetches the role assigned to the user in DB, regardless of any elevation held.
+func (ops *operations) GetUserRole(userID uint) (string, error) {
+	user := new(models.User)
+	if err := ops.db.Select("role").Where("id = ?", userID).Take(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", appErrors.ErrUserDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch role of user with id %d: %v", userID, err)
+		return "", appErrors.ErrInternal
+	}
+	return user.Role, nil
+}
@@ -0,0 +1,155 @@
+package elevation
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Role Elevation [operations]", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		mockDb *sql.DB
+		ops    *operations
+	)
+	BeforeEach(func() {
+		mockDb, mock, _ = sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ := gorm.Open(dialector)
+		ops = newOperations(db, zap.NewExample().Sugar())
+	})
+
+	Context("create elevation", func() {
+		It("Elevation to the role is already pending", func() {
+			createdAt := time.Now()
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "role_elevation" `+
+				`WHERE user_id = $1 AND role = $2 AND status = $3 AND expires_at > $4`)).
+				WithArgs(2, "admin", models.ElevationStatusPending, createdAt).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectRollback()
+			err := ops.CreateElevation(&models.RoleElevation{CreatedAt: createdAt, UserID: 2, Role: "admin",
+				Status: models.ElevationStatusPending})
+			Expect(err).To(MatchError(appErrors.ErrElevationAlreadyPending))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Unexpected DB issues", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "role_elevation"`)).
+				WillReturnError(errors.New("connection is already closed"))
+			mock.ExpectRollback()
+			err := ops.CreateElevation(&models.RoleElevation{UserID: 2, Role: "admin"})
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully create elevation", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "role_elevation"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "role_elevation"`)).
+				WithArgs(sqlmock.AnyArg(), 2, "admin", "incident", 60, models.ElevationStatusPending, nil, nil, nil, nil,
+					nil).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+			mock.ExpectCommit()
+			elevation := &models.RoleElevation{UserID: 2, Role: "admin", Justification: "incident",
+				DurationInMinutes: 60, Status: models.ElevationStatusPending}
+			Expect(ops.CreateElevation(elevation)).To(BeNil())
+			Expect(elevation.ID).To(Equal(uint(5)))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("get elevation", func() {
+		It("Elevation doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "role_elevation" WHERE id = $1`)).
+				WithArgs(5, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, err := ops.GetElevation(5)
+			Expect(err).To(MatchError(appErrors.ErrElevationDoesNotExist))
+		})
+	})
+	Context("fetch elevations", func() {
+		It("Expired elevations of the user", func() {
+			now := time.Now()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "role_elevation" WHERE user_id = $1 `+
+				`AND (status IN ($2,$3) AND expires_at <= $4)`)).
+				WithArgs(2, models.ElevationStatusApproved, models.ElevationStatusPending, now).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "role_elevation" WHERE user_id = $1 `+
+				`AND (status IN ($2,$3) AND expires_at <= $4) ORDER BY id DESC LIMIT $5`)).
+				WithArgs(2, models.ElevationStatusApproved, models.ElevationStatusPending, now, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "status", "expires_at"}).
+					AddRow(3, 2, models.ElevationStatusApproved, now.Add(-time.Minute)))
+			elevations, err := ops.FetchElevationsWithPagination(2, models.ElevationStatusExpired, now, 1, 10)
+			Expect(err).To(BeNil())
+			Expect(elevations.TotalItems).To(Equal(int64(1)))
+			Expect(elevations.Data[0].Status).To(Equal(models.ElevationStatusExpired))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Unexpected DB issues", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "role_elevation"`)).
+				WillReturnError(errors.New("connection is already closed"))
+			_, err := ops.FetchElevationsWithPagination(0, "", time.Now(), 1, 10)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+	})
+	Context("review elevation", func() {
+		It("Elevation reviewed concurrently", func() {
+			reviewedBy, reviewedAt := uint(1), time.Now()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "role_elevation" SET "status"=$1,"reviewed_by"=$2,`+
+				`"reviewed_at"=$3,"expires_at"=$4 WHERE id = $5 AND status = $6 AND expires_at > $7`)).
+				WithArgs(models.ElevationStatusDenied, 1, reviewedAt, nil, 5, models.ElevationStatusPending, reviewedAt).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.ReviewElevation(&models.RoleElevation{ID: 5, Status: models.ElevationStatusDenied,
+				ReviewedBy: &reviewedBy, ReviewedAt: &reviewedAt})
+			Expect(err).To(MatchError(appErrors.ErrElevationAlreadyReviewed))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("revoke elevation", func() {
+		It("Elevation revoked concurrently, or its window ended", func() {
+			revokedBy, revokedAt := uint(1), time.Now()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "role_elevation" SET "status"=$1,"expires_at"=$2,`+
+				`"revoked_by"=$3,"revoked_at"=$4 WHERE id = $5 AND status = $6 AND expires_at > $7`)).
+				WithArgs(models.ElevationStatusRevoked, revokedAt, 1, revokedAt, 5, models.ElevationStatusApproved,
+					revokedAt).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.RevokeElevation(&models.RoleElevation{ID: 5, Status: models.ElevationStatusRevoked,
+				RevokedBy: &revokedBy, RevokedAt: &revokedAt, ExpiresAt: &revokedAt})
+			Expect(err).To(MatchError(appErrors.ErrElevationNotActive))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("get role of user", func() {
+		It("User doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "role" FROM "user" WHERE id = $1`)).
+				WithArgs(2, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"role"}))
+			_, err := ops.GetUserRole(2)
+			Expect(err).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("Role assigned to the user", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "role" FROM "user" WHERE id = $1`)).
+				WithArgs(2, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("advanced"))
+			role, err := ops.GetUserRole(2)
+			Expect(err).To(BeNil())
+			Expect(role).To(Equal("advanced"))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package elevation
+
+import (
+	"userservice/internal/configs"
+	"userservice/internal/middleware"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Handler for temporary role elevation.
+type Handler struct {
+	runtimeConfig *configs.Config
+	operations    models.RoleElevationOperations
+	revoker       models.TokenRevocationOperations
+}
+
+// NewHandler initializes role elevation handler context with desired parameters.
+func NewHandler(log *zap.SugaredLogger, config *configs.Config, db *gorm.DB,
+	revoker models.TokenRevocationOperations) *Handler {
+	return &Handler{runtimeConfig: config, operations: newOperations(db, log), revoker: revoker}
+}
+
+// RegisterRoutes has sent of route endpoints categorized as per authz roles using middleware.
+func (h *Handler) RegisterRoutes(routers *gin.RouterGroup) {
+
+	// Authorized routes for all user roles, only through user session, as elevation is held by users.
+	userSessionRoutes := routers.Group("/")
+	userSessionRoutes.Use(middleware.RequireUserSession())
+	{
+		userSessionRoutes.POST("/user/self/elevation", h.requestElevation)
+		userSessionRoutes.GET("/user/self/elevations", h.fetchOwnElevations)
+	}
+
+	// Authorized routes for roles permitted to manage users.
+	userWriteRoutes := routers.Group("/")
+	userWriteRoutes.Use(middleware.RequirePermission(models.PermissionUserWrite))
+	{
+		userWriteRoutes.GET("/elevations", h.fetchElevations)
+	}
+
+	// Authorized routes for roles permitted to manage users, only through user session, such that every review
+	// and revocation is attributed to a user.
+	userWriteSessionRoutes := routers.Group("/")
+	userWriteSessionRoutes.Use(middleware.RequirePermission(models.PermissionUserWrite), middleware.RequireUserSession())
+	{
+		userWriteSessionRoutes.POST("/elevation/:id/approve", h.approveElevation)
+		userWriteSessionRoutes.POST("/elevation/:id/deny", h.denyElevation)
+		userWriteSessionRoutes.POST("/elevation/:id/revoke", h.revokeElevation)
+	}
+}
@@ -0,0 +1,285 @@
+package invitation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"userservice/internal/auth"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+	"userservice/internal/notify"
+	"userservice/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inviteUser invites new user into the system, sending invitation token to the invitee. User is added only once
+// the invitation is accepted, with the password chosen by the invitee.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) inviteUser(c *gin.Context) {
+	var userToInvite map[string]interface{}
+	if err := c.BindJSON(&userToInvite); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Invitation payload is invalid; Expected JSON payload"))
+		return
+	}
+
+	if !utils.EnsureFieldsStrictlyExists(userToInvite, models.InvitationPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Invitation payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.InvitationPayloadTemplate))))
+		return
+	}
+	if err := misc.PayloadValidator.Var(userToInvite[models.AttributeEmail], "required,email"); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Invitation payload contains invalid email"))
+		return
+	}
+	if !misc.IsRoleConfigured(userToInvite[models.AttributeRole].(string)) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("User Role %s doesn't exist", userToInvite[models.AttributeRole].(string))))
+		return
+	}
+	if !misc.CoversPermissions(c.GetString(auth.JWTClaimRole), userToInvite[models.AttributeRole].(string)) {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrGrantingBeyondPermissions.Error()))
+		return
+	}
+
+	invitation := models.Invitation{
+		Name:  userToInvite[models.AttributeName].(string),
+		Email: userToInvite[models.AttributeEmail].(string),
+		Role:  userToInvite[models.AttributeRole].(string),
+	}
+	// let us consider email as the user name if not explicitly mentioned
+	if len(invitation.Name) == 0 {
+		invitation.Name = invitation.Email
+	}
+	// invitation through service account isn't attributed to any user
+	if userID, ok := c.Get(auth.JWTClaimSubject); ok {
+		invitedBy := userID.(uint)
+		invitation.InvitedBy = &invitedBy
+	}
+	message, ok := h.issueInvitationToken(c, &invitation)
+	if !ok {
+		return
+	}
+	if err := h.operations.CreateInvitation(&invitation); err != nil {
+		switch err {
+		case appErrors.ErrUserWithSameEmailAlreadyExists:
+			c.JSON(http.StatusConflict,
+				utils.FormatErrorResponse(fmt.Sprintf("User with email %s already exists", invitation.Email)))
+		case appErrors.ErrInvitationAlreadyExists:
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(
+				fmt.Sprintf("Invitation is already pending for email %s; Resend or revoke it", invitation.Email)))
+		default:
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		}
+		return
+	}
+	if err := h.notifier.Notify(message); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrInvitationNotDelivered.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// issueInvitationToken generates new token and expiry of the invitation, and renders the message delivering the token.
+// Message is rendered ahead of persisting the token, such that a broken template doesn't leave behind an
+// undeliverable invitation. Failure is responded as internal error.
+func (h *Handler) issueInvitationToken(c *gin.Context, invitation *models.Invitation) (notify.Message, bool) {
+	token, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return notify.Message{}, false
+	}
+	invitation.TokenHash = auth.HashOpaqueToken(token)
+	invitation.ExpiresAt = time.Now().Add(time.Second * time.Duration(h.runtimeConfig.InvitationExpirationInSeconds))
+
+	data := notify.InvitationData{Name: invitation.Name, Role: invitation.Role, Token: token,
+		ExpiresAt: invitation.ExpiresAt}
+	if h.runtimeConfig.InvitationURL != "" {
+		data.Link = fmt.Sprintf("%s?token=%s", h.runtimeConfig.InvitationURL, url.QueryEscape(token))
+	}
+	message, err := notify.NewMessage(notify.TemplateInvitation, invitation.Email, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return notify.Message{}, false
+	}
+	return message, true
+}
+
+// fetchInvitations list the pending invitations in system
+func (h *Handler) fetchInvitations(c *gin.Context) {
+	pageStr := c.DefaultQuery("page", "0")
+	page, paramErr := strconv.Atoi(pageStr)
+	if paramErr != nil || page < 0 {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Request Path contains invalid page number, choose positive numerical value"))
+		return
+	}
+	// page 0 or unset page parameter will represent the first page
+	if page == 0 {
+		page = 1
+	}
+
+	pageSizeStr := c.DefaultQuery("size", models.DefaultPageSize)
+	pageSize, paramErr := strconv.Atoi(pageSizeStr)
+	if paramErr != nil || pageSize < 0 {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Request Path contains invalid page size, choose positive numerical value"))
+		return
+	}
+
+	invitations, err := h.operations.FetchInvitationsWithPagination(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// invitationID parses invitation ID path param, responding with client error if it isn't numerical.
+func invitationID(c *gin.Context) (uint, bool) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Invitation ID should be numerical"))
+		return 0, false
+	}
+	return id, true
+}
+
+// resendInvitation sends a new token to the invitee with renewed expiry, the formerly sent token can't be used anymore.
+// Expired invitation can be resent as well, till it is cleaned up.
+func (h *Handler) resendInvitation(c *gin.Context) {
+	id, ok := invitationID(c)
+	if !ok {
+		return
+	}
+	invitation, err := h.operations.GetInvitation(id)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Invitation[ID:%d] doesn't exist", id)))
+		return
+	}
+	message, ok := h.issueInvitationToken(c, invitation)
+	if !ok {
+		return
+	}
+	invitation, err = h.operations.RenewInvitation(id, invitation.TokenHash, invitation.ExpiresAt)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Invitation[ID:%d] doesn't exist", id)))
+		return
+	}
+	if err := h.notifier.Notify(message); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrInvitationNotDelivered.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, invitation)
+}
+
+// revokeInvitation deletes pending invitation, such that it can't be accepted anymore.
+func (h *Handler) revokeInvitation(c *gin.Context) {
+	id, ok := invitationID(c)
+	if !ok {
+		return
+	}
+	if err := h.operations.DeleteInvitation(id); err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Invitation[ID:%d] doesn't exist", id)))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Invitation revoked"))
+}
+
+// acceptInvitation adds the invited user with the password chosen by the invitee, presenting the invitation token.
+// Invitation is single use, and the user can login right away. Invitation to a role deleted meanwhile can't be
+// accepted.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) acceptInvitation(c *gin.Context) {
+	var acceptance map[string]interface{}
+	if err := c.BindJSON(&acceptance); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Invitation acceptance payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(acceptance, models.InvitationAcceptPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Invitation acceptance payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.InvitationAcceptPayloadTemplate))))
+		return
+	}
+
+	tokenHash := auth.HashOpaqueToken(acceptance[models.AttributeInvitationToken].(string))
+	invitation, err := h.operations.GetInvitationByToken(tokenHash)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredInvitation.Error()))
+		return
+	}
+	password := acceptance[models.AttributePassword].(string)
+	if err := h.passwordPolicy.Validate(password, invitation.Email); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	passwordHash, err := h.passwordHasher.Hash(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+
+	user, err := h.operations.AcceptInvitation(tokenHash, passwordHash)
+	if err != nil {
+		switch err {
+		case appErrors.ErrInvalidOrExpiredInvitation:
+			c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(err.Error()))
+		case appErrors.ErrUserWithSameEmailAlreadyExists:
+			c.JSON(http.StatusConflict,
+				utils.FormatErrorResponse(fmt.Sprintf("User with email %s already exists", invitation.Email)))
+		case appErrors.ErrRoleDoesNotExist:
+			c.JSON(http.StatusConflict,
+				utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", invitation.Role)))
+		default:
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// cleanupExpiredInvitations deletes expired invitations at every interval, until the context is done.
+// Non-positive interval disables the cleanup.
+func (h *Handler) cleanupExpiredInvitations(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	cleanupTicker := time.NewTicker(interval)
+	defer cleanupTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanupTicker.C:
+			// failure is logged by operations, and cleanup is retried at next tick
+			if deleted, err := h.operations.DeleteExpiredInvitations(time.Now()); err == nil && deleted > 0 {
+				h.log.Infof("Deleted %d expired invitations", deleted)
+			}
+		}
+	}
+}
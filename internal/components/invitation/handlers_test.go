@@ -0,0 +1,273 @@
+package invitation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+	"userservice/internal/auth"
+	"userservice/internal/configs"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+func GetTestGinContext(w *httptest.ResponseRecorder) *gin.Context {
+	gin.SetMode(gin.TestMode)
+
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{},
+	}
+	return ctx
+}
+func MockJsonPostOrPut(c *gin.Context, content interface{}) {
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	jsonbytes, err := json.Marshal(content)
+	if err != nil {
+		panic(err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonbytes))
+}
+
+var _ = Describe("Invitations", func() {
+
+	var (
+		ctx        *gin.Context
+		handler    *Handler
+		w          *httptest.ResponseRecorder
+		operations *InvitationMock
+		notifier   *NotifierMock
+	)
+	BeforeEach(func() {
+		handler = new(Handler)
+		handler.runtimeConfig = &configs.Config{InvitationExpirationInSeconds: 3600}
+		handler.passwordPolicy = auth.NewPasswordPolicy(auth.MinLength(10))
+		handler.passwordHasher = auth.NewArgon2idHasher(auth.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1})
+		handler.log = zap.NewExample().Sugar()
+		operations = &InvitationMock{Invitation: &models.Invitation{ID: 1, Name: "invitee",
+			Email: "invitee@mgmtportal.com", Role: "basic", TokenHash: auth.HashOpaqueToken("token"),
+			ExpiresAt: time.Now().Add(time.Hour)}}
+		handler.operations = operations
+		notifier = &NotifierMock{}
+		handler.notifier = notifier
+		w = httptest.NewRecorder()
+		ctx = GetTestGinContext(w)
+		misc.InitPayloadValidator()
+		misc.SetRole(models.UserRole{Name: "basic"})
+	})
+	Context("inviteUser", func() {
+		It("Invalid payload", func() {
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Invitation payload is invalid; Expected JSON payload"))
+		})
+		It("Missing fields in payload", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "invitee@mgmtportal.com"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Invitation payload is invalid; Strictly Allowed Params:"))
+		})
+		It("Invalid email", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "invitee", "role": "basic"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Invitation payload contains invalid email"))
+		})
+		It("Invalid role", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "invitee@mgmtportal.com",
+				"role": "unknown"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("User Role unknown doesn't exist"))
+		})
+		It("Role granted permissions beyond the requester's", func() {
+			misc.SetRole(models.UserRole{Name: "recruiter", Permissions: []string{models.PermissionInvitationWrite}})
+			misc.SetRole(models.UserRole{Name: models.RoleAdmin, Permissions: []string{models.PermissionInvitationWrite,
+				models.PermissionUserWrite}})
+			defer misc.RemoveRole("recruiter")
+			defer misc.RemoveRole(models.RoleAdmin)
+			ctx.Set("role", "recruiter")
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "invitee@mgmtportal.com",
+				"role": "admin"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrGrantingBeyondPermissions.Error()))
+			Expect(notifier.Messages).To(BeEmpty())
+		})
+		It("User with same email exists", func() {
+			operations.SetUserExists = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "invitee@mgmtportal.com",
+				"role": "basic"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring("User with email invitee@mgmtportal.com already exists"))
+			Expect(notifier.Messages).To(BeEmpty())
+		})
+		It("Invitation is already pending", func() {
+			operations.SetInvitationExists = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "invitee@mgmtportal.com",
+				"role": "basic"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring("Invitation is already pending"))
+		})
+		It("Delivery failure", func() {
+			notifier.SetInternalError = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "invitee@mgmtportal.com",
+				"role": "basic"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvitationNotDelivered.Error()))
+		})
+		It("Successfully invite user", func() {
+			handler.runtimeConfig.InvitationURL = "https://mgmtportal.com/invitation"
+			ctx.Set(auth.JWTClaimSubject, uint(7))
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "invitee@mgmtportal.com",
+				"role": "basic"})
+			handler.inviteUser(ctx)
+			Expect(w.Code).To(Equal(201))
+			Expect(w.Body.String()).To(Not(ContainSubstring("token")))
+			invitation := operations.Invitation
+			Expect(invitation.Name).To(Equal("invitee@mgmtportal.com"))
+			Expect(*invitation.InvitedBy).To(Equal(uint(7)))
+			Expect(invitation.ExpiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+			Expect(notifier.Messages).To(HaveLen(1))
+			Expect(notifier.Messages[0].To).To(Equal("invitee@mgmtportal.com"))
+			Expect(notifier.Messages[0].Body).To(ContainSubstring("https://mgmtportal.com/invitation?token="))
+			// only the hash of the sent token is persisted
+			link := notifier.Messages[0].Body[strings.Index(notifier.Messages[0].Body, "https://"):]
+			token, _ := url.QueryUnescape(strings.TrimSpace(strings.SplitN(link, "token=", 2)[1]))
+			Expect(invitation.TokenHash).To(Equal(auth.HashOpaqueToken(token)))
+		})
+	})
+	Context("fetchInvitations", func() {
+		It("Invalid page", func() {
+			ctx.Request.URL.RawQuery = "page=-1"
+			handler.fetchInvitations(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Internal error", func() {
+			operations.SetInternalError = true
+			handler.fetchInvitations(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Successfully list invitations", func() {
+			handler.fetchInvitations(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("invitee@mgmtportal.com"))
+		})
+	})
+	Context("resendInvitation", func() {
+		It("Non-numerical ID", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "invalid"}}
+			handler.resendInvitation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Invitation ID should be numerical"))
+		})
+		It("Invitation doesn't exist", func() {
+			operations.SetInvitationDoesntExist = true
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			handler.resendInvitation(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Successfully resend invitation with new token", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			formerTokenHash := operations.Invitation.TokenHash
+			handler.resendInvitation(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.Invitation.TokenHash).To(Not(Equal(formerTokenHash)))
+			Expect(notifier.Messages).To(HaveLen(1))
+			Expect(notifier.Messages[0].Body).To(ContainSubstring("invitation token:"))
+		})
+	})
+	Context("revokeInvitation", func() {
+		It("Invitation doesn't exist", func() {
+			operations.SetInvitationDoesntExist = true
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			handler.revokeInvitation(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Successfully revoke invitation", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			handler.revokeInvitation(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.Invitation).To(BeNil())
+		})
+	})
+	Context("acceptInvitation", func() {
+		It("Missing fields in payload", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "token"})
+			handler.acceptInvitation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Strictly Allowed Params:"))
+		})
+		It("Invalid token", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "unknown", "password": "long enough password"})
+			handler.acceptInvitation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredInvitation.Error()))
+		})
+		It("Password violates policy", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "token", "password": "short"})
+			handler.acceptInvitation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(operations.AcceptedPasswordHash).To(BeEmpty())
+		})
+		It("Invitation accepted concurrently", func() {
+			operations.SetInvitationAlreadyTaken = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "token", "password": "long enough password"})
+			handler.acceptInvitation(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredInvitation.Error()))
+		})
+		It("Role of the invitation got deleted meanwhile", func() {
+			operations.SetRoleDeleted = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "token", "password": "long enough password"})
+			handler.acceptInvitation(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring("User Role basic doesn't exist"))
+		})
+		It("Successfully accept invitation", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "token", "password": "long enough password"})
+			handler.acceptInvitation(ctx)
+			Expect(w.Code).To(Equal(201))
+			Expect(w.Body.String()).To(ContainSubstring(`"email":"invitee@mgmtportal.com"`))
+			Expect(auth.IsPasswordReused("long enough password",
+				[]string{operations.AcceptedPasswordHash})).To(BeTrue())
+		})
+	})
+	It("cleans up expired invitations periodically", func() {
+		cleanupCtx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			handler.cleanupExpiredInvitations(cleanupCtx, 10*time.Millisecond)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(operations.ExpiredDeletedBefore).To(BeTemporally("~", time.Now(), time.Second))
+	})
+	It("cleanup is disabled by non-positive interval", func() {
+		handler.runtimeConfig.InvitationCleanupIntervalInSeconds = 0
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			handler.StartCleanup(context.Background())
+		}()
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -0,0 +1,129 @@
+package invitation
+
+import (
+	"errors"
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+	"userservice/internal/notify"
+)
+
+// InvitationMock...
+type InvitationMock struct {
+	Invitation                *models.Invitation
+	AcceptedPasswordHash      string
+	ExpiredDeletedBefore      time.Time
+	SetInternalError          bool
+	SetInvitationDoesntExist  bool
+	SetInvitationExists       bool
+	SetUserExists             bool
+	SetInvitationInvalid      bool
+	SetInvitationAlreadyTaken bool
+	SetRoleDeleted            bool
+}
+
+// CreateInvitation...
+func (m *InvitationMock) CreateInvitation(invitation *models.Invitation) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetUserExists {
+		return appErrors.ErrUserWithSameEmailAlreadyExists
+	} else if m.SetInvitationExists {
+		return appErrors.ErrInvitationAlreadyExists
+	}
+	invitation.ID = 1
+	m.Invitation = invitation
+	return nil
+}
+
+// GetInvitation...
+func (m *InvitationMock) GetInvitation(uint) (*models.Invitation, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetInvitationDoesntExist {
+		return nil, appErrors.ErrInvitationDoesNotExist
+	}
+	invitation := *m.Invitation
+	return &invitation, nil
+}
+
+// GetInvitationByToken...
+func (m *InvitationMock) GetInvitationByToken(tokenHash string) (*models.Invitation, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetInvitationInvalid || m.Invitation.TokenHash != tokenHash {
+		return nil, appErrors.ErrInvalidOrExpiredInvitation
+	}
+	return m.Invitation, nil
+}
+
+// FetchInvitationsWithPagination...
+func (m *InvitationMock) FetchInvitationsWithPagination(page int, size int) (*models.PaginatedInvitationList, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	return &models.PaginatedInvitationList{Data: []models.Invitation{*m.Invitation},
+		TotalItems: 1, CurrentPage: page, PageSize: size}, nil
+}
+
+// RenewInvitation...
+func (m *InvitationMock) RenewInvitation(_ uint, tokenHash string, expiresAt time.Time) (*models.Invitation, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetInvitationDoesntExist {
+		return nil, appErrors.ErrInvitationDoesNotExist
+	}
+	m.Invitation.TokenHash, m.Invitation.ExpiresAt = tokenHash, expiresAt
+	return m.Invitation, nil
+}
+
+// DeleteInvitation...
+func (m *InvitationMock) DeleteInvitation(uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetInvitationDoesntExist {
+		return appErrors.ErrInvitationDoesNotExist
+	}
+	m.Invitation = nil
+	return nil
+}
+
+// AcceptInvitation...
+func (m *InvitationMock) AcceptInvitation(_ string, passwordHash string) (*models.User, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetInvitationAlreadyTaken {
+		return nil, appErrors.ErrInvalidOrExpiredInvitation
+	} else if m.SetUserExists {
+		return nil, appErrors.ErrUserWithSameEmailAlreadyExists
+	} else if m.SetRoleDeleted {
+		return nil, appErrors.ErrRoleDoesNotExist
+	}
+	m.AcceptedPasswordHash = passwordHash
+	return &models.User{DBModel: models.DBModel{ID: 5}, Name: m.Invitation.Name, Email: m.Invitation.Email,
+		Role: m.Invitation.Role, PasswordHash: passwordHash}, nil
+}
+
+// DeleteExpiredInvitations...
+func (m *InvitationMock) DeleteExpiredInvitations(now time.Time) (int64, error) {
+	if m.SetInternalError {
+		return 0, appErrors.ErrInternal
+	}
+	m.ExpiredDeletedBefore = now
+	return 1, nil
+}
+
+// NotifierMock...
+type NotifierMock struct {
+	SetInternalError bool
+	Messages         []notify.Message
+}
+
+// Notify...
+func (m *NotifierMock) Notify(message notify.Message) error {
+	if m.SetInternalError {
+		return errors.New("delivery failed")
+	}
+	m.Messages = append(m.Messages, message)
+	return nil
+}
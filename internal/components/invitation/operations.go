@@ -0,0 +1,185 @@
+package invitation
+
+import (
+	"errors"
+	"strings"
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// operations...
+type operations struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// newOperations initializes invitation operation handler
+func newOperations(db *gorm.DB, log *zap.SugaredLogger) *operations {
+	return &operations{db: db, log: log}
+}
+
+// CreateInvitation creates invitation record in DB, unless a user or a pending invitation exists with the email.
+// Expired invitation of the email is replaced, without waiting for the cleanup.
+func (ops *operations) CreateInvitation(invitation *models.Invitation) error {
+	var usersWithSameEmail int64
+	if err := ops.db.Model(&models.User{}).Where("email = ?", invitation.Email).
+		Count(&usersWithSameEmail).Error; err != nil {
+		ops.log.Errorf("Failed to determine if user with email %s already exists: %v", invitation.Email, err)
+		return appErrors.ErrInternal
+	}
+	if usersWithSameEmail != 0 {
+		return appErrors.ErrUserWithSameEmailAlreadyExists
+	}
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ? AND expires_at <= ?", invitation.Email, time.Now()).
+			Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return appErrors.ErrInvitationAlreadyExists
+		}
+		ops.log.Errorf("Failed to create invitation of %s: %v", invitation.Email, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// GetInvitation fetches invitation record in DB for the given id
+func (ops *operations) GetInvitation(id uint) (*models.Invitation, error) {
+	invitation := new(models.Invitation)
+	if err := ops.db.Where("id = ?", id).First(invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrInvitationDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch invitation by id %d: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	return invitation, nil
+}
+
+// GetInvitationByToken fetches invitation by hash of its token, only if it isn't expired.
+func (ops *operations) GetInvitationByToken(tokenHash string) (*models.Invitation, error) {
+	invitation := new(models.Invitation)
+	if err := ops.db.Where("token_hash = ?", tokenHash).First(invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrInvalidOrExpiredInvitation
+		}
+		ops.log.Errorf("Failed to fetch invitation by token: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	if invitation.IsExpired(time.Now()) {
+		return nil, appErrors.ErrInvalidOrExpiredInvitation
+	}
+	return invitation, nil
+}
+
+// FetchInvitationsWithPagination responds with pending invitations associated with currentPage of given size.
+// Expired invitations are listed as well, till they are cleaned up.
+func (ops *operations) FetchInvitationsWithPagination(currentPage int,
+	pageSize int) (*models.PaginatedInvitationList, error) {
+
+	var (
+		total       int64
+		invitations []models.Invitation
+	)
+	if err := ops.db.Model(&models.Invitation{}).Count(&total).Error; err != nil {
+		ops.log.Errorf("Failed to get the total count of invitations: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	offset := (currentPage - 1) * pageSize
+	if err := ops.db.Order("id").Limit(pageSize).Offset(offset).Find(&invitations).Error; err != nil {
+		ops.log.Errorf("Failed to fetch invitations: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	return &models.PaginatedInvitationList{
+		Data:        invitations,
+		TotalItems:  total,
+		CurrentPage: currentPage,
+		PageSize:    pageSize,
+	}, nil
+}
+
+// RenewInvitation replaces token of the invitation and extends its expiry, invalidating the former token.
+func (ops *operations) RenewInvitation(id uint, tokenHash string, expiresAt time.Time) (*models.Invitation, error) {
+	invitation, err := ops.GetInvitation(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ops.db.Model(invitation).
+		Updates(map[string]interface{}{"token_hash": tokenHash, "expires_at": expiresAt}).Error; err != nil {
+		ops.log.Errorf("Failed to renew invitation with id %d: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	invitation.TokenHash, invitation.ExpiresAt = tokenHash, expiresAt
+	return invitation, nil
+}
+
+// DeleteInvitation deletes existing record by id, revoking the invitation.
+func (ops *operations) DeleteInvitation(id uint) error {
+	result := ops.db.Where("id = ?", id).Delete(&models.Invitation{})
+	if result.Error != nil {
+		ops.log.Errorf("Failed to delete invitation with id %d: %v", id, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrInvitationDoesNotExist
+	}
+	return nil
+}
+
+// AcceptInvitation adds the invited user with the chosen password, and deletes the invitation within the same
+// transaction. Invitation is claimed through conditional delete, such that concurrent requests can't accept it twice.
+func (ops *operations) AcceptInvitation(tokenHash string, passwordHash string) (*models.User, error) {
+	var user *models.User
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		var invitations []models.Invitation
+		result := tx.Clauses(clause.Returning{}).Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).
+			Delete(&invitations)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(invitations) == 0 {
+			return appErrors.ErrInvalidOrExpiredInvitation
+		}
+		// role is locked, such that it isn't deleted till the user is added, see role deletion
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("name").
+			Where("name = ?", invitations[0].Role).Take(&models.UserRole{}).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return appErrors.ErrRoleDoesNotExist
+			}
+			return err
+		}
+		user = &models.User{Name: invitations[0].Name, Email: invitations[0].Email, Role: invitations[0].Role,
+			PasswordHash: passwordHash}
+		return tx.Create(user).Error
+	})
+	if err != nil {
+		if err == appErrors.ErrInvalidOrExpiredInvitation || err == appErrors.ErrRoleDoesNotExist {
+			return nil, err
+		}
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return nil, appErrors.ErrUserWithSameEmailAlreadyExists
+		}
+		ops.log.Errorf("Failed to accept invitation: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	return user, nil
+}
+
+// DeleteExpiredInvitations deletes invitations expired by the given time, reporting the count of deleted ones.
+func (ops *operations) DeleteExpiredInvitations(now time.Time) (int64, error) {
+	result := ops.db.Where("expires_at <= ?", now).Delete(&models.Invitation{})
+	if result.Error != nil {
+		ops.log.Errorf("Failed to delete expired invitations: %v", result.Error)
+		return 0, appErrors.ErrInternal
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,220 @@
+package invitation
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Invitations [operations]", func() {
+	var (
+		mockLog *zap.SugaredLogger
+		mock    sqlmock.Sqlmock
+		mockDb  *sql.DB
+		ops     *operations
+		db      *gorm.DB
+	)
+	columns := []string{"id", "created_at", "updated_at", "name", "email", "role", "invited_by", "token_hash",
+		"expires_at"}
+	BeforeEach(func() {
+		mockLog = zap.NewExample().Sugar()
+		mockDb, mock, _ = sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ = gorm.Open(dialector)
+		ops = newOperations(db, mockLog)
+	})
+
+	It("Initialize operations", func() {
+		Expect(ops).To(Not(BeNil()))
+	})
+	Context("create invitation", func() {
+		It("User with same email exists", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE email = $1`)).
+				WithArgs("invitee@mgmtportal.com").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			err := ops.CreateInvitation(&models.Invitation{Email: "invitee@mgmtportal.com"})
+			Expect(err).To(MatchError(appErrors.ErrUserWithSameEmailAlreadyExists))
+		})
+		It("Invitation is already pending", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE email = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "invitation" WHERE email = $1 AND expires_at <= $2`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "invitation"`)).
+				WillReturnError(errors.New(appErrors.ErrUniqueKeyConstrainViolation.Error()))
+			mock.ExpectRollback()
+			err := ops.CreateInvitation(&models.Invitation{Email: "invitee@mgmtportal.com"})
+			Expect(err).To(MatchError(appErrors.ErrInvitationAlreadyExists))
+		})
+		It("Successfully create invitation, replacing expired one", func() {
+			expiresAt := time.Now().Add(time.Hour)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE email = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "invitation" WHERE email = $1 AND expires_at <= $2`)).
+				WithArgs("invitee@mgmtportal.com", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "invitation"`)).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "invitee", "invitee@mgmtportal.com", "basic", nil,
+					"hash", expiresAt).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+			mock.ExpectCommit()
+			invitation := &models.Invitation{Name: "invitee", Email: "invitee@mgmtportal.com", Role: "basic",
+				TokenHash: "hash", ExpiresAt: expiresAt}
+			Expect(ops.CreateInvitation(invitation)).To(Succeed())
+			Expect(invitation.ID).To(Equal(uint(3)))
+		})
+	})
+	Context("get invitation", func() {
+		It("Invitation doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitation" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns))
+			_, err := ops.GetInvitation(3)
+			Expect(err).To(MatchError(appErrors.ErrInvitationDoesNotExist))
+		})
+		It("Internal DB error", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitation" WHERE id = $1`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.GetInvitation(3)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Expired invitation is rejected by token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitation" WHERE token_hash = $1`)).
+				WithArgs("hash", 1).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, time.Now(), time.Now(), "invitee",
+					"invitee@mgmtportal.com", "basic", nil, "hash", time.Now().Add(-time.Minute)))
+			_, err := ops.GetInvitationByToken("hash")
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredInvitation))
+		})
+		It("Successfully get invitation by token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitation" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, time.Now(), time.Now(), "invitee",
+					"invitee@mgmtportal.com", "basic", 1, "hash", time.Now().Add(time.Hour)))
+			invitation, err := ops.GetInvitationByToken("hash")
+			Expect(err).To(BeNil())
+			Expect(invitation.Email).To(Equal("invitee@mgmtportal.com"))
+			Expect(*invitation.InvitedBy).To(Equal(uint(1)))
+		})
+	})
+	Context("fetch invitations", func() {
+		It("Successfully fetch invitations", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "invitation"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitation" ORDER BY id LIMIT $1`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, time.Now(), time.Now(), "invitee",
+					"invitee@mgmtportal.com", "basic", nil, "hash", time.Now()))
+			invitations, err := ops.FetchInvitationsWithPagination(1, 10)
+			Expect(err).To(BeNil())
+			Expect(invitations.TotalItems).To(Equal(int64(1)))
+			Expect(invitations.Data).To(HaveLen(1))
+		})
+	})
+	Context("renew invitation", func() {
+		It("Successfully renew invitation", func() {
+			expiresAt := time.Now().Add(time.Hour)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "invitation" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, time.Now(), time.Now(), "invitee",
+					"invitee@mgmtportal.com", "basic", nil, "old", time.Now()))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "invitation" SET "expires_at"=$1,"token_hash"=$2,"updated_at"=$3 WHERE "id" = $4`)).
+				WithArgs(expiresAt, "new", sqlmock.AnyArg(), 3).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			invitation, err := ops.RenewInvitation(3, "new", expiresAt)
+			Expect(err).To(BeNil())
+			Expect(invitation.TokenHash).To(Equal("new"))
+			Expect(invitation.ExpiresAt).To(Equal(expiresAt))
+		})
+	})
+	Context("delete invitation", func() {
+		It("Invitation doesn't exist", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "invitation" WHERE id = $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			Expect(ops.DeleteInvitation(3)).To(MatchError(appErrors.ErrInvitationDoesNotExist))
+		})
+		It("Successfully delete expired invitations", func() {
+			now := time.Now()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "invitation" WHERE expires_at <= $1`)).
+				WithArgs(now).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectCommit()
+			deleted, err := ops.DeleteExpiredInvitations(now)
+			Expect(err).To(BeNil())
+			Expect(deleted).To(Equal(int64(2)))
+		})
+	})
+	Context("accept invitation", func() {
+		deleteQuery := regexp.QuoteMeta(
+			`DELETE FROM "invitation" WHERE token_hash = $1 AND expires_at > $2 RETURNING *`)
+		It("Invalid, expired or concurrently accepted invitation", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(deleteQuery).WithArgs("hash", sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows(columns))
+			mock.ExpectRollback()
+			_, err := ops.AcceptInvitation("hash", "passwordHash")
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredInvitation))
+		})
+		It("Role of the invitation got deleted meanwhile", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(deleteQuery).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, time.Now(), time.Now(), "invitee",
+					"invitee@mgmtportal.com", "basic", nil, "hash", time.Now().Add(time.Hour)))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "name" FROM "user_role" WHERE name = $1 LIMIT $2 FOR SHARE`)).
+				WillReturnRows(sqlmock.NewRows([]string{"name"}))
+			mock.ExpectRollback()
+			_, err := ops.AcceptInvitation("hash", "passwordHash")
+			Expect(err).To(MatchError(appErrors.ErrRoleDoesNotExist))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("User with same email got added meanwhile", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(deleteQuery).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, time.Now(), time.Now(), "invitee",
+					"invitee@mgmtportal.com", "basic", nil, "hash", time.Now().Add(time.Hour)))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "name" FROM "user_role" WHERE name = $1 LIMIT $2 FOR SHARE`)).
+				WithArgs("basic", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("basic"))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user"`)).
+				WillReturnError(errors.New(appErrors.ErrUniqueKeyConstrainViolation.Error()))
+			mock.ExpectRollback()
+			_, err := ops.AcceptInvitation("hash", "passwordHash")
+			Expect(err).To(MatchError(appErrors.ErrUserWithSameEmailAlreadyExists))
+		})
+		It("Successfully accept invitation", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(deleteQuery).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(3, time.Now(), time.Now(), "invitee",
+					"invitee@mgmtportal.com", "basic", nil, "hash", time.Now().Add(time.Hour)))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "name" FROM "user_role" WHERE name = $1 LIMIT $2 FOR SHARE`)).
+				WithArgs("basic", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("basic"))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user"`)).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "invitee", "invitee@mgmtportal.com", "basic",
+					"passwordHash", false, nil, 0, nil, false, "", "", 0, nil, nil, false, false).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+			mock.ExpectCommit()
+			user, err := ops.AcceptInvitation("hash", "passwordHash")
+			Expect(err).To(BeNil())
+			Expect(user.ID).To(Equal(uint(5)))
+			Expect(user.IsTemporaryPassword).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,58 @@
+package invitation
+
+import (
+	"context"
+	"time"
+	"userservice/internal/auth"
+	"userservice/internal/configs"
+	"userservice/internal/middleware"
+	"userservice/internal/models"
+	"userservice/internal/notify"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Handler for invitation based user onboarding.
+type Handler struct {
+	runtimeConfig  *configs.Config
+	operations     models.InvitationOperations
+	passwordPolicy *auth.PasswordPolicy
+	passwordHasher auth.PasswordHasher
+	notifier       notify.Notifier
+	log            *zap.SugaredLogger
+}
+
+// NewHandler initializes invitation handler context with desired parameters.
+func NewHandler(log *zap.SugaredLogger, config *configs.Config, db *gorm.DB, passwordPolicy *auth.PasswordPolicy,
+	passwordHasher auth.PasswordHasher, notifier notify.Notifier) *Handler {
+	return &Handler{runtimeConfig: config, operations: newOperations(db, log), passwordPolicy: passwordPolicy,
+		passwordHasher: passwordHasher, notifier: notifier, log: log}
+}
+
+// StartCleanup deletes expired invitations at the configured interval, until the context is done.
+func (h *Handler) StartCleanup(ctx context.Context) {
+	h.cleanupExpiredInvitations(ctx, time.Second*time.Duration(h.runtimeConfig.InvitationCleanupIntervalInSeconds))
+}
+
+// RegisterRoutes has sent of route endpoints categorized as per authz roles using middleware.
+func (h *Handler) RegisterRoutes(routers *gin.RouterGroup) {
+
+	// Invitee accepts the invitation, presenting the invitation token in place of a token.
+	routers.POST("/invitation/accept", h.acceptInvitation)
+
+	// Authorized routes for roles permitted to view and manage invitations respectively.
+	invitationReadRoutes := routers.Group("/")
+	invitationReadRoutes.Use(middleware.RequirePermission(models.PermissionInvitationRead))
+	{
+		invitationReadRoutes.GET("/invitations", h.fetchInvitations)
+	}
+	invitationWriteRoutes := routers.Group("/")
+	invitationWriteRoutes.Use(middleware.RequirePermission(models.PermissionInvitationWrite))
+	{
+		invitationWriteRoutes.POST("/invitation", h.inviteUser)
+		invitationWriteRoutes.POST("/invitation/:id/resend", h.resendInvitation)
+		invitationWriteRoutes.DELETE("/invitation/:id", h.revokeInvitation)
+	}
+}
@@ -0,0 +1,24 @@
+package invitation
+
+import (
+	"userservice/internal/configs"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Invitation [Handler]", func() {
+
+	It("Initializer Handler, list and ensure expected number of routes", func() {
+		h := NewHandler(zap.NewExample().Sugar(), &configs.Config{InvitationCleanupIntervalInSeconds: 60},
+			nil, nil, nil, nil)
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		apiGroup := router.Group("/api/v1")
+		h.RegisterRoutes(apiGroup)
+		routes := router.Routes()
+		Expect(routes).To(HaveLen(5))
+	})
+})
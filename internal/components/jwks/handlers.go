@@ -0,0 +1,13 @@
+package jwks
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fetchKeySet respond with public keys against which issued tokens can be verified.
+func (h *Handler) fetchKeySet(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
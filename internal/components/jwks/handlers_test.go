@@ -0,0 +1,58 @@
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"userservice/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fetch Key Set [Handler]", func() {
+
+	var (
+		ctx     *gin.Context
+		handler *Handler
+		w       *httptest.ResponseRecorder
+	)
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		w = httptest.NewRecorder()
+		ctx, _ = gin.CreateTestContext(w)
+	})
+
+	It("Shared secret is never published", func() {
+		handler = NewHandler(auth.NewHMACKeySet([]byte("secret")))
+		handler.fetchKeySet(ctx)
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Body.String()).To(MatchJSON(`{"keys":[]}`))
+	})
+	It("Public signing key is published", func() {
+		dir, err := os.MkdirTemp("", "jwks")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+		der, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+		keyFile := filepath.Join(dir, "ed.pem")
+		Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+		keys, err := auth.LoadKeySet(auth.AlgorithmEdDSA, nil, keyFile, nil)
+		Expect(err).To(BeNil())
+
+		handler = NewHandler(keys)
+		handler.fetchKeySet(ctx)
+		Expect(w.Code).To(Equal(200))
+		var jwks auth.JWKSet
+		Expect(json.Unmarshal(w.Body.Bytes(), &jwks)).To(Succeed())
+		Expect(jwks.Keys).To(HaveLen(1))
+		Expect(jwks.Keys[0].KeyType).To(Equal("OKP"))
+		Expect(jwks.Keys[0].Algorithm).To(Equal(auth.AlgorithmEdDSA))
+	})
+})
@@ -0,0 +1,22 @@
+package jwks
+
+import (
+	"userservice/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler for publishing token verification keys.
+type Handler struct {
+	keys *auth.KeySet
+}
+
+// NewHandler initializes jwks handler context with desired parameters.
+func NewHandler(keys *auth.KeySet) *Handler {
+	return &Handler{keys: keys}
+}
+
+// RegisterRoutes has sent of route endpoints, which are public as the keys are consumed by other services.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/.well-known/jwks.json", h.fetchKeySet)
+}
@@ -0,0 +1,19 @@
+package jwks
+
+import (
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JWKS [Handler]", func() {
+
+	It("Initializer Handler, list and ensure expected number of routes", func() {
+		h := NewHandler(nil)
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		h.RegisterRoutes(router.Group("/"))
+		routes := router.Routes()
+		Expect(routes).To(HaveLen(1))
+	})
+})
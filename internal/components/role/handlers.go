@@ -1,14 +1,59 @@
 package role
 
 import (
+	"fmt"
 	"net/http"
+	"regexp"
 	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
 	"userservice/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// fetchUserRoles respond with pre-configured user roles
+// roleNamePattern restricts role names to lowercase identifiers, as they are carried in tokens and configs.
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
+// parsePermissions validates the permissions requested for the role, and drops duplicates.
+func parsePermissions(requestedPermissions []interface{}) ([]string, error) {
+	permissions := make([]string, 0, len(requestedPermissions))
+	for _, requestedPermission := range requestedPermissions {
+		permission, ok := requestedPermission.(string)
+		if !ok || !models.IsPermission(permission) {
+			return nil, fmt.Errorf("permission %v doesn't exist; Known permissions: %v", requestedPermission,
+				models.Permissions)
+		}
+		if !containsPermission(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
+// containsPermission...
+func containsPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// respondRoleInheritanceError responds with the rejection of the parent role, reporting whether the error was one.
+func respondRoleInheritanceError(c *gin.Context, err error) bool {
+	if err == appErrors.ErrParentRoleDoesNotExist {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrParentRoleDoesNotExist.Error()))
+		return true
+	} else if err == appErrors.ErrRoleInheritanceCycle {
+		c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrRoleInheritanceCycle.Error()))
+		return true
+	}
+	return false
+}
+
+// fetchUserRoles respond with pre-configured user roles along with their permissions
 func (h *Handler) fetchUserRoles(c *gin.Context) {
 	roles, err := h.operations.FetchRoles()
 	if err != nil {
@@ -17,3 +62,146 @@ func (h *Handler) fetchUserRoles(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, roles)
 }
+
+// addRole configures new role, which can be assigned to users right away.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) addRole(c *gin.Context) {
+	var roleToAdd map[string]interface{}
+	if err := c.BindJSON(&roleToAdd); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Role creation payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(roleToAdd, models.CreateRolePayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Role creation payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.CreateRolePayloadTemplate))))
+		return
+	}
+	name := roleToAdd[models.AttributeName].(string)
+	if !roleNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			"Role creation payload is invalid; name should be lowercase alphanumeric, optionally with '-' or '_'"))
+		return
+	}
+	description := roleToAdd[models.AttributeDescription].(string)
+	if description == "" {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Role creation payload is invalid; description is empty"))
+		return
+	}
+	permissions, err := parsePermissions(roleToAdd[models.AttributePermissions].([]interface{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Role creation payload is invalid; "+err.Error()))
+		return
+	}
+
+	parent := roleToAdd[models.AttributeParent].(string)
+
+	userRole := models.UserRole{Name: name, Description: description, Permissions: permissions, Parent: parent}
+	if err := h.operations.CreateRole(&userRole); err != nil {
+		if err == appErrors.ErrRoleAlreadyExists {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrRoleAlreadyExists.Error()))
+			return
+		} else if respondRoleInheritanceError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	misc.SetRole(userRole)
+	c.JSON(http.StatusCreated, userRole)
+}
+
+// updateRole updates description, permissions and parent of the role, which take effect on the following requests
+// of users holding the role or any role inheriting it.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) updateRole(c *gin.Context) {
+	name := c.Param(models.QueryParamRoleName)
+	var roleToUpdate map[string]interface{}
+	if err := c.BindJSON(&roleToUpdate); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Role update payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(roleToUpdate, models.UpdateRolePayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Role update payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.UpdateRolePayloadTemplate))))
+		return
+	}
+	description := roleToUpdate[models.AttributeDescription].(string)
+	if description == "" {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Role update payload is invalid; description is empty"))
+		return
+	}
+	permissions, err := parsePermissions(roleToUpdate[models.AttributePermissions].([]interface{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Role update payload is invalid; "+err.Error()))
+		return
+	}
+
+	// admin should always be able to manage users and roles, lest no account can recover the others
+	if name == models.RoleAdmin && (!containsPermission(permissions, models.PermissionRoleWrite) ||
+		!containsPermission(permissions, models.PermissionUserWrite)) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrAdminPermissionsRequired.Error()))
+		return
+	}
+
+	parent := roleToUpdate[models.AttributeParent].(string)
+
+	updatedRole, err := h.operations.UpdateRole(name, description, permissions, parent)
+	if err != nil {
+		if err == appErrors.ErrRoleDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", name)))
+			return
+		} else if respondRoleInheritanceError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	misc.SetRole(*updatedRole)
+	c.JSON(http.StatusOK, updatedRole)
+}
+
+// fetchEffectivePermissions responds with the permissions the role is granted, including the ones
+// inherited from its ancestors, as enforced for the requests.
+func (h *Handler) fetchEffectivePermissions(c *gin.Context) {
+	name := c.Param(models.QueryParamRoleName)
+	roleLineage := misc.RoleLineage(name)
+	if len(roleLineage) == 0 {
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", name)))
+		return
+	}
+	c.JSON(http.StatusOK, models.EffectivePermissions{
+		Name:        name,
+		Inherits:    roleLineage[1:],
+		Permissions: misc.RolePermissions(name),
+	})
+}
+
+// deleteRole deletes role from system. Built-in roles, and roles still assigned to
+// users, service accounts or pending invitations, or inherited by other roles, can't be deleted.
+func (h *Handler) deleteRole(c *gin.Context) {
+	name := c.Param(models.QueryParamRoleName)
+	if models.IsBuiltInRole(name) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrBuiltInRole.Error()))
+		return
+	}
+
+	if err := h.operations.DeleteRole(name); err != nil {
+		if err == appErrors.ErrRoleDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", name)))
+			return
+		} else if err == appErrors.ErrRoleInUse || err == appErrors.ErrRoleInherited {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	misc.RemoveRole(name)
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Role deleted from system"))
+}
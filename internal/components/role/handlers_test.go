@@ -1,12 +1,15 @@
 package role
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"userservice/internal/errors"
+	"userservice/internal/misc"
 	"userservice/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -24,6 +27,15 @@ func GetTestGinContext(w *httptest.ResponseRecorder) *gin.Context {
 	}
 	return ctx
 }
+func MockJsonPostOrPut(c *gin.Context, content interface{}) {
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	jsonbytes, err := json.Marshal(content)
+	if err != nil {
+		panic(err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonbytes))
+}
 
 var _ = Describe("Fetch Role [Handler]", func() {
 
@@ -67,3 +79,223 @@ var _ = Describe("Fetch Role [Handler]", func() {
 		Expect(w.Body.String()).To(ContainSubstring(errors.ErrFailureToProcessRequest.Error()))
 	})
 })
+
+var _ = Describe("Manage Roles [Handler]", func() {
+
+	var (
+		ctx        *gin.Context
+		handler    *Handler
+		w          *httptest.ResponseRecorder
+		operations *RoleMockDefault
+	)
+	BeforeEach(func() {
+		operations = new(RoleMockDefault)
+		handler = &Handler{operations: operations}
+		w = httptest.NewRecorder()
+		ctx = GetTestGinContext(w)
+	})
+	Context("Create role", func() {
+		It("Invalid payload", func() {
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Role creation payload is invalid; Expected JSON payload"))
+		})
+		It("Additional fields in payload", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "audit",
+				"permissions": []string{}, "level": 1})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Strictly Allowed Params"))
+		})
+		It("Invalid name", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "Audit Team", "description": "audit",
+				"permissions": []string{}, "parent": ""})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("name should be lowercase alphanumeric"))
+		})
+		It("Empty description", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "",
+				"permissions": []string{}, "parent": ""})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("description is empty"))
+		})
+		It("Duplicate role", func() {
+			operations.SetDuplicate = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "audit",
+				"permissions": []string{models.PermissionServiceRead}, "parent": ""})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(misc.IsRoleConfigured("auditor")).To(BeFalse())
+		})
+		It("Facing DB errors", func() {
+			handler.operations = new(RoleMockForcedError)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "audit",
+				"permissions": []string{models.PermissionServiceRead}, "parent": ""})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Unknown permission", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "audit",
+				"permissions": []string{"service:read", "service:own"}, "parent": ""})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("permission service:own doesn't exist"))
+		})
+		It("Created role is assignable right away", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "audit",
+				"permissions": []string{models.PermissionServiceRead}, "parent": ""})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(201))
+			Expect(misc.IsRoleConfigured("auditor")).To(BeTrue())
+			Expect(misc.RolePermissions("auditor")).To(ConsistOf(models.PermissionServiceRead))
+			misc.RemoveRole("auditor")
+		})
+		It("Parent role doesn't exist", func() {
+			operations.SetMissingParent = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "audit",
+				"permissions": []string{}, "parent": "reviewer"})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(errors.ErrParentRoleDoesNotExist.Error()))
+			Expect(misc.IsRoleConfigured("auditor")).To(BeFalse())
+		})
+		It("Created role inherits permissions of parent", func() {
+			misc.SetRole(models.UserRole{Name: "reviewer", Description: "review",
+				Permissions: []string{models.PermissionUserRead}})
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "auditor", "description": "audit",
+				"permissions": []string{models.PermissionServiceRead}, "parent": "reviewer"})
+			handler.addRole(ctx)
+			Expect(w.Code).To(Equal(201))
+			Expect(misc.HasPermission("auditor", models.PermissionUserRead)).To(BeTrue())
+			Expect(misc.RolePermissions("auditor")).To(
+				Equal([]string{models.PermissionServiceRead, models.PermissionUserRead}))
+			Expect(misc.HasPermission("reviewer", models.PermissionServiceRead)).To(BeFalse())
+			misc.RemoveRole("auditor")
+			misc.RemoveRole("reviewer")
+		})
+	})
+	Context("Update role", func() {
+		BeforeEach(func() {
+			ctx.Params = []gin.Param{{Key: "name", Value: "auditor"}}
+		})
+		It("Empty description", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"description": "", "permissions": []string{}, "parent": ""})
+			handler.updateRole(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Role doesn't exist", func() {
+			operations.SetRoleDoesntExist = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"description": "audit", "permissions": []string{}, "parent": ""})
+			handler.updateRole(ctx)
+			Expect(w.Code).To(Equal(404))
+			Expect(w.Body.String()).To(ContainSubstring("User Role auditor doesn't exist"))
+		})
+		It("Admin role can't be stripped of managing users and roles", func() {
+			ctx.Params = []gin.Param{{Key: "name", Value: models.RoleAdmin}}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"description": "admin",
+				"permissions": []string{models.PermissionRoleWrite}, "parent": "advanced"})
+			handler.updateRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(errors.ErrAdminPermissionsRequired.Error()))
+		})
+		It("Updated description is refreshed in memory", func() {
+			misc.SetRole(models.UserRole{Name: "auditor", Description: "audit"})
+			MockJsonPostOrPut(ctx, map[string]interface{}{"description": "audit services",
+				"permissions": []string{models.PermissionServiceRead, models.PermissionUserRead}, "parent": ""})
+			handler.updateRole(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(misc.ConfiguredRoles()).To(HaveKeyWithValue("auditor", "audit services"))
+			Expect(misc.HasPermission("auditor", models.PermissionUserRead)).To(BeTrue())
+			misc.RemoveRole("auditor")
+		})
+		It("Parent would make role inherit itself", func() {
+			operations.SetCycle = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"description": "audit", "permissions": []string{},
+				"parent": "reviewer"})
+			handler.updateRole(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring(errors.ErrRoleInheritanceCycle.Error()))
+		})
+		It("Parent doesn't exist", func() {
+			operations.SetMissingParent = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"description": "audit", "permissions": []string{},
+				"parent": "reviewer"})
+			handler.updateRole(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+	})
+	Context("Effective permissions", func() {
+		It("Role doesn't exist", func() {
+			ctx.Params = []gin.Param{{Key: "name", Value: "auditor"}}
+			handler.fetchEffectivePermissions(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Permissions inherited through ancestors", func() {
+			misc.SetRole(models.UserRole{Name: "reviewer", Description: "review",
+				Permissions: []string{models.PermissionRoleRead}})
+			misc.SetRole(models.UserRole{Name: "auditor", Description: "audit",
+				Permissions: []string{models.PermissionUserRead}, Parent: "reviewer"})
+			misc.SetRole(models.UserRole{Name: "lead-auditor", Description: "lead audit",
+				Permissions: []string{models.PermissionServiceRead, models.PermissionUserRead}, Parent: "auditor"})
+			ctx.Params = []gin.Param{{Key: "name", Value: "lead-auditor"}}
+			handler.fetchEffectivePermissions(ctx)
+			Expect(w.Code).To(Equal(200))
+			var effective models.EffectivePermissions
+			Expect(json.Unmarshal(w.Body.Bytes(), &effective)).To(BeNil())
+			Expect(effective.Inherits).To(Equal([]string{"auditor", "reviewer"}))
+			Expect(effective.Permissions).To(Equal([]string{models.PermissionServiceRead,
+				models.PermissionUserRead, models.PermissionRoleRead}))
+			misc.RemoveRole("lead-auditor")
+			misc.RemoveRole("auditor")
+			misc.RemoveRole("reviewer")
+		})
+	})
+	Context("Delete role", func() {
+		It("Built-in role", func() {
+			ctx.Params = []gin.Param{{Key: "name", Value: models.RoleBasic}}
+			handler.deleteRole(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(errors.ErrBuiltInRole.Error()))
+			Expect(operations.DeletedRoles).To(BeEmpty())
+		})
+		It("Role doesn't exist", func() {
+			operations.SetRoleDoesntExist = true
+			ctx.Params = []gin.Param{{Key: "name", Value: "auditor"}}
+			handler.deleteRole(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Role still assigned", func() {
+			misc.SetRole(models.UserRole{Name: "auditor", Description: "audit"})
+			operations.SetRoleInUse = true
+			ctx.Params = []gin.Param{{Key: "name", Value: "auditor"}}
+			handler.deleteRole(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring(errors.ErrRoleInUse.Error()))
+			Expect(misc.IsRoleConfigured("auditor")).To(BeTrue())
+			misc.RemoveRole("auditor")
+		})
+		It("Role still inherited", func() {
+			operations.SetRoleInherited = true
+			ctx.Params = []gin.Param{{Key: "name", Value: "auditor"}}
+			handler.deleteRole(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring(errors.ErrRoleInherited.Error()))
+		})
+		It("Facing DB errors", func() {
+			handler.operations = new(RoleMockForcedError)
+			ctx.Params = []gin.Param{{Key: "name", Value: "auditor"}}
+			handler.deleteRole(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Deleted role is no longer assignable", func() {
+			misc.SetRole(models.UserRole{Name: "auditor", Description: "audit"})
+			ctx.Params = []gin.Param{{Key: "name", Value: "auditor"}}
+			handler.deleteRole(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.DeletedRoles).To(ConsistOf("auditor"))
+			Expect(misc.IsRoleConfigured("auditor")).To(BeFalse())
+		})
+	})
+})
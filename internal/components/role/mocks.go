@@ -2,15 +2,60 @@ package role
 
 import (
 	"errors"
+	appErrors "userservice/internal/errors"
 	"userservice/internal/models"
 )
 
 // RoleMockDefault...
-type RoleMockDefault struct{}
+type RoleMockDefault struct {
+	SetRoleDoesntExist bool
+	SetRoleInUse       bool
+	SetDuplicate       bool
+	SetMissingParent   bool
+	SetCycle           bool
+	SetRoleInherited   bool
+	DeletedRoles       []string
+}
 
 // FetchRoles...
 func (m *RoleMockDefault) FetchRoles() ([]models.UserRole, error) {
-	return []models.UserRole{{Name: "basic", Description: "desc"}}, nil
+	return []models.UserRole{{Name: "basic", Description: "desc", Permissions: []string{models.PermissionServiceRead}}}, nil
+}
+
+// CreateRole...
+func (m *RoleMockDefault) CreateRole(*models.UserRole) error {
+	if m.SetDuplicate {
+		return appErrors.ErrRoleAlreadyExists
+	} else if m.SetMissingParent {
+		return appErrors.ErrParentRoleDoesNotExist
+	}
+	return nil
+}
+
+// UpdateRole...
+func (m *RoleMockDefault) UpdateRole(name string, description string, permissions []string,
+	parent string) (*models.UserRole, error) {
+	if m.SetRoleDoesntExist {
+		return nil, appErrors.ErrRoleDoesNotExist
+	} else if m.SetMissingParent {
+		return nil, appErrors.ErrParentRoleDoesNotExist
+	} else if m.SetCycle {
+		return nil, appErrors.ErrRoleInheritanceCycle
+	}
+	return &models.UserRole{Name: name, Description: description, Permissions: permissions, Parent: parent}, nil
+}
+
+// DeleteRole...
+func (m *RoleMockDefault) DeleteRole(name string) error {
+	if m.SetRoleDoesntExist {
+		return appErrors.ErrRoleDoesNotExist
+	} else if m.SetRoleInUse {
+		return appErrors.ErrRoleInUse
+	} else if m.SetRoleInherited {
+		return appErrors.ErrRoleInherited
+	}
+	m.DeletedRoles = append(m.DeletedRoles, name)
+	return nil
 }
 
 // RoleMockForcedError...
@@ -22,3 +67,18 @@ type RoleMockForcedError struct {
 func (m *RoleMockForcedError) FetchRoles() ([]models.UserRole, error) {
 	return nil, errors.New("connection error")
 }
+
+// CreateRole...
+func (m *RoleMockForcedError) CreateRole(*models.UserRole) error {
+	return appErrors.ErrInternal
+}
+
+// UpdateRole...
+func (m *RoleMockForcedError) UpdateRole(string, string, []string, string) (*models.UserRole, error) {
+	return nil, appErrors.ErrInternal
+}
+
+// DeleteRole...
+func (m *RoleMockForcedError) DeleteRole(string) error {
+	return appErrors.ErrInternal
+}
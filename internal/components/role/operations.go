@@ -1,11 +1,15 @@
 package role
 
 import (
+	"errors"
+	"strings"
+	"time"
 	appErrors "userservice/internal/errors"
 	"userservice/internal/models"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // operations...
@@ -28,3 +32,138 @@ func (ops *operations) FetchRoles() (userRoles []models.UserRole, returnErr erro
 	}
 	return
 }
+
+// ensureAcyclicInheritance walks up the ancestors of the parent, ensuring the role isn't one of them.
+// Ancestors are locked till the transaction completes, such that concurrent updates can't close a cycle.
+func ensureAcyclicInheritance(tx *gorm.DB, name string, parent string) error {
+	visited := make(map[string]struct{})
+	for ancestor := parent; ancestor != ""; {
+		if _, ok := visited[ancestor]; ancestor == name || ok {
+			return appErrors.ErrRoleInheritanceCycle
+		}
+		visited[ancestor] = struct{}{}
+		var ancestorRole models.UserRole
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("parent").
+			Where("name = ?", ancestor).Take(&ancestorRole).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return appErrors.ErrParentRoleDoesNotExist
+			}
+			return err
+		}
+		ancestor = ancestorRole.Parent
+	}
+	return nil
+}
+
+// CreateRole creates role record in DB, inheriting the parent role if any.
+func (ops *operations) CreateRole(userRole *models.UserRole) error {
+	var rolesWithSameName int64
+	if err := ops.db.Model(&models.UserRole{}).Where("name = ?", userRole.Name).
+		Count(&rolesWithSameName).Error; err != nil {
+		ops.log.Errorf("Failed to determine if role %s is already configured: %v", userRole.Name, err)
+		return appErrors.ErrInternal
+	}
+	if rolesWithSameName != 0 {
+		return appErrors.ErrRoleAlreadyExists
+	}
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureAcyclicInheritance(tx, userRole.Name, userRole.Parent); err != nil {
+			return err
+		}
+		return tx.Model(&models.UserRole{}).Create(userRole).Error
+	})
+	if err != nil {
+		if errors.Is(err, appErrors.ErrParentRoleDoesNotExist) || errors.Is(err, appErrors.ErrRoleInheritanceCycle) {
+			return err
+		} else if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return appErrors.ErrRoleAlreadyExists
+		}
+		ops.log.Errorf("Failed to create role %s: %v", userRole.Name, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// UpdateRole updates description, permissions and parent of existing role.
+// Parent is rejected if it would make the role inherit itself.
+func (ops *operations) UpdateRole(name string, description string, permissions []string,
+	parent string) (*models.UserRole, error) {
+	userRole := models.UserRole{Name: name, Description: description, Permissions: permissions, Parent: parent}
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureAcyclicInheritance(tx, name, parent); err != nil {
+			return err
+		}
+		result := tx.Model(&models.UserRole{}).Where("name = ?", name).
+			Select("description", "permissions", "parent").Updates(&userRole)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return appErrors.ErrRoleDoesNotExist
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, appErrors.ErrRoleDoesNotExist) || errors.Is(err, appErrors.ErrParentRoleDoesNotExist) ||
+			errors.Is(err, appErrors.ErrRoleInheritanceCycle) {
+			return nil, err
+		}
+		ops.log.Errorf("Failed to update role %s: %v", name, err)
+		return nil, appErrors.ErrInternal
+	}
+	return &userRole, nil
+}
+
+// DeleteRole deletes role, unless it is still assigned to users, service accounts or pending invitations,
+// or inherited by other roles. Role is locked ahead of checking the assignments, such that invitation accepted
+// concurrently, which locks the role as well, either adds the user ahead of the checks or finds the role deleted.
+func (ops *operations) DeleteRole(name string) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("name").Where("name = ?", name).
+			Take(&models.UserRole{}).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return appErrors.ErrRoleDoesNotExist
+			}
+			return err
+		}
+		var assignedUsers, assignedServiceAccounts, pendingInvitations int64
+		if err := tx.Model(&models.User{}).Where("role = ?", name).Count(&assignedUsers).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ServiceAccount{}).Where("role = ?", name).
+			Count(&assignedServiceAccounts).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Invitation{}).Where("role = ? AND expires_at > ?", name, time.Now()).
+			Count(&pendingInvitations).Error; err != nil {
+			return err
+		}
+		if assignedUsers != 0 || assignedServiceAccounts != 0 || pendingInvitations != 0 {
+			return appErrors.ErrRoleInUse
+		}
+		var childRoles int64
+		if err := tx.Model(&models.UserRole{}).Where("parent = ?", name).Count(&childRoles).Error; err != nil {
+			return err
+		}
+		if childRoles != 0 {
+			return appErrors.ErrRoleInherited
+		}
+		result := tx.Where("name = ?", name).Delete(&models.UserRole{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return appErrors.ErrRoleDoesNotExist
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, appErrors.ErrRoleInUse) || errors.Is(err, appErrors.ErrRoleInherited) ||
+			errors.Is(err, appErrors.ErrRoleDoesNotExist) {
+			return err
+		}
+		ops.log.Errorf("Failed to delete role %s: %v", name, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package role
 import (
 	"database/sql"
 	"errors"
+	"regexp"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	. "github.com/onsi/ginkgo"
@@ -44,10 +47,227 @@ var _ = Describe("Fetch Roles [operations]", func() {
 	})
 	It("Successful fetch", func() {
 		ops = newOperations(db, mockLog)
-		mock.ExpectQuery(`SELECT`).WillReturnRows(sqlmock.NewRows([]string{"name", "description"}).AddRow("basic", "read"))
+		mock.ExpectQuery(`SELECT`).WillReturnRows(sqlmock.NewRows([]string{"name", "description", "permissions"}).
+			AddRow("basic", "read", `["service:read","role:read"]`))
 		userRoles, err := ops.FetchRoles()
 		Expect(err).To(BeNil())
-		Expect(userRoles).To(Not(BeNil()))
+		Expect(userRoles).To(HaveLen(1))
+		Expect(userRoles[0].Permissions).To(ConsistOf(models.PermissionServiceRead, models.PermissionRoleRead))
+	})
+	Context("create role", func() {
+		It("Role with same name exists", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role" WHERE name = $1`)).
+				WithArgs("auditor").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			err := ops.CreateRole(&models.UserRole{Name: "auditor", Description: "audit"})
+			Expect(err).To(MatchError(appErrors.ErrRoleAlreadyExists))
+		})
+		It("Role with same name created concurrently", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user_role"`)).
+				WillReturnError(errors.New("duplicate key value violates unique constraint"))
+			mock.ExpectRollback()
+			err := ops.CreateRole(&models.UserRole{Name: "auditor", Description: "audit"})
+			Expect(err).To(MatchError(appErrors.ErrRoleAlreadyExists))
+		})
+		It("successfully create role", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user_role"`)).
+				WithArgs("auditor", "audit", `["service:read"]`, "").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			err := ops.CreateRole(&models.UserRole{Name: "auditor", Description: "audit",
+				Permissions: []string{models.PermissionServiceRead}})
+			Expect(err).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Parent role doesn't exist", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT "parent" FROM "user_role" WHERE name = $1 LIMIT $2 FOR UPDATE`)).
+				WithArgs("reviewer", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent"}))
+			mock.ExpectRollback()
+			err := ops.CreateRole(&models.UserRole{Name: "auditor", Description: "audit", Parent: "reviewer"})
+			Expect(err).To(MatchError(appErrors.ErrParentRoleDoesNotExist))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("successfully create role inheriting parent", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT "parent" FROM "user_role" WHERE name = $1 LIMIT $2 FOR UPDATE`)).
+				WithArgs("reviewer", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent"}).AddRow("basic"))
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT "parent" FROM "user_role" WHERE name = $1 LIMIT $2 FOR UPDATE`)).
+				WithArgs("basic", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent"}).AddRow(""))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user_role"`)).
+				WithArgs("auditor", "audit", nil, "reviewer").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			err := ops.CreateRole(&models.UserRole{Name: "auditor", Description: "audit", Parent: "reviewer"})
+			Expect(err).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("update role", func() {
+		It("Role doesn't exist", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user_role" SET "description"=$1,"permissions"=$2,"parent"=$3 WHERE name = $4`)).
+				WithArgs("audit", `["service:read"]`, "", "auditor").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+			userRole, err := ops.UpdateRole("auditor", "audit", []string{models.PermissionServiceRead}, "")
+			Expect(err).To(MatchError(appErrors.ErrRoleDoesNotExist))
+			Expect(userRole).To(BeNil())
+		})
+		It("Role inheriting itself", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			mock.ExpectRollback()
+			userRole, err := ops.UpdateRole("auditor", "audit", nil, "auditor")
+			Expect(err).To(MatchError(appErrors.ErrRoleInheritanceCycle))
+			Expect(userRole).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Role inheriting itself through ancestors", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT "parent" FROM "user_role" WHERE name = $1 LIMIT $2 FOR UPDATE`)).
+				WithArgs("lead-auditor", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent"}).AddRow("auditor"))
+			mock.ExpectRollback()
+			userRole, err := ops.UpdateRole("auditor", "audit", nil, "lead-auditor")
+			Expect(err).To(MatchError(appErrors.ErrRoleInheritanceCycle))
+			Expect(userRole).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("successfully update role", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT "parent" FROM "user_role" WHERE name = $1 LIMIT $2 FOR UPDATE`)).
+				WithArgs("basic", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent"}).AddRow(""))
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user_role" SET "description"=$1,"permissions"=$2,"parent"=$3 WHERE name = $4`)).
+				WithArgs("audit", `["service:read"]`, "basic", "auditor").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			userRole, err := ops.UpdateRole("auditor", "audit", []string{models.PermissionServiceRead}, "basic")
+			Expect(err).To(BeNil())
+			Expect(userRole.Description).To(Equal("audit"))
+			Expect(userRole.Permissions).To(ConsistOf(models.PermissionServiceRead))
+			Expect(userRole.Parent).To(Equal("basic"))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("delete role", func() {
+		lockQuery := regexp.QuoteMeta(`SELECT "name" FROM "user_role" WHERE name = $1 LIMIT $2 FOR UPDATE`)
+		expectUnassigned := func() {
+			mock.ExpectQuery(lockQuery).WithArgs("auditor", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("auditor"))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE role = $1`)).
+				WithArgs("auditor").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account" WHERE role = $1`)).
+				WithArgs("auditor").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		}
+		It("Role still assigned to users", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			mock.ExpectQuery(lockQuery).WithArgs("auditor", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("auditor"))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE role = $1`)).
+				WithArgs("auditor").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account" WHERE role = $1`)).
+				WithArgs("auditor").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "invitation" WHERE role = $1 AND expires_at > $2`)).
+				WithArgs("auditor", sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectRollback()
+			err := ops.DeleteRole("auditor")
+			Expect(err).To(MatchError(appErrors.ErrRoleInUse))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Role still assigned to pending invitations", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			expectUnassigned()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "invitation" WHERE role = $1 AND expires_at > $2`)).
+				WithArgs("auditor", sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectRollback()
+			err := ops.DeleteRole("auditor")
+			Expect(err).To(MatchError(appErrors.ErrRoleInUse))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Role still inherited by other roles", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			expectUnassigned()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "invitation"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role" WHERE parent = $1`)).
+				WithArgs("auditor").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectRollback()
+			err := ops.DeleteRole("auditor")
+			Expect(err).To(MatchError(appErrors.ErrRoleInherited))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Role doesn't exist", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			mock.ExpectQuery(lockQuery).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+			mock.ExpectRollback()
+			err := ops.DeleteRole("auditor")
+			Expect(err).To(MatchError(appErrors.ErrRoleDoesNotExist))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Unexpected DB issues", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			mock.ExpectQuery(lockQuery).WillReturnError(errors.New("connection is already closed"))
+			mock.ExpectRollback()
+			err := ops.DeleteRole("auditor")
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully delete role", func() {
+			ops = newOperations(db, mockLog)
+			mock.ExpectBegin()
+			expectUnassigned()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "invitation"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_role" WHERE parent = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user_role" WHERE name = $1`)).
+				WithArgs("auditor").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.DeleteRole("auditor")
+			Expect(err).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
 	})
-
 })
@@ -1,6 +1,7 @@
 package role
 
 import (
+	"userservice/internal/middleware"
 	"userservice/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -8,7 +9,7 @@ import (
 	"gorm.io/gorm"
 )
 
-// Handler for service management.
+// Handler for role management.
 type Handler struct {
 	operations models.RoleOperations
 }
@@ -18,9 +19,23 @@ func NewHandler(log *zap.SugaredLogger, db *gorm.DB) *Handler {
 	return &Handler{operations: newOperations(db, log)}
 }
 
-// RegisterRoutes has sent of route
+// RegisterRoutes has sent of route endpoints categorized as per authz roles using middleware.
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 
-	// Authorized routes for all user roles.
-	router.GET("/roles", h.fetchUserRoles)
+	// Authorized routes for roles permitted to view roles.
+	roleReadRoutes := router.Group("/")
+	roleReadRoutes.Use(middleware.RequirePermission(models.PermissionRoleRead))
+	{
+		roleReadRoutes.GET("/roles", h.fetchUserRoles)
+		roleReadRoutes.GET("/role/:name/permissions", h.fetchEffectivePermissions)
+	}
+
+	// Authorized routes for roles permitted to manage roles.
+	roleWriteRoutes := router.Group("/")
+	roleWriteRoutes.Use(middleware.RequirePermission(models.PermissionRoleWrite))
+	{
+		roleWriteRoutes.POST("/role", h.addRole)
+		roleWriteRoutes.PUT("/role/:name", h.updateRole)
+		roleWriteRoutes.DELETE("/role/:name", h.deleteRole)
+	}
 }
@@ -15,6 +15,6 @@ var _ = Describe("Role [Handler]", func() {
 		apiGroup := router.Group("/api/v1")
 		h.RegisterRoutes(apiGroup)
 		routes := router.Routes()
-		Expect(routes).To(HaveLen(1))
+		Expect(routes).To(HaveLen(5))
 	})
 })
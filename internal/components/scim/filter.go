@@ -0,0 +1,104 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+)
+
+// filterableAttributes maps the user attributes, which can be filtered on, to their column. Attributes are
+// lower-cased, as SCIM attribute names are case-insensitive.
+var filterableAttributes = map[string]string{
+	"username":       "email",
+	"emails":         "email",
+	"emails.value":   "email",
+	"displayname":    "name",
+	"name.formatted": "name",
+	"roles":          "role",
+	"roles.value":    "role",
+}
+
+// parseFilter parses SCIM filter into conditions on user columns. Only the subset of filter grammar needed by
+// provisioning clients is supported, i.e. comparisons of string attributes, optionally joined with "and".
+// Grouping, "or" and "not" are rejected along with unknown attributes.
+func parseFilter(filter string) ([]models.UserFilter, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var filters []models.UserFilter
+	for len(tokens) > 0 {
+		if len(filters) > 0 {
+			if !strings.EqualFold(tokens[0], "and") {
+				return nil, appErrors.ErrInvalidSCIMFilter
+			}
+			tokens = tokens[1:]
+		}
+		if len(tokens) < 2 {
+			return nil, appErrors.ErrInvalidSCIMFilter
+		}
+		attribute := strings.ToLower(strings.TrimPrefix(tokens[0], schemaUser+":"))
+		column, ok := filterableAttributes[attribute]
+		if !ok {
+			return nil, appErrors.ErrInvalidSCIMFilter
+		}
+		operator := strings.ToLower(tokens[1])
+		if operator == models.FilterOperatorPresent {
+			filters = append(filters, models.UserFilter{Column: column, Operator: operator})
+			tokens = tokens[2:]
+			continue
+		}
+		if len(tokens) < 3 {
+			return nil, appErrors.ErrInvalidSCIMFilter
+		}
+		switch operator {
+		case models.FilterOperatorEqual, models.FilterOperatorNotEqual, models.FilterOperatorContains,
+			models.FilterOperatorStartsWith, models.FilterOperatorEndsWith:
+		default:
+			return nil, appErrors.ErrInvalidSCIMFilter
+		}
+		// compared value has to be a JSON string, since every filterable attribute is a string
+		var value string
+		if !strings.HasPrefix(tokens[2], `"`) || json.Unmarshal([]byte(tokens[2]), &value) != nil {
+			return nil, appErrors.ErrInvalidSCIMFilter
+		}
+		filters = append(filters, models.UserFilter{Column: column, Operator: operator, Value: value})
+		tokens = tokens[3:]
+	}
+	return filters, nil
+}
+
+// tokenizeFilter splits filter by spaces, keeping quoted values along with their quotes and escapes.
+func tokenizeFilter(filter string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(filter); {
+		switch filter[i] {
+		case ' ':
+			i++
+		case '(', ')', '[', ']':
+			return nil, appErrors.ErrInvalidSCIMFilter
+		case '"':
+			end := i + 1
+			for end < len(filter) && filter[end] != '"' {
+				if filter[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(filter) {
+				return nil, appErrors.ErrInvalidSCIMFilter
+			}
+			tokens = append(tokens, filter[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(filter) && !strings.ContainsRune(` "()[]`, rune(filter[end])) {
+				end++
+			}
+			tokens = append(tokens, filter[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,56 @@
+package scim
+
+import (
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SCIM filter", func() {
+
+	It("empty filter matches every user", func() {
+		filters, err := parseFilter("")
+		Expect(err).To(BeNil())
+		Expect(filters).To(BeEmpty())
+	})
+	It("parse supported filters", func() {
+		for _, test := range []struct {
+			filter   string
+			expected []models.UserFilter
+		}{
+			{`userName eq "john@mgmtportal.com"`,
+				[]models.UserFilter{{Column: "email", Operator: "eq", Value: "john@mgmtportal.com"}}},
+			{`USERNAME Eq "john@mgmtportal.com"`,
+				[]models.UserFilter{{Column: "email", Operator: "eq", Value: "john@mgmtportal.com"}}},
+			{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "john"`,
+				[]models.UserFilter{{Column: "email", Operator: "sw", Value: "john"}}},
+			{`displayName co "Doe \"Jr\""`,
+				[]models.UserFilter{{Column: "name", Operator: "co", Value: `Doe "Jr"`}}},
+			{`emails.value ew "@mgmtportal.com" and roles.value ne "admin" and name.formatted pr`,
+				[]models.UserFilter{{Column: "email", Operator: "ew", Value: "@mgmtportal.com"},
+					{Column: "role", Operator: "ne", Value: "admin"}, {Column: "name", Operator: "pr"}}},
+		} {
+			filters, err := parseFilter(test.filter)
+			Expect(err).To(BeNil(), test.filter)
+			Expect(filters).To(Equal(test.expected), test.filter)
+		}
+	})
+	It("reject unsupported or malformed filters", func() {
+		for _, filter := range []string{
+			`externalId eq "abc"`,
+			`userName gt "a"`,
+			`userName eq true`,
+			`userName eq "john@mgmtportal.com" or userName eq "jane@mgmtportal.com"`,
+			`(userName eq "john@mgmtportal.com")`,
+			`emails[type eq "work"]`,
+			`userName eq "unterminated`,
+			`userName eq`,
+			`userName eq "a" userName eq "b"`,
+		} {
+			_, err := parseFilter(filter)
+			Expect(err).To(MatchError(appErrors.ErrInvalidSCIMFilter), filter)
+		}
+	})
+})
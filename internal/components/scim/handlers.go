@@ -0,0 +1,281 @@
+package scim
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"userservice/internal/auth"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authenticate rejects requests which don't present the SCIM bearer token.
+func (h *Handler) authenticate(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(auth.HashOpaqueToken(token)), []byte(h.tokenHash)) != 1 {
+		respondError(c, newSCIMError(http.StatusUnauthorized, "", appErrors.ErrInvalidSCIMToken.Error()))
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// getServiceProviderConfig...
+func (h *Handler) getServiceProviderConfig(c *gin.Context) {
+	respond(c, http.StatusOK, newServiceProviderConfig(h.runtimeConfig.SCIMMaxResults,
+		h.location(c, "/ServiceProviderConfig")))
+}
+
+// fetchResourceTypes...
+func (h *Handler) fetchResourceTypes(c *gin.Context) {
+	respond(c, http.StatusOK, listResponse{Schemas: []string{schemaListResponse}, TotalResults: 1, StartIndex: 1,
+		ItemsPerPage: 1, Resources: []resourceType{newUserResourceType(h.location(c, "/ResourceTypes/User"))}})
+}
+
+// fetchSchemas...
+func (h *Handler) fetchSchemas(c *gin.Context) {
+	respond(c, http.StatusOK, listResponse{Schemas: []string{schemaListResponse}, TotalResults: 1, StartIndex: 1,
+		ItemsPerPage: 1, Resources: []schema{newUserSchema(h.location(c, "/Schemas/"+schemaUser))}})
+}
+
+// getSchema...
+func (h *Handler) getSchema(c *gin.Context) {
+	if c.Param(models.QueryParamID) != schemaUser {
+		respondError(c, newSCIMError(http.StatusNotFound, "", "Schema doesn't exist"))
+		return
+	}
+	respond(c, http.StatusOK, newUserSchema(h.location(c, "/Schemas/"+schemaUser)))
+}
+
+// fetchUsers lists users matching the filter, if any. startIndex is 1-based, and count is bounded by max results.
+func (h *Handler) fetchUsers(c *gin.Context) {
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil {
+		respondError(c, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "startIndex should be numerical"))
+		return
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.FormatInt(h.runtimeConfig.SCIMMaxResults, 10)))
+	if err != nil {
+		respondError(c, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue, "count should be numerical"))
+		return
+	}
+	// out of range values are interpreted as the nearest valid value, as mandated by SCIM
+	startIndex = max(startIndex, 1)
+	count = min(max(count, 0), int(h.runtimeConfig.SCIMMaxResults))
+
+	filters, err := parseFilter(c.Query("filter"))
+	if err != nil {
+		respondError(c, newSCIMError(http.StatusBadRequest, scimTypeInvalidFilter, err.Error()))
+		return
+	}
+	users, total, err := h.operations.FetchUsers(filters, startIndex-1, count)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resources := make([]*userResource, 0, len(users))
+	for i := range users {
+		resources = append(resources, newUserResource(&users[i], h.userLocation(c, users[i].ID)))
+	}
+	respond(c, http.StatusOK, listResponse{Schemas: []string{schemaListResponse}, TotalResults: total,
+		StartIndex: startIndex, ItemsPerPage: len(resources), Resources: resources})
+}
+
+// createUser adds the user without password, the user signs in through single sign-on or recovers through the
+// password reset. User is given the default role, unless any role is provisioned.
+func (h *Handler) createUser(c *gin.Context) {
+	var resource userResource
+	if err := c.BindJSON(&resource); err != nil {
+		respondError(c, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax,
+			"User payload is invalid; Expected SCIM User resource"))
+		return
+	}
+	if !resource.isActive() {
+		respondError(c, newSCIMError(http.StatusBadRequest, scimTypeInvalidValue,
+			"Inactive users can't be provisioned"))
+		return
+	}
+	name, email, role, err := resource.attributes(h.runtimeConfig.SCIMDefaultRole)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	user := &models.User{Name: name, Email: email, Role: role}
+	if err := h.operations.CreateUser(user); err != nil {
+		respondError(c, err)
+		return
+	}
+	location := h.userLocation(c, user.ID)
+	c.Header("Location", location)
+	respond(c, http.StatusCreated, newUserResource(user, location))
+}
+
+// getUser...
+func (h *Handler) getUser(c *gin.Context) {
+	user, err := h.fetchUser(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, newUserResource(user, h.userLocation(c, user.ID)))
+}
+
+// replaceUser replaces name, email and role of the user with the ones of the resource.
+func (h *Handler) replaceUser(c *gin.Context) {
+	user, err := h.fetchUser(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	var resource userResource
+	if err := c.BindJSON(&resource); err != nil {
+		respondError(c, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax,
+			"User payload is invalid; Expected SCIM User resource"))
+		return
+	}
+	h.saveUser(c, user, &resource)
+}
+
+// patchUser applies the operations onto the user resource, and saves the outcome like replaceUser.
+func (h *Handler) patchUser(c *gin.Context) {
+	user, err := h.fetchUser(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	var patch patchRequest
+	if err := c.BindJSON(&patch); err != nil {
+		respondError(c, newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax,
+			"Patch payload is invalid; Expected SCIM PatchOp"))
+		return
+	}
+	if err := patch.validate(); err != nil {
+		respondError(c, err)
+		return
+	}
+	resource := newUserResource(user, "")
+	for _, operation := range patch.Operations {
+		if err := resource.applyPatch(operation); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	h.saveUser(c, user, resource)
+}
+
+// deleteUser deletes the user, revoking the tokens issued to the user.
+func (h *Handler) deleteUser(c *gin.Context) {
+	user, err := h.fetchUser(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.users.DeleteUser(user.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.revokeUserTokens(user); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+	c.Writer.WriteHeaderNow()
+}
+
+// saveUser saves the resource onto the user. Deactivated user is disabled rather than deleted, such that the user
+// can be reactivated later on. Tokens carry email and role of the user as claims, hence they are revoked once either
+// of them changes, as well as once the user is deactivated.
+func (h *Handler) saveUser(c *gin.Context, user *models.User, resource *userResource) {
+	name, email, role, err := resource.attributes(h.runtimeConfig.SCIMDefaultRole)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	updatedUser, err := h.operations.ReplaceUser(user.ID, name, email, role, !resource.isActive())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if user.Email != updatedUser.Email || user.Role != updatedUser.Role || (!user.Disabled && updatedUser.Disabled) {
+		if err := h.revokeUserTokens(user); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	respond(c, http.StatusOK, newUserResource(updatedUser, h.userLocation(c, updatedUser.ID)))
+}
+
+// fetchUser fetches the user of id in path.
+func (h *Handler) fetchUser(c *gin.Context) (*models.User, error) {
+	var userID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &userID); err != nil {
+		return nil, appErrors.ErrUserDoesNotExist
+	}
+	return h.operations.GetUser(userID)
+}
+
+// revokeUserTokens revokes every token issued to the user, including refresh tokens.
+func (h *Handler) revokeUserTokens(user *models.User) error {
+	if err := h.revoker.RevokeSubjectTokens(auth.SubjectFromUserID(user.ID)); err != nil {
+		return err
+	}
+	return h.users.RevokeUserRefreshTokens(user.ID)
+}
+
+// location is the absolute URL of the SCIM endpoint, as reached by the client. Scheme forwarded by the proxy is
+// honoured only if the request is sent by a trusted proxy, otherwise any client could forge it.
+func (h *Handler) location(c *gin.Context, path string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || (h.isTrustedProxy(c.RemoteIP()) && c.GetHeader("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s%s", scheme, c.Request.Host, h.basePath, path)
+}
+
+// isTrustedProxy reports whether the IP is of any trusted proxy.
+func (h *Handler) isTrustedProxy(remoteIP string) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, network := range h.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// userLocation...
+func (h *Handler) userLocation(c *gin.Context, id uint) string {
+	return h.location(c, fmt.Sprintf("/Users/%d", id))
+}
+
+// respond writes the body as SCIM response.
+func respond(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", contentType)
+	c.JSON(status, body)
+}
+
+// respondError writes the error as SCIM error response, mapping the errors of operations onto their status.
+func respondError(c *gin.Context, err error) {
+	scimErr, ok := err.(*scimError)
+	if !ok {
+		switch err {
+		case appErrors.ErrUserDoesNotExist:
+			scimErr = newSCIMError(http.StatusNotFound, "", err.Error())
+		case appErrors.ErrUserWithSameEmailAlreadyExists:
+			scimErr = newSCIMError(http.StatusConflict, scimTypeUniqueness, err.Error())
+		default:
+			scimErr = newSCIMError(http.StatusInternalServerError, "",
+				appErrors.ErrFailureToProcessRequest.Error())
+		}
+	}
+	status, _ := strconv.Atoi(scimErr.Status)
+	respond(c, status, scimErr)
+}
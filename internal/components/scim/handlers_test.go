@@ -0,0 +1,343 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"userservice/internal/components/user"
+	"userservice/internal/configs"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SCIM", func() {
+
+	var (
+		router     *gin.Engine
+		revoker    *RevocationMock
+		operations *SCIMMock
+		users      *user.UserMock
+		// serve sends the request with SCIM bearer token, unless other authorization is given
+		serve = func(method string, path string, body interface{}, authorization ...string) *httptest.ResponseRecorder {
+			var payload []byte
+			if raw, ok := body.(string); ok {
+				payload = []byte(raw)
+			} else if body != nil {
+				payload, _ = json.Marshal(body)
+			}
+			req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+			req.Host = "userservice"
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("Authorization", "Bearer scim-token")
+			if len(authorization) > 0 {
+				req.Header.Set("Authorization", authorization[0])
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		decode = func(w *httptest.ResponseRecorder) map[string]interface{} {
+			var body map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(BeNil())
+			return body
+		}
+	)
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		router = gin.New()
+		handler := NewHandler(nil, &configs.Config{SCIMBearerToken: "scim-token", SCIMDefaultRole: "basic",
+			SCIMMaxResults: 50, TrustedProxies: []string{"10.0.0.0/8"}}, nil, nil)
+		revoker = &RevocationMock{}
+		handler.revoker = revoker
+		operations = &SCIMMock{User: &models.User{DBModel: models.DBModel{ID: 1, CreatedAt: time.Now()},
+			Name: "John", Email: "john@mgmtportal.com", Role: "basic"}}
+		handler.operations = operations
+		users = &user.UserMock{}
+		handler.users = users
+		handler.RegisterRoutes(router.Group("/scim/v2"))
+		misc.InitPayloadValidator()
+		misc.SetRole(models.UserRole{Name: "basic"})
+		misc.SetRole(models.UserRole{Name: "admin"})
+	})
+	Context("Authentication", func() {
+		It("Missing or wrong bearer token", func() {
+			for _, authorization := range []string{"", "Bearer other-token", "scim-token"} {
+				w := serve(http.MethodGet, "/scim/v2/Users", nil, authorization)
+				Expect(w.Code).To(Equal(401))
+				Expect(w.Header().Get("Content-Type")).To(ContainSubstring(contentType))
+				body := decode(w)
+				Expect(body["schemas"]).To(ContainElement(schemaError))
+				Expect(body["status"]).To(Equal("401"))
+				Expect(body["detail"]).To(Equal(appErrors.ErrInvalidSCIMToken.Error()))
+			}
+		})
+	})
+	Context("Discovery", func() {
+		It("Service provider config", func() {
+			w := serve(http.MethodGet, "/scim/v2/ServiceProviderConfig", nil)
+			Expect(w.Code).To(Equal(200))
+			body := decode(w)
+			Expect(body["patch"]).To(Equal(map[string]interface{}{"supported": true}))
+			Expect(body["filter"]).To(Equal(map[string]interface{}{"supported": true, "maxResults": float64(50)}))
+			Expect(body["bulk"].(map[string]interface{})["supported"]).To(BeFalse())
+			Expect(w.Body.String()).To(ContainSubstring("oauthbearertoken"))
+		})
+		It("Resource types and schemas", func() {
+			w := serve(http.MethodGet, "/scim/v2/ResourceTypes", nil)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring(`"endpoint":"/Users"`))
+			w = serve(http.MethodGet, "/scim/v2/Schemas", nil)
+			Expect(w.Code).To(Equal(200))
+			Expect(decode(w)["totalResults"]).To(Equal(float64(1)))
+			w = serve(http.MethodGet, "/scim/v2/Schemas/"+schemaUser, nil)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring(`"name":"userName"`))
+			w = serve(http.MethodGet, "/scim/v2/Schemas/urn:unknown", nil)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Forwarded scheme is honoured only from trusted proxies", func() {
+			for remoteAddr, scheme := range map[string]string{"10.0.0.2:4000": "https", "203.0.113.5:4000": "http"} {
+				req, _ := http.NewRequest(http.MethodGet, "/scim/v2/ResourceTypes", nil)
+				req.Host = "userservice"
+				req.RemoteAddr = remoteAddr
+				req.Header.Set("Authorization", "Bearer scim-token")
+				req.Header.Set("X-Forwarded-Proto", "https")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				Expect(w.Code).To(Equal(200))
+				Expect(w.Body.String()).To(ContainSubstring(
+					`"location":"` + scheme + `://userservice/scim/v2/ResourceTypes/User"`))
+			}
+		})
+	})
+	Context("List users", func() {
+		It("Filtered and paginated list", func() {
+			w := serve(http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22john%40mgmtportal.com%22&startIndex=3&count=10`, nil)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.Filters).To(Equal([]models.UserFilter{{Column: "email", Operator: "eq",
+				Value: "john@mgmtportal.com"}}))
+			Expect(operations.Offset).To(Equal(2))
+			Expect(operations.Limit).To(Equal(10))
+			body := decode(w)
+			Expect(body["schemas"]).To(ContainElement(schemaListResponse))
+			Expect(body["totalResults"]).To(Equal(float64(1)))
+			Expect(body["startIndex"]).To(Equal(float64(3)))
+			resources := body["Resources"].([]interface{})
+			Expect(resources).To(HaveLen(1))
+			user := resources[0].(map[string]interface{})
+			Expect(user["id"]).To(Equal("1"))
+			Expect(user["userName"]).To(Equal("john@mgmtportal.com"))
+			Expect(user["meta"].(map[string]interface{})["location"]).To(Equal("http://userservice/scim/v2/Users/1"))
+		})
+		It("Out of range pagination is bounded", func() {
+			w := serve(http.MethodGet, "/scim/v2/Users?startIndex=-4&count=1000", nil)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.Offset).To(Equal(0))
+			Expect(operations.Limit).To(Equal(50))
+		})
+		It("Zero count responds with total alone", func() {
+			w := serve(http.MethodGet, "/scim/v2/Users?count=0", nil)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring(`"Resources":[]`))
+		})
+		It("Unsupported filter", func() {
+			w := serve(http.MethodGet, `/scim/v2/Users?filter=userName+gt+%22a%22`, nil)
+			Expect(w.Code).To(Equal(400))
+			Expect(decode(w)["scimType"]).To(Equal(scimTypeInvalidFilter))
+		})
+		It("Internal error", func() {
+			operations.SetInternalError = true
+			w := serve(http.MethodGet, "/scim/v2/Users", nil)
+			Expect(w.Code).To(Equal(500))
+		})
+	})
+	Context("Get user", func() {
+		It("Unknown user", func() {
+			operations.SetUserDoesntExist = true
+			w := serve(http.MethodGet, "/scim/v2/Users/7", nil)
+			Expect(w.Code).To(Equal(404))
+			w = serve(http.MethodGet, "/scim/v2/Users/abc", nil)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Existing user", func() {
+			w := serve(http.MethodGet, "/scim/v2/Users/1", nil)
+			Expect(w.Code).To(Equal(200))
+			body := decode(w)
+			Expect(body["displayName"]).To(Equal("John"))
+			Expect(body["active"]).To(BeTrue())
+			Expect(body["roles"]).To(Equal([]interface{}{map[string]interface{}{"value": "basic", "primary": true}}))
+		})
+	})
+	Context("Create user", func() {
+		It("Invalid payload", func() {
+			w := serve(http.MethodPost, "/scim/v2/Users", "{")
+			Expect(w.Code).To(Equal(400))
+			Expect(decode(w)["scimType"]).To(Equal(scimTypeInvalidSyntax))
+		})
+		It("userName isn't an email", func() {
+			w := serve(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "john"})
+			Expect(w.Code).To(Equal(400))
+			Expect(decode(w)["scimType"]).To(Equal(scimTypeInvalidValue))
+		})
+		It("Unknown role", func() {
+			w := serve(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "jane@mgmtportal.com", "roles": []map[string]interface{}{{"value": "superuser"}}})
+			Expect(w.Code).To(Equal(400))
+			Expect(decode(w)["detail"]).To(Equal("User Role superuser doesn't exist"))
+		})
+		It("Inactive user", func() {
+			w := serve(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "jane@mgmtportal.com", "active": false})
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Duplicate email", func() {
+			operations.SetDuplicateEmail = true
+			w := serve(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "jane@mgmtportal.com"})
+			Expect(w.Code).To(Equal(409))
+			Expect(decode(w)["scimType"]).To(Equal(scimTypeUniqueness))
+		})
+		It("Successful creation with default role, ignoring attributes not modelled", func() {
+			w := serve(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "jane@mgmtportal.com", "externalId": "00u1", "active": "True",
+				"name":         map[string]interface{}{"givenName": "Jane", "familyName": "Doe"},
+				"phoneNumbers": []map[string]interface{}{{"value": "555-0100"}}})
+			Expect(w.Code).To(Equal(201))
+			Expect(w.Header().Get("Location")).To(Equal("http://userservice/scim/v2/Users/2"))
+			Expect(operations.CreatedUser.Name).To(Equal("Jane Doe"))
+			Expect(operations.CreatedUser.Email).To(Equal("jane@mgmtportal.com"))
+			Expect(operations.CreatedUser.Role).To(Equal("basic"))
+			Expect(operations.CreatedUser.PasswordHash).To(BeEmpty())
+			Expect(decode(w)["id"]).To(Equal("2"))
+		})
+		It("Primary role is provisioned", func() {
+			w := serve(http.MethodPost, "/scim/v2/Users", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "jane@mgmtportal.com", "displayName": "Jane",
+				"roles": []map[string]interface{}{{"value": "basic"}, {"value": "admin", "primary": true}}})
+			Expect(w.Code).To(Equal(201))
+			Expect(operations.CreatedUser.Name).To(Equal("Jane"))
+			Expect(operations.CreatedUser.Role).To(Equal("admin"))
+		})
+	})
+	Context("Replace user", func() {
+		It("Unchanged email and role retain tokens", func() {
+			w := serve(http.MethodPut, "/scim/v2/Users/1", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "john@mgmtportal.com", "displayName": "John Doe", "roles": []map[string]interface{}{
+					{"value": "basic"}}})
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.ReplacedUser.Name).To(Equal("John Doe"))
+			Expect(revoker.RevokedSubjects).To(BeEmpty())
+		})
+		It("Changed role revokes tokens", func() {
+			w := serve(http.MethodPut, "/scim/v2/Users/1", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "john@mgmtportal.com", "roles": []map[string]interface{}{{"value": "admin"}}})
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.ReplacedUser.Role).To(Equal("admin"))
+			Expect(revoker.RevokedSubjects).To(ContainElement("1"))
+			Expect(users.RefreshTokensRevokedOf).To(ContainElement(uint(1)))
+		})
+		It("Deactivation disables the user and revokes tokens", func() {
+			w := serve(http.MethodPut, "/scim/v2/Users/1", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "john@mgmtportal.com", "active": false})
+			Expect(w.Code).To(Equal(200))
+			Expect(decode(w)["active"]).To(BeFalse())
+			Expect(operations.ReplacedUser.Disabled).To(BeTrue())
+			Expect(users.DeletedUserIDs).To(BeEmpty())
+			Expect(revoker.RevokedSubjects).To(ContainElement("1"))
+			Expect(users.RefreshTokensRevokedOf).To(ContainElement(uint(1)))
+		})
+		It("Reactivation enables the disabled user", func() {
+			operations.User.Disabled = true
+			w := serve(http.MethodPut, "/scim/v2/Users/1", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "john@mgmtportal.com", "active": true})
+			Expect(w.Code).To(Equal(200))
+			Expect(decode(w)["active"]).To(BeTrue())
+			Expect(operations.ReplacedUser.Disabled).To(BeFalse())
+			Expect(revoker.RevokedSubjects).To(BeEmpty())
+		})
+		It("Unknown user", func() {
+			operations.SetUserDoesntExist = true
+			w := serve(http.MethodPut, "/scim/v2/Users/1", map[string]interface{}{"schemas": []string{schemaUser},
+				"userName": "john@mgmtportal.com"})
+			Expect(w.Code).To(Equal(404))
+		})
+	})
+	Context("Patch user", func() {
+		patch := func(operations ...map[string]interface{}) map[string]interface{} {
+			return map[string]interface{}{"schemas": []string{schemaPatchOp}, "Operations": operations}
+		}
+		It("Not a patch request", func() {
+			w := serve(http.MethodPatch, "/scim/v2/Users/1", map[string]interface{}{"schemas": []string{schemaUser}})
+			Expect(w.Code).To(Equal(400))
+			Expect(decode(w)["scimType"]).To(Equal(scimTypeInvalidSyntax))
+		})
+		It("Unsupported operation", func() {
+			w := serve(http.MethodPatch, "/scim/v2/Users/1", patch(map[string]interface{}{"op": "move",
+				"path": "userName", "value": "jane@mgmtportal.com"}))
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Read only attribute", func() {
+			w := serve(http.MethodPatch, "/scim/v2/Users/1", patch(map[string]interface{}{"op": "replace",
+				"path": "id", "value": "2"}))
+			Expect(w.Code).To(Equal(400))
+			Expect(decode(w)["scimType"]).To(Equal(scimTypeMutability))
+		})
+		It("Replace attributes with and without path", func() {
+			w := serve(http.MethodPatch, "/scim/v2/Users/1", patch(
+				map[string]interface{}{"op": "Replace", "path": "userName", "value": "johnny@mgmtportal.com"},
+				map[string]interface{}{"op": "replace", "value": map[string]interface{}{"displayName": "Johnny"}},
+				map[string]interface{}{"op": "add", "path": `roles[primary eq "True"].value`, "value": "admin"}))
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.ReplacedUser.Email).To(Equal("johnny@mgmtportal.com"))
+			Expect(operations.ReplacedUser.Name).To(Equal("Johnny"))
+			Expect(operations.ReplacedUser.Role).To(Equal("admin"))
+			Expect(revoker.RevokedSubjects).To(ContainElement("1"))
+		})
+		It("Removed role falls back to default role", func() {
+			operations.User.Role = "admin"
+			w := serve(http.MethodPatch, "/scim/v2/Users/1", patch(map[string]interface{}{"op": "remove",
+				"path": "roles"}))
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.ReplacedUser.Role).To(Equal("basic"))
+		})
+		It("Deactivation disables the user", func() {
+			w := serve(http.MethodPatch, "/scim/v2/Users/1", patch(map[string]interface{}{"op": "replace",
+				"path": "active", "value": "False"}))
+			Expect(w.Code).To(Equal(200))
+			Expect(decode(w)["active"]).To(BeFalse())
+			Expect(operations.ReplacedUser.Disabled).To(BeTrue())
+			Expect(users.DeletedUserIDs).To(BeEmpty())
+		})
+		It("Reactivation of deactivated user", func() {
+			operations.User.Disabled = true
+			w := serve(http.MethodPatch, "/scim/v2/Users/1", patch(map[string]interface{}{"op": "replace",
+				"path": "active", "value": true}))
+			Expect(w.Code).To(Equal(200))
+			Expect(decode(w)["active"]).To(BeTrue())
+			Expect(operations.ReplacedUser.Disabled).To(BeFalse())
+		})
+	})
+	Context("Delete user", func() {
+		It("Successful deletion revokes tokens", func() {
+			w := serve(http.MethodDelete, "/scim/v2/Users/1", nil)
+			Expect(w.Code).To(Equal(204))
+			Expect(w.Body.String()).To(BeEmpty())
+			Expect(users.DeletedUserIDs).To(Equal([]uint{1}))
+			Expect(revoker.RevokedSubjects).To(ContainElement("1"))
+		})
+		It("Unknown user", func() {
+			operations.SetUserDoesntExist = true
+			w := serve(http.MethodDelete, "/scim/v2/Users/1", nil)
+			Expect(w.Code).To(Equal(404))
+			Expect(users.DeletedUserIDs).To(BeEmpty())
+		})
+	})
+})
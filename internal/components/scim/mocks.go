@@ -0,0 +1,100 @@
+package scim
+
+import (
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+)
+
+// SCIMMock...
+type SCIMMock struct {
+	User               *models.User
+	CreatedUser        *models.User
+	ReplacedUser       *models.User
+	Filters            []models.UserFilter
+	Offset             int
+	Limit              int
+	SetInternalError   bool
+	SetUserDoesntExist bool
+	SetDuplicateEmail  bool
+}
+
+// FetchUsers...
+func (m *SCIMMock) FetchUsers(filters []models.UserFilter, offset int, limit int) ([]models.User, int64, error) {
+	if m.SetInternalError {
+		return nil, 0, appErrors.ErrInternal
+	}
+	m.Filters, m.Offset, m.Limit = filters, offset, limit
+	if m.User == nil || limit == 0 {
+		return nil, 0, nil
+	}
+	return []models.User{*m.User}, 1, nil
+}
+
+// GetUser...
+func (m *SCIMMock) GetUser(uint) (*models.User, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetUserDoesntExist {
+		return nil, appErrors.ErrUserDoesNotExist
+	}
+	return m.User, nil
+}
+
+// CreateUser...
+func (m *SCIMMock) CreateUser(user *models.User) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetDuplicateEmail {
+		return appErrors.ErrUserWithSameEmailAlreadyExists
+	}
+	user.ID = 2
+	m.CreatedUser = user
+	return nil
+}
+
+// ReplaceUser...
+func (m *SCIMMock) ReplaceUser(id uint, name string, email string, role string, disabled bool) (*models.User,
+	error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetDuplicateEmail {
+		return nil, appErrors.ErrUserWithSameEmailAlreadyExists
+	}
+	m.ReplacedUser = &models.User{DBModel: models.DBModel{ID: id}, Name: name, Email: email, Role: role,
+		Disabled: disabled}
+	return m.ReplacedUser, nil
+}
+
+// RevocationMock...
+type RevocationMock struct {
+	SetInternalError bool
+	RevokedSubjects  []string
+}
+
+// RevokeToken...
+func (m *RevocationMock) RevokeToken(string, time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RevokeSubjectTokens...
+func (m *RevocationMock) RevokeSubjectTokens(subject string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RevokedSubjects = append(m.RevokedSubjects, subject)
+	return nil
+}
+
+// IsRevoked...
+func (m *RevocationMock) IsRevoked(_ string, subject string, _ time.Time) bool {
+	for _, revokedSubject := range m.RevokedSubjects {
+		if revokedSubject == subject {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// likeEscaper escapes wildcards of LIKE pattern, such that filter value is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// operations...
+type operations struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// newOperations initializes SCIM operation handler
+func newOperations(db *gorm.DB, log *zap.SugaredLogger) *operations {
+	return &operations{db: db, log: log}
+}
+
+// FetchUsers responds with users matching every filter, ordered by id, starting at the offset.
+// Total is the count of matching users, irrespective of the offset and limit.
+func (ops *operations) FetchUsers(filters []models.UserFilter, offset int, limit int) (users []models.User,
+	total int64, returnErr error) {
+
+	query := ops.db.Model(&models.User{})
+	for _, filter := range filters {
+		switch filter.Operator {
+		case models.FilterOperatorEqual:
+			query = query.Where(fmt.Sprintf("LOWER(%s) = LOWER(?)", filter.Column), filter.Value)
+		case models.FilterOperatorNotEqual:
+			query = query.Where(fmt.Sprintf("LOWER(%s) <> LOWER(?)", filter.Column), filter.Value)
+		case models.FilterOperatorContains:
+			query = query.Where(fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", filter.Column),
+				"%"+likeEscaper.Replace(filter.Value)+"%")
+		case models.FilterOperatorStartsWith:
+			query = query.Where(fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", filter.Column),
+				likeEscaper.Replace(filter.Value)+"%")
+		case models.FilterOperatorEndsWith:
+			query = query.Where(fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", filter.Column),
+				"%"+likeEscaper.Replace(filter.Value))
+		case models.FilterOperatorPresent:
+			query = query.Where(fmt.Sprintf("%s <> ''", filter.Column))
+		default:
+			return nil, 0, appErrors.ErrInvalidSCIMFilter
+		}
+	}
+	if err := query.Count(&total).Error; err != nil {
+		ops.log.Errorf("Failed to get the total count of filtered users: %v", err)
+		return nil, 0, appErrors.ErrInternal
+	}
+	if limit == 0 {
+		return nil, total, nil
+	}
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		ops.log.Errorf("Failed to fetch filtered users: %v", err)
+		return nil, 0, appErrors.ErrInternal
+	}
+	return
+}
+
+// GetUser fetches user record in DB for the given id
+func (ops *operations) GetUser(id uint) (*models.User, error) {
+	user := new(models.User)
+	if err := ops.db.Where("id = ?", id).First(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrUserDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch user record by id %d: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	return user, nil
+}
+
+// CreateUser creates user record in DB without password, user signs in through single sign-on or recovers
+// through the password reset.
+func (ops *operations) CreateUser(user *models.User) error {
+	if err := ops.db.Create(user).Error; err != nil {
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return appErrors.ErrUserWithSameEmailAlreadyExists
+		}
+		ops.log.Errorf("Failed to provision user with email %s: %v", user.Email, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// ReplaceUser updates name, email, role and whether the user is disabled, responding with the updated user.
+func (ops *operations) ReplaceUser(id uint, name string, email string, role string, disabled bool) (*models.User,
+	error) {
+	result := ops.db.Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"name": name, "email": email, "role": role, "disabled": disabled})
+	if result.Error != nil {
+		if strings.Contains(result.Error.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return nil, appErrors.ErrUserWithSameEmailAlreadyExists
+		}
+		ops.log.Errorf("Failed to replace user with id %d: %v", id, result.Error)
+		return nil, appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return nil, appErrors.ErrUserDoesNotExist
+	}
+	return ops.GetUser(id)
+}
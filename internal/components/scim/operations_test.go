@@ -0,0 +1,143 @@
+package scim
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("SCIM [operations]", func() {
+	var (
+		mockLog *zap.SugaredLogger
+		mock    sqlmock.Sqlmock
+		mockDb  *sql.DB
+		ops     *operations
+		db      *gorm.DB
+	)
+	BeforeEach(func() {
+		mockLog = zap.NewExample().Sugar()
+		mockDb, mock, _ = sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ = gorm.Open(dialector)
+		ops = newOperations(db, mockLog)
+	})
+
+	It("Initialize operations", func() {
+		Expect(ops).To(Not(BeNil()))
+	})
+	Context("fetch users", func() {
+		It("Filtered users with total", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE LOWER(email) = LOWER($1) `+
+				`AND LOWER(name) LIKE LOWER($2) AND role <> '' AND "user"."deleted_at" IS NULL`)).
+				WithArgs("john@mgmtportal.com", `%100\%%`).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE LOWER(email) = LOWER($1) `+
+				`AND LOWER(name) LIKE LOWER($2) AND role <> '' AND "user"."deleted_at" IS NULL `+
+				`ORDER BY id LIMIT $3 OFFSET $4`)).
+				WithArgs("john@mgmtportal.com", `%100\%%`, 2, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(2, "john@mgmtportal.com"))
+			users, total, err := ops.FetchUsers([]models.UserFilter{
+				{Column: "email", Operator: models.FilterOperatorEqual, Value: "john@mgmtportal.com"},
+				{Column: "name", Operator: models.FilterOperatorContains, Value: "100%"},
+				{Column: "role", Operator: models.FilterOperatorPresent}}, 1, 2)
+			Expect(err).To(BeNil())
+			Expect(total).To(Equal(int64(3)))
+			Expect(users).To(HaveLen(1))
+		})
+		It("Zero limit counts users alone", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			users, total, err := ops.FetchUsers(nil, 0, 0)
+			Expect(err).To(BeNil())
+			Expect(total).To(Equal(int64(3)))
+			Expect(users).To(BeEmpty())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Internal error", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user"`)).
+				WillReturnError(errors.New("connection error"))
+			_, _, err := ops.FetchUsers(nil, 0, 10)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+	})
+	Context("get user", func() {
+		It("Unknown user", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, err := ops.GetUser(1)
+			Expect(err).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("Internal error", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE id = $1`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.GetUser(1)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+	})
+	Context("create user", func() {
+		It("successfully create user without password", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user"`)).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "Jane", "jane@mgmtportal.com",
+					"basic", "", false, nil, 0, nil, false, "", "", 0, nil, nil, false, false).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			mock.ExpectCommit()
+			user := &models.User{Name: "Jane", Email: "jane@mgmtportal.com", Role: "basic"}
+			err := ops.CreateUser(user)
+			Expect(err).To(BeNil())
+			Expect(user.ID).To(Equal(uint(2)))
+		})
+		It("Duplicate email", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user"`)).
+				WillReturnError(appErrors.ErrUniqueKeyConstrainViolation)
+			mock.ExpectRollback()
+			err := ops.CreateUser(&models.User{Name: "Jane", Email: "jane@mgmtportal.com", Role: "basic"})
+			Expect(err).To(MatchError(appErrors.ErrUserWithSameEmailAlreadyExists))
+		})
+	})
+	Context("replace user", func() {
+		It("successfully replace user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "disabled"=$1,"email"=$2,"name"=$3,"role"=$4,`+
+				`"updated_at"=$5 WHERE id = $6 AND "user"."deleted_at" IS NULL`)).
+				WithArgs(true, "jane@mgmtportal.com", "Jane", "admin", sqlmock.AnyArg(), 2).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "role"}).
+					AddRow(2, "Jane", "jane@mgmtportal.com", "admin"))
+			user, err := ops.ReplaceUser(2, "Jane", "jane@mgmtportal.com", "admin", true)
+			Expect(err).To(BeNil())
+			Expect(user.Role).To(Equal("admin"))
+		})
+		It("Unknown user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user"`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			_, err := ops.ReplaceUser(2, "Jane", "jane@mgmtportal.com", "admin", false)
+			Expect(err).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("Email taken by other user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user"`)).
+				WillReturnError(appErrors.ErrUniqueKeyConstrainViolation)
+			mock.ExpectRollback()
+			_, err := ops.ReplaceUser(2, "Jane", "jane@mgmtportal.com", "admin", false)
+			Expect(err).To(MatchError(appErrors.ErrUserWithSameEmailAlreadyExists))
+		})
+	})
+})
@@ -0,0 +1,151 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	patchOpAdd     = "add"
+	patchOpReplace = "replace"
+	patchOpRemove  = "remove"
+)
+
+// valueFilterPattern matches value filter of patch path, e.g. [primary eq true] of roles[primary eq true].value
+var valueFilterPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// patchRequest...
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+// patchOperation...
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// validate ensures the request is a SCIM patch of at least one operation.
+func (p *patchRequest) validate() error {
+	isPatchOp := false
+	for _, schema := range p.Schemas {
+		isPatchOp = isPatchOp || schema == schemaPatchOp
+	}
+	if !isPatchOp || len(p.Operations) == 0 {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax,
+			fmt.Sprintf("Patch has to be of %s schema with at least one operation", schemaPatchOp))
+	}
+	return nil
+}
+
+// applyPatch applies the operation to the resource. Since user holds a single role, adding a role replaces it.
+// Value filters of paths are disregarded for the same reason, and attributes not modelled by user are ignored.
+func (r *userResource) applyPatch(operation patchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != patchOpAdd && op != patchOpReplace && op != patchOpRemove {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidSyntax,
+			fmt.Sprintf("Patch operation %s is not supported; expected add, replace or remove", operation.Op))
+	}
+	if operation.Path == "" {
+		if op == patchOpRemove {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidPath, "Path is required to remove attributes")
+		}
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+			return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue,
+				"Patch without path expects value to be an object of attributes")
+		}
+		for path, value := range attributes {
+			if err := r.applyPatch(patchOperation{Op: op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path := strings.ToLower(strings.TrimPrefix(operation.Path, schemaUser+":"))
+	path = valueFilterPattern.ReplaceAllString(path, "")
+	if op == patchOpRemove {
+		return r.removeAttribute(path)
+	}
+	var err error
+	switch path {
+	case "username":
+		err = json.Unmarshal(operation.Value, &r.UserName)
+	case "displayname":
+		err = json.Unmarshal(operation.Value, &r.DisplayName)
+	case "name":
+		r.Name = new(userName)
+		err = json.Unmarshal(operation.Value, r.Name)
+		// display name takes precedence over name, whereas both of them are the name of user
+		r.DisplayName = ""
+	case "name.formatted", "name.givenname", "name.familyname":
+		if r.Name == nil {
+			r.Name = new(userName)
+		}
+		switch path {
+		case "name.formatted":
+			err = json.Unmarshal(operation.Value, &r.Name.Formatted)
+		case "name.givenname":
+			err = json.Unmarshal(operation.Value, &r.Name.GivenName)
+		default:
+			err = json.Unmarshal(operation.Value, &r.Name.FamilyName)
+		}
+		// formatted name is retained alone, hence the given and family names are reflected onto it
+		if path != "name.formatted" {
+			r.Name.Formatted = strings.TrimSpace(r.Name.GivenName + " " + r.Name.FamilyName)
+		}
+		r.DisplayName = ""
+	case "active":
+		r.Active = new(flexibleBool)
+		err = json.Unmarshal(operation.Value, r.Active)
+	case "roles", "roles.value":
+		r.Roles, err = parseRoles(operation.Value)
+	case "id", "meta", "schemas":
+		return newSCIMError(http.StatusBadRequest, scimTypeMutability, fmt.Sprintf("%s is read only", operation.Path))
+	}
+	if err != nil {
+		return newSCIMError(http.StatusBadRequest, scimTypeInvalidValue,
+			fmt.Sprintf("Patch value of %s is invalid", operation.Path))
+	}
+	return nil
+}
+
+// removeAttribute...
+func (r *userResource) removeAttribute(path string) error {
+	switch path {
+	case "username", "active":
+		return newSCIMError(http.StatusBadRequest, scimTypeMutability, fmt.Sprintf("%s can't be removed", path))
+	case "displayname":
+		r.DisplayName = ""
+	case "name", "name.formatted":
+		r.Name, r.DisplayName = nil, ""
+	case "roles", "roles.value":
+		r.Roles = nil
+	case "id", "meta", "schemas":
+		return newSCIMError(http.StatusBadRequest, scimTypeMutability, fmt.Sprintf("%s is read only", path))
+	}
+	return nil
+}
+
+// parseRoles parses roles sent as array of roles, a single role or just the name of role.
+func parseRoles(value json.RawMessage) ([]multiValuedAttribute, error) {
+	var roles []multiValuedAttribute
+	if err := json.Unmarshal(value, &roles); err == nil {
+		return roles, nil
+	}
+	var role multiValuedAttribute
+	if err := json.Unmarshal(value, &role); err == nil {
+		return []multiValuedAttribute{role}, nil
+	}
+	var roleName string
+	if err := json.Unmarshal(value, &roleName); err != nil {
+		return nil, err
+	}
+	return []multiValuedAttribute{{Value: roleName, Primary: true}}, nil
+}
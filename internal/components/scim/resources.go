@@ -0,0 +1,310 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+)
+
+const (
+	schemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	schemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	schemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	schemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+
+	// contentType of SCIM requests and responses
+	contentType = "application/scim+json"
+
+	resourceTypeUser = "User"
+
+	// Error types of SCIM, detailing bad requests
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeInvalidSyntax = "invalidSyntax"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeInvalidValue  = "invalidValue"
+	scimTypeUniqueness    = "uniqueness"
+	scimTypeMutability    = "mutability"
+)
+
+// scimError is the error response of SCIM, validations of requests fail with it as well.
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// Error...
+func (e *scimError) Error() string {
+	return e.Detail
+}
+
+// newSCIMError...
+func newSCIMError(status int, scimType string, detail string) *scimError {
+	return &scimError{Schemas: []string{schemaError}, Status: strconv.Itoa(status), ScimType: scimType,
+		Detail: detail}
+}
+
+// flexibleBool accepts booleans sent as string as well, as some provisioning clients do.
+type flexibleBool bool
+
+// UnmarshalJSON...
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = flexibleBool(v)
+	case string:
+		parsed, err := strconv.ParseBool(strings.ToLower(v))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*b = flexibleBool(parsed)
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// userName is the name of user, of which only the formatted name is retained.
+type userName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// multiValuedAttribute is a value of multi-valued attribute, e.g. emails and roles.
+type multiValuedAttribute struct {
+	Value   string       `json:"value"`
+	Type    string       `json:"type,omitempty"`
+	Primary flexibleBool `json:"primary,omitempty"`
+}
+
+// resourceMeta...
+type resourceMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+// userResource is the SCIM representation of user. userName is the email of user, and the user holds a single
+// role, hence only the primary role is retained. Attributes which aren't modelled by user are ignored.
+type userResource struct {
+	Schemas     []string               `json:"schemas"`
+	ID          string                 `json:"id,omitempty"`
+	UserName    string                 `json:"userName"`
+	Name        *userName              `json:"name,omitempty"`
+	DisplayName string                 `json:"displayName,omitempty"`
+	Emails      []multiValuedAttribute `json:"emails,omitempty"`
+	Active      *flexibleBool          `json:"active,omitempty"`
+	Roles       []multiValuedAttribute `json:"roles,omitempty"`
+	Meta        *resourceMeta          `json:"meta,omitempty"`
+}
+
+// newUserResource represents the user as SCIM resource located at the given URL.
+func newUserResource(user *models.User, location string) *userResource {
+	active := flexibleBool(!user.Disabled)
+	return &userResource{
+		Schemas:     []string{schemaUser},
+		ID:          strconv.FormatUint(uint64(user.ID), 10),
+		UserName:    user.Email,
+		Name:        &userName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []multiValuedAttribute{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Roles:       []multiValuedAttribute{{Value: user.Role, Primary: true}},
+		Meta: &resourceMeta{ResourceType: resourceTypeUser, Created: &user.CreatedAt,
+			LastModified: &user.UpdatedAt, Location: location},
+	}
+}
+
+// isActive reports whether the resource is active, which it is unless stated otherwise.
+func (r *userResource) isActive() bool {
+	return r.Active == nil || bool(*r.Active)
+}
+
+// attributes validates the resource and maps it to name, email and role of user. Name falls back to the email,
+// and role to the default role.
+func (r *userResource) attributes(defaultRole string) (string, string, string, error) {
+	email := strings.TrimSpace(r.UserName)
+	if err := misc.PayloadValidator.Var(email, "required,email"); err != nil {
+		return "", "", "", newSCIMError(http.StatusBadRequest, scimTypeInvalidValue,
+			"userName is required, and has to be the email of the user")
+	}
+
+	name := strings.TrimSpace(r.DisplayName)
+	if name == "" && r.Name != nil {
+		name = strings.TrimSpace(r.Name.Formatted)
+		if name == "" {
+			name = strings.TrimSpace(r.Name.GivenName + " " + r.Name.FamilyName)
+		}
+	}
+	if name == "" {
+		name = email
+	}
+
+	role := defaultRole
+	for i, value := range r.Roles {
+		if i == 0 || value.Primary {
+			role = value.Value
+		}
+		if value.Primary {
+			break
+		}
+	}
+	if !misc.IsRoleConfigured(role) {
+		return "", "", "", newSCIMError(http.StatusBadRequest, scimTypeInvalidValue,
+			fmt.Sprintf("User Role %s doesn't exist", role))
+	}
+	return name, email, role, nil
+}
+
+// listResponse...
+type listResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// supported...
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+// filterSupported...
+type filterSupported struct {
+	Supported  bool  `json:"supported"`
+	MaxResults int64 `json:"maxResults"`
+}
+
+// bulkSupported...
+type bulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// authenticationScheme...
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// serviceProviderConfig advertises the SCIM features supported by the service.
+type serviceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	DocumentationURI      string                 `json:"documentationUri,omitempty"`
+	Patch                 supported              `json:"patch"`
+	Bulk                  bulkSupported          `json:"bulk"`
+	Filter                filterSupported        `json:"filter"`
+	ChangePassword        supported              `json:"changePassword"`
+	Sort                  supported              `json:"sort"`
+	ETag                  supported              `json:"etag"`
+	AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+	Meta                  resourceMeta           `json:"meta"`
+}
+
+// newServiceProviderConfig...
+func newServiceProviderConfig(maxResults int64, location string) serviceProviderConfig {
+	return serviceProviderConfig{
+		Schemas: []string{schemaServiceProviderConfig},
+		Patch:   supported{Supported: true},
+		Filter:  filterSupported{Supported: true, MaxResults: maxResults},
+		AuthenticationSchemes: []authenticationScheme{{Type: "oauthbearertoken", Name: "OAuth Bearer Token",
+			Description: "Authentication with the bearer token configured for SCIM provisioning", Primary: true}},
+		Meta: resourceMeta{ResourceType: "ServiceProviderConfig", Location: location},
+	}
+}
+
+// resourceType...
+type resourceType struct {
+	Schemas  []string     `json:"schemas"`
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	Endpoint string       `json:"endpoint"`
+	Schema   string       `json:"schema"`
+	Meta     resourceMeta `json:"meta"`
+}
+
+// newUserResourceType...
+func newUserResourceType(location string) resourceType {
+	return resourceType{Schemas: []string{schemaResourceType}, ID: resourceTypeUser, Name: resourceTypeUser,
+		Endpoint: "/Users", Schema: schemaUser, Meta: resourceMeta{ResourceType: "ResourceType", Location: location}}
+}
+
+// schemaAttribute...
+type schemaAttribute struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	MultiValued   bool              `json:"multiValued"`
+	Description   string            `json:"description"`
+	Required      bool              `json:"required"`
+	CaseExact     bool              `json:"caseExact"`
+	Mutability    string            `json:"mutability"`
+	Returned      string            `json:"returned"`
+	Uniqueness    string            `json:"uniqueness"`
+	SubAttributes []schemaAttribute `json:"subAttributes,omitempty"`
+}
+
+// schema...
+type schema struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Attributes  []schemaAttribute `json:"attributes"`
+	Meta        resourceMeta      `json:"meta"`
+}
+
+// stringAttribute...
+func stringAttribute(name string, description string) schemaAttribute {
+	return schemaAttribute{Name: name, Type: "string", Description: description, Mutability: "readWrite",
+		Returned: "default", Uniqueness: "none"}
+}
+
+// newUserSchema describes the attributes of user resource which are modelled by user.
+func newUserSchema(location string) schema {
+	userNameAttribute := stringAttribute("userName", "Email of the user, which the user signs in with.")
+	userNameAttribute.Required, userNameAttribute.Uniqueness = true, "server"
+	name := stringAttribute("name", "Name of the user, of which only the formatted name is retained.")
+	name.Type, name.SubAttributes = "complex", []schemaAttribute{stringAttribute("formatted", "Full name."),
+		stringAttribute("givenName", "Given name."), stringAttribute("familyName", "Family name.")}
+	emails := stringAttribute("emails", "Email of the user, same as userName.")
+	emails.Type, emails.MultiValued, emails.Mutability = "complex", true, "readOnly"
+	emails.SubAttributes = []schemaAttribute{stringAttribute("value", "Email address."),
+		stringAttribute("type", "Type of the email, i.e. work."),
+		{Name: "primary", Type: "boolean", Description: "Whether the email is the primary one.",
+			Mutability: "readOnly", Returned: "default"}}
+	roles := stringAttribute("roles", "Role of the user, only the primary role is retained.")
+	roles.Type, roles.MultiValued = "complex", true
+	roles.SubAttributes = []schemaAttribute{stringAttribute("value", "Name of the role."),
+		{Name: "primary", Type: "boolean", Description: "Whether the role is the primary one.",
+			Mutability: "readWrite", Returned: "default"}}
+	return schema{
+		Schemas:     []string{schemaSchema},
+		ID:          schemaUser,
+		Name:        resourceTypeUser,
+		Description: "User Account",
+		Attributes: []schemaAttribute{userNameAttribute, name,
+			stringAttribute("displayName", "Name of the user, takes precedence over name."), emails,
+			{Name: "active", Type: "boolean", Description: "Deactivated user can't sign in till reactivated.",
+				Mutability: "readWrite", Returned: "default"}, roles},
+		Meta: resourceMeta{ResourceType: "Schema", Location: location},
+	}
+}
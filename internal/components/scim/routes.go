@@ -0,0 +1,74 @@
+package scim
+
+import (
+	"net"
+	"strings"
+	"userservice/internal/auth"
+	"userservice/internal/components/user"
+	"userservice/internal/configs"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Handler for SCIM provisioning of users.
+type Handler struct {
+	runtimeConfig *configs.Config
+	operations    models.SCIMOperations
+	// users are operations of user package, which deletion and revocation of tokens are shared with
+	users   models.UserOperations
+	revoker models.TokenRevocationOperations
+	// tokenHash is the hash of SCIM bearer token, such that presented token is compared in constant time
+	tokenHash string
+	// basePath locates the resources in responses
+	basePath string
+	// trustedProxies are the proxies whose forwarding headers are honoured, as configured for the router
+	trustedProxies []*net.IPNet
+}
+
+// NewHandler initializes SCIM handler context with desired parameters.
+func NewHandler(log *zap.SugaredLogger, config *configs.Config, db *gorm.DB,
+	revoker models.TokenRevocationOperations) *Handler {
+	return &Handler{runtimeConfig: config, operations: newOperations(db, log), users: user.NewOperations(db, log),
+		revoker: revoker, tokenHash: auth.HashOpaqueToken(config.SCIMBearerToken),
+		trustedProxies: parseTrustedProxies(config.TrustedProxies)}
+}
+
+// parseTrustedProxies parses the proxies given as either IP or CIDR. Invalid proxies are skipped, since they are
+// already refused by the router.
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// RegisterRoutes registers SCIM endpoints, every one of which demands the SCIM bearer token.
+func (h *Handler) RegisterRoutes(routers *gin.RouterGroup) {
+	h.basePath = routers.BasePath()
+	routers.Use(h.authenticate)
+
+	routers.GET("/ServiceProviderConfig", h.getServiceProviderConfig)
+	routers.GET("/ResourceTypes", h.fetchResourceTypes)
+	routers.GET("/Schemas", h.fetchSchemas)
+	routers.GET("/Schemas/:id", h.getSchema)
+
+	routers.GET("/Users", h.fetchUsers)
+	routers.POST("/Users", h.createUser)
+	routers.GET("/Users/:id", h.getUser)
+	routers.PUT("/Users/:id", h.replaceUser)
+	routers.PATCH("/Users/:id", h.patchUser)
+	routers.DELETE("/Users/:id", h.deleteUser)
+}
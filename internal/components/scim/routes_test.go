@@ -0,0 +1,22 @@
+package scim
+
+import (
+	"userservice/internal/configs"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SCIM [Handler]", func() {
+
+	It("Initializer Handler, list and ensure expected number of routes", func() {
+		h := NewHandler(nil, &configs.Config{SCIMBearerToken: "token"}, nil, nil)
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		h.RegisterRoutes(router.Group("/scim/v2"))
+		routes := router.Routes()
+		Expect(routes).To(HaveLen(10))
+		Expect(h.basePath).To(Equal("/scim/v2"))
+	})
+})
@@ -3,7 +3,9 @@ package service
 import (
 	"fmt"
 	"strconv"
+	"userservice/internal/auth"
 	appErrors "userservice/internal/errors"
+	"userservice/internal/middleware"
 	"userservice/internal/models"
 	"userservice/internal/utils"
 
@@ -12,6 +14,69 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// authorizeServiceMutation ensures requester is a member of the team owning the service, ahead of modifying
+// the service or its versions. Requesters permitted to administer services, and services yet to be owned by a team,
+// are exempted.
+// Responds to the request and reports false, if requester isn't authorized.
+func (h *Handler) authorizeServiceMutation(c *gin.Context, serviceID uint) bool {
+	if middleware.IsPermitted(c, models.PermissionServiceAdmin) {
+		return true
+	}
+	service, ok := h.getServiceToAuthorize(c, serviceID)
+	if !ok {
+		return false
+	}
+	if service.OwnerTeamID == nil {
+		return true
+	}
+	return h.authorizeTeamMember(c, *service.OwnerTeamID)
+}
+
+// getServiceToAuthorize fetches the service, whose owner the requester is authorized against.
+// Responds to the request and reports false, if service can't be fetched.
+func (h *Handler) getServiceToAuthorize(c *gin.Context, serviceID uint) (*models.Service, bool) {
+	service, err := h.operations.GetService(serviceID)
+	if err != nil {
+		if err == appErrors.ErrServiceDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Service[ID:%d] doesn't exist", serviceID)))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return nil, false
+	}
+	return service, true
+}
+
+// authorizeTeamMember ensures requester is a member of the team.
+// Service accounts can't be members of a team, hence they are never authorized.
+// Responds to the request and reports false, if requester isn't authorized.
+func (h *Handler) authorizeTeamMember(c *gin.Context, teamID uint) bool {
+	userID := c.GetUint(auth.JWTClaimSubject)
+	if userID == 0 {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrServiceNotOwned.Error()))
+		return false
+	}
+	isMember, err := h.operations.IsTeamMember(teamID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return false
+	}
+	if !isMember {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrServiceNotOwned.Error()))
+		return false
+	}
+	return true
+}
+
+// parseTeamID reads the team of the payload, reporting false unless it is a positive integer.
+func parseTeamID(payload map[string]interface{}) (uint, bool) {
+	requestedTeamID := payload[models.AttributeTeamID].(float64)
+	if requestedTeamID < 1 || requestedTeamID != float64(uint(requestedTeamID)) {
+		return 0, false
+	}
+	return uint(requestedTeamID), true
+}
+
 // getServiceByID fetches service record in DB for the given id
 func (h *Handler) getServiceByID(c *gin.Context) {
 	id := c.Param(models.QueryParamID)
@@ -33,7 +98,8 @@ func (h *Handler) getServiceByID(c *gin.Context) {
 	c.JSON(http.StatusOK, service)
 }
 
-// addService configures new service
+// addService configures new service, owned by the team in payload.
+// Requester is expected to be a member of the team, unless requester is permitted to administer services.
 // Request will be rejected if additional fields to desired ones are present in payload.
 func (h *Handler) addService(c *gin.Context) {
 	var serviceToAdd map[string]interface{}
@@ -43,10 +109,10 @@ func (h *Handler) addService(c *gin.Context) {
 		return
 	}
 
-	if !utils.EnsureFieldsStrictlyExists(serviceToAdd, models.RegisterOrUpdateServicePayloadTemplate) {
+	if !utils.EnsureFieldsStrictlyExists(serviceToAdd, models.RegisterServicePayloadTemplate) {
 		c.JSON(http.StatusBadRequest,
 			utils.FormatErrorResponse(fmt.Sprintf("Service creation payload is invalid; Strictly Allowed Params: %v",
-				utils.ConvertFieldTypeToString(models.RegisterOrUpdateServicePayloadTemplate))))
+				utils.ConvertFieldTypeToString(models.RegisterServicePayloadTemplate))))
 		return
 	}
 
@@ -56,14 +122,27 @@ func (h *Handler) addService(c *gin.Context) {
 				appErrors.ErrServiceNameEmpty)))
 		return
 	}
+	teamID, ok := parseTeamID(serviceToAdd)
+	if !ok {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service creation payload is invalid; team_id should be a positive integer"))
+		return
+	}
+
+	if !middleware.IsPermitted(c, models.PermissionServiceAdmin) && !h.authorizeTeamMember(c, teamID) {
+		return
+	}
 
 	createdService, err := h.operations.CreateService(serviceToAdd[models.AttributeServiceName].(string),
-		serviceToAdd[models.AttributeServiceDescription].(string))
+		serviceToAdd[models.AttributeServiceDescription].(string), teamID)
 	if err != nil {
 		if err == appErrors.ErrServiceAlreadyExists {
 			c.JSON(http.StatusConflict, utils.FormatErrorResponse(
 				fmt.Sprintf("Service %s already exists", serviceToAdd[models.AttributeServiceName].(string))))
 			return
+		} else if err == appErrors.ErrTeamDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Team[ID:%d] doesn't exist", teamID)))
+			return
 		}
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(
 			appErrors.ErrFailureToProcessRequest.Error()))
@@ -91,10 +170,10 @@ func (h *Handler) updateService(c *gin.Context) {
 		return
 	}
 
-	if !utils.EnsureFieldsStrictlyExists(serviceToUpdate, models.RegisterOrUpdateServicePayloadTemplate) {
+	if !utils.EnsureFieldsStrictlyExists(serviceToUpdate, models.UpdateServicePayloadTemplate) {
 		c.JSON(http.StatusBadRequest,
 			utils.FormatErrorResponse(fmt.Sprintf("Service Metadata update payload is invalid; Strictly Allowed Params: %v",
-				utils.ConvertFieldTypeToString(models.RegisterOrUpdateServicePayloadTemplate))))
+				utils.ConvertFieldTypeToString(models.UpdateServicePayloadTemplate))))
 		return
 	}
 
@@ -105,6 +184,10 @@ func (h *Handler) updateService(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeServiceMutation(c, serviceID) {
+		return
+	}
+
 	updateService, err := h.operations.UpdateService(serviceID,
 		serviceToUpdate[models.AttributeServiceName].(string), serviceToUpdate[models.AttributeServiceDescription].(string))
 	if err != nil {
@@ -136,6 +219,10 @@ func (h *Handler) deleteService(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeServiceMutation(c, serviceID) {
+		return
+	}
+
 	err := h.operations.DeleteService(serviceID)
 	if err != nil {
 		if err == appErrors.ErrServiceDoesNotExist {
@@ -150,6 +237,68 @@ func (h *Handler) deleteService(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.FormatGenericResponse("Service deleted from system"))
 }
 
+// updateServiceOwner assigns the team owning the service.
+// Requester is expected to be a member of both, the team currently owning the service and the team to own it,
+// unless requester is permitted to administer services. Only the latter can assign the owner of service yet to be
+// owned, such that anyone permitted to manage services can't claim it for their team.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) updateServiceOwner(c *gin.Context) {
+	id := c.Param(models.QueryParamID)
+	var serviceID uint
+	if _, err := fmt.Sscanf(id, "%d", &serviceID); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service ID should be numerical"))
+		return
+	}
+
+	var ownerToUpdate map[string]interface{}
+	if err := c.BindJSON(&ownerToUpdate); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service owner payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(ownerToUpdate, models.ServiceOwnerPayloadTemplate) {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse(fmt.Sprintf("Service owner payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.ServiceOwnerPayloadTemplate))))
+		return
+	}
+	teamID, ok := parseTeamID(ownerToUpdate)
+	if !ok {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service owner payload is invalid; team_id should be a positive integer"))
+		return
+	}
+
+	if !middleware.IsPermitted(c, models.PermissionServiceAdmin) {
+		service, ok := h.getServiceToAuthorize(c, serviceID)
+		if !ok {
+			return
+		}
+		if service.OwnerTeamID == nil {
+			c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrServiceOwnerNotPermitted.Error()))
+			return
+		}
+		if !h.authorizeTeamMember(c, *service.OwnerTeamID) || !h.authorizeTeamMember(c, teamID) {
+			return
+		}
+	}
+
+	updatedService, err := h.operations.UpdateServiceOwner(serviceID, teamID)
+	if err != nil {
+		if err == appErrors.ErrServiceDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Service[ID:%d] doesn't exist", serviceID)))
+			return
+		} else if err == appErrors.ErrTeamDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Team[ID:%d] doesn't exist", teamID)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, updatedService)
+}
+
 // fetchServices list the services in system
 func (h *Handler) fetchServices(c *gin.Context) {
 	pageStr := c.DefaultQuery("page", "0")
@@ -193,6 +342,16 @@ func (h *Handler) fetchServices(c *gin.Context) {
 		fetchNameSortedServices = true
 	}
 
+	// services owned by the team are only fetched, if owner is set
+	var ownerTeamID uint
+	if owner := c.DefaultQuery(models.QueryParamOwner, ""); owner != "" {
+		if _, err := fmt.Sscanf(owner, "%d", &ownerTeamID); err != nil || ownerTeamID == 0 {
+			c.JSON(http.StatusBadRequest,
+				utils.FormatErrorResponse("Request Path contains invalid owner, choose numerical team ID"))
+			return
+		}
+	}
+
 	getInverted := c.DefaultQuery("inverted", "")
 	if getInverted != "" && getInverted != "true" && getInverted != "false" {
 		c.JSON(http.StatusBadRequest,
@@ -204,7 +363,7 @@ func (h *Handler) fetchServices(c *gin.Context) {
 	}
 
 	users, totalEntries, fetchErr = h.operations.FetchServices(
-		page, pageSize, searchStr, invertedFetch, fetchNameSortedServices)
+		page, pageSize, searchStr, invertedFetch, fetchNameSortedServices, ownerTeamID)
 	if fetchErr != nil {
 		c.JSON(http.StatusInternalServerError,
 			utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
@@ -301,6 +460,10 @@ func (h *Handler) addServiceVersion(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeServiceMutation(c, serviceID) {
+		return
+	}
+
 	createdServiceVersion, err := h.operations.CreateServiceVersion(
 		serviceID, serviceVersionToAdd[models.AttributeServiceVersionTag].(string),
 		serviceVersionToAdd[models.AttributeServiceVersionInfo].(string))
@@ -360,6 +523,10 @@ func (h *Handler) updateServiceVersion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("service[ID:%d] doesn't exist", serviceID)))
 		return
 	}
+	if !h.authorizeServiceMutation(c, serviceID) {
+		return
+	}
+
 	updatedServiceVersion, err := h.operations.UpdateServiceVersion(
 		serviceID, tag, serviceVersionToUpdate[models.AttributeServiceVersionInfo].(string))
 	if err != nil {
@@ -404,6 +571,10 @@ func (h *Handler) deleteServiceVersion(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeServiceMutation(c, serviceID) {
+		return
+	}
+
 	err = h.operations.DeleteServiceVersion(serviceID, tag)
 	if err != nil {
 		if err == appErrors.ErrServiceVersionDoesNotExist {
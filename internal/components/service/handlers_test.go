@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"userservice/internal/auth"
 	"userservice/internal/configs"
 	appErrors "userservice/internal/errors"
 	"userservice/internal/misc"
@@ -79,6 +80,10 @@ var _ = Describe("Services", func() {
 		ctx = GetTestGinContext(w)
 		misc.InitPayloadValidator()
 		operationsWithoutErr = ServiceAndVersionMock{}
+		// admins aren't limited by team owning the service, which is covered separately
+		misc.SetRole(models.UserRole{Name: models.RoleAdmin, Permissions: []string{models.PermissionServiceAdmin}})
+		misc.SetRole(models.UserRole{Name: models.RoleAdvanced, Permissions: []string{models.PermissionServiceWrite}})
+		ctx.Set(auth.JWTClaimRole, models.RoleAdmin)
 
 		u = url.Values{}
 	})
@@ -158,6 +163,7 @@ var _ = Describe("Services", func() {
 			ctx.Request.Header.Set("Content-Type", "application/json")
 			var payload = map[string]interface{}{
 				"description": "Support scalability",
+				"team_id":     7,
 			}
 			MockJsonPostOrPut(ctx, payload)
 			handler.addService(ctx)
@@ -170,6 +176,7 @@ var _ = Describe("Services", func() {
 			var payload = map[string]interface{}{
 				"name":        "",
 				"description": "Support scalability",
+				"team_id":     7,
 			}
 			MockJsonPostOrPut(ctx, payload)
 			handler.addService(ctx)
@@ -183,6 +190,7 @@ var _ = Describe("Services", func() {
 			var payload = map[string]interface{}{
 				"name":        "postman",
 				"description": "Support scalability",
+				"team_id":     7,
 			}
 			MockJsonPostOrPut(ctx, payload)
 			handler.addService(ctx)
@@ -196,6 +204,7 @@ var _ = Describe("Services", func() {
 			var payload = map[string]interface{}{
 				"name":        "postman",
 				"description": "Support scalability",
+				"team_id":     7,
 			}
 			MockJsonPostOrPut(ctx, payload)
 			handler.addService(ctx)
@@ -212,6 +221,7 @@ var _ = Describe("Services", func() {
 			var payload = map[string]interface{}{
 				"name":        service.Name,
 				"description": "Support scalability",
+				"team_id":     7,
 			}
 			MockJsonPostOrPut(ctx, payload)
 			handler.addService(ctx)
@@ -224,6 +234,51 @@ var _ = Describe("Services", func() {
 			Expect(recvService.Name).To(Equal(recvService.Name))
 			Expect(w.Body.String()).To(Not(BeEmpty()))
 		})
+		It("Missing team owning the service in payload", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "postman", "description": "Support scalability"})
+			handler.addService(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Service creation payload is invalid; Strictly Allowed Params:"))
+		})
+		It("Invalid team owning the service", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "postman", "description": "Support scalability",
+				"team_id": 1.5})
+			handler.addService(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("team_id should be a positive integer"))
+		})
+		It("Team owning the service doesn't exist", func() {
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{CreateServiceFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "postman", "description": "Support scalability",
+				"team_id": 7})
+			handler.addService(ctx)
+			Expect(w.Code).To(Equal(404))
+			Expect(w.Body.String()).To(ContainSubstring("Team[ID:7] doesn't exist"))
+		})
+		It("Non members of the team can't create service owned by the team", func() {
+			ctx.Set(auth.JWTClaimRole, models.RoleAdvanced)
+			ctx.Set(auth.JWTClaimSubject, uint(3))
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "postman", "description": "Support scalability",
+				"team_id": 7})
+			handler.addService(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrServiceNotOwned.Error()))
+		})
+		It("Members of the team can create service owned by the team", func() {
+			ctx.Set(auth.JWTClaimRole, models.RoleAdvanced)
+			ctx.Set(auth.JWTClaimSubject, uint(3))
+			operationsWithoutErr.Service = &models.Service{Name: "postman"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "postman", "description": "Support scalability",
+				"team_id": 7})
+			handler.addService(ctx)
+			Expect(w.Code).To(Equal(201))
+		})
 	})
 	Context("updateService", func() {
 
@@ -539,8 +594,136 @@ var _ = Describe("Services", func() {
 			}
 			Expect(recvService.Data[0].Name).To(Equal(service.Name))
 		})
+		It("Invalid owner param [non numerical]", func() {
+			u.Add(models.QueryParamOwner, "payments")
+			ctx.Request.URL.RawQuery = u.Encode()
+			handler.fetchServices(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Request Path contains invalid owner"))
+		})
 
 	})
+	Context("Team owned services", func() {
+		var ownerTeamID uint = 7
+		BeforeEach(func() {
+			ctx.Set(auth.JWTClaimRole, models.RoleAdvanced)
+			ctx.Set(auth.JWTClaimSubject, uint(3))
+			operationsWithoutErr.Service = &models.Service{Name: "postman", OwnerTeamID: &ownerTeamID}
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+		})
+		It("Members of owning team can update the service", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "postman", "description": "Support scalability"})
+			handler.updateService(ctx)
+			Expect(w.Code).To(Equal(200))
+		})
+		It("Non members of owning team can't delete the service", func() {
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrServiceNotOwned.Error()))
+		})
+		It("Service accounts can't modify team owned service", func() {
+			handler.operations = &operationsWithoutErr
+			ctx.Keys[auth.JWTClaimSubject] = uint(0)
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(403))
+		})
+		It("Internal error while determining team membership", func() {
+			operationsWithoutErr.SetInternalError = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Anyone permitted to manage services can modify service not owned by a team", func() {
+			operationsWithoutErr.Service.OwnerTeamID = nil
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(200))
+		})
+		It("Admins can modify service owned by a team they aren't a member of", func() {
+			ctx.Set(auth.JWTClaimRole, models.RoleAdmin)
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(200))
+		})
+		It("Any role permitted to administer services can modify service owned by a team", func() {
+			misc.SetRole(models.UserRole{Name: "release-manager", Permissions: []string{models.PermissionServiceWrite,
+				models.PermissionServiceAdmin}})
+			ctx.Set(auth.JWTClaimRole, "release-manager")
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(200))
+		})
+		It("Admin role isn't exempted by its name, but by the permission", func() {
+			misc.SetRole(models.UserRole{Name: models.RoleAdmin, Permissions: []string{models.PermissionServiceWrite}})
+			ctx.Set(auth.JWTClaimRole, models.RoleAdmin)
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(403))
+		})
+		It("Personal access token of admin not scoped to administer services", func() {
+			ctx.Set(auth.JWTClaimRole, models.RoleAdmin)
+			ctx.Set(models.AttributeScopes, []string{models.PermissionServiceWrite})
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			handler.deleteService(ctx)
+			Expect(w.Code).To(Equal(403))
+		})
+		It("Assign owner with invalid team ID", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"team_id": -1})
+			handler.updateServiceOwner(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Members can't transfer the service to team they aren't a member of", func() {
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"team_id": 8})
+			handler.updateServiceOwner(ctx)
+			Expect(w.Code).To(Equal(403))
+		})
+		It("Owner of service yet to be owned can't be assigned without service admin permission", func() {
+			operationsWithoutErr.Service.OwnerTeamID = nil
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"team_id": 8})
+			handler.updateServiceOwner(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrServiceOwnerNotPermitted.Error()))
+		})
+		It("Admins can assign owner of service yet to be owned", func() {
+			ctx.Set(auth.JWTClaimRole, models.RoleAdmin)
+			operationsWithoutErr.Service.OwnerTeamID = nil
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{TeamMembershipFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"team_id": 8})
+			handler.updateServiceOwner(ctx)
+			Expect(w.Code).To(Equal(200))
+		})
+		It("Assign owner which doesn't exist", func() {
+			ctx.Set(auth.JWTClaimRole, models.RoleAdmin)
+			operationsWithoutErr.SetRecordNotFound = MockFuncs{UpdateServiceOwnerFn: struct{}{}}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"team_id": 8})
+			handler.updateServiceOwner(ctx)
+			Expect(w.Code).To(Equal(404))
+			Expect(w.Body.String()).To(ContainSubstring("Team[ID:8] doesn't exist"))
+		})
+		It("Successful assignment of owner", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"team_id": 8})
+			handler.updateServiceOwner(ctx)
+			Expect(w.Code).To(Equal(200))
+			var recvService models.Service
+			Expect(json.Unmarshal(w.Body.Bytes(), &recvService)).To(Succeed())
+			Expect(recvService.OwnerTeamID).To(Not(BeNil()))
+		})
+	})
 
 })
 
@@ -560,6 +743,8 @@ var _ = Describe("Service versions", func() {
 		ctx = GetTestGinContext(w)
 		misc.InitPayloadValidator()
 		operations = ServiceAndVersionMock{}
+		misc.SetRole(models.UserRole{Name: models.RoleAdmin, Permissions: []string{models.PermissionServiceAdmin}})
+		ctx.Set(auth.JWTClaimRole, models.RoleAdmin)
 		u = url.Values{}
 	})
 	Context("getServiceVersionByID", func() {
@@ -21,6 +21,8 @@ const (
 	UpdateServiceVersionFn    = "UpdateServiceVersion"
 	DeleteServiceVersionFn    = "DeleteServiceVersion"
 	FetchServiceVersionFn     = "FetchServiceVersionsInverted"
+	UpdateServiceOwnerFn      = "UpdateServiceOwner"
+	TeamMembershipFn          = "IsTeamMember"
 )
 
 // ServiceAndVersionMock...
@@ -63,11 +65,13 @@ func (m *ServiceAndVersionMock) GetService(uint) (*models.Service, error) {
 }
 
 // CreateService...
-func (m *ServiceAndVersionMock) CreateService(string, string) (*models.Service, error) {
+func (m *ServiceAndVersionMock) CreateService(string, string, uint) (*models.Service, error) {
 	if _, ok := m.SetInternalError[CreateServiceFn]; ok {
 		return nil, appErrors.ErrInternal
 	} else if _, ok := m.SetRecordAlreadyExist[CreateServiceFn]; ok {
 		return nil, appErrors.ErrServiceAlreadyExists
+	} else if _, ok := m.SetRecordNotFound[CreateServiceFn]; ok {
+		return nil, appErrors.ErrTeamDoesNotExist
 	}
 	return m.Service, nil
 }
@@ -94,8 +98,28 @@ func (m *ServiceAndVersionMock) DeleteService(uint) error {
 	return nil
 }
 
+// UpdateServiceOwner...
+func (m *ServiceAndVersionMock) UpdateServiceOwner(uint, uint) (*models.Service, error) {
+	if _, ok := m.SetInternalError[UpdateServiceOwnerFn]; ok {
+		return nil, appErrors.ErrInternal
+	} else if _, ok := m.SetRecordNotFound[UpdateServiceOwnerFn]; ok {
+		return nil, appErrors.ErrTeamDoesNotExist
+	}
+	return m.Service, nil
+}
+
+// IsTeamMember...
+func (m *ServiceAndVersionMock) IsTeamMember(uint, uint) (bool, error) {
+	if _, ok := m.SetInternalError[TeamMembershipFn]; ok {
+		return false, appErrors.ErrInternal
+	} else if _, ok := m.SetRecordNotFound[TeamMembershipFn]; ok {
+		return false, nil
+	}
+	return true, nil
+}
+
 // FetchServices...
-func (m *ServiceAndVersionMock) FetchServices(int, int, string, bool, bool) ([]models.Service, int64, error) {
+func (m *ServiceAndVersionMock) FetchServices(int, int, string, bool, bool, uint) ([]models.Service, int64, error) {
 	if _, ok := m.SetInternalError[FetchServiceFn]; ok {
 		return nil, 0, appErrors.ErrInternal
 	}
@@ -161,8 +161,9 @@ func (ops *operations) GetService(id uint) (service *models.Service, returnErr e
 // Since service creation happens seldom, we have additional DB call
 // to check if record exist with same service name rather than waiting for DB to report uniqueKey constrain.
 // We still need to handle duplicate record constrain gracefully if create request happens at once
+// Service is owned by the team, whose members can modify the service thereafter.
 // Materialized View refresh will be scheduled accordingly.
-func (ops *operations) CreateService(name string, description string) (*models.Service, error) {
+func (ops *operations) CreateService(name string, description string, teamID uint) (*models.Service, error) {
 
 	var userWithSameServiceName int64 = 0
 	if gormErr := ops.db.Model(&models.Service{}).Where("name = ?", name).
@@ -173,8 +174,16 @@ func (ops *operations) CreateService(name string, description string) (*models.S
 	if userWithSameServiceName == 1 {
 		return nil, appErrors.ErrServiceAlreadyExists
 	}
+	var teamCount int64
+	if err := ops.db.Model(&models.Team{}).Where("id = ?", teamID).Count(&teamCount).Error; err != nil {
+		ops.log.Errorf("Failed to determine if team [ID:%d] exists: %v", teamID, err)
+		return nil, appErrors.ErrInternal
+	}
+	if teamCount == 0 {
+		return nil, appErrors.ErrTeamDoesNotExist
+	}
 
-	newService := &models.Service{Name: name, Description: description}
+	newService := &models.Service{Name: name, Description: description, OwnerTeamID: &teamID}
 	if gormErr := ops.db.Model(&models.Service{}).Create(newService).Error; gormErr != nil {
 		if strings.Contains(gormErr.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
 			return nil, appErrors.ErrServiceAlreadyExists
@@ -270,15 +279,54 @@ func (ops *operations) DeleteService(id uint) error {
 	return returnErr
 }
 
+// UpdateServiceOwner assigns the team owning existing service, whose members can modify the service thereafter.
+func (ops *operations) UpdateServiceOwner(id uint, teamID uint) (*models.Service, error) {
+	var teamCount int64
+	if err := ops.db.Model(&models.Team{}).Where("id = ?", teamID).Count(&teamCount).Error; err != nil {
+		ops.log.Errorf("Failed to determine if team [ID:%d] exists: %v", teamID, err)
+		return nil, appErrors.ErrInternal
+	}
+	if teamCount == 0 {
+		return nil, appErrors.ErrTeamDoesNotExist
+	}
+
+	result := ops.db.Model(&models.Service{}).Where("id = ?", id).Update("owner_team_id", teamID)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to assign team [ID:%d] as owner of service [ID:%d]: %v", teamID, id, result.Error)
+		return nil, appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return nil, appErrors.ErrServiceDoesNotExist
+	}
+
+	ops.mux.Lock()
+	ops.toRefreshViews = true
+	ops.mux.Unlock()
+	return ops.GetService(id)
+}
+
+// IsTeamMember checks if user is a member of the team
+func (ops *operations) IsTeamMember(teamID uint, userID uint) (bool, error) {
+	var memberCount int64
+	if err := ops.db.Model(&models.TeamMember{}).Where("team_id = ? and user_id = ?", teamID, userID).
+		Count(&memberCount).Error; err != nil {
+		ops.log.Errorf("Failed to determine if user [ID:%d] is a member of team [ID:%d]: %v", userID, teamID, err)
+		return false, appErrors.ErrInternal
+	}
+	return memberCount != 0, nil
+}
+
 // FetchServices responds with services associated with currentPage of given size and sorting order.
-// InvertedFetch and string searches are supported
+// InvertedFetch, string searches and filtering by owning team are supported, ownerTeamID 0 fetches services
+// regardless of their owner.
 // Non existing pages are returning with empty service list, rather than nil, and expected caller to handle it
 func (ops *operations) FetchServices(
 	currentPage int,
 	pageSize int,
 	searchString string,
 	invertedFetch bool,
-	fetchNameSortedServices bool) (services []models.Service, total int64, returnErr error) {
+	fetchNameSortedServices bool,
+	ownerTeamID uint) (services []models.Service, total int64, returnErr error) {
 
 	var (
 		offset           int
@@ -290,7 +338,15 @@ func (ops *operations) FetchServices(
 		referenceDBTable = models.NameSortedServiceView
 	}
 
-	if err := ops.db.Table(referenceDBTable).Where("name like ?", searchString).Count(&total).Error; err != nil {
+	matchingServices := func() *gorm.DB {
+		query := ops.db.Table(referenceDBTable).Where("name like ?", searchString)
+		if ownerTeamID != 0 {
+			query = query.Where("owner_team_id = ?", ownerTeamID)
+		}
+		return query
+	}
+
+	if err := matchingServices().Count(&total).Error; err != nil {
 		ops.log.Errorf("Failed to get the total count of services: %v", err)
 		return nil, 0, appErrors.ErrInternal
 	}
@@ -311,8 +367,7 @@ func (ops *operations) FetchServices(
 		offset = (currentPage - 1) * pageSize
 	}
 
-	if err := ops.db.Table(referenceDBTable).Where("name like ?", searchString).
-		Limit(limit).Offset(offset).Find(&services).Error; err != nil {
+	if err := matchingServices().Limit(limit).Offset(offset).Find(&services).Error; err != nil {
 		ops.log.Errorf("Failed to fetch services: %v", err)
 		return nil, 0, appErrors.ErrInternal
 	}
@@ -195,7 +195,7 @@ var _ = Describe("Services [operations]", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE name = $1`)).
 				WillReturnError(errors.New("connection is already closed"))
 
-			service, err := ops.CreateService("postman", "Nice Product")
+			service, err := ops.CreateService("postman", "Nice Product", 7)
 			Expect(err).To(Not(BeNil()))
 			Expect(err).To(MatchError(appErrors.ErrInternal))
 			Expect(service).To(BeNil())
@@ -203,16 +203,27 @@ var _ = Describe("Services [operations]", func() {
 		It("service already exist with same name", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE name = $1`)).
 				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
-			service, err := ops.CreateService("postman", "Nice Product")
+			service, err := ops.CreateService("postman", "Nice Product", 7)
 			Expect(err).To(Not(BeNil()))
 			Expect(err).To(MatchError(appErrors.ErrServiceAlreadyExists))
 			Expect(service).To(BeNil())
 
 		})
+		It("team to own the service doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE id = $1`)).
+				WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			service, err := ops.CreateService("postman", "Nice Product", 7)
+			Expect(err).To(MatchError(appErrors.ErrTeamDoesNotExist))
+			Expect(service).To(BeNil())
+		})
 		It("In distributed/concurrent env, while proceeding to create service,"+
 			"we experience UniqueKey Constrain Violation due to same name", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE name = $1`)).
 				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE id = $1`)).
+				WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 			mock.ExpectBegin()
 			mock.ExpectQuery(regexp.QuoteMeta(
 				`INSERT INTO "service"`)).
@@ -223,9 +234,10 @@ var _ = Describe("Services [operations]", func() {
 					"postman",
 					"Nice Product",
 					0,
+					7,
 				).WillReturnError(appErrors.ErrUniqueKeyConstrainViolation)
 			mock.ExpectRollback()
-			service, err := ops.CreateService("postman", "Nice Product")
+			service, err := ops.CreateService("postman", "Nice Product", 7)
 			Expect(err).To(MatchError(appErrors.ErrServiceAlreadyExists))
 			Expect(service).To(BeNil())
 		})
@@ -233,6 +245,8 @@ var _ = Describe("Services [operations]", func() {
 			"we experience Internal error", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE name = $1`)).
 				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE id = $1`)).
+				WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 			mock.ExpectBegin()
 			mock.ExpectQuery(regexp.QuoteMeta(
 				`INSERT INTO "service"`)).
@@ -243,15 +257,18 @@ var _ = Describe("Services [operations]", func() {
 					"postman",
 					"Nice Product",
 					0,
+					7,
 				).WillReturnError(errors.New("connection error"))
 			mock.ExpectRollback()
-			service, err := ops.CreateService("postman", "Nice Product")
+			service, err := ops.CreateService("postman", "Nice Product", 7)
 			Expect(err).To(MatchError(appErrors.ErrInternal))
 			Expect(service).To(BeNil())
 		})
 		It("successfully create service", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE name = $1`)).
 				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE id = $1`)).
+				WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 			mock.ExpectBegin()
 			mock.ExpectQuery(regexp.QuoteMeta(
 				`INSERT INTO "service"`)).
@@ -262,11 +279,13 @@ var _ = Describe("Services [operations]", func() {
 					"postman",
 					"Nice Product",
 					0,
+					7,
 				).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
 			mock.ExpectCommit()
-			service, err := ops.CreateService("postman", "Nice Product")
+			service, err := ops.CreateService("postman", "Nice Product", 7)
 			Expect(err).To(BeNil())
 			Expect(service.Name).To(Equal("postman"))
+			Expect(*service.OwnerTeamID).To(Equal(uint(7)))
 		})
 	})
 	Context("Update service record by ID", func() {
@@ -433,7 +452,7 @@ var _ = Describe("Services [operations]", func() {
 		It("Internal error while getting total count of date sorted service", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE name like`)).
 				WillReturnError(errors.New("connection error"))
-			services, total, err := ops.FetchServices(1, 1, searchStr, false, false)
+			services, total, err := ops.FetchServices(1, 1, searchStr, false, false, 0)
 			Expect(err).To(Not(BeNil()))
 			Expect(err).To(MatchError(appErrors.ErrInternal))
 			Expect(services).To(HaveLen(0))
@@ -446,7 +465,7 @@ var _ = Describe("Services [operations]", func() {
 			mock.ExpectQuery(
 				regexp.QuoteMeta(`SELECT * FROM "service" WHERE name like $1 AND "service"."deleted_at" IS NULL LIMIT $2`)).
 				WillReturnError(errors.New("connection error"))
-			services, total, err := ops.FetchServices(1, 1, searchStr, false, false)
+			services, total, err := ops.FetchServices(1, 1, searchStr, false, false, 0)
 			Expect(err).To(Not(BeNil()))
 			Expect(err).To(MatchError(appErrors.ErrInternal))
 			Expect(services).To(HaveLen(0))
@@ -465,7 +484,7 @@ var _ = Describe("Services [operations]", func() {
 							"admin",
 							"admin@mgmtportal.com",
 							1))
-			services, total, err := ops.FetchServices(1, 1, searchStr, false, false)
+			services, total, err := ops.FetchServices(1, 1, searchStr, false, false, 0)
 			Expect(err).To(BeNil())
 			Expect(services).To(HaveLen(1))
 			Expect(total).To(Equal(int64(1)))
@@ -491,7 +510,7 @@ var _ = Describe("Services [operations]", func() {
 						"admin",
 						"admin@mgmtportal.com",
 						1))
-			services, total, err := ops.FetchServices(3, 1, searchStr, true, false)
+			services, total, err := ops.FetchServices(3, 1, searchStr, true, false, 0)
 			Expect(err).To(BeNil())
 			Expect(services).To(HaveLen(2))
 			Expect(services[0].ID).To(Equal(uint(2)))
@@ -518,7 +537,7 @@ var _ = Describe("Services [operations]", func() {
 						"admin",
 						"admin@mgmtportal.com",
 						1))
-			services, total, err := ops.FetchServices(4, 1, searchStr, true, false)
+			services, total, err := ops.FetchServices(4, 1, searchStr, true, false, 0)
 			Expect(err).To(BeNil())
 			Expect(services).To(HaveLen(0))
 			Expect(total).To(Equal(int64(2)))
@@ -543,7 +562,7 @@ var _ = Describe("Services [operations]", func() {
 						"admin",
 						"admin@mgmtportal.com",
 						1))
-			services, total, err := ops.FetchServices(1, 1, searchStr, false, true)
+			services, total, err := ops.FetchServices(1, 1, searchStr, false, true, 0)
 			Expect(err).To(BeNil())
 			Expect(services).To(HaveLen(2))
 			Expect(services[0].ID).To(Equal(uint(1)))
@@ -551,6 +570,66 @@ var _ = Describe("Services [operations]", func() {
 
 		})
 	})
+	Context("Service owned by team", func() {
+		It("Fetch services owned by team", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT count(*) FROM "service" WHERE name like $1 AND owner_team_id = $2`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT * FROM "service" WHERE name like $1 AND owner_team_id = $2 AND "service"."deleted_at" IS NULL`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_team_id"}).AddRow(1, "postman", 7))
+			services, total, err := ops.FetchServices(1, 1, "%%", false, false, 7)
+			Expect(err).To(BeNil())
+			Expect(total).To(Equal(int64(1)))
+			Expect(*services[0].OwnerTeamID).To(Equal(uint(7)))
+		})
+		It("Assign team which doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			service, err := ops.UpdateServiceOwner(1, 7)
+			Expect(err).To(MatchError(appErrors.ErrTeamDoesNotExist))
+			Expect(service).To(BeNil())
+		})
+		It("Assign owner to service which doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "service" SET "owner_team_id"=$1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			service, err := ops.UpdateServiceOwner(1, 7)
+			Expect(err).To(MatchError(appErrors.ErrServiceDoesNotExist))
+			Expect(service).To(BeNil())
+		})
+		It("Successful assignment of owner", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "service" SET "owner_team_id"=$1`)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_team_id"}).AddRow(1, "postman", 7))
+			service, err := ops.UpdateServiceOwner(1, 7)
+			Expect(err).To(BeNil())
+			Expect(*service.OwnerTeamID).To(Equal(uint(7)))
+			Expect(ops.toRefreshViews).To(BeTrue())
+		})
+		It("Internal error while determining team membership", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team_member" WHERE team_id = $1 and user_id = $2`)).
+				WillReturnError(errors.New("connection error"))
+			isMember, err := ops.IsTeamMember(7, 3)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+			Expect(isMember).To(BeFalse())
+		})
+		It("User is a member of team", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team_member" WHERE team_id = $1 and user_id = $2`)).
+				WithArgs(7, 3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			isMember, err := ops.IsTeamMember(7, 3)
+			Expect(err).To(BeNil())
+			Expect(isMember).To(BeTrue())
+		})
+	})
 	Context("Format service records with page", func() {
 		res := ops.FormatServiceDetailsWithPageDetails([]models.Service{{Name: "postman"}}, 1, 1, 1)
 		Expect(res.TotalItems).To(Equal(int64(1)))
@@ -26,22 +26,29 @@ func NewHandler(ctx context.Context, wg *sync.WaitGroup, log *zap.SugaredLogger,
 // RegisterRoutes has sent of route endpoints categorized as per authz roles using middleware
 func (h *Handler) RegisterRoutes(routers *gin.RouterGroup) {
 
-	// Authorized routes for all user roles.
-	routers.GET("/service/:id", h.getServiceByID)
-	routers.GET("/services", h.fetchServices)
-	routers.GET("/service/:id/version/:tag", h.getServiceVersion)
-	routers.GET("/service/:id/versions", h.fetchServiceVersions)
-
-	// Authorized routes for advanced, and admin users.
-	advancedAndAdminRoutes := routers.Group("/")
-	advancedAndAdminRoutes.Use(middleware.AuthzRoles(models.RoleAdvanced, models.RoleAdmin))
+	// Authorized routes for roles permitted to view services.
+	serviceReadRoutes := routers.Group("/")
+	serviceReadRoutes.Use(middleware.RequirePermission(models.PermissionServiceRead))
 	{
-		advancedAndAdminRoutes.POST("/service", h.addService)
-		advancedAndAdminRoutes.PUT("/service/:id", h.updateService)
-		advancedAndAdminRoutes.DELETE("/service/:id", h.deleteService)
-		advancedAndAdminRoutes.POST("/service/:id/version", h.addServiceVersion)
-		advancedAndAdminRoutes.PUT("/service/:id/version/:tag", h.updateServiceVersion)
-		advancedAndAdminRoutes.DELETE("/service/:id/version/:tag", h.deleteServiceVersion)
+		serviceReadRoutes.GET("/service/:id", h.getServiceByID)
+		serviceReadRoutes.GET("/services", h.fetchServices)
+		serviceReadRoutes.GET("/service/:id/version/:tag", h.getServiceVersion)
+		serviceReadRoutes.GET("/service/:id/versions", h.fetchServiceVersions)
+	}
+
+	// Authorized routes for roles permitted to manage services.
+	// Services owned by a team are further limited to be managed by the team members, except for the ones
+	// permitted to administer services.
+	serviceWriteRoutes := routers.Group("/")
+	serviceWriteRoutes.Use(middleware.RequirePermission(models.PermissionServiceWrite))
+	{
+		serviceWriteRoutes.POST("/service", h.addService)
+		serviceWriteRoutes.PUT("/service/:id", h.updateService)
+		serviceWriteRoutes.DELETE("/service/:id", h.deleteService)
+		serviceWriteRoutes.PUT("/service/:id/owner", h.updateServiceOwner)
+		serviceWriteRoutes.POST("/service/:id/version", h.addServiceVersion)
+		serviceWriteRoutes.PUT("/service/:id/version/:tag", h.updateServiceVersion)
+		serviceWriteRoutes.DELETE("/service/:id/version/:tag", h.deleteServiceVersion)
 	}
 
 }
@@ -38,6 +38,6 @@ var _ = Describe("Service [Handler]", func() {
 		apiGroup := router.Group("/api/v1")
 		h.RegisterRoutes(apiGroup)
 		routes := router.Routes()
-		Expect(routes).To(HaveLen(10))
+		Expect(routes).To(HaveLen(11))
 	})
 })
@@ -0,0 +1,324 @@
+package serviceaccount
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"userservice/internal/auth"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+	"userservice/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// clientIDPrefix marks client ids of service accounts, such that they are distinguishable in logs and configs.
+	clientIDPrefix = "sa_"
+	// clientIDRandomLength is the count of random characters following the prefix of client id.
+	clientIDRandomLength = 20
+)
+
+// issueToken implements OAuth2 client credentials grant, where service account exchanges
+// its client id and client secret for an access token. No refresh token is issued, as
+// service account can simply request a new token with its credentials.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) issueToken(c *gin.Context) {
+	var tokenRequest map[string]interface{}
+	if err := c.BindJSON(&tokenRequest); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Token request payload is invalid; Expected JSON payload"))
+		return
+	}
+
+	if !utils.EnsureFieldsStrictlyExists(tokenRequest, models.ClientCredentialsPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Token request payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.ClientCredentialsPayloadTemplate))))
+		return
+	}
+	if tokenRequest[models.AttributeGrantType] != models.GrantTypeClientCredentials {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrUnsupportedGrantType.Error()))
+		return
+	}
+
+	serviceAccount, err := h.operations.GetServiceAccountByClientID(tokenRequest[models.AttributeClientID].(string))
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		// unknown client id and wrong secret are indistinguishable for the caller
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidClientCredentials.Error()))
+		return
+	}
+	secretHash := auth.HashOpaqueToken(tokenRequest[models.AttributeClientSecret].(string))
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(serviceAccount.ClientSecretHash)) != 1 {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidClientCredentials.Error()))
+		return
+	}
+
+	token, err := h.tokens.CreateJWT(auth.TokenSubject{ServiceAccountID: serviceAccount.ID, Role: serviceAccount.Role})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.operations.RecordTokenIssued(serviceAccount.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatClientCredentialsTokenResponse(*token, int64(h.tokens.Expiration().Seconds())))
+}
+
+// generateClientCredentials generates client id and client secret for service account.
+func generateClientCredentials() (clientID string, clientSecret string, err error) {
+	randomID, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	clientSecret, err = auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	return clientIDPrefix + randomID[:clientIDRandomLength], clientSecret, nil
+}
+
+// validateServiceAccountPayload validates service account creation/update payload and
+// responds with client error if payload is invalid.
+func validateServiceAccountPayload(c *gin.Context, action string) (map[string]interface{}, bool) {
+	var serviceAccount map[string]interface{}
+	if err := c.BindJSON(&serviceAccount); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse(fmt.Sprintf("Service account %s payload is invalid; Expected JSON payload", action)))
+		return nil, false
+	}
+
+	if !utils.EnsureFieldsStrictlyExists(serviceAccount, models.ServiceAccountPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Service account %s payload is invalid; Strictly Allowed Params: %v", action,
+				utils.ConvertFieldTypeToString(models.ServiceAccountPayloadTemplate))))
+		return nil, false
+	}
+	if serviceAccount[models.AttributeName] == "" {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse(fmt.Sprintf("Service account %s payload is invalid; name is empty", action)))
+		return nil, false
+	}
+	if !misc.IsRoleConfigured(serviceAccount[models.AttributeRole].(string)) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("User Role %s doesn't exist", serviceAccount[models.AttributeRole].(string))))
+		return nil, false
+	}
+	if !misc.CoversPermissions(c.GetString(auth.JWTClaimRole), serviceAccount[models.AttributeRole].(string)) {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrGrantingBeyondPermissions.Error()))
+		return nil, false
+	}
+	return serviceAccount, true
+}
+
+// addServiceAccount registers new service account. Client secret is generated and shown
+// only in this response, as only its hash is persisted.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) addServiceAccount(c *gin.Context) {
+	serviceAccountToAdd, ok := validateServiceAccountPayload(c, "creation")
+	if !ok {
+		return
+	}
+
+	clientID, clientSecret, err := generateClientCredentials()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	serviceAccount := models.ServiceAccount{
+		Name:             serviceAccountToAdd[models.AttributeName].(string),
+		Description:      serviceAccountToAdd[models.AttributeDescription].(string),
+		Role:             serviceAccountToAdd[models.AttributeRole].(string),
+		ClientID:         clientID,
+		ClientSecretHash: auth.HashOpaqueToken(clientSecret),
+	}
+	if err := h.operations.CreateServiceAccount(&serviceAccount); err != nil {
+		if err == appErrors.ErrServiceAccountAlreadyExists {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(
+				fmt.Sprintf("Service account %s already exists", serviceAccount.Name)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, models.ServiceAccountCredentials{ServiceAccount: serviceAccount, ClientSecret: clientSecret})
+}
+
+// getServiceAccountByID fetches service account by ID
+func (h *Handler) getServiceAccountByID(c *gin.Context) {
+	id := c.Param(models.QueryParamID)
+	var serviceAccountID uint
+	if _, err := fmt.Sscanf(id, "%d", &serviceAccountID); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service account ID should be numerical"))
+		return
+	}
+
+	serviceAccount, err := h.operations.GetServiceAccount(serviceAccountID)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound,
+			utils.FormatErrorResponse(fmt.Sprintf("Service account[ID:%d] doesn't exist", serviceAccountID)))
+		return
+	}
+	c.JSON(http.StatusOK, serviceAccount)
+}
+
+// fetchServiceAccounts list the service accounts in system
+func (h *Handler) fetchServiceAccounts(c *gin.Context) {
+	pageStr := c.DefaultQuery("page", "0")
+	page, paramErr := strconv.Atoi(pageStr)
+	if paramErr != nil || page < 0 {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Request Path contains invalid page number, choose positive numerical value"))
+		return
+	}
+	// page 0 or unset page parameter will represent the first page
+	if page == 0 {
+		page = 1
+	}
+
+	pageSizeStr := c.DefaultQuery("size", models.DefaultPageSize)
+	pageSize, paramErr := strconv.Atoi(pageSizeStr)
+	if paramErr != nil || pageSize < 0 {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Request Path contains invalid page size, choose positive numerical value"))
+		return
+	}
+
+	serviceAccounts, err := h.operations.FetchServiceAccountsWithPagination(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, serviceAccounts)
+}
+
+// updateServiceAccount updates name, description and role of service account.
+// Role change revokes tokens already issued to the service account, as they carry the role as claim.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) updateServiceAccount(c *gin.Context) {
+	id := c.Param(models.QueryParamID)
+	var serviceAccountID uint
+	if _, err := fmt.Sscanf(id, "%d", &serviceAccountID); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service account ID should be numerical"))
+		return
+	}
+	serviceAccountToUpdate, ok := validateServiceAccountPayload(c, "update")
+	if !ok {
+		return
+	}
+
+	existingServiceAccount, err := h.operations.GetServiceAccount(serviceAccountID)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrServiceAccountDoesNotExist.Error()))
+		return
+	}
+	existingRole := existingServiceAccount.Role
+
+	updatedServiceAccount, err := h.operations.UpdateServiceAccount(serviceAccountID,
+		serviceAccountToUpdate[models.AttributeName].(string),
+		serviceAccountToUpdate[models.AttributeDescription].(string),
+		serviceAccountToUpdate[models.AttributeRole].(string))
+	if err != nil {
+		if err == appErrors.ErrServiceAccountDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrServiceAccountDoesNotExist.Error()))
+			return
+		} else if err == appErrors.ErrServiceAccountAlreadyExists {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(fmt.Sprintf("Service account %s already exists",
+				serviceAccountToUpdate[models.AttributeName].(string))))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if existingRole != updatedServiceAccount.Role {
+		if err := h.revoker.RevokeSubjectTokens(auth.SubjectFromServiceAccountID(serviceAccountID)); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, updatedServiceAccount)
+}
+
+// rotateServiceAccountSecret replaces client secret of service account. Previous secret stops working
+// immediately and tokens issued with it are revoked. New secret is shown only in this response.
+func (h *Handler) rotateServiceAccountSecret(c *gin.Context) {
+	id := c.Param(models.QueryParamID)
+	var serviceAccountID uint
+	if _, err := fmt.Sscanf(id, "%d", &serviceAccountID); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service account ID should be numerical"))
+		return
+	}
+
+	serviceAccount, err := h.operations.GetServiceAccount(serviceAccountID)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrServiceAccountDoesNotExist.Error()))
+		return
+	}
+	clientSecret, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.operations.RotateServiceAccountSecret(serviceAccountID, auth.HashOpaqueToken(clientSecret)); err != nil {
+		if err == appErrors.ErrServiceAccountDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrServiceAccountDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.revoker.RevokeSubjectTokens(auth.SubjectFromServiceAccountID(serviceAccountID)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.ServiceAccountCredentials{ServiceAccount: *serviceAccount, ClientSecret: clientSecret})
+}
+
+// deleteServiceAccount deletes service account from system, and revokes tokens issued to it.
+func (h *Handler) deleteServiceAccount(c *gin.Context) {
+	id := c.Param(models.QueryParamID)
+	var serviceAccountID uint
+	if _, err := fmt.Sscanf(id, "%d", &serviceAccountID); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Service account ID should be numerical"))
+		return
+	}
+
+	if err := h.operations.DeleteServiceAccount(serviceAccountID); err != nil {
+		if err == appErrors.ErrServiceAccountDoesNotExist {
+			c.JSON(http.StatusNotFound,
+				utils.FormatGenericResponse(fmt.Sprintf("Service account[ID:%d] doesn't exist", serviceAccountID)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	// tokens issued to deleted service account shouldn't be usable till their expiry
+	if err := h.revoker.RevokeSubjectTokens(auth.SubjectFromServiceAccountID(serviceAccountID)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Service account deleted from system"))
+}
@@ -0,0 +1,260 @@
+package serviceaccount
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"userservice/internal/auth"
+	"userservice/internal/configs"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+	"userservice/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func GetTestGinContext(w *httptest.ResponseRecorder) *gin.Context {
+	gin.SetMode(gin.TestMode)
+
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{},
+	}
+	return ctx
+}
+func MockJsonPostOrPut(c *gin.Context, content interface{}) {
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	jsonbytes, err := json.Marshal(content)
+	if err != nil {
+		panic(err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonbytes))
+}
+
+var _ = Describe("Service Accounts", func() {
+
+	var (
+		ctx        *gin.Context
+		handler    *Handler
+		w          *httptest.ResponseRecorder
+		revoker    *RevocationMock
+		operations *ServiceAccountMock
+	)
+	BeforeEach(func() {
+		handler = new(Handler)
+		handler.runtimeConfig = new(configs.Config)
+		handler.tokens = auth.NewTokenIssuer(auth.NewHMACKeySet([]byte("mgmtportal")),
+			"userservice", "userservice", 100, 0)
+		revoker = &RevocationMock{}
+		handler.revoker = revoker
+		operations = &ServiceAccountMock{ServiceAccount: &models.ServiceAccount{DBModel: models.DBModel{ID: 2},
+			Name: "ci", Role: "basic", ClientID: "sa_abc", ClientSecretHash: auth.HashOpaqueToken("secret")}}
+		handler.operations = operations
+		w = httptest.NewRecorder()
+		ctx = GetTestGinContext(w)
+		misc.SetRole(models.UserRole{Name: "basic"})
+		misc.SetRole(models.UserRole{Name: "admin"})
+	})
+	Context("Client credentials grant", func() {
+		It("Invalid payload", func() {
+			handler.issueToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Token request payload is invalid; Expected JSON payload"))
+		})
+		It("Unsupported grant type", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"grant_type": "password", "client_id": "sa_abc",
+				"client_secret": "secret"})
+			handler.issueToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("unsupported grant type"))
+		})
+		It("Unknown client id", func() {
+			operations.SetServiceAccountDoesntExist = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"grant_type": "client_credentials", "client_id": "sa_xyz",
+				"client_secret": "secret"})
+			handler.issueToken(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring("invalid client credentials"))
+		})
+		It("Wrong client secret", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"grant_type": "client_credentials", "client_id": "sa_abc",
+				"client_secret": "guess"})
+			handler.issueToken(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring("invalid client credentials"))
+			Expect(operations.TokenIssuedFor).To(BeEmpty())
+		})
+		It("Internal error", func() {
+			operations.SetInternalError = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"grant_type": "client_credentials", "client_id": "sa_abc",
+				"client_secret": "secret"})
+			handler.issueToken(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Valid credentials", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"grant_type": "client_credentials", "client_id": "sa_abc",
+				"client_secret": "secret"})
+			handler.issueToken(ctx)
+			Expect(w.Code).To(Equal(200))
+			var response utils.ClientCredentialsTokenResponse
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(BeNil())
+			Expect(response.TokenType).To(Equal("Bearer"))
+			Expect(response.ExpiresIn).To(Equal(int64(100)))
+			token, err := handler.tokens.ValidateJWT(response.AccessToken)
+			Expect(err).To(BeNil())
+			subject, err := token.Claims.GetSubject()
+			Expect(err).To(BeNil())
+			Expect(subject).To(Equal(auth.SubjectFromServiceAccountID(2)))
+			Expect(operations.TokenIssuedFor).To(ConsistOf(uint(2)))
+		})
+	})
+	Context("Create service account", func() {
+		It("Missing fields in payload", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci"})
+			handler.addServiceAccount(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Service account creation payload is invalid; Strictly Allowed Params"))
+		})
+		It("Empty name", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "description": "", "role": "basic"})
+			handler.addServiceAccount(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("name is empty"))
+		})
+		It("Unknown role", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "description": "", "role": "root"})
+			handler.addServiceAccount(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("User Role root doesn't exist"))
+		})
+		It("Role granted permissions beyond the requester's", func() {
+			misc.SetRole(models.UserRole{Name: "automation", Permissions: []string{models.PermissionServiceAccountWrite}})
+			misc.SetRole(models.UserRole{Name: "admin", Permissions: []string{models.PermissionServiceAccountWrite,
+				models.PermissionUserWrite}})
+			defer misc.RemoveRole("automation")
+			ctx.Set("role", "automation")
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "description": "", "role": "admin"})
+			handler.addServiceAccount(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrGrantingBeyondPermissions.Error()))
+		})
+		It("Duplicate name", func() {
+			operations.SetDuplicateName = true
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "description": "", "role": "basic"})
+			handler.addServiceAccount(ctx)
+			Expect(w.Code).To(Equal(409))
+		})
+		It("Created with credentials shown once", func() {
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "deploy", "description": "pipeline", "role": "basic"})
+			handler.addServiceAccount(ctx)
+			Expect(w.Code).To(Equal(201))
+			var response map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(BeNil())
+			Expect(response["client_id"]).To(HavePrefix("sa_"))
+			Expect(response["client_secret"]).To(Not(BeEmpty()))
+			Expect(response).To(Not(HaveKey("client_secret_hash")))
+			Expect(operations.ServiceAccount.ClientSecretHash).
+				To(Equal(auth.HashOpaqueToken(response["client_secret"].(string))))
+		})
+	})
+	Context("Fetch service accounts", func() {
+		It("Invalid ID", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "abc"}}
+			handler.getServiceAccountByID(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Service account doesn't exist", func() {
+			operations.SetServiceAccountDoesntExist = true
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			handler.getServiceAccountByID(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Fetch by ID", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			handler.getServiceAccountByID(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("sa_abc"))
+		})
+		It("Invalid page", func() {
+			ctx.Request.URL.RawQuery = "page=-1"
+			handler.fetchServiceAccounts(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("Fetch page", func() {
+			handler.fetchServiceAccounts(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring(`"TotalItems":1`))
+		})
+	})
+	Context("Update service account", func() {
+		It("Service account doesn't exist", func() {
+			operations.SetServiceAccountDoesntExist = true
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "description": "", "role": "basic"})
+			handler.updateServiceAccount(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Update without role change keeps tokens", func() {
+			operations.UpdatedServiceAccount = &models.ServiceAccount{DBModel: models.DBModel{ID: 2}, Name: "ci",
+				Description: "pipeline", Role: "basic"}
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "description": "pipeline", "role": "basic"})
+			handler.updateServiceAccount(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(revoker.RevokedSubjects).To(BeEmpty())
+		})
+		It("Role change revokes tokens", func() {
+			operations.UpdatedServiceAccount = &models.ServiceAccount{DBModel: models.DBModel{ID: 2}, Name: "ci",
+				Role: "admin"}
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "description": "", "role": "admin"})
+			handler.updateServiceAccount(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(revoker.RevokedSubjects).To(ConsistOf("service-account:2"))
+		})
+	})
+	Context("Rotate secret and delete service account", func() {
+		It("Rotate secret", func() {
+			previousHash := operations.ServiceAccount.ClientSecretHash
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			handler.rotateServiceAccountSecret(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operations.ServiceAccount.ClientSecretHash).To(Not(Equal(previousHash)))
+			Expect(w.Body.String()).To(ContainSubstring("client_secret"))
+			Expect(revoker.RevokedSubjects).To(ConsistOf("service-account:2"))
+		})
+		It("Rotate secret of unknown service account", func() {
+			operations.SetServiceAccountDoesntExist = true
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			handler.rotateServiceAccountSecret(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("Delete unknown service account", func() {
+			operations.SetServiceAccountDoesntExist = true
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			handler.deleteServiceAccount(ctx)
+			Expect(w.Code).To(Equal(404))
+			Expect(revoker.RevokedSubjects).To(BeEmpty())
+		})
+		It("Delete revokes tokens", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			handler.deleteServiceAccount(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(revoker.RevokedSubjects).To(ConsistOf("service-account:2"))
+		})
+		It("Revocation failure", func() {
+			revoker.SetInternalError = true
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+			handler.deleteServiceAccount(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package serviceaccount
+
+import (
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+)
+
+// ServiceAccountMock...
+type ServiceAccountMock struct {
+	ServiceAccount               *models.ServiceAccount
+	UpdatedServiceAccount        *models.ServiceAccount
+	SetInternalError             bool
+	SetServiceAccountDoesntExist bool
+	SetDuplicateName             bool
+	TokenIssuedFor               []uint
+}
+
+// CreateServiceAccount...
+func (m *ServiceAccountMock) CreateServiceAccount(serviceAccount *models.ServiceAccount) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetDuplicateName {
+		return appErrors.ErrServiceAccountAlreadyExists
+	}
+	m.ServiceAccount = serviceAccount
+	return nil
+}
+
+// GetServiceAccount...
+func (m *ServiceAccountMock) GetServiceAccount(uint) (*models.ServiceAccount, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetServiceAccountDoesntExist {
+		return nil, appErrors.ErrServiceAccountDoesNotExist
+	}
+	return m.ServiceAccount, nil
+}
+
+// GetServiceAccountByClientID...
+func (m *ServiceAccountMock) GetServiceAccountByClientID(string) (*models.ServiceAccount, error) {
+	return m.GetServiceAccount(0)
+}
+
+// FetchServiceAccountsWithPagination...
+func (m *ServiceAccountMock) FetchServiceAccountsWithPagination(page int,
+	size int) (*models.PaginatedServiceAccountList, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	return &models.PaginatedServiceAccountList{Data: []models.ServiceAccount{*m.ServiceAccount},
+		TotalItems: 1, CurrentPage: page, PageSize: size}, nil
+}
+
+// UpdateServiceAccount...
+func (m *ServiceAccountMock) UpdateServiceAccount(uint, string, string, string) (*models.ServiceAccount, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetDuplicateName {
+		return nil, appErrors.ErrServiceAccountAlreadyExists
+	} else if m.SetServiceAccountDoesntExist {
+		return nil, appErrors.ErrServiceAccountDoesNotExist
+	}
+	return m.UpdatedServiceAccount, nil
+}
+
+// RotateServiceAccountSecret...
+func (m *ServiceAccountMock) RotateServiceAccountSecret(_ uint, clientSecretHash string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetServiceAccountDoesntExist {
+		return appErrors.ErrServiceAccountDoesNotExist
+	}
+	m.ServiceAccount.ClientSecretHash = clientSecretHash
+	return nil
+}
+
+// DeleteServiceAccount...
+func (m *ServiceAccountMock) DeleteServiceAccount(uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetServiceAccountDoesntExist {
+		return appErrors.ErrServiceAccountDoesNotExist
+	}
+	return nil
+}
+
+// RecordTokenIssued...
+func (m *ServiceAccountMock) RecordTokenIssued(id uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.TokenIssuedFor = append(m.TokenIssuedFor, id)
+	return nil
+}
+
+// RevocationMock...
+type RevocationMock struct {
+	SetInternalError bool
+	RevokedSubjects  []string
+}
+
+// RevokeToken...
+func (m *RevocationMock) RevokeToken(string, time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RevokeSubjectTokens...
+func (m *RevocationMock) RevokeSubjectTokens(subject string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RevokedSubjects = append(m.RevokedSubjects, subject)
+	return nil
+}
+
+// IsRevoked...
+func (m *RevocationMock) IsRevoked(_ string, subject string, _ time.Time) bool {
+	for _, revokedSubject := range m.RevokedSubjects {
+		if revokedSubject == subject {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package serviceaccount
+
+import (
+	"errors"
+	"strings"
+	"time"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// operations...
+type operations struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// newOperations initializes service account operation handler
+func newOperations(db *gorm.DB, log *zap.SugaredLogger) *operations {
+	return &operations{db: db, log: log}
+}
+
+// CreateServiceAccount creates service account record in DB with necessary metadata.
+func (ops *operations) CreateServiceAccount(serviceAccount *models.ServiceAccount) error {
+	var accountsWithSameName int64
+	if err := ops.db.Model(&models.ServiceAccount{}).Where("name = ?", serviceAccount.Name).
+		Count(&accountsWithSameName).Error; err != nil {
+		ops.log.Errorf("Failed to determine if service account %s is already registered: %v", serviceAccount.Name, err)
+		return appErrors.ErrInternal
+	}
+	if accountsWithSameName != 0 {
+		return appErrors.ErrServiceAccountAlreadyExists
+	}
+	if err := ops.db.Model(&models.ServiceAccount{}).Create(serviceAccount).Error; err != nil {
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return appErrors.ErrServiceAccountAlreadyExists
+		}
+		ops.log.Errorf("Failed to create service account %s: %v", serviceAccount.Name, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// GetServiceAccount fetches service account record in DB for the given id
+func (ops *operations) GetServiceAccount(id uint) (*models.ServiceAccount, error) {
+	serviceAccount := new(models.ServiceAccount)
+	if err := ops.db.Where("id = ?", id).First(serviceAccount).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrServiceAccountDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch service account by id %d: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	return serviceAccount, nil
+}
+
+// GetServiceAccountByClientID fetches service account record in DB for the given client id
+func (ops *operations) GetServiceAccountByClientID(clientID string) (*models.ServiceAccount, error) {
+	serviceAccount := new(models.ServiceAccount)
+	if err := ops.db.Where("client_id = ?", clientID).First(serviceAccount).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrServiceAccountDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch service account by client id %s: %v", clientID, err)
+		return nil, appErrors.ErrInternal
+	}
+	return serviceAccount, nil
+}
+
+// FetchServiceAccountsWithPagination responds with service accounts associated with currentPage of given size
+func (ops *operations) FetchServiceAccountsWithPagination(currentPage int,
+	pageSize int) (*models.PaginatedServiceAccountList, error) {
+
+	var (
+		total           int64
+		serviceAccounts []models.ServiceAccount
+	)
+	if err := ops.db.Model(&models.ServiceAccount{}).Count(&total).Error; err != nil {
+		ops.log.Errorf("Failed to get the total count of service accounts: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	offset := (currentPage - 1) * pageSize
+	if err := ops.db.Order("id").Limit(pageSize).Offset(offset).Find(&serviceAccounts).Error; err != nil {
+		ops.log.Errorf("Failed to fetch service accounts: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	return &models.PaginatedServiceAccountList{
+		Data:        serviceAccounts,
+		TotalItems:  total,
+		CurrentPage: currentPage,
+		PageSize:    pageSize,
+	}, nil
+}
+
+// UpdateServiceAccount updates name, description and role of existing service account.
+func (ops *operations) UpdateServiceAccount(id uint, name string, description string,
+	role string) (*models.ServiceAccount, error) {
+
+	serviceAccount, err := ops.GetServiceAccount(id)
+	if err != nil {
+		return nil, err
+	}
+	var accountsWithSameName int64
+	if err := ops.db.Model(&models.ServiceAccount{}).Where("name = ? and id != ?", name, id).
+		Count(&accountsWithSameName).Error; err != nil {
+		ops.log.Errorf("Failed to determine if service account %s is already registered: %v", name, err)
+		return nil, appErrors.ErrInternal
+	}
+	if accountsWithSameName != 0 {
+		return nil, appErrors.ErrServiceAccountAlreadyExists
+	}
+	serviceAccountToUpdate := map[string]interface{}{"name": name, "description": description, "role": role}
+	if err := ops.db.Model(serviceAccount).Updates(serviceAccountToUpdate).Error; err != nil {
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return nil, appErrors.ErrServiceAccountAlreadyExists
+		}
+		ops.log.Errorf("Failed to update service account with id %d: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	return serviceAccount, nil
+}
+
+// RotateServiceAccountSecret replaces client secret hash of the service account.
+func (ops *operations) RotateServiceAccountSecret(id uint, clientSecretHash string) error {
+	result := ops.db.Model(&models.ServiceAccount{}).Where("id = ?", id).
+		Update("client_secret_hash", clientSecretHash)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to rotate secret of service account with id %d: %v", id, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrServiceAccountDoesNotExist
+	}
+	return nil
+}
+
+// DeleteServiceAccount deletes existing record by id
+func (ops *operations) DeleteServiceAccount(id uint) error {
+	result := ops.db.Unscoped().Where("id = ?", id).Delete(&models.ServiceAccount{})
+	if result.Error != nil {
+		ops.log.Errorf("Failed to delete service account with id %d: %v", id, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrServiceAccountDoesNotExist
+	}
+	return nil
+}
+
+// RecordTokenIssued records when the service account last obtained token, for auditing.
+func (ops *operations) RecordTokenIssued(id uint) error {
+	if err := ops.db.Model(&models.ServiceAccount{}).Where("id = ?", id).
+		UpdateColumn("last_token_issued_at", time.Now()).Error; err != nil {
+		ops.log.Errorf("Failed to record token issue of service account with id %d: %v", id, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package serviceaccount
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Service Accounts [operations]", func() {
+	var (
+		mockLog *zap.SugaredLogger
+		mock    sqlmock.Sqlmock
+		mockDb  *sql.DB
+		ops     *operations
+		db      *gorm.DB
+	)
+	BeforeEach(func() {
+		mockLog = zap.NewExample().Sugar()
+		mockDb, mock, _ = sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ = gorm.Open(dialector)
+		ops = newOperations(db, mockLog)
+	})
+
+	It("Initialize operations", func() {
+		Expect(ops).To(Not(BeNil()))
+	})
+	Context("create service account", func() {
+		It("Internal error while checking name", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account" WHERE name = $1`)).
+				WillReturnError(errors.New("connection error"))
+			err := ops.CreateServiceAccount(&models.ServiceAccount{Name: "ci"})
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Service account with same name exists", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account" WHERE name = $1`)).
+				WithArgs("ci").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			err := ops.CreateServiceAccount(&models.ServiceAccount{Name: "ci"})
+			Expect(err).To(MatchError(appErrors.ErrServiceAccountAlreadyExists))
+		})
+		It("successfully create service account", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account" WHERE name = $1`)).
+				WithArgs("ci").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "service_account"`)).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "ci", "pipeline", "basic", "sa_abc", "hash", nil).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			mock.ExpectCommit()
+			serviceAccount := &models.ServiceAccount{Name: "ci", Description: "pipeline", Role: "basic",
+				ClientID: "sa_abc", ClientSecretHash: "hash"}
+			err := ops.CreateServiceAccount(serviceAccount)
+			Expect(err).To(BeNil())
+			Expect(serviceAccount.ID).To(Equal(uint(2)))
+		})
+	})
+	Context("get service account", func() {
+		It("No service account with client id", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_account" WHERE client_id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "client_id"}))
+			serviceAccount, err := ops.GetServiceAccountByClientID("sa_abc")
+			Expect(err).To(MatchError(appErrors.ErrServiceAccountDoesNotExist))
+			Expect(serviceAccount).To(BeNil())
+		})
+		It("Internal DB error", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_account" WHERE id = $1`)).
+				WillReturnError(errors.New("connection error"))
+			serviceAccount, err := ops.GetServiceAccount(2)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+			Expect(serviceAccount).To(BeNil())
+		})
+		It("service account with expected client id", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_account" WHERE client_id = $1`)).
+				WithArgs("sa_abc", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "client_id", "client_secret_hash"}).
+					AddRow(2, "ci", "sa_abc", "hash"))
+			serviceAccount, err := ops.GetServiceAccountByClientID("sa_abc")
+			Expect(err).To(BeNil())
+			Expect(serviceAccount.ID).To(Equal(uint(2)))
+			Expect(serviceAccount.ClientSecretHash).To(Equal("hash"))
+		})
+	})
+	Context("fetch service accounts", func() {
+		It("Internal error while counting", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account"`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.FetchServiceAccountsWithPagination(1, 10)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully fetch page", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_account"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(11, "ci"))
+			serviceAccounts, err := ops.FetchServiceAccountsWithPagination(2, 10)
+			Expect(err).To(BeNil())
+			Expect(serviceAccounts.TotalItems).To(Equal(int64(11)))
+			Expect(serviceAccounts.CurrentPage).To(Equal(2))
+			Expect(serviceAccounts.Data).To(HaveLen(1))
+		})
+	})
+	Context("update service account", func() {
+		It("Service account doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_account" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, err := ops.UpdateServiceAccount(2, "ci", "", "basic")
+			Expect(err).To(MatchError(appErrors.ErrServiceAccountDoesNotExist))
+		})
+		It("Other service account with same name exists", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_account" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "ci"))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account" WHERE (name = $1 and id != $2)`)).
+				WithArgs("deploy", 2).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			_, err := ops.UpdateServiceAccount(2, "deploy", "", "basic")
+			Expect(err).To(MatchError(appErrors.ErrServiceAccountAlreadyExists))
+		})
+		It("successfully update service account", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "service_account" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role"}).AddRow(2, "ci", "basic"))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service_account" WHERE (name = $1 and id != $2)`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "service_account" SET`)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			serviceAccount, err := ops.UpdateServiceAccount(2, "deploy", "deployments", "admin")
+			Expect(err).To(BeNil())
+			Expect(serviceAccount.Name).To(Equal("deploy"))
+			Expect(serviceAccount.Role).To(Equal("admin"))
+		})
+	})
+	Context("rotate secret and delete service account", func() {
+		It("Rotating secret of unknown service account", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "service_account" SET "client_secret_hash"=$1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.RotateServiceAccountSecret(2, "hash")
+			Expect(err).To(MatchError(appErrors.ErrServiceAccountDoesNotExist))
+		})
+		It("successfully rotate secret", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "service_account" SET "client_secret_hash"=$1`)).
+				WithArgs("hash", sqlmock.AnyArg(), 2).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.RotateServiceAccountSecret(2, "hash")
+			Expect(err).To(BeNil())
+		})
+		It("Deleting unknown service account", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "service_account" WHERE id = $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.DeleteServiceAccount(2)
+			Expect(err).To(MatchError(appErrors.ErrServiceAccountDoesNotExist))
+		})
+		It("Internal error while deleting", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "service_account" WHERE id = $1`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.DeleteServiceAccount(2)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully record token issue", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "service_account" SET "last_token_issued_at"=$1`)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.RecordTokenIssued(2)
+			Expect(err).To(BeNil())
+		})
+	})
+})
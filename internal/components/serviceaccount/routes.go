@@ -0,0 +1,49 @@
+package serviceaccount
+
+import (
+	"userservice/internal/auth"
+	"userservice/internal/configs"
+	"userservice/internal/middleware"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Handler for service account management.
+type Handler struct {
+	runtimeConfig *configs.Config
+	operations    models.ServiceAccountOperations
+	tokens        *auth.TokenIssuer
+	revoker       models.TokenRevocationOperations
+}
+
+// NewHandler initializes service account handler context with desired parameters.
+func NewHandler(log *zap.SugaredLogger, config *configs.Config, db *gorm.DB,
+	tokens *auth.TokenIssuer, revoker models.TokenRevocationOperations) *Handler {
+	return &Handler{runtimeConfig: config, operations: newOperations(db, log), tokens: tokens, revoker: revoker}
+}
+
+// RegisterRoutes has sent of route endpoints categorized as per authz roles using middleware.
+func (h *Handler) RegisterRoutes(routers *gin.RouterGroup) {
+
+	// Service accounts obtain token through client credentials grant.
+	routers.POST("/oauth/token", h.issueToken)
+
+	// Authorized routes for roles permitted to view and manage service accounts respectively.
+	serviceAccountReadRoutes := routers.Group("/")
+	serviceAccountReadRoutes.Use(middleware.RequirePermission(models.PermissionServiceAccountRead))
+	{
+		serviceAccountReadRoutes.GET("/service-accounts", h.fetchServiceAccounts)
+		serviceAccountReadRoutes.GET("/service-account/:id", h.getServiceAccountByID)
+	}
+	serviceAccountWriteRoutes := routers.Group("/")
+	serviceAccountWriteRoutes.Use(middleware.RequirePermission(models.PermissionServiceAccountWrite))
+	{
+		serviceAccountWriteRoutes.POST("/service-account", h.addServiceAccount)
+		serviceAccountWriteRoutes.PUT("/service-account/:id", h.updateServiceAccount)
+		serviceAccountWriteRoutes.DELETE("/service-account/:id", h.deleteServiceAccount)
+		serviceAccountWriteRoutes.POST("/service-account/:id/secret", h.rotateServiceAccountSecret)
+	}
+}
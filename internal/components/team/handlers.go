@@ -0,0 +1,221 @@
+package team
+
+import (
+	"fmt"
+	"net/http"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+	"userservice/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fetchTeams list the teams in system
+func (h *Handler) fetchTeams(c *gin.Context) {
+	teams, err := h.operations.FetchTeams()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, teams)
+}
+
+// getTeamByID fetches team record in DB for the given id
+func (h *Handler) getTeamByID(c *gin.Context) {
+	var teamID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &teamID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team ID should be numerical"))
+		return
+	}
+	team, err := h.operations.GetTeam(teamID)
+	if err != nil {
+		if err == appErrors.ErrTeamDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Team[ID:%d] doesn't exist", teamID)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, team)
+}
+
+// addTeam configures new team, which can be assigned services to own
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) addTeam(c *gin.Context) {
+	var teamToAdd map[string]interface{}
+	if err := c.BindJSON(&teamToAdd); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Team creation payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(teamToAdd, models.CreateOrUpdateTeamPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Team creation payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.CreateOrUpdateTeamPayloadTemplate))))
+		return
+	}
+	name := teamToAdd[models.AttributeName].(string)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team creation payload is invalid; name is empty"))
+		return
+	}
+
+	createdTeam, err := h.operations.CreateTeam(name, teamToAdd[models.AttributeDescription].(string))
+	if err != nil {
+		if err == appErrors.ErrTeamAlreadyExists {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(fmt.Sprintf("Team %s already exists", name)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, createdTeam)
+}
+
+// updateTeam update team in DB.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) updateTeam(c *gin.Context) {
+	var teamID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &teamID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team ID should be numerical"))
+		return
+	}
+	var teamToUpdate map[string]interface{}
+	if err := c.BindJSON(&teamToUpdate); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Team update payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(teamToUpdate, models.CreateOrUpdateTeamPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Team update payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.CreateOrUpdateTeamPayloadTemplate))))
+		return
+	}
+	name := teamToUpdate[models.AttributeName].(string)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team update payload is invalid; name is empty"))
+		return
+	}
+
+	updatedTeam, err := h.operations.UpdateTeam(teamID, name, teamToUpdate[models.AttributeDescription].(string))
+	if err != nil {
+		if err == appErrors.ErrTeamDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Team[ID:%d] doesn't exist", teamID)))
+			return
+		} else if err == appErrors.ErrTeamAlreadyExists {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(fmt.Sprintf("Team %s already exists", name)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, updatedTeam)
+}
+
+// deleteTeam deletes team from system, team still owning services can't be deleted.
+func (h *Handler) deleteTeam(c *gin.Context) {
+	var teamID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &teamID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team ID should be numerical"))
+		return
+	}
+	if err := h.operations.DeleteTeam(teamID); err != nil {
+		if err == appErrors.ErrTeamDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Team[ID:%d] doesn't exist", teamID)))
+			return
+		} else if err == appErrors.ErrTeamOwnsServices {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrTeamOwnsServices.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Team deleted from system"))
+}
+
+// fetchTeamMembers list the users who are members of the team
+func (h *Handler) fetchTeamMembers(c *gin.Context) {
+	var teamID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &teamID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team ID should be numerical"))
+		return
+	}
+	members, err := h.operations.FetchTeamMembers(teamID)
+	if err != nil {
+		if err == appErrors.ErrTeamDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Team[ID:%d] doesn't exist", teamID)))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// addTeamMember adds user to the team, granting the user to modify services owned by the team
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) addTeamMember(c *gin.Context) {
+	var teamID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &teamID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team ID should be numerical"))
+		return
+	}
+	var memberToAdd map[string]interface{}
+	if err := c.BindJSON(&memberToAdd); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Team member payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(memberToAdd, models.AddTeamMemberPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Team member payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.AddTeamMemberPayloadTemplate))))
+		return
+	}
+	requestedUserID := memberToAdd[models.AttributeUserID].(float64)
+	if requestedUserID < 1 || requestedUserID != float64(uint(requestedUserID)) {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Team member payload is invalid; user_id should be a positive integer"))
+		return
+	}
+	userID := uint(requestedUserID)
+
+	if err := h.operations.AddTeamMember(teamID, userID); err != nil {
+		switch err {
+		case appErrors.ErrTeamDoesNotExist:
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("Team[ID:%d] doesn't exist", teamID)))
+		case appErrors.ErrUserDoesNotExist:
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(fmt.Sprintf("User[ID:%d] doesn't exist", userID)))
+		case appErrors.ErrTeamMemberAlreadyExists:
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrTeamMemberAlreadyExists.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, models.TeamMember{TeamID: teamID, UserID: userID})
+}
+
+// removeTeamMember removes user from the team
+func (h *Handler) removeTeamMember(c *gin.Context) {
+	var teamID, userID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &teamID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Team ID should be numerical"))
+		return
+	}
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamUserID), "%d", &userID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("User ID should be numerical"))
+		return
+	}
+	if err := h.operations.RemoveTeamMember(teamID, userID); err != nil {
+		if err == appErrors.ErrTeamMemberDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrTeamMemberDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("User removed from team"))
+}
@@ -0,0 +1,236 @@
+package team
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func GetTestGinContext(w *httptest.ResponseRecorder) *gin.Context {
+	gin.SetMode(gin.TestMode)
+
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = &http.Request{
+		Header: make(http.Header),
+		URL:    &url.URL{},
+	}
+	return ctx
+}
+func MockJsonPostOrPut(c *gin.Context, content interface{}) {
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	jsonbytes, err := json.Marshal(content)
+	if err != nil {
+		panic(err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonbytes))
+}
+
+var _ = Describe("Team [Handler]", func() {
+
+	var (
+		ctx     *gin.Context
+		handler *Handler
+		w       *httptest.ResponseRecorder
+	)
+	BeforeEach(func() {
+		handler = new(Handler)
+		w = httptest.NewRecorder()
+		ctx = GetTestGinContext(w)
+	})
+
+	Context("Fetch teams", func() {
+		It("Successful Fetch", func() {
+			handler.operations = new(TeamMockDefault)
+			handler.fetchTeams(ctx)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var teams []models.Team
+			Expect(json.Unmarshal(w.Body.Bytes(), &teams)).To(Succeed())
+			Expect(teams).To(HaveLen(1))
+			Expect(teams[0].Name).To(Equal("payments"))
+		})
+		It("Facing DB errors", func() {
+			handler.operations = new(TeamMockForcedError)
+			handler.fetchTeams(ctx)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+	})
+
+	Context("Get team", func() {
+		It("Non numerical ID", func() {
+			handler.operations = new(TeamMockDefault)
+			ctx.Params = []gin.Param{{Key: models.QueryParamID, Value: "abc"}}
+			handler.getTeamByID(ctx)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+		It("Team doesn't exist", func() {
+			handler.operations = &TeamMockDefault{SetTeamDoesntExist: true}
+			ctx.Params = []gin.Param{{Key: models.QueryParamID, Value: "1"}}
+			handler.getTeamByID(ctx)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+		It("Successful Fetch", func() {
+			handler.operations = new(TeamMockDefault)
+			ctx.Params = []gin.Param{{Key: models.QueryParamID, Value: "1"}}
+			handler.getTeamByID(ctx)
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("Add team", func() {
+		It("Invalid payload", func() {
+			handler.operations = new(TeamMockDefault)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "payments"})
+			handler.addTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(w.Body.String()).To(ContainSubstring("Strictly Allowed Params"))
+		})
+		It("Empty name", func() {
+			handler.operations = new(TeamMockDefault)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "description": "payments team"})
+			handler.addTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+		It("Team with same name exists", func() {
+			handler.operations = &TeamMockDefault{SetDuplicate: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "payments", "description": "payments team"})
+			handler.addTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusConflict))
+		})
+		It("Facing DB errors", func() {
+			handler.operations = new(TeamMockForcedError)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "payments", "description": "payments team"})
+			handler.addTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		})
+		It("Successful creation", func() {
+			handler.operations = new(TeamMockDefault)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "payments", "description": "payments team"})
+			handler.addTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			var team models.Team
+			Expect(json.Unmarshal(w.Body.Bytes(), &team)).To(Succeed())
+			Expect(team.Name).To(Equal("payments"))
+		})
+	})
+
+	Context("Update team", func() {
+		BeforeEach(func() {
+			ctx.Params = []gin.Param{{Key: models.QueryParamID, Value: "1"}}
+		})
+		It("Team doesn't exist", func() {
+			handler.operations = &TeamMockDefault{SetTeamDoesntExist: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "payments", "description": "payments team"})
+			handler.updateTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+		It("Team with same name exists", func() {
+			handler.operations = &TeamMockDefault{SetDuplicate: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "payments", "description": "payments team"})
+			handler.updateTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusConflict))
+		})
+		It("Successful update", func() {
+			handler.operations = new(TeamMockDefault)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "billing", "description": "billing team"})
+			handler.updateTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring("billing"))
+		})
+	})
+
+	Context("Delete team", func() {
+		BeforeEach(func() {
+			ctx.Params = []gin.Param{{Key: models.QueryParamID, Value: "1"}}
+		})
+		It("Team doesn't exist", func() {
+			handler.operations = &TeamMockDefault{SetTeamDoesntExist: true}
+			handler.deleteTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+		It("Team still owns services", func() {
+			handler.operations = &TeamMockDefault{SetTeamOwnsServices: true}
+			handler.deleteTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusConflict))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrTeamOwnsServices.Error()))
+		})
+		It("Successful deletion", func() {
+			handler.operations = new(TeamMockDefault)
+			handler.deleteTeam(ctx)
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("Team members", func() {
+		BeforeEach(func() {
+			ctx.Params = []gin.Param{{Key: models.QueryParamID, Value: "1"}}
+		})
+		It("Fetch members of team which doesn't exist", func() {
+			handler.operations = &TeamMockDefault{SetTeamDoesntExist: true}
+			handler.fetchTeamMembers(ctx)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+		It("Successful fetch of members", func() {
+			handler.operations = new(TeamMockDefault)
+			handler.fetchTeamMembers(ctx)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring("admin@mgmtportal.com"))
+		})
+		It("Add member with invalid user ID", func() {
+			handler.operations = new(TeamMockDefault)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"user_id": 1.5})
+			handler.addTeamMember(ctx)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+		It("Add member who doesn't exist", func() {
+			handler.operations = &TeamMockDefault{SetUserDoesntExist: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"user_id": 2})
+			handler.addTeamMember(ctx)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+		It("Add member who is already a member", func() {
+			handler.operations = &TeamMockDefault{SetDuplicate: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"user_id": 2})
+			handler.addTeamMember(ctx)
+			Expect(w.Code).To(Equal(http.StatusConflict))
+		})
+		It("Successful addition of member", func() {
+			handler.operations = new(TeamMockDefault)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"user_id": 2})
+			handler.addTeamMember(ctx)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			var member models.TeamMember
+			Expect(json.Unmarshal(w.Body.Bytes(), &member)).To(Succeed())
+			Expect(member.TeamID).To(Equal(uint(1)))
+			Expect(member.UserID).To(Equal(uint(2)))
+		})
+		It("Remove user who isn't a member", func() {
+			handler.operations = &TeamMockDefault{SetMemberDoesntExist: true}
+			ctx.Params = append(ctx.Params, gin.Param{Key: models.QueryParamUserID, Value: "2"})
+			handler.removeTeamMember(ctx)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+		It("Remove member facing DB errors", func() {
+			handler.operations = new(TeamMockForcedError)
+			ctx.Params = append(ctx.Params, gin.Param{Key: models.QueryParamUserID, Value: "2"})
+			handler.removeTeamMember(ctx)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		})
+		It("Successful removal of member", func() {
+			handler.operations = new(TeamMockDefault)
+			ctx.Params = append(ctx.Params, gin.Param{Key: models.QueryParamUserID, Value: "2"})
+			handler.removeTeamMember(ctx)
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
@@ -0,0 +1,129 @@
+package team
+
+import (
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+)
+
+// TeamMockDefault...
+type TeamMockDefault struct {
+	SetTeamDoesntExist   bool
+	SetUserDoesntExist   bool
+	SetDuplicate         bool
+	SetTeamOwnsServices  bool
+	SetMemberDoesntExist bool
+}
+
+// FetchTeams...
+func (m *TeamMockDefault) FetchTeams() ([]models.Team, error) {
+	return []models.Team{{Name: "payments", Description: "payments team"}}, nil
+}
+
+// GetTeam...
+func (m *TeamMockDefault) GetTeam(id uint) (*models.Team, error) {
+	if m.SetTeamDoesntExist {
+		return nil, appErrors.ErrTeamDoesNotExist
+	}
+	return &models.Team{DBModel: models.DBModel{ID: id}, Name: "payments"}, nil
+}
+
+// CreateTeam...
+func (m *TeamMockDefault) CreateTeam(name string, description string) (*models.Team, error) {
+	if m.SetDuplicate {
+		return nil, appErrors.ErrTeamAlreadyExists
+	}
+	return &models.Team{DBModel: models.DBModel{ID: 1}, Name: name, Description: description}, nil
+}
+
+// UpdateTeam...
+func (m *TeamMockDefault) UpdateTeam(id uint, name string, description string) (*models.Team, error) {
+	if m.SetTeamDoesntExist {
+		return nil, appErrors.ErrTeamDoesNotExist
+	} else if m.SetDuplicate {
+		return nil, appErrors.ErrTeamAlreadyExists
+	}
+	return &models.Team{DBModel: models.DBModel{ID: id}, Name: name, Description: description}, nil
+}
+
+// DeleteTeam...
+func (m *TeamMockDefault) DeleteTeam(uint) error {
+	if m.SetTeamDoesntExist {
+		return appErrors.ErrTeamDoesNotExist
+	} else if m.SetTeamOwnsServices {
+		return appErrors.ErrTeamOwnsServices
+	}
+	return nil
+}
+
+// FetchTeamMembers...
+func (m *TeamMockDefault) FetchTeamMembers(uint) ([]models.User, error) {
+	if m.SetTeamDoesntExist {
+		return nil, appErrors.ErrTeamDoesNotExist
+	}
+	return []models.User{{Name: "admin", Email: "admin@mgmtportal.com", Role: models.RoleAdmin}}, nil
+}
+
+// AddTeamMember...
+func (m *TeamMockDefault) AddTeamMember(uint, uint) error {
+	if m.SetTeamDoesntExist {
+		return appErrors.ErrTeamDoesNotExist
+	} else if m.SetUserDoesntExist {
+		return appErrors.ErrUserDoesNotExist
+	} else if m.SetDuplicate {
+		return appErrors.ErrTeamMemberAlreadyExists
+	}
+	return nil
+}
+
+// RemoveTeamMember...
+func (m *TeamMockDefault) RemoveTeamMember(uint, uint) error {
+	if m.SetMemberDoesntExist {
+		return appErrors.ErrTeamMemberDoesNotExist
+	}
+	return nil
+}
+
+// TeamMockForcedError...
+type TeamMockForcedError struct {
+	TeamMockDefault
+}
+
+// FetchTeams...
+func (m *TeamMockForcedError) FetchTeams() ([]models.Team, error) {
+	return nil, appErrors.ErrInternal
+}
+
+// GetTeam...
+func (m *TeamMockForcedError) GetTeam(uint) (*models.Team, error) {
+	return nil, appErrors.ErrInternal
+}
+
+// CreateTeam...
+func (m *TeamMockForcedError) CreateTeam(string, string) (*models.Team, error) {
+	return nil, appErrors.ErrInternal
+}
+
+// UpdateTeam...
+func (m *TeamMockForcedError) UpdateTeam(uint, string, string) (*models.Team, error) {
+	return nil, appErrors.ErrInternal
+}
+
+// DeleteTeam...
+func (m *TeamMockForcedError) DeleteTeam(uint) error {
+	return appErrors.ErrInternal
+}
+
+// FetchTeamMembers...
+func (m *TeamMockForcedError) FetchTeamMembers(uint) ([]models.User, error) {
+	return nil, appErrors.ErrInternal
+}
+
+// AddTeamMember...
+func (m *TeamMockForcedError) AddTeamMember(uint, uint) error {
+	return appErrors.ErrInternal
+}
+
+// RemoveTeamMember...
+func (m *TeamMockForcedError) RemoveTeamMember(uint, uint) error {
+	return appErrors.ErrInternal
+}
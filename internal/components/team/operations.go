@@ -0,0 +1,174 @@
+package team
+
+import (
+	"errors"
+	"strings"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// operations...
+type operations struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// newOperations initializes operations with necessary configs
+func newOperations(db *gorm.DB, log *zap.SugaredLogger) *operations {
+	return &operations{db: db, log: log}
+}
+
+// FetchTeams fetches teams from DB
+func (ops *operations) FetchTeams() (teams []models.Team, returnErr error) {
+	if err := ops.db.Order("name").Find(&teams).Error; err != nil {
+		ops.log.Errorf("Failed to fetch teams: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	return
+}
+
+// GetTeam fetch team of given ID
+func (ops *operations) GetTeam(id uint) (*models.Team, error) {
+	team := new(models.Team)
+	if err := ops.db.Where("id = ?", id).First(team).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrTeamDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch team record by id %d: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	return team, nil
+}
+
+// CreateTeam creates team record in DB.
+func (ops *operations) CreateTeam(name string, description string) (*models.Team, error) {
+	var teamsWithSameName int64
+	if err := ops.db.Model(&models.Team{}).Where("name = ?", name).Count(&teamsWithSameName).Error; err != nil {
+		ops.log.Errorf("Failed to determine if team %s exists: %v", name, err)
+		return nil, appErrors.ErrInternal
+	}
+	if teamsWithSameName != 0 {
+		return nil, appErrors.ErrTeamAlreadyExists
+	}
+	newTeam := &models.Team{Name: name, Description: description}
+	if err := ops.db.Model(&models.Team{}).Create(newTeam).Error; err != nil {
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return nil, appErrors.ErrTeamAlreadyExists
+		}
+		ops.log.Errorf("Failed to create team %s: %v", name, err)
+		return nil, appErrors.ErrInternal
+	}
+	return newTeam, nil
+}
+
+// UpdateTeam updates name and description of existing team.
+func (ops *operations) UpdateTeam(id uint, name string, description string) (*models.Team, error) {
+	var teamsWithSameName int64
+	if err := ops.db.Model(&models.Team{}).Where("name = ? and id != ?", name, id).
+		Count(&teamsWithSameName).Error; err != nil {
+		ops.log.Errorf("Failed to determine if team %s exists: %v", name, err)
+		return nil, appErrors.ErrInternal
+	}
+	if teamsWithSameName != 0 {
+		return nil, appErrors.ErrTeamAlreadyExists
+	}
+	teamToUpdate := &models.Team{Name: name, Description: description, DBModel: models.DBModel{ID: id}}
+	result := ops.db.Model(&models.Team{}).Where("id = ?", id).Select("name", "description").Updates(teamToUpdate)
+	if result.Error != nil {
+		if strings.Contains(result.Error.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return nil, appErrors.ErrTeamAlreadyExists
+		}
+		ops.log.Errorf("Failed to update team [ID:%d]: %v", id, result.Error)
+		return nil, appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return nil, appErrors.ErrTeamDoesNotExist
+	}
+	return teamToUpdate, nil
+}
+
+// DeleteTeam deletes team along with its memberships, unless it still owns services.
+// Owned services are checked within the transaction deleting the team, such that
+// team isn't deleted while being assigned a service concurrently.
+func (ops *operations) DeleteTeam(id uint) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		var ownedServices int64
+		if err := tx.Model(&models.Service{}).Where("owner_team_id = ?", id).Count(&ownedServices).Error; err != nil {
+			return err
+		}
+		if ownedServices != 0 {
+			return appErrors.ErrTeamOwnsServices
+		}
+		if err := tx.Where("team_id = ?", id).Delete(&models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.Team{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return appErrors.ErrTeamDoesNotExist
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, appErrors.ErrTeamOwnsServices) || errors.Is(err, appErrors.ErrTeamDoesNotExist) {
+			return err
+		}
+		ops.log.Errorf("Failed to delete team [ID:%d]: %v", id, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// FetchTeamMembers fetches users who are members of the team
+func (ops *operations) FetchTeamMembers(id uint) (users []models.User, returnErr error) {
+	if _, err := ops.GetTeam(id); err != nil {
+		return nil, err
+	}
+	if err := ops.db.Joins(`JOIN team_member ON team_member.user_id = "user".id`).
+		Where("team_member.team_id = ?", id).Find(&users).Error; err != nil {
+		ops.log.Errorf("Failed to fetch members of team [ID:%d]: %v", id, err)
+		return nil, appErrors.ErrInternal
+	}
+	return
+}
+
+// AddTeamMember adds user to the team
+func (ops *operations) AddTeamMember(teamID uint, userID uint) error {
+	if _, err := ops.GetTeam(teamID); err != nil {
+		return err
+	}
+	var userCount int64
+	if err := ops.db.Model(&models.User{}).Where("id = ?", userID).Count(&userCount).Error; err != nil {
+		ops.log.Errorf("Failed to determine if user with id %d exists: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	if userCount == 0 {
+		return appErrors.ErrUserDoesNotExist
+	}
+	if err := ops.db.Create(&models.TeamMember{TeamID: teamID, UserID: userID}).Error; err != nil {
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return appErrors.ErrTeamMemberAlreadyExists
+		}
+		ops.log.Errorf("Failed to add user [ID:%d] to team [ID:%d]: %v", userID, teamID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RemoveTeamMember removes user from the team
+func (ops *operations) RemoveTeamMember(teamID uint, userID uint) error {
+	result := ops.db.Where("team_id = ? and user_id = ?", teamID, userID).Delete(&models.TeamMember{})
+	if result.Error != nil {
+		ops.log.Errorf("Failed to remove user [ID:%d] from team [ID:%d]: %v", userID, teamID, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrTeamMemberDoesNotExist
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package team
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	appErrors "userservice/internal/errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Team [operations]", func() {
+	var (
+		mockLog *zap.SugaredLogger
+		mock    sqlmock.Sqlmock
+		mockDb  *sql.DB
+		ops     *operations
+		db      *gorm.DB
+	)
+	BeforeEach(func() {
+		mockLog = zap.NewExample().Sugar()
+		mockDb, mock, _ = sqlmock.New()
+		dialector := postgres.New(postgres.Config{
+			Conn:       mockDb,
+			DriverName: "postgres",
+		})
+		db, _ = gorm.Open(dialector)
+		ops = newOperations(db, mockLog)
+	})
+
+	Context("fetch teams", func() {
+		It("Unexpected DB issues", func() {
+			mock.ExpectQuery(`SELECT`).WillReturnError(errors.New("connection is already closed"))
+			teams, err := ops.FetchTeams()
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+			Expect(teams).To(BeNil())
+		})
+		It("Successful fetch", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "team" WHERE "team"."deleted_at" IS NULL ORDER BY name`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description"}).AddRow(1, "payments", "team"))
+			teams, err := ops.FetchTeams()
+			Expect(err).To(BeNil())
+			Expect(teams).To(HaveLen(1))
+		})
+	})
+
+	Context("create team", func() {
+		It("Team with same name exists", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			team, err := ops.CreateTeam("payments", "team")
+			Expect(err).To(MatchError(appErrors.ErrTeamAlreadyExists))
+			Expect(team).To(BeNil())
+		})
+		It("Concurrent creation of team with same name", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "team"`)).
+				WillReturnError(errors.New(appErrors.ErrUniqueKeyConstrainViolation.Error()))
+			mock.ExpectRollback()
+			_, err := ops.CreateTeam("payments", "team")
+			Expect(err).To(MatchError(appErrors.ErrTeamAlreadyExists))
+		})
+		It("Successful creation", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE name = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "team"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectCommit()
+			team, err := ops.CreateTeam("payments", "team")
+			Expect(err).To(BeNil())
+			Expect(team.ID).To(Equal(uint(1)))
+		})
+	})
+
+	Context("update team", func() {
+		It("Team doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE (name = $1 and id != $2)`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "team" SET "updated_at"=$1,"name"=$2,"description"=$3 WHERE id = $4`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			_, err := ops.UpdateTeam(1, "payments", "team")
+			Expect(err).To(MatchError(appErrors.ErrTeamDoesNotExist))
+		})
+		It("Successful update", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team" WHERE (name = $1 and id != $2)`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "team" SET "updated_at"=$1,"name"=$2,"description"=$3 WHERE id = $4`)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			team, err := ops.UpdateTeam(1, "billing", "team")
+			Expect(err).To(BeNil())
+			Expect(team.Name).To(Equal("billing"))
+		})
+	})
+
+	Context("delete team", func() {
+		It("Team still owns services", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE owner_team_id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+			mock.ExpectRollback()
+			Expect(ops.DeleteTeam(1)).To(MatchError(appErrors.ErrTeamOwnsServices))
+		})
+		It("Team doesn't exist", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE owner_team_id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "team_member" WHERE team_id = $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "team" WHERE "team"."id" = $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+			Expect(ops.DeleteTeam(1)).To(MatchError(appErrors.ErrTeamDoesNotExist))
+		})
+		It("Successful deletion", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "service" WHERE owner_team_id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "team_member" WHERE team_id = $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "team" WHERE "team"."id" = $1`)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			Expect(ops.DeleteTeam(1)).To(Succeed())
+		})
+	})
+
+	Context("team members", func() {
+		It("Fetch members of team which doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "team" WHERE id = $1`)).
+				WillReturnError(gorm.ErrRecordNotFound)
+			users, err := ops.FetchTeamMembers(1)
+			Expect(err).To(MatchError(appErrors.ErrTeamDoesNotExist))
+			Expect(users).To(BeNil())
+		})
+		It("Successful fetch of members", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "team" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "payments"))
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT "user"."id","user"."created_at","user"."updated_at","user"."deleted_at","user"."name"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "role"}).
+					AddRow(2, "dev", "dev@mgmtportal.com", "advanced"))
+			users, err := ops.FetchTeamMembers(1)
+			Expect(err).To(BeNil())
+			Expect(users).To(HaveLen(1))
+			Expect(users[0].Email).To(Equal("dev@mgmtportal.com"))
+		})
+		It("Add user who doesn't exist", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "team" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "payments"))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			Expect(ops.AddTeamMember(1, 2)).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("Add user who is already a member", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "team" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "payments"))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "team_member"`)).
+				WillReturnError(errors.New(appErrors.ErrUniqueKeyConstrainViolation.Error()))
+			mock.ExpectRollback()
+			Expect(ops.AddTeamMember(1, 2)).To(MatchError(appErrors.ErrTeamMemberAlreadyExists))
+		})
+		It("Successful addition of member", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "team" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "payments"))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE id = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "team_member" ("team_id","user_id","created_at")`)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			Expect(ops.AddTeamMember(1, 2)).To(Succeed())
+		})
+		It("Remove user who isn't a member", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "team_member" WHERE team_id = $1 and user_id = $2`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			Expect(ops.RemoveTeamMember(1, 2)).To(MatchError(appErrors.ErrTeamMemberDoesNotExist))
+		})
+		It("Successful removal of member", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "team_member" WHERE team_id = $1 and user_id = $2`)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			Expect(ops.RemoveTeamMember(1, 2)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,45 @@
+package team
+
+import (
+	"userservice/internal/middleware"
+	"userservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Handler for team management.
+type Handler struct {
+	operations models.TeamOperations
+}
+
+// NewHandler initializes team handler context with desired parameters.
+func NewHandler(log *zap.SugaredLogger, db *gorm.DB) *Handler {
+	return &Handler{operations: newOperations(db, log)}
+}
+
+// RegisterRoutes has sent of route endpoints categorized as per authz roles using middleware.
+// Teams group users, hence they are managed by roles permitted to manage users.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+
+	// Authorized routes for roles permitted to view teams.
+	teamReadRoutes := router.Group("/")
+	teamReadRoutes.Use(middleware.RequirePermission(models.PermissionUserRead))
+	{
+		teamReadRoutes.GET("/teams", h.fetchTeams)
+		teamReadRoutes.GET("/team/:id", h.getTeamByID)
+		teamReadRoutes.GET("/team/:id/members", h.fetchTeamMembers)
+	}
+
+	// Authorized routes for roles permitted to manage teams.
+	teamWriteRoutes := router.Group("/")
+	teamWriteRoutes.Use(middleware.RequirePermission(models.PermissionUserWrite))
+	{
+		teamWriteRoutes.POST("/team", h.addTeam)
+		teamWriteRoutes.PUT("/team/:id", h.updateTeam)
+		teamWriteRoutes.DELETE("/team/:id", h.deleteTeam)
+		teamWriteRoutes.POST("/team/:id/member", h.addTeamMember)
+		teamWriteRoutes.DELETE("/team/:id/member/:user_id", h.removeTeamMember)
+	}
+}
@@ -2,12 +2,17 @@ package user
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 	"userservice/internal/auth"
 	appErrors "userservice/internal/errors"
 	"userservice/internal/misc"
 	"userservice/internal/models"
+	"userservice/internal/notify"
+	"userservice/internal/oidc"
 	"userservice/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +20,13 @@ import (
 
 // login validates payload and generate JWT token if its a successful login.
 // Upon Successful login, if user has temporary password set,
-// a flag[password_change_required] will be sent along.
+// a flag[password_change_required] will be sent along, and the same claim in the token restricts it to password change.
+// Temporary password past its expiry is rejected; the user has to reset the password instead.
+// Password hash of outdated algorithm or parameters is rehashed upon successful password verification.
+// If user has enrolled MFA, MFA challenge token is responded instead, to be exchanged for token pair along with
+// the second factor through loginWithMFA.
+// Login to locked account is rejected alike login with unknown email, such that lockout doesn't reveal
+// whether the email is registered.
 // Request will be rejected if additional fields to desired ones are present in payload.
 func (h *Handler) login(c *gin.Context) {
 	var userLogin map[string]interface{}
@@ -38,6 +49,12 @@ func (h *Handler) login(c *gin.Context) {
 			utils.FormatErrorResponse("Login payload contains invalid email"))
 		return
 	}
+
+	now := time.Now()
+	if h.rejectLockedIP(c, now) {
+		return
+	}
+
 	user, err := h.operations.GetUserByEmail(userLogin[models.AttributeEmail].(string))
 	if err != nil {
 		if err == appErrors.ErrInternal {
@@ -47,23 +64,335 @@ func (h *Handler) login(c *gin.Context) {
 		}
 		// let us not explicitly inform about the user email not found,
 		// such that non-legitimate users/hackers wont get an insight
-		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidEmailOrPass.Error()))
+		h.rejectLogin(c, nil, now)
+		return
+	}
+	if user.IsLocked(now) {
+		h.rejectLogin(c, nil, now)
 		return
 	}
 
 	if !auth.CompareHashAndPassword(user.PasswordHash, []byte(userLogin["password"].(string))) {
-		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidEmailOrPass.Error()))
+		h.rejectLogin(c, user, now)
+		return
+	}
+	// Hash of outdated algorithm or parameters is upgraded while the password is at hand. Failure is
+	// not fatal to the login, rehash is attempted again at the next login.
+	if h.passwordHasher.NeedsRehash(user.PasswordHash) {
+		if passwordHash, err := h.passwordHasher.Hash(userLogin["password"].(string)); err == nil {
+			_ = h.operations.RehashPassword(user.ID, user.PasswordHash, passwordHash)
+		}
+	}
+
+	h.completeFirstFactor(c, user)
+}
+
+// completeFirstFactor proceeds login of user authenticated through password or single sign-on.
+// Login of user enrolled in MFA is completed only with the second factor, failed login attempts are
+// retained till then such that the second factor can't be guessed by interleaving password logins.
+func (h *Handler) completeFirstFactor(c *gin.Context, user *models.User) {
+	if user.MFAEnabled {
+		challenge, err := h.tokens.CreateMFAChallenge(user.ID,
+			time.Second*time.Duration(h.runtimeConfig.MFAChallengeExpirationInSeconds))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, utils.FormatMFAChallengeResponse(challenge))
+		return
+	}
+	h.completeLogin(c, user)
+}
+
+// completeLogin clears failed login attempts of the user, and responds with a token pair of fresh login.
+func (h *Handler) completeLogin(c *gin.Context, user *models.User) {
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrUserDisabled.Error()))
+		return
+	}
+	if user.IsTemporaryPasswordExpired(time.Now()) {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrTemporaryPasswordExpired.Error()))
+		return
+	}
+	if user.FailedLoginAttempts != 0 || user.LockedUntil != nil {
+		if err := h.operations.ResetLoginFailures(user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+	}
+
+	refreshToken, sessionID, err := h.startSession(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	subject, err := h.tokenSubject(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	subject.SessionID = sessionID
+	token, err := h.tokens.CreateJWT(subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	// Frontend will handle the response and forward it to change password endpoint if temp password not changed,
+	// token is anyway restricted to password change till then.
+	response := utils.FormatTokenResponse(*token, refreshToken, user.IsTemporaryPassword)
+	response.MFAEnrollmentRequired = subject.MFAEnrollmentRequired
+	c.JSON(http.StatusOK, response)
+}
+
+// tokenSubject derives token subject from the user record. Token of user holding an approved role elevation
+// carries the role elevated to, and doesn't outlive the elevation. Token of user whose role demands MFA,
+// is restricted to MFA enrollment till the user enrolls. Likewise token of user with temporary password,
+// is restricted to password change.
+func (h *Handler) tokenSubject(user *models.User) (auth.TokenSubject, error) {
+	subject := auth.TokenSubject{UserID: user.ID, Email: user.Email, Role: user.Role,
+		PasswordChangeRequired: user.IsTemporaryPassword}
+	elevation, err := h.operations.GetActiveElevation(user.ID, time.Now())
+	if err != nil && err != appErrors.ErrElevationDoesNotExist {
+		return auth.TokenSubject{}, err
+	}
+	// elevation to role deleted since its approval, or no longer granted every permission of the user's role,
+	// is disregarded
+	if elevation != nil && misc.IsRoleConfigured(elevation.Role) && misc.CoversPermissions(elevation.Role, user.Role) {
+		subject.Role = elevation.Role
+		subject.NotAfter = *elevation.ExpiresAt
+	}
+	subject.MFAEnrollmentRequired = !user.MFAEnabled && h.mfaRequiredForRole(subject.Role)
+	return subject, nil
+}
+
+// mfaRequiredForRole...
+func (h *Handler) mfaRequiredForRole(role string) bool {
+	for _, mfaRequiredRole := range h.runtimeConfig.MFARequiredRoles {
+		if mfaRequiredRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// lockoutPolicy...
+func (h *Handler) lockoutPolicy() auth.LockoutPolicy {
+	return auth.LockoutPolicy{
+		MaxFailedAttempts:      int(h.runtimeConfig.LoginMaxFailedAttempts),
+		MaxFailedAttemptsPerIP: int(h.runtimeConfig.LoginMaxFailedAttemptsPerIP),
+		LockoutDuration:        time.Second * time.Duration(h.runtimeConfig.LoginLockoutDurationInSeconds),
+		BaseDelay:              time.Millisecond * time.Duration(h.runtimeConfig.LoginFailureDelayInMilliseconds),
+		MaxDelay:               time.Millisecond * time.Duration(h.runtimeConfig.LoginFailureMaxDelayInMilliseconds),
+	}
+}
+
+// rejectLogin accounts failed login attempt against the client IP, and against the user if the email is known.
+// Response is delayed progressively with the consecutive failures, either of the user or of the client IP,
+// such that response time doesn't reveal whether the email is registered.
+func (h *Handler) rejectLogin(c *gin.Context, user *models.User, now time.Time) {
+	policy := h.lockoutPolicy()
+	failedAttempts, err := h.recordIPFailure(c.ClientIP(), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if user != nil {
+		userFailedAttempts, err := h.recordLoginFailure(user.ID, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		if userFailedAttempts > failedAttempts {
+			failedAttempts = userFailedAttempts
+		}
+	}
+	time.Sleep(policy.Delay(failedAttempts))
+	c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidEmailOrPass.Error()))
+}
+
+// rejectLockedIP responds with 429 if the client IP is locked out, reporting whether it did.
+func (h *Handler) rejectLockedIP(c *gin.Context, now time.Time) bool {
+	ipFailure, err := h.operations.GetLoginFailure(c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return true
+	}
+	if ipFailure.IsLocked(now) {
+		c.Header("Retry-After", retryAfter(*ipFailure.LockedUntil, now))
+		c.JSON(http.StatusTooManyRequests, utils.FormatErrorResponse(appErrors.ErrTooManyLoginAttempts.Error()))
+		return true
+	}
+	return false
+}
+
+// recordIPFailure accounts failed attempt against the client IP, and locks the IP out once the failures reach
+// the threshold. Responds with the failures of the IP.
+func (h *Handler) recordIPFailure(ip string, now time.Time) (int, error) {
+	policy := h.lockoutPolicy()
+	failedAttempts, err := h.operations.RecordIPLoginFailure(ip, now, now.Add(-policy.LockoutDuration))
+	if err != nil {
+		return 0, err
+	}
+	if lockedUntil := policy.IPLockedUntil(failedAttempts, now); lockedUntil != nil {
+		if err := h.operations.LockIP(ip, *lockedUntil); err != nil {
+			return 0, err
+		}
+	}
+	return failedAttempts, nil
+}
+
+// recordLoginFailure accounts failed login attempt against the user, and locks the account once the consecutive
+// failures reach the threshold. Lockout is decided on the failures counted in DB, such that concurrent attempts
+// can't slip past the threshold. Responds with the consecutive failures of the user.
+func (h *Handler) recordLoginFailure(userID uint, now time.Time) (int, error) {
+	failedAttempts, err := h.operations.RecordLoginFailure(userID)
+	if err != nil {
+		return 0, err
+	}
+	if lockedUntil := h.lockoutPolicy().AccountLockedUntil(failedAttempts, now); lockedUntil != nil {
+		if err := h.operations.LockUser(userID, *lockedUntil); err != nil {
+			return 0, err
+		}
+	}
+	return failedAttempts, nil
+}
+
+// retryAfter formats Retry-After header value, i.e. seconds till the lockout expires.
+func retryAfter(lockedUntil time.Time, now time.Time) string {
+	return strconv.FormatInt(int64(math.Ceil(lockedUntil.Sub(now).Seconds())), 10)
+}
+
+// unlockUser clears lockout and failed login attempts of the user. Only admin users can unlock users.
+func (h *Handler) unlockUser(c *gin.Context) {
+	id := c.Param(models.QueryParamID)
+	var userId uint
+	if _, err := fmt.Sscanf(id, "%d", &userId); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("User ID should be numerical"))
+		return
+	}
+	if err := h.operations.ResetLoginFailures(userId); err != nil {
+		if err == appErrors.ErrUserDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("User unlocked"))
+}
+
+// startSession records a fresh login as session of the device the request is made from, and issues
+// refresh token for it, which starts a new token family. Session ID is returned, such that access tokens
+// are issued within the session.
+func (h *Handler) startSession(c *gin.Context, userID uint) (string, uint, error) {
+	refreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", 0, err
+	}
+	familyID, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", 0, err
+	}
+	session := models.Session{UserID: userID, FamilyID: familyID, UserAgent: c.Request.UserAgent(),
+		IP: c.ClientIP(), LastSeenAt: time.Now()}
+	if err := h.operations.CreateSession(&session); err != nil {
+		return "", 0, err
+	}
+	err = h.operations.CreateRefreshToken(userID, familyID, auth.HashOpaqueToken(refreshToken), h.refreshTokenExpiry())
+	if err != nil {
+		return "", 0, err
+	}
+	return refreshToken, session.ID, nil
+}
+
+// refreshTokenExpiry...
+func (h *Handler) refreshTokenExpiry() time.Time {
+	return time.Now().Add(time.Second * time.Duration(h.runtimeConfig.RefreshTokenExpirationInSeconds))
+}
+
+// refreshToken exchanges valid refresh token for new access token.
+// Presented refresh token is rotated, i.e. it can't be used again and a new refresh token is sent along.
+// Session the refresh token is issued for is marked as last seen now.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) refreshToken(c *gin.Context) {
+	var tokenRefresh map[string]interface{}
+	if err := c.BindJSON(&tokenRefresh); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Token refresh payload is invalid; Expected JSON payload"))
+		return
+	}
+
+	if !utils.EnsureFieldsStrictlyExists(tokenRefresh, models.RefreshTokenPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Token refresh payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.RefreshTokenPayloadTemplate))))
+		return
+	}
+	if tokenRefresh[models.AttributeRefreshToken] == "" {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Token refresh payload is invalid; refresh_token is empty"))
+		return
+	}
+
+	newRefreshToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	rotatedToken, err := h.operations.RotateRefreshToken(
+		auth.HashOpaqueToken(tokenRefresh[models.AttributeRefreshToken].(string)),
+		auth.HashOpaqueToken(newRefreshToken), h.refreshTokenExpiry())
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+
+	// role or email might have changed since the login, hence claims are derived from latest user record.
+	user, err := h.operations.GetUser(rotatedToken.UserID)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredRefreshToken.Error()))
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrUserDisabled.Error()))
+		return
+	}
+	if user.IsTemporaryPasswordExpired(time.Now()) {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrTemporaryPasswordExpired.Error()))
 		return
 	}
 
-	secret := []byte(h.runtimeConfig.JWTSecret)
-	token, err := auth.CreateJWT(secret, h.runtimeConfig.JWTExpirationInSeconds, user.Email, user.Role)
+	// session is unknown for the token families started before sessions were tracked
+	subject, err := h.tokenSubject(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	session, err := h.operations.TouchSession(rotatedToken.FamilyID, time.Now())
+	if err != nil && err != appErrors.ErrSessionDoesNotExist {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if session != nil {
+		subject.SessionID = session.ID
+	}
+	token, err := h.tokens.CreateJWT(subject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
 	}
-	// Frontend will handle the response and forward it to change password endpoint if temp password not changed
-	c.JSON(http.StatusOK, utils.FormatTokenResponse(*token, user.IsTemporaryPassword))
+	response := utils.FormatTokenResponse(*token, newRefreshToken, user.IsTemporaryPassword)
+	response.MFAEnrollmentRequired = subject.MFAEnrollmentRequired
+	c.JSON(http.StatusOK, response)
 }
 
 // getUserByID endpoint fetches user by ID
@@ -89,7 +418,8 @@ func (h *Handler) getUserByID(c *gin.Context) {
 }
 
 // addUser adds new user to the system. Only admin users can add users.
-// User will be created with temporary password, and shown to admin User.
+// User will be created with temporary password, and shown to admin User. If configured, temporary password is
+// rather sent to the new user through the notifier, and is never shown to the admin.
 // New user can login and change his password.
 // Request will be rejected if additional fields to desired ones are present in payload.
 func (h *Handler) addUser(c *gin.Context) {
@@ -111,18 +441,22 @@ func (h *Handler) addUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("User creation payload contains invalid email"))
 		return
 	}
-	if _, ok := misc.Roles[userToAdd[models.AttributeRole].(string)]; !ok {
+	if !misc.IsRoleConfigured(userToAdd[models.AttributeRole].(string)) {
 		c.JSON(http.StatusBadRequest,
 			utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", userToAdd[models.AttributeRole].(string))))
 		return
 	}
+	if !misc.CoversPermissions(c.GetString(auth.JWTClaimRole), userToAdd[models.AttributeRole].(string)) {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrGrantingBeyondPermissions.Error()))
+		return
+	}
 
 	temporaryPass, err := auth.GeneratePassword()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
 	}
-	temporaryPassHash, err := auth.GeneratePasswordHash(*temporaryPass)
+	temporaryPassHash, err := h.passwordHasher.Hash(*temporaryPass)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
@@ -132,8 +466,29 @@ func (h *Handler) addUser(c *gin.Context) {
 	if len(userToAdd[models.AttributeName].(string)) == 0 {
 		userToAdd[models.AttributeName] = userToAdd[models.AttributeEmail]
 	}
+	// message is rendered upfront, such that a broken template doesn't leave behind a user without password
+	var temporaryPassMessage notify.Message
+	if h.runtimeConfig.NotifyTemporaryPassword {
+		temporaryPassMessage, err = notify.NewMessage(notify.TemplateTemporaryPassword,
+			userToAdd[models.AttributeEmail].(string), notify.TemporaryPasswordData{
+				Name:     userToAdd[models.AttributeName].(string),
+				Email:    userToAdd[models.AttributeEmail].(string),
+				Password: *temporaryPass,
+			})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+	}
+	var temporaryPassExpiresAt *time.Time
+	if h.runtimeConfig.TemporaryPasswordExpirationInSeconds > 0 {
+		expiresAt := time.Now().Add(time.Second * time.Duration(h.runtimeConfig.TemporaryPasswordExpirationInSeconds))
+		temporaryPassExpiresAt = &expiresAt
+	}
 	err = h.operations.CreateUser(userToAdd[models.AttributeName].(string),
-		userToAdd[models.AttributeEmail].(string), userToAdd[models.AttributeRole].(string), temporaryPassHash)
+		userToAdd[models.AttributeEmail].(string), userToAdd[models.AttributeRole].(string), temporaryPassHash,
+		temporaryPassExpiresAt)
 	if err != nil {
 		if err == appErrors.ErrUserWithSameEmailAlreadyExists {
 			c.JSON(http.StatusConflict,
@@ -144,6 +499,17 @@ func (h *Handler) addUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
 	}
+	if h.runtimeConfig.NotifyTemporaryPassword {
+		if err := h.notifier.Notify(temporaryPassMessage); err != nil {
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrTemporaryPasswordNotDelivered.Error()))
+			return
+		}
+		c.JSON(http.StatusCreated,
+			utils.FormatGenericResponse(fmt.Sprintf("User is added; Temporary password is sent to %s",
+				temporaryPassMessage.To)))
+		return
+	}
 	// intimating the calling user about the temporary password being set for the newly added user
 	c.JSON(http.StatusCreated, utils.FormatTempPassResponse(*temporaryPass))
 }
@@ -176,11 +542,25 @@ func (h *Handler) updateUser(c *gin.Context) {
 		return
 	}
 
-	if _, ok := misc.Roles[userToUpdate[models.AttributeRole].(string)]; !ok || userToUpdate[models.AttributeRole] == "" {
+	if !misc.IsRoleConfigured(userToUpdate[models.AttributeRole].(string)) || userToUpdate[models.AttributeRole] == "" {
 		c.JSON(http.StatusBadRequest,
 			utils.FormatErrorResponse(fmt.Sprintf("User Role %s doesn't exist", userToUpdate[models.AttributeRole].(string))))
 		return
 	}
+	if !misc.CoversPermissions(c.GetString(auth.JWTClaimRole), userToUpdate[models.AttributeRole].(string)) {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrGrantingBeyondPermissions.Error()))
+		return
+	}
+
+	existingUser, err := h.operations.GetUser(userId)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+		return
+	}
 
 	updaterUser, err := h.operations.UpdateUser(userId, userToUpdate[models.AttributeName].(string),
 		userToUpdate[models.AttributeEmail].(string), userToUpdate[models.AttributeRole].(string))
@@ -195,6 +575,20 @@ func (h *Handler) updateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
 	}
+	// Role elevations were requested and reviewed against the former role, hence they end along with it.
+	if existingUser.Role != updaterUser.Role {
+		if err := h.operations.EndElevations(userId, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+	}
+	// Issued tokens carry email and role as claims, hence they are stale once either of them changes.
+	if existingUser.Email != updaterUser.Email || existingUser.Role != updaterUser.Role {
+		if err := h.revokeUserTokens(existingUser); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+	}
 	// UI can reflect UserManagement page to get the updated users list
 	c.JSON(http.StatusOK, updaterUser)
 }
@@ -209,7 +603,16 @@ func (h *Handler) deleteUser(c *gin.Context) {
 			utils.FormatErrorResponse("User ID should be numerical"))
 		return
 	}
-	err := h.operations.DeleteUser(userId)
+	user, err := h.operations.GetUser(userId)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatGenericResponse(appErrors.ErrUserDoesNotExist.Error()))
+		return
+	}
+	err = h.operations.DeleteUser(userId)
 	if err != nil {
 		if err == appErrors.ErrUserDoesNotExist {
 			c.JSON(http.StatusNotFound, utils.FormatGenericResponse(appErrors.ErrUserDoesNotExist.Error()))
@@ -218,6 +621,11 @@ func (h *Handler) deleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
 	}
+	// tokens issued to deleted user shouldn't be usable till their expiry
+	if err := h.revokeUserTokens(user); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
 	// UI can reflect UserManagement page to get the updated users list
 	c.JSON(http.StatusOK, utils.FormatGenericResponse("User deleted from system"))
 }
@@ -253,10 +661,13 @@ func (h *Handler) fetchUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// ChangeUserPassword applies to authn user
+// ChangeUserPassword applies to authn user.
+// Current password has to be presented along, unless the user is replacing temporary password, such that
+// a stolen access token can't be used to take over the account. Every other session of the user is revoked,
+// and the requester is handed over a fresh token pair.
 func (h *Handler) changeUserPassword(c *gin.Context) {
-	// email should be set by middleware, if not its considered as Internal error
-	email, ok := c.Get(models.AttributeEmail)
+	// user ID should be set by middleware, if not its considered as Internal error
+	userID, ok := c.Get(auth.JWTClaimSubject)
 	if !ok {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
@@ -267,10 +678,12 @@ func (h *Handler) changeUserPassword(c *gin.Context) {
 		return
 	}
 
-	if !utils.EnsureFieldsStrictlyExists(userRequiringPassChange, models.PasswordChangePayloadTemplate) {
+	if !utils.EnsureFieldsStrictlyExists(userRequiringPassChange, models.PasswordChangePayloadTemplate) &&
+		!utils.EnsureFieldsStrictlyExists(userRequiringPassChange, models.TemporaryPasswordChangePayloadTemplate) {
 		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
-			fmt.Sprintf("User password change payload is invalid; Strictly Allowed Params: %v",
-				utils.ConvertFieldTypeToString(models.PasswordChangePayloadTemplate))))
+			fmt.Sprintf("User password change payload is invalid; Strictly Allowed Params: %v or %v",
+				utils.ConvertFieldTypeToString(models.PasswordChangePayloadTemplate),
+				utils.ConvertFieldTypeToString(models.TemporaryPasswordChangePayloadTemplate))))
 		return
 	}
 
@@ -279,27 +692,1123 @@ func (h *Handler) changeUserPassword(c *gin.Context) {
 		return
 	}
 
-	// Hash generation accepts password of length <= 72, hence having this upper limit.
-	if len(userRequiringPassChange["password"].(string)) > 72 {
-		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrPasswordTooLong.Error()))
+	user, err := h.operations.GetUser(userID.(uint))
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+		return
+	}
+
+	if user.IsTemporaryPasswordExpired(time.Now()) {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrTemporaryPasswordExpired.Error()))
+		return
+	}
+	if !user.IsTemporaryPassword {
+		if status, err := h.verifyCurrentPassword(user, userRequiringPassChange[models.AttributeCurrentPassword]); err != nil {
+			c.JSON(status, utils.FormatErrorResponse(err.Error()))
+			return
+		}
+	}
+
+	password := userRequiringPassChange[models.AttributePassword].(string)
+	if status, err := h.validateNewPassword(user, password); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+
+	passwordHash, err := h.passwordHasher.Hash(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if status, err := h.storeNewPassword(user, passwordHash); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
 		return
 	}
 
-	passwordHash, err := auth.GeneratePasswordHash(userRequiringPassChange["password"].(string))
+	// The requester is handed over a fresh token pair, such that UI can continue without forcing a login again.
+	refreshToken, sessionID, err := h.startSession(c, user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
 	}
-	// Email as well serves as a unique id to user
-	err = h.operations.ChangePassword(email.(string), passwordHash)
+	subject, err := h.tokenSubject(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	subject.SessionID = sessionID
+	token, err := h.tokens.CreateJWT(subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	response := utils.FormatTokenResponse(*token, refreshToken, false)
+	response.MFAEnrollmentRequired = subject.MFAEnrollmentRequired
+	c.JSON(http.StatusOK, response)
+}
+
+// verifyCurrentPassword checks current password presented by the user. Mismatches are accounted as failed login
+// attempts, such that the current password can't be guessed through password change either.
+// Rejection is responded with the http status and the error to be reported.
+func (h *Handler) verifyCurrentPassword(user *models.User, currentPassword interface{}) (int, error) {
+	if currentPassword == nil || currentPassword == "" {
+		return http.StatusBadRequest, appErrors.ErrCurrentPasswordMissing
+	}
+	now := time.Now()
+	if user.IsLocked(now) {
+		return http.StatusTooManyRequests, appErrors.ErrAccountLocked
+	}
+	if !auth.CompareHashAndPassword(user.PasswordHash, []byte(currentPassword.(string))) {
+		if _, err := h.recordLoginFailure(user.ID, now); err != nil {
+			return http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+		}
+		return http.StatusUnauthorized, appErrors.ErrCurrentPasswordMismatch
+	}
+	return http.StatusOK, nil
+}
+
+// validateNewPassword checks password chosen by the user against password policy and recently used passwords.
+// Rejection is responded with the http status and the error to be reported.
+func (h *Handler) validateNewPassword(user *models.User, password string) (int, error) {
+	if err := h.passwordPolicy.Validate(password, user.Email); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if h.runtimeConfig.PasswordHistorySize <= 0 {
+		return http.StatusOK, nil
+	}
+	passwordHashes, err := h.operations.FetchPasswordHistory(user.ID, int(h.runtimeConfig.PasswordHistorySize))
 	if err != nil {
+		return http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+	}
+	// current password might predate the history, hence checked explicitly
+	if auth.IsPasswordReused(password, append(passwordHashes, user.PasswordHash)) {
+		return http.StatusBadRequest, appErrors.ErrPasswordReused
+	}
+	return http.StatusOK, nil
+}
+
+// storeNewPassword replaces password of the user, records it in password history and revokes every token
+// issued with the former password. Failure is responded with the http status and the error to be reported.
+func (h *Handler) storeNewPassword(user *models.User, passwordHash string) (int, error) {
+	// Email as well serves as a unique id to user
+	if err := h.operations.ChangePassword(user.Email, passwordHash); err != nil {
 		if err == appErrors.ErrUserDoesNotExist {
-			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
-			return
+			return http.StatusNotFound, appErrors.ErrUserDoesNotExist
+		}
+		return http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+	}
+	if h.runtimeConfig.PasswordHistorySize > 0 {
+		if err := h.operations.RecordPasswordHistory(user.ID, passwordHash,
+			int(h.runtimeConfig.PasswordHistorySize)); err != nil {
+			return http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
 		}
+	}
+	if err := h.revokeUserTokens(user); err != nil {
+		return http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+	}
+	// chosen password is never temporary, tokens issued hereafter aren't restricted to password change
+	user.IsTemporaryPassword = false
+	return http.StatusOK, nil
+}
+
+// revokeUserTokens revokes every access and refresh token issued to the user till now.
+func (h *Handler) revokeUserTokens(user *models.User) error {
+	if err := h.revoker.RevokeSubjectTokens(auth.SubjectFromUserID(user.ID)); err != nil {
+		return err
+	}
+	return h.operations.RevokeUserRefreshTokens(user.ID)
+}
+
+// logout revokes the access token used for the request.
+// Optionally refresh token can be sent along, such that every token issued from the login is revoked.
+func (h *Handler) logout(c *gin.Context) {
+	// token claims should be set by middleware, if not its considered as Internal error
+	userID, userIDOk := c.Get(auth.JWTClaimSubject)
+	tokenID, tokenIDOk := c.Get(auth.JWTClaimID)
+	expiresAt, expiresAtOk := c.Get(auth.JWTClaimExpiresAt)
+	if !userIDOk || !tokenIDOk || !expiresAtOk {
 		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
 		return
 	}
-	// UI can reflect UserManagement page to get the updated users list
-	c.Status(http.StatusOK)
+
+	if c.Request.ContentLength > 0 {
+		var userLogout map[string]interface{}
+		if err := c.BindJSON(&userLogout); err != nil {
+			c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Logout payload is invalid; Expected JSON payload"))
+			return
+		}
+		if !utils.EnsureFieldsStrictlyExists(userLogout, models.RefreshTokenPayloadTemplate) {
+			c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+				fmt.Sprintf("Logout payload is invalid; Strictly Allowed Params: %v",
+					utils.ConvertFieldTypeToString(models.RefreshTokenPayloadTemplate))))
+			return
+		}
+		err := h.operations.RevokeRefreshTokenFamily(userID.(uint),
+			auth.HashOpaqueToken(userLogout[models.AttributeRefreshToken].(string)))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+	}
+
+	if err := h.revoker.RevokeToken(tokenID.(string), expiresAt.(time.Time)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Logged out successfully"))
+}
+
+// createAccessToken creates personal access token for the requesting user, limited to the given scopes
+// which should be a subset of what user's role is authorized for. Token is shown only once in the response.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) createAccessToken(c *gin.Context) {
+	// identity should be set by middleware, if not its considered as Internal error
+	userID, userIDOk := c.Get(auth.JWTClaimSubject)
+	role, roleOk := c.Get(auth.JWTClaimRole)
+	if !userIDOk || !roleOk {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	var tokenToCreate map[string]interface{}
+	if err := c.BindJSON(&tokenToCreate); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Personal access token payload is invalid; Expected JSON payload"))
+		return
+	}
+
+	if !utils.EnsureFieldsStrictlyExists(tokenToCreate, models.PersonalAccessTokenPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Personal access token payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.PersonalAccessTokenPayloadTemplate))))
+		return
+	}
+	if tokenToCreate[models.AttributeName] == "" {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Personal access token payload is invalid; name is empty"))
+		return
+	}
+
+	requestedScopes := tokenToCreate[models.AttributeScopes].([]interface{})
+	if len(requestedScopes) == 0 {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Personal access token payload is invalid; scopes are empty"))
+		return
+	}
+	grantableScopes := misc.RolePermissions(role.(string))
+	var scopes []string
+	for _, requestedScope := range requestedScopes {
+		scope, ok := requestedScope.(string)
+		if !ok || !containsScope(grantableScopes, scope) {
+			c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+				fmt.Sprintf("Scope %v can't be granted; Grantable scopes: %v", requestedScope, grantableScopes)))
+			return
+		}
+		if !containsScope(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	expiresInDays := tokenToCreate[models.AttributeExpiresInDays].(float64)
+	if expiresInDays < 1 || expiresInDays > float64(h.runtimeConfig.AccessTokenMaxLifetimeInDays) ||
+		expiresInDays != float64(int64(expiresInDays)) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Personal access token payload is invalid; expires_in_days should be between 1 and %d",
+				h.runtimeConfig.AccessTokenMaxLifetimeInDays)))
+		return
+	}
+
+	token, displayPrefix, err := auth.GenerateAccessToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	accessToken := models.PersonalAccessToken{
+		UserID:    userID.(uint),
+		Name:      tokenToCreate[models.AttributeName].(string),
+		Prefix:    displayPrefix,
+		TokenHash: auth.HashOpaqueToken(token),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Duration(expiresInDays) * 24 * time.Hour),
+	}
+	if err := h.operations.CreateAccessToken(&accessToken); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusCreated, models.CreatedPersonalAccessToken{PersonalAccessToken: accessToken, Token: token})
+}
+
+// containsScope...
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchAccessTokens lists personal access tokens of the requesting user
+func (h *Handler) fetchAccessTokens(c *gin.Context) {
+	// user ID should be set by middleware, if not its considered as Internal error
+	userID, ok := c.Get(auth.JWTClaimSubject)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	accessTokens, err := h.operations.FetchAccessTokens(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, accessTokens)
+}
+
+// revokeAccessToken revokes personal access token of the requesting user
+func (h *Handler) revokeAccessToken(c *gin.Context) {
+	// user ID should be set by middleware, if not its considered as Internal error
+	userID, ok := c.Get(auth.JWTClaimSubject)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	id := c.Param(models.QueryParamID)
+	var tokenID uint
+	if _, err := fmt.Sscanf(id, "%d", &tokenID); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Personal access token ID should be numerical"))
+		return
+	}
+	err := h.operations.RevokeAccessToken(userID.(uint), tokenID)
+	if err != nil {
+		if err == appErrors.ErrAccessTokenDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrAccessTokenDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Personal access token revoked"))
+}
+
+// fetchSessions lists active sessions of the requesting user, marking the one the request is made from.
+func (h *Handler) fetchSessions(c *gin.Context) {
+	// user ID should be set by middleware, if not its considered as Internal error
+	userID, ok := c.Get(auth.JWTClaimSubject)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	h.respondSessions(c, userID.(uint))
+}
+
+// revokeSession revokes session of the requesting user, i.e. logs the device out.
+func (h *Handler) revokeSession(c *gin.Context) {
+	// user ID should be set by middleware, if not its considered as Internal error
+	userID, ok := c.Get(auth.JWTClaimSubject)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	var sessionID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &sessionID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Session ID should be numerical"))
+		return
+	}
+	h.endSession(c, userID.(uint), sessionID)
+}
+
+// fetchUserSessions lists active sessions of the user. Only admin users can list sessions of users.
+func (h *Handler) fetchUserSessions(c *gin.Context) {
+	var userID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &userID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("User ID should be numerical"))
+		return
+	}
+	if _, err := h.operations.GetUser(userID); err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+		return
+	}
+	h.respondSessions(c, userID)
+}
+
+// revokeUserSession revokes session of the user. Only admin users can revoke sessions of users.
+func (h *Handler) revokeUserSession(c *gin.Context) {
+	var userID, sessionID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &userID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("User ID should be numerical"))
+		return
+	}
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamSessionID), "%d", &sessionID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("Session ID should be numerical"))
+		return
+	}
+	h.endSession(c, userID, sessionID)
+}
+
+// respondSessions responds with active sessions of the user, marking the one the request is made from.
+func (h *Handler) respondSessions(c *gin.Context, userID uint) {
+	sessions, err := h.operations.FetchSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if currentSessionID, ok := c.Get(auth.JWTClaimSessionID); ok {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == currentSessionID.(uint)
+		}
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// endSession revokes refresh tokens of the session, and the access tokens issued within the session till now.
+func (h *Handler) endSession(c *gin.Context, userID uint, sessionID uint) {
+	if err := h.operations.RevokeSession(userID, sessionID); err != nil {
+		if err == appErrors.ErrSessionDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrSessionDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.revoker.RevokeSubjectTokens(auth.SubjectFromSessionID(sessionID)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Session revoked"))
+}
+
+// impersonateUser issues short-lived access token of the user to the requesting admin, such that admin can
+// troubleshoot on behalf of the user. Token carries the admin as actor; it is never refreshed, and every request
+// made with it is audited. Admin users can't be impersonated, nor users granted any permission the admin lacks,
+// such that impersonation never escalates the admin's permissions.
+func (h *Handler) impersonateUser(c *gin.Context) {
+	// user ID should be set by middleware, if not its considered as Internal error
+	actorID, ok := c.Get(auth.JWTClaimSubject)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	var userID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &userID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("User ID should be numerical"))
+		return
+	}
+	user, err := h.operations.GetUser(userID)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+		return
+	}
+	// users able to manage users are admins, whatever their role is named
+	if misc.HasPermission(user.Role, models.PermissionUserWrite) {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrImpersonatingAdmin.Error()))
+		return
+	}
+	if !misc.CoversPermissions(c.GetString(auth.JWTClaimRole), user.Role) {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrImpersonatingBeyondPermissions.Error()))
+		return
+	}
+
+	token, expiration, err := h.tokens.CreateImpersonationJWT(
+		auth.TokenSubject{UserID: user.ID, Email: user.Email, Role: user.Role}, actorID.(uint),
+		time.Duration(h.runtimeConfig.ImpersonationExpirationInSeconds)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatClientCredentialsTokenResponse(token, int64(expiration.Seconds())))
+}
+
+// fetchImpersonationAudits lists requests made while impersonating the user. Only admin users can list them.
+func (h *Handler) fetchImpersonationAudits(c *gin.Context) {
+	var userID uint
+	if _, err := fmt.Sscanf(c.Param(models.QueryParamID), "%d", &userID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("User ID should be numerical"))
+		return
+	}
+	audits, err := h.operations.FetchImpersonationAudits(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, audits)
+}
+
+// loginWithMFA completes login of user enrolled in MFA. MFA challenge token responded by login is exchanged
+// for token pair, along with either TOTP code from authenticator app or an unused recovery code.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) loginWithMFA(c *gin.Context) {
+	var mfaLogin map[string]interface{}
+	if err := c.BindJSON(&mfaLogin); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("MFA login payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(mfaLogin, models.MFALoginPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("MFA login payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.MFALoginPayloadTemplate))))
+		return
+	}
+
+	userID, err := h.tokens.ValidateMFAChallenge(mfaLogin[models.AttributeMFAToken].(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredMFAToken.Error()))
+		return
+	}
+	user, err := h.operations.GetUser(userID)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredMFAToken.Error()))
+		return
+	}
+	// MFA might have been reset by admin since the challenge is issued
+	if !user.MFAEnabled {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredMFAToken.Error()))
+		return
+	}
+	now := time.Now()
+	if user.IsLocked(now) {
+		c.Header("Retry-After", retryAfter(*user.LockedUntil, now))
+		c.JSON(http.StatusTooManyRequests, utils.FormatErrorResponse(appErrors.ErrAccountLocked.Error()))
+		return
+	}
+	if status, err := h.verifySecondFactor(user, mfaLogin[models.AttributeMFACode]); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	h.completeLogin(c, user)
+}
+
+// verifySecondFactor checks TOTP or recovery code presented by the user. Mismatches are accounted as failed
+// login attempts, such that the codes can't be guessed either. Accepted TOTP code can't be used again,
+// and accepted recovery code is consumed. Rejection is responded with the http status and the error to be reported.
+func (h *Handler) verifySecondFactor(user *models.User, code interface{}) (int, error) {
+	if code == nil || code == "" {
+		return http.StatusBadRequest, appErrors.ErrInvalidMFACode
+	}
+	now := time.Now()
+	if user.IsLocked(now) {
+		return http.StatusTooManyRequests, appErrors.ErrAccountLocked
+	}
+	var (
+		accepted bool
+		err      error
+	)
+	if step, ok := auth.ValidateTOTPCode(user.MFASecret, code.(string), now); ok {
+		accepted, err = h.operations.RecordMFAStep(user.ID, step)
+	} else {
+		accepted, err = h.operations.ConsumeRecoveryCode(user.ID, auth.HashRecoveryCode(code.(string)))
+	}
+	if err != nil {
+		return http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+	}
+	if !accepted {
+		if _, err := h.recordLoginFailure(user.ID, now); err != nil {
+			return http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+		}
+		return http.StatusUnauthorized, appErrors.ErrInvalidMFACode
+	}
+	return http.StatusOK, nil
+}
+
+// generateRecoveryCodes generates recovery codes to be shown to the user, along with their hashes to be persisted.
+func (h *Handler) generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes, err := auth.GenerateRecoveryCodes(int(h.runtimeConfig.MFARecoveryCodeCount))
+	if err != nil {
+		return nil, nil, err
+	}
+	recoveryCodeHashes := make([]string, 0, len(recoveryCodes))
+	for _, recoveryCode := range recoveryCodes {
+		recoveryCodeHashes = append(recoveryCodeHashes, auth.HashRecoveryCode(recoveryCode))
+	}
+	return recoveryCodes, recoveryCodeHashes, nil
+}
+
+// bindMFACode binds payload presenting TOTP or recovery code, responding with bad request if payload is invalid.
+func bindMFACode(c *gin.Context) (interface{}, bool) {
+	var mfaCode map[string]interface{}
+	if err := c.BindJSON(&mfaCode); err != nil {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse("MFA payload is invalid; Expected JSON payload"))
+		return nil, false
+	}
+	if !utils.EnsureFieldsStrictlyExists(mfaCode, models.MFACodePayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("MFA payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.MFACodePayloadTemplate))))
+		return nil, false
+	}
+	return mfaCode[models.AttributeMFACode], true
+}
+
+// sessionUser fetches the user the request is authenticated as, responding with error if it fails.
+func (h *Handler) sessionUser(c *gin.Context) (*models.User, bool) {
+	// user ID should be set by middleware, if not its considered as Internal error
+	userID, ok := c.Get(auth.JWTClaimSubject)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return nil, false
+	}
+	user, err := h.operations.GetUser(userID.(uint))
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return nil, false
+		}
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+		return nil, false
+	}
+	return user, true
+}
+
+// enrollMFA starts MFA enrollment of authn user, by generating TOTP secret to be added to authenticator app.
+// Enrollment takes effect only once confirmed with a code generated from the secret, through confirmMFA.
+func (h *Handler) enrollMFA(c *gin.Context) {
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+	if user.MFAEnabled {
+		c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrMFAAlreadyEnabled.Error()))
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.operations.SetPendingMFASecret(user.ID, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(h.runtimeConfig.MFAIssuer, user.Email, secret),
+	})
+}
+
+// confirmMFA enables MFA of authn user, once code generated from the secret of enrollment is presented.
+// Recovery codes are responded, and are shown only once.
+// Token restricted to MFA enrollment has to be refreshed afterwards, to access everything else.
+func (h *Handler) confirmMFA(c *gin.Context) {
+	code, ok := bindMFACode(c)
+	if !ok {
+		return
+	}
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+	if user.MFAEnabled {
+		c.JSON(http.StatusConflict, utils.FormatErrorResponse(appErrors.ErrMFAAlreadyEnabled.Error()))
+		return
+	}
+	if user.MFAPendingSecret == "" {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrMFAEnrollmentNotStarted.Error()))
+		return
+	}
+	step, valid := auth.ValidateTOTPCode(user.MFAPendingSecret, code.(string), time.Now())
+	if !valid {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrInvalidMFACode.Error()))
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := h.generateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.operations.EnableMFA(user.ID, user.MFAPendingSecret, recoveryCodeHashes); err != nil {
+		if err == appErrors.ErrUserDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	// code used for confirmation can't be used for login
+	if _, err := h.operations.RecordMFAStep(user.ID, step); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MFARecoveryCodes{RecoveryCodes: recoveryCodes})
+}
+
+// disableMFA disables MFA of authn user, once TOTP or recovery code is presented.
+// MFA can't be disabled if user's role demands it.
+func (h *Handler) disableMFA(c *gin.Context) {
+	code, ok := bindMFACode(c)
+	if !ok {
+		return
+	}
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+	if !user.MFAEnabled {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrMFANotEnabled.Error()))
+		return
+	}
+	if h.mfaRequiredForRole(user.Role) {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrMFAEnrollmentRequired.Error()))
+		return
+	}
+	if status, err := h.verifySecondFactor(user, code); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	if err := h.operations.DisableMFA(user.ID); err != nil {
+		if err == appErrors.ErrUserDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("MFA disabled"))
+}
+
+// regenerateRecoveryCodes replaces recovery codes of authn user, once TOTP or recovery code is presented.
+// Recovery codes are responded, and are shown only once.
+func (h *Handler) regenerateRecoveryCodes(c *gin.Context) {
+	code, ok := bindMFACode(c)
+	if !ok {
+		return
+	}
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+	if !user.MFAEnabled {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrMFANotEnabled.Error()))
+		return
+	}
+	if status, err := h.verifySecondFactor(user, code); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	recoveryCodes, recoveryCodeHashes, err := h.generateRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	if err := h.operations.ReplaceRecoveryCodes(user.ID, recoveryCodeHashes); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.MFARecoveryCodes{RecoveryCodes: recoveryCodes})
+}
+
+// resetUserMFA clears MFA enrollment of the user, who has lost both authenticator app and recovery codes.
+// Only admin users can reset MFA of users.
+func (h *Handler) resetUserMFA(c *gin.Context) {
+	id := c.Param(models.QueryParamID)
+	var userId uint
+	if _, err := fmt.Sscanf(id, "%d", &userId); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("User ID should be numerical"))
+		return
+	}
+	if err := h.operations.DisableMFA(userId); err != nil {
+		if err == appErrors.ErrUserDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrUserDoesNotExist.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("User MFA reset"))
+}
+
+// forgotPassword issues single use password reset token to the user, which is delivered out-of-band through
+// the notifier. Response is the same whether the email is registered or not, such that it can't be used to
+// enumerate users.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) forgotPassword(c *gin.Context) {
+	var passwordForgot map[string]interface{}
+	if err := c.BindJSON(&passwordForgot); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Password forgot payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(passwordForgot, models.PasswordForgotPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Password forgot payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.PasswordForgotPayloadTemplate))))
+		return
+	}
+	if err := misc.PayloadValidator.Var(passwordForgot[models.AttributeEmail], "required,email"); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Password forgot payload contains invalid email"))
+		return
+	}
+
+	// every request counts against the client IP like a failed login, such that registered emails can't be
+	// probed nor flooded with reset instructions
+	now := time.Now()
+	if h.rejectLockedIP(c, now) {
+		return
+	}
+	if _, err := h.recordIPFailure(c.ClientIP(), now); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+
+	user, err := h.operations.GetUserByEmail(passwordForgot[models.AttributeEmail].(string))
+	if err != nil && err == appErrors.ErrInternal {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	// reset token is issued and delivered in background, such that known emails are responded as fast as
+	// unknown ones
+	if err == nil {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			if err := h.sendPasswordResetToken(user); err != nil {
+				h.log.Errorf("Failed to send password reset token to user with id %d: %v", user.ID, err)
+			}
+		}()
+	}
+	c.JSON(http.StatusAccepted,
+		utils.FormatGenericResponse("If the email is registered, password reset instructions are sent to it"))
+}
+
+// sendPasswordResetToken issues password reset token to the user, and delivers it through the notifier.
+func (h *Handler) sendPasswordResetToken(user *models.User) error {
+	resetToken, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	expiration := time.Second * time.Duration(h.runtimeConfig.PasswordResetTokenExpirationInSeconds)
+	err = h.operations.CreatePasswordResetToken(user.ID, auth.HashOpaqueToken(resetToken), time.Now().Add(expiration))
+	if err != nil {
+		return err
+	}
+	data := notify.PasswordResetData{
+		Name:             user.Name,
+		Token:            resetToken,
+		ExpiresInMinutes: int64(expiration.Minutes()),
+	}
+	if h.runtimeConfig.PasswordResetURL != "" {
+		data.Link = fmt.Sprintf("%s?token=%s", h.runtimeConfig.PasswordResetURL, url.QueryEscape(resetToken))
+	}
+	message, err := notify.NewMessage(notify.TemplatePasswordReset, user.Email, data)
+	if err != nil {
+		return err
+	}
+	return h.notifier.Notify(message)
+}
+
+// resetPassword sets password chosen by the user, presenting password reset token. Token is single use,
+// and every session of the user is revoked upon reset.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) resetPassword(c *gin.Context) {
+	var passwordReset map[string]interface{}
+	if err := c.BindJSON(&passwordReset); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Password reset payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(passwordReset, models.PasswordResetPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Password reset payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.PasswordResetPayloadTemplate))))
+		return
+	}
+	password := passwordReset[models.AttributePassword].(string)
+	if password == "" {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrPasswordMissingOrEmpty.Error()))
+		return
+	}
+	tokenHash := auth.HashOpaqueToken(passwordReset[models.AttributeResetToken].(string))
+	resetToken, err := h.operations.GetPasswordResetToken(tokenHash)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredResetToken.Error()))
+		return
+	}
+	user, err := h.operations.GetUser(resetToken.UserID)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredResetToken.Error()))
+		return
+	}
+	if status, err := h.validateNewPassword(user, password); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	passwordHash, err := h.passwordHasher.Hash(password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+
+	// token is consumed only once the password is acceptable, such that user can retry with another password
+	if err := h.operations.ConsumePasswordResetToken(tokenHash); err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredResetToken.Error()))
+		return
+	}
+	if status, err := h.storeNewPassword(user, passwordHash); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	// user has proven control over the email, hence lockout due to failed logins is lifted as well
+	if user.FailedLoginAttempts != 0 || user.LockedUntil != nil {
+		if err := h.operations.ResetLoginFailures(user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError,
+				utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Password reset successfully"))
+}
+
+// startOIDCLogin starts single sign-on login, responding with URL of identity provider which user is to be
+// redirected to. State, nonce and PKCE code verifier of the login are recorded till the user returns.
+func (h *Handler) startOIDCLogin(c *gin.Context) {
+	h.startOIDCAuthorization(c, nil)
+}
+
+// startOIDCLink starts linking identity provider to the authn user, alike single sign-on login, once the user
+// confirms the current password. Thereafter, the user can login through single sign-on as well.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) startOIDCLink(c *gin.Context) {
+	var linkRequest map[string]interface{}
+	if err := c.BindJSON(&linkRequest); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Single sign-on link payload is invalid; Expected JSON payload"))
+		return
+	}
+	if !utils.EnsureFieldsStrictlyExists(linkRequest, models.OIDCLinkPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Single sign-on link payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.OIDCLinkPayloadTemplate))))
+		return
+	}
+	user, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+	if status, err := h.verifyCurrentPassword(user, linkRequest[models.AttributeCurrentPassword]); err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	h.startOIDCAuthorization(c, &user.ID)
+}
+
+// startOIDCAuthorization responds with URL of identity provider which user is to be redirected to, recording
+// state, nonce and PKCE code verifier till the user returns. Authorization linking the identity provider is bound
+// to the user linking it, whereas the one of login isn't bound to any user.
+func (h *Handler) startOIDCAuthorization(c *gin.Context, userID *uint) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrOIDCNotConfigured.Error()))
+		return
+	}
+	secrets := make([]string, 3)
+	for i := range secrets {
+		secret, err := auth.GenerateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return
+		}
+		secrets[i] = secret
+	}
+	state, nonce, codeVerifier := secrets[0], secrets[1], secrets[2]
+	authorizationURL, err := h.oidc.AuthorizationURL(c.Request.Context(), state, nonce, codeVerifier)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, utils.FormatErrorResponse(appErrors.ErrOIDCProviderUnavailable.Error()))
+		return
+	}
+	expiresAt := time.Now().Add(time.Second * time.Duration(h.runtimeConfig.OIDCStateExpirationInSeconds))
+	if err := h.operations.CreateOIDCAuthorization(&models.OIDCAuthorization{StateHash: auth.HashOpaqueToken(state),
+		CodeVerifier: codeVerifier, Nonce: nonce, ExpiresAt: expiresAt, UserID: userID}); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatAuthorizationURLResponse(authorizationURL))
+}
+
+// completeOIDCAuthorization redeems the authorization code and state, which identity provider redirected the user
+// back with, for the identity of the user. State has to be of authorization started for the same user, nil for
+// login. Responds to the request and reports false, if identity provider didn't authenticate the user.
+func (h *Handler) completeOIDCAuthorization(c *gin.Context, userID *uint) (*oidc.Identity, bool) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotFound, utils.FormatErrorResponse(appErrors.ErrOIDCNotConfigured.Error()))
+		return nil, false
+	}
+	var callback map[string]interface{}
+	if err := c.BindJSON(&callback); err != nil {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Single sign-on payload is invalid; Expected JSON payload"))
+		return nil, false
+	}
+	if !utils.EnsureFieldsStrictlyExists(callback, models.OIDCCallbackPayloadTemplate) {
+		c.JSON(http.StatusBadRequest, utils.FormatErrorResponse(
+			fmt.Sprintf("Single sign-on payload is invalid; Strictly Allowed Params: %v",
+				utils.ConvertFieldTypeToString(models.OIDCCallbackPayloadTemplate))))
+		return nil, false
+	}
+	if callback[models.AttributeOIDCCode] == "" || callback[models.AttributeOIDCState] == "" {
+		c.JSON(http.StatusBadRequest,
+			utils.FormatErrorResponse("Single sign-on payload is invalid; code or state is empty"))
+		return nil, false
+	}
+
+	authorization, err := h.operations.ConsumeOIDCAuthorization(
+		auth.HashOpaqueToken(callback[models.AttributeOIDCState].(string)), time.Now())
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+			return nil, false
+		}
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(err.Error()))
+		return nil, false
+	}
+	// state of login can't link, nor the state of linking login or link other user
+	if (authorization.UserID == nil) != (userID == nil) ||
+		(userID != nil && *authorization.UserID != *userID) {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrInvalidOrExpiredOIDCState.Error()))
+		return nil, false
+	}
+	identity, err := h.oidc.Exchange(c.Request.Context(), callback[models.AttributeOIDCCode].(string),
+		authorization.CodeVerifier, authorization.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(appErrors.ErrOIDCLoginFailed.Error()))
+		return nil, false
+	}
+	return identity, true
+}
+
+// completeOIDCLogin completes single sign-on login with the authorization code and state, which identity provider
+// redirected the user back with. User is identified by the issuer and subject at identity provider. Groups of the
+// user are mapped to role, which is kept in sync upon every login for users added by single sign-on.
+// User unknown to the system is added if just-in-time provisioning is enabled. Response is the same as of login.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) completeOIDCLogin(c *gin.Context) {
+	identity, ok := h.completeOIDCAuthorization(c, nil)
+	if !ok {
+		return
+	}
+	role, ok := h.oidc.Role(identity)
+	if !ok {
+		c.JSON(http.StatusForbidden, utils.FormatErrorResponse(appErrors.ErrOIDCRoleNotMapped.Error()))
+		return
+	}
+
+	user, status, err := h.syncOIDCUser(identity, role)
+	if err != nil {
+		c.JSON(status, utils.FormatErrorResponse(err.Error()))
+		return
+	}
+	now := time.Now()
+	if user.IsLocked(now) {
+		c.Header("Retry-After", retryAfter(*user.LockedUntil, now))
+		c.JSON(http.StatusTooManyRequests, utils.FormatErrorResponse(appErrors.ErrAccountLocked.Error()))
+		return
+	}
+	h.completeFirstFactor(c, user)
+}
+
+// completeOIDCLink links the identity, which identity provider redirected the authn user back with, to the user.
+// Request will be rejected if additional fields to desired ones are present in payload.
+func (h *Handler) completeOIDCLink(c *gin.Context) {
+	// user ID should be set by middleware, if not its considered as Internal error
+	userID, ok := c.Get(auth.JWTClaimSubject)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	linkingUserID := userID.(uint)
+	identity, ok := h.completeOIDCAuthorization(c, &linkingUserID)
+	if !ok {
+		return
+	}
+	if err := h.operations.LinkOIDCIdentity(linkingUserID, identity.Issuer, identity.Subject); err != nil {
+		if err == appErrors.ErrOIDCIdentityAlreadyLinked {
+			c.JSON(http.StatusConflict, utils.FormatErrorResponse(err.Error()))
+			return
+		} else if err == appErrors.ErrUserDoesNotExist {
+			c.JSON(http.StatusNotFound, utils.FormatErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(appErrors.ErrFailureToProcessRequest.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, utils.FormatGenericResponse("Single sign-on linked"))
+}
+
+// syncOIDCUser fetches the user linked to the identity at identity provider, adding the user if just-in-time
+// provisioning is enabled. User with the same email, yet to be linked, is never matched by the email, since
+// the email might be of other person at identity provider; the user has to link the identity provider instead.
+// Role of the user added by single sign-on is updated as granted by identity provider, and tokens issued with
+// former role are revoked. Role of other users is managed in the system only.
+func (h *Handler) syncOIDCUser(identity *oidc.Identity, role string) (*models.User, int, error) {
+	user, err := h.operations.GetUserByOIDCIdentity(identity.Issuer, identity.Subject)
+	if err != nil {
+		if err == appErrors.ErrInternal {
+			return nil, http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+		}
+		if _, err := h.operations.GetUserByEmail(identity.Email); err == nil {
+			return nil, http.StatusConflict, appErrors.ErrOIDCUserNotLinked
+		} else if err == appErrors.ErrInternal {
+			return nil, http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+		}
+		if !h.runtimeConfig.OIDCJITProvisioning {
+			return nil, http.StatusForbidden, appErrors.ErrOIDCUserNotProvisioned
+		}
+		name := identity.Name
+		if name == "" {
+			name = identity.Email
+		}
+		user, err = h.operations.ProvisionUser(name, identity.Email, role, identity.Issuer, identity.Subject)
+		if err != nil {
+			if err == appErrors.ErrUserWithSameEmailAlreadyExists {
+				return nil, http.StatusConflict, err
+			}
+			return nil, http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+		}
+		return user, 0, nil
+	}
+
+	if !user.OIDCProvisioned || user.Role == role {
+		return user, 0, nil
+	}
+	updatedUser, err := h.operations.UpdateUser(user.ID, user.Name, user.Email, role)
+	if err != nil {
+		return nil, http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+	}
+	if err := h.operations.EndElevations(user.ID, time.Now()); err != nil {
+		return nil, http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+	}
+	if err := h.revokeUserTokens(user); err != nil {
+		return nil, http.StatusInternalServerError, appErrors.ErrFailureToProcessRequest
+	}
+	return updatedUser, 0, nil
 }
@@ -8,14 +8,22 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
+	"time"
+	"userservice/internal/auth"
 	"userservice/internal/configs"
 	appErrors "userservice/internal/errors"
 	"userservice/internal/misc"
 	"userservice/internal/models"
+	"userservice/internal/oidc"
+	"userservice/internal/oidc/oidctest"
+	"userservice/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
 )
 
 func GetTestGinContext(w *httptest.ResponseRecorder) *gin.Context {
@@ -56,11 +64,21 @@ var _ = Describe("Users", func() {
 		handler.runtimeConfig = new(configs.Config)
 		handler.runtimeConfig.JWTSecret = "mgmtportal"
 		handler.runtimeConfig.JWTExpirationInSeconds = 100
+		handler.runtimeConfig.AccessTokenMaxLifetimeInDays = 365
+		handler.tokens = auth.NewTokenIssuer(auth.NewHMACKeySet([]byte(handler.runtimeConfig.JWTSecret)),
+			"userservice", "userservice", handler.runtimeConfig.JWTExpirationInSeconds, 0)
+		handler.revoker = &RevocationMock{}
+		handler.notifier = &NotifierMock{}
+		handler.wg = new(sync.WaitGroup)
+		handler.log = zap.NewNop().Sugar()
+		handler.runtimeConfig.PasswordHistorySize = 3
+		handler.passwordPolicy, _ = auth.LoadPasswordPolicy(10, 128, []string{"lower", "upper", "digit"})
+		handler.passwordHasher = auth.NewArgon2idHasher(auth.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1})
 		w = httptest.NewRecorder()
 		ctx = GetTestGinContext(w)
 		misc.InitPayloadValidator()
 		operationsWithoutErr = UserMock{}
-		misc.Roles["basic"] = ""
+		misc.SetRole(models.UserRole{Name: "basic"})
 		u = url.Values{}
 
 	})
@@ -146,6 +164,202 @@ var _ = Describe("Users", func() {
 			Expect(w.Code).To(Equal(200))
 			Expect(w.Body.String()).To(Not(BeEmpty()))
 		})
+		It("login is recorded as session of the device, which tokens are issued within", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				Role: "admin", PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			ctx.Request.Header.Set("User-Agent", "Mozilla/5.0")
+			ctx.Request.RemoteAddr = "10.0.0.7:52814"
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.Sessions).To(HaveLen(1))
+			session := operationsWithoutErr.Sessions[0]
+			Expect(session.UserID).To(Equal(uint(1)))
+			Expect(session.UserAgent).To(Equal("Mozilla/5.0"))
+			Expect(session.IP).To(Equal("10.0.0.7"))
+			Expect(session.FamilyID).To(Not(BeEmpty()))
+			Expect(session.LastSeenAt).To(BeTemporally("~", time.Now(), time.Second))
+
+			var tokens map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &tokens)).To(Succeed())
+			token, err := handler.tokens.ValidateJWT(tokens["access_token"].(string))
+			Expect(err).To(BeNil())
+			Expect(token.Claims.(jwt.MapClaims)[auth.JWTClaimSessionID]).To(Equal("1"))
+		})
+		It("password hash of outdated algorithm is rehashed upon login", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1},
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(handler.passwordHasher.NeedsRehash(operationsWithoutErr.RehashedPassword)).To(BeFalse())
+			Expect(auth.CompareHashAndPassword(operationsWithoutErr.RehashedPassword, []byte("sabari123"))).To(BeTrue())
+		})
+		It("password hash of current parameters is retained upon login", func() {
+			passwordHash, _ := handler.passwordHasher.Hash("sabari123")
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, PasswordHash: passwordHash}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.RehashedPassword).To(BeEmpty())
+		})
+		It("password hash isn't rehashed upon wrong password", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1},
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "wrongPass"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(operationsWithoutErr.RehashedPassword).To(BeEmpty())
+		})
+		It("wrong password is accounted against the user and the client IP", func() {
+			handler.runtimeConfig.LoginMaxFailedAttempts = 3
+			handler.runtimeConfig.LoginLockoutDurationInSeconds = 60
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, FailedLoginAttempts: 1,
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "wrongPass"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(operationsWithoutErr.LoginFailureCount).To(Equal(1))
+			Expect(operationsWithoutErr.LockedUntil).To(BeNil())
+			Expect(operationsWithoutErr.LoginFailure.FailedAttempts).To(Equal(1))
+		})
+		It("wrong password beyond threshold locks the account", func() {
+			handler.runtimeConfig.LoginMaxFailedAttempts = 3
+			handler.runtimeConfig.LoginLockoutDurationInSeconds = 60
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, FailedLoginAttempts: 2,
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "wrongPass"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(operationsWithoutErr.LockedUntil).To(Not(BeNil()))
+			Expect(*operationsWithoutErr.LockedUntil).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+		})
+		It("locked account is rejected even with right password, alike unknown email", func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, LockedUntil: &lockedUntil,
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidEmailOrPass.Error()))
+			Expect(w.Header().Get("Retry-After")).To(BeEmpty())
+			// failure is accounted against the client IP alone, as for unknown email
+			Expect(operationsWithoutErr.LoginFailureCount).To(Equal(0))
+			Expect(operationsWithoutErr.LoginFailure.FailedAttempts).To(Equal(1))
+		})
+		It("locked client IP is rejected", func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			operationsWithoutErr.LoginFailure = &models.LoginFailure{LockedUntil: &lockedUntil}
+			operationsWithoutErr.User = &models.User{
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(429))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrTooManyLoginAttempts.Error()))
+		})
+		It("successful login after expired lockout resets failures", func() {
+			lockedUntil := time.Now().Add(-time.Minute)
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, LockedUntil: &lockedUntil,
+				FailedLoginAttempts: 2, PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.LoginFailuresReset).To(BeTrue())
+		})
+		It("user enrolled in MFA is challenged for second factor", func() {
+			handler.runtimeConfig.MFAChallengeExpirationInSeconds = 60
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, FailedLoginAttempts: 2,
+				MFAEnabled: true, PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(200))
+			var challenge map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &challenge)).To(Succeed())
+			Expect(challenge["mfa_required"]).To(Equal(true))
+			Expect(challenge).To(Not(HaveKey("access_token")))
+			userID, err := handler.tokens.ValidateMFAChallenge(challenge["mfa_token"].(string))
+			Expect(err).To(BeNil())
+			Expect(userID).To(Equal(uint(1)))
+			// failures are retained till the second factor is verified
+			Expect(operationsWithoutErr.LoginFailuresReset).To(BeFalse())
+		})
+		It("user of role demanding MFA gets token restricted to enrollment", func() {
+			handler.runtimeConfig.MFARequiredRoles = []string{"admin"}
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Role: "admin",
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring(`"mfa_enrollment_required":true`))
+		})
+		It("user with temporary password gets token restricted to password change", func() {
+			expiresAt := time.Now().Add(time.Hour)
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, IsTemporaryPassword: true,
+				TemporaryPasswordExpiresAt: &expiresAt,
+				PasswordHash:               "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(200))
+			var tokens map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &tokens)).To(Succeed())
+			Expect(tokens["password_change_required"]).To(Equal(true))
+			token, err := handler.tokens.ValidateJWT(tokens["access_token"].(string))
+			Expect(err).To(BeNil())
+			Expect(token.Claims.(jwt.MapClaims)).To(HaveKeyWithValue(auth.JWTClaimPasswordChangeRequired, true))
+		})
+		It("disabled user is rejected", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Disabled: true,
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrUserDisabled.Error()))
+		})
+		It("expired temporary password is rejected", func() {
+			expiresAt := time.Now().Add(-time.Minute)
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, IsTemporaryPassword: true,
+				TemporaryPasswordExpiresAt: &expiresAt,
+				PasswordHash:               "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "password": "sabari123"})
+			handler.login(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrTemporaryPasswordExpired.Error()))
+		})
+	})
+	Context("unlockUser", func() {
+		It("invalid/Non-numerical path param ID", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "abc"}}
+			handler.operations = &operationsWithoutErr
+			handler.unlockUser(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("user doesn't exist", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			handler.operations = &operationsUserDoesntExist
+			handler.unlockUser(ctx)
+			Expect(w.Code).To(Equal(404))
+		})
+		It("unlock user", func() {
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			handler.operations = &operationsWithoutErr
+			handler.unlockUser(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.LoginFailuresReset).To(BeTrue())
+		})
 	})
 	Context("getUserByID", func() {
 		It("invalid/Non-numerical path param ID", func() {
@@ -255,6 +469,19 @@ var _ = Describe("Users", func() {
 			Expect(w.Code).To(Equal(400))
 			Expect(w.Body.String()).To(ContainSubstring("User Role unknown doesn't exist"))
 		})
+		It("Role granted permissions beyond the requester's", func() {
+			handler.operations = &operationsWithoutErr
+			misc.SetRole(models.UserRole{Name: "user-manager", Permissions: []string{models.PermissionUserWrite}})
+			misc.SetRole(models.UserRole{Name: models.RoleAdmin, Permissions: []string{models.PermissionUserWrite,
+				models.PermissionRoleWrite}})
+			defer misc.RemoveRole("user-manager")
+			defer misc.SetRole(models.UserRole{Name: models.RoleAdmin})
+			ctx.Set("role", "user-manager")
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "admin@gmail.com", "role": "admin"})
+			handler.addUser(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrGrantingBeyondPermissions.Error()))
+		})
 		It("DB Internal error", func() {
 
 			handler.operations = &operationsInternalErr
@@ -297,6 +524,52 @@ var _ = Describe("Users", func() {
 			Expect(w.Code).To(Equal(201))
 			Expect(w.Body.String()).To(Not(BeEmpty()))
 		})
+
+		It("temporary password expires after configured duration", func() {
+			handler.operations = &operationsWithoutErr
+			handler.runtimeConfig.TemporaryPasswordExpirationInSeconds = 3600
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "admin@gmail.com", "role": "basic"})
+			handler.addUser(ctx)
+			Expect(w.Code).To(Equal(201))
+			Expect(operationsWithoutErr.TempPassExpiresAt).To(Not(BeNil()))
+			Expect(*operationsWithoutErr.TempPassExpiresAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+		})
+
+		It("temporary password is sent to the user, and not shown to admin", func() {
+			handler.operations = &operationsWithoutErr
+			handler.runtimeConfig.NotifyTemporaryPassword = true
+			ctx.Request.Header.Set("Content-Type", "application/json")
+			var payload = map[string]interface{}{
+				"name":  "",
+				"email": "admin@gmail.com",
+				"role":  "basic",
+			}
+			MockJsonPostOrPut(ctx, payload)
+			handler.addUser(ctx)
+			Expect(w.Code).To(Equal(201))
+			Expect(w.Body.String()).To(ContainSubstring("Temporary password is sent to admin@gmail.com"))
+			Expect(w.Body.String()).To(Not(ContainSubstring("temporary_password")))
+			messages := handler.notifier.(*NotifierMock).Messages
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].To).To(Equal("admin@gmail.com"))
+			Expect(messages[0].Body).To(HavePrefix("Hi admin@gmail.com,"))
+		})
+
+		It("temporary password delivery failure", func() {
+			handler.operations = &operationsWithoutErr
+			handler.runtimeConfig.NotifyTemporaryPassword = true
+			handler.notifier = &NotifierMock{SetInternalError: true}
+			ctx.Request.Header.Set("Content-Type", "application/json")
+			var payload = map[string]interface{}{
+				"name":  "admin",
+				"email": "admin@gmail.com",
+				"role":  "basic",
+			}
+			MockJsonPostOrPut(ctx, payload)
+			handler.addUser(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrTemporaryPasswordNotDelivered.Error()))
+		})
 	})
 	Context("updateUser", func() {
 
@@ -385,6 +658,20 @@ var _ = Describe("Users", func() {
 			Expect(w.Code).To(Equal(400))
 			Expect(w.Body.String()).To(ContainSubstring("User Role unknown doesn't exist"))
 		})
+		It("Role granted permissions beyond the requester's", func() {
+			handler.operations = &operationsWithoutErr
+			misc.SetRole(models.UserRole{Name: "user-manager", Permissions: []string{models.PermissionUserWrite}})
+			misc.SetRole(models.UserRole{Name: models.RoleAdmin, Permissions: []string{models.PermissionUserWrite,
+				models.PermissionRoleWrite}})
+			defer misc.RemoveRole("user-manager")
+			defer misc.SetRole(models.UserRole{Name: models.RoleAdmin})
+			ctx.Set("role", "user-manager")
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "email": "admin@gmail.com", "role": "admin"})
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			handler.updateUser(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrGrantingBeyondPermissions.Error()))
+		})
 
 		It("user with same email exist", func() {
 			handler.operations = &operationsDuplicateEmail
@@ -450,6 +737,7 @@ var _ = Describe("Users", func() {
 		})
 		It("Successful Update request", func() {
 			var user models.User
+			user.ID = 1
 			user.Email = "adminv2@gmail.com"
 			operationsWithoutErr.User = &user
 			handler.operations = &operationsWithoutErr
@@ -475,6 +763,19 @@ var _ = Describe("Users", func() {
 			}
 			Expect(w.Code).To(Equal(200))
 			Expect(recvUser.Email).To(Equal(user.Email))
+			Expect(operationsWithoutErr.ElevationsEndedOf).To(ConsistOf(uint(1)))
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ConsistOf("1"))
+		})
+		It("Update request keeping the role keeps elevations of the user", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@gmail.com",
+				Role: "basic"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "admin", "email": "admin@gmail.com", "role": "basic"})
+			ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+			handler.updateUser(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.ElevationsEndedOf).To(BeEmpty())
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(BeEmpty())
 		})
 	})
 	Context("deleteUser", func() {
@@ -533,6 +834,7 @@ var _ = Describe("Users", func() {
 			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrUserDoesNotExist.Error()))
 		})
 		It("successful deletion request", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@gmail.com"}
 			handler.operations = &operationsWithoutErr
 			ctx.Request.Header.Set("Content-Type", "application/json")
 			var payload = map[string]interface{}{
@@ -551,6 +853,7 @@ var _ = Describe("Users", func() {
 			handler.deleteUser(ctx)
 			Expect(w.Code).To(Equal(200))
 			Expect(w.Body.String()).To(ContainSubstring("User deleted from system"))
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ConsistOf("1"))
 		})
 	})
 	Context("fetchUsers", func() {
@@ -614,7 +917,7 @@ var _ = Describe("Users", func() {
 	})
 	Context("changeUserPassword", func() {
 
-		It("email context not set", func() {
+		It("user ID context not set", func() {
 			handler.operations = &operationsWithoutErr
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(500))
@@ -622,7 +925,7 @@ var _ = Describe("Users", func() {
 		})
 		It("Invalid payload", func() {
 			handler.operations = &operationsWithoutErr
-			ctx.Set("email", "admin@mgmtportal.com")
+			ctx.Set("sub", uint(1))
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(400))
 			Expect(w.Body.String()).To(ContainSubstring("User password change payload is invalid; Expected JSON payload"))
@@ -633,10 +936,11 @@ var _ = Describe("Users", func() {
 			ctx.Request.Header.Set("Content-Type", "application/json")
 			var payload = map[string]interface{}{}
 			MockJsonPostOrPut(ctx, payload)
-			ctx.Set("email", "admin@mgmtportal.com")
+			ctx.Set("sub", uint(1))
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(400))
 			Expect(w.Body.String()).To(ContainSubstring("User password change payload is invalid; Strictly Allowed Params"))
+			Expect(w.Body.String()).To(ContainSubstring(" or password[string]"))
 		})
 		It("empty Password", func() {
 			handler.operations = &operationsWithoutErr
@@ -645,12 +949,14 @@ var _ = Describe("Users", func() {
 				"password": "",
 			}
 			MockJsonPostOrPut(ctx, payload)
-			ctx.Set("email", "admin@mgmtportal.com")
+			ctx.Set("sub", uint(1))
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(400))
 			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrPasswordMissingOrEmpty.Error()))
 		})
-		It("Password length more than 72", func() {
+		It("Password longer than policy permits", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin1@mgmtportal.com",
+				IsTemporaryPassword: true}
 			handler.operations = &operationsWithoutErr
 			ctx.Request.Header.Set("Content-Type", "application/json")
 			var payload = map[string]interface{}{
@@ -659,21 +965,128 @@ var _ = Describe("Users", func() {
 					"behavior in tests, without needing a real database connection. It helps to maintain correct TDD workflow",
 			}
 			MockJsonPostOrPut(ctx, payload)
-			ctx.Set("email", "admin@mgmtportal.com")
+			ctx.Set("sub", uint(1))
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(400))
 			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrPasswordTooLong.Error()))
 		})
+		It("Password violating policy", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin1@mgmtportal.com",
+				IsTemporaryPassword: true}
+			handler.operations = &operationsWithoutErr
+			for password, expectedErr := range map[string]error{
+				"a":                     appErrors.ErrPasswordTooShort,
+				"mgmt-portal-2024":      appErrors.ErrPasswordMissingCharacterClass,
+				"Password1234":          appErrors.ErrPasswordTooCommon,
+				"Admin1@MgmtPortal.com": appErrors.ErrPasswordSameAsEmail,
+			} {
+				w = httptest.NewRecorder()
+				ctx = GetTestGinContext(w)
+				MockJsonPostOrPut(ctx, map[string]interface{}{"password": password})
+				ctx.Set("sub", uint(1))
+				handler.changeUserPassword(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring(expectedErr.Error()))
+			}
+			Expect(operationsWithoutErr.PasswordHistory).To(BeEmpty())
+		})
+		It("Recently used password", func() {
+			formerHash, _ := auth.GeneratePasswordHash("Mgmt-Portal-2023")
+			currentHash, _ := auth.GeneratePasswordHash("Mgmt-Portal-2024")
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				PasswordHash: currentHash}
+			operationsWithoutErr.PasswordHistory = []string{currentHash, formerHash}
+			handler.operations = &operationsWithoutErr
+			for _, password := range []string{"Mgmt-Portal-2023", "Mgmt-Portal-2024"} {
+				w = httptest.NewRecorder()
+				ctx = GetTestGinContext(w)
+				MockJsonPostOrPut(ctx, map[string]interface{}{"password": password, "current_password": "Mgmt-Portal-2024"})
+				ctx.Set("sub", uint(1))
+				handler.changeUserPassword(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrPasswordReused.Error()))
+			}
+		})
+		It("Current password missing", func() {
+			currentHash, _ := auth.GeneratePasswordHash("Mgmt-Portal-2023")
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				PasswordHash: currentHash}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"password": "Mgmt-Portal-2024"})
+			ctx.Set("sub", uint(1))
+			handler.changeUserPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrCurrentPasswordMissing.Error()))
+		})
+		It("Current password is wrong", func() {
+			handler.runtimeConfig.LoginMaxFailedAttempts = 2
+			handler.runtimeConfig.LoginLockoutDurationInSeconds = 60
+			currentHash, _ := auth.GeneratePasswordHash("Mgmt-Portal-2023")
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				PasswordHash: currentHash, FailedLoginAttempts: 1}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"password": "Mgmt-Portal-2024", "current_password": "guess"})
+			ctx.Set("sub", uint(1))
+			handler.changeUserPassword(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrCurrentPasswordMismatch.Error()))
+			Expect(operationsWithoutErr.LoginFailureCount).To(Equal(1))
+			Expect(operationsWithoutErr.LockedUntil).To(Not(BeNil()))
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(BeEmpty())
+		})
+		It("Locked account", func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			currentHash, _ := auth.GeneratePasswordHash("Mgmt-Portal-2023")
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				PasswordHash: currentHash, LockedUntil: &lockedUntil}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"password": "Mgmt-Portal-2024",
+				"current_password": "Mgmt-Portal-2023"})
+			ctx.Set("sub", uint(1))
+			handler.changeUserPassword(ctx)
+			Expect(w.Code).To(Equal(429))
+		})
+		It("Successful change request with current password", func() {
+			currentHash, _ := auth.GeneratePasswordHash("Mgmt-Portal-2023")
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				PasswordHash: currentHash}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"password": "Mgmt-Portal-2024",
+				"current_password": "Mgmt-Portal-2023"})
+			ctx.Set("sub", uint(1))
+			handler.changeUserPassword(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("access_token"))
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ConsistOf("1"))
+		})
 		It("Successful change request", func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				IsTemporaryPassword: true}
 			handler.operations = &operationsWithoutErr
 			ctx.Request.Header.Set("Content-Type", "application/json")
 			var payload = map[string]interface{}{
-				"password": "admin123",
+				"password": "Mgmt-Portal-2024",
 			}
 			MockJsonPostOrPut(ctx, payload)
-			ctx.Set("email", "admin@mgmtportal.com")
+			ctx.Set("sub", uint(1))
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("access_token"))
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ConsistOf("1"))
+			Expect(operationsWithoutErr.PasswordHistory).To(HaveLen(1))
+			Expect(auth.CompareHashAndPassword(operationsWithoutErr.PasswordHistory[0], []byte("Mgmt-Portal-2024"))).
+				To(BeTrue())
+		})
+		It("Expired temporary password", func() {
+			expiresAt := time.Now().Add(-time.Minute)
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				IsTemporaryPassword: true, TemporaryPasswordExpiresAt: &expiresAt}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"password": "Mgmt-Portal-2024"})
+			ctx.Set("sub", uint(1))
+			handler.changeUserPassword(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrTemporaryPasswordExpired.Error()))
 		})
 		It("DB Internal error", func() {
 			handler.operations = &operationsInternalErr
@@ -682,7 +1095,7 @@ var _ = Describe("Users", func() {
 				"password": "admin123",
 			}
 			MockJsonPostOrPut(ctx, payload)
-			ctx.Set("email", "admin@mgmtportal.com")
+			ctx.Set("sub", uint(1))
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(500))
 			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
@@ -695,11 +1108,1180 @@ var _ = Describe("Users", func() {
 				"password": "admin123",
 			}
 			MockJsonPostOrPut(ctx, payload)
-			ctx.Set("email", "admin@mgmtportal.com")
+			ctx.Set("sub", uint(1))
 			handler.changeUserPassword(ctx)
 			Expect(w.Code).To(Equal(404))
 			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrUserDoesNotExist.Error()))
 
 		})
 	})
+	Context("refreshToken", func() {
+		It("Invalid payload", func() {
+			handler.operations = &operationsWithoutErr
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Token refresh payload is invalid; Expected JSON payload"))
+		})
+		It("Missing refresh token in payload", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Token refresh payload is invalid; Strictly Allowed Params:"))
+		})
+		It("Empty refresh token", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": ""})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("refresh_token is empty"))
+		})
+		It("DB Internal error", func() {
+			handler.operations = &operationsInternalErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+		It("Unknown or expired refresh token", func() {
+			handler.operations = &UserMock{SetTokenInvalid: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredRefreshToken.Error()))
+		})
+		It("Replayed refresh token", func() {
+			handler.operations = &UserMock{SetTokenReused: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrRefreshTokenReused.Error()))
+		})
+		It("Token owner no longer exists", func() {
+			handler.operations = &operationsEmailOrIDNotFound
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredRefreshToken.Error()))
+		})
+		It("Successful refresh", func() {
+			operationsWithoutErr.User = &models.User{Email: "admin@mgmtportal.com", Role: "admin"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(200))
+			var tokens map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &tokens)
+			if err != nil {
+				Fail(fmt.Sprintf("Internal error: %v", err))
+			}
+			Expect(tokens["access_token"]).To(Not(BeEmpty()))
+			Expect(tokens["refresh_token"]).To(Not(BeEmpty()))
+			Expect(tokens["refresh_token"]).To(Not(Equal("xyz")))
+		})
+		It("Refreshed token is issued within the session, which is marked as seen", func() {
+			lastSeenAt := time.Now().Add(-time.Hour)
+			operationsWithoutErr.User = &models.User{Email: "admin@mgmtportal.com", Role: "admin"}
+			operationsWithoutErr.Sessions = []models.Session{{ID: 5, UserID: 1, FamilyID: "family",
+				LastSeenAt: lastSeenAt}}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.Sessions[0].LastSeenAt).To(BeTemporally(">", lastSeenAt))
+			var tokens map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &tokens)).To(Succeed())
+			token, err := handler.tokens.ValidateJWT(tokens["access_token"].(string))
+			Expect(err).To(BeNil())
+			Expect(token.Claims.(jwt.MapClaims)[auth.JWTClaimSessionID]).To(Equal("5"))
+		})
+		It("Refreshed token carries the role of active elevation till the elevation expires", func() {
+			misc.SetRole(models.UserRole{Name: models.RoleAdmin, Parent: models.RoleAdvanced})
+			elevationExpiresAt := time.Now().Add(2 * time.Second).Truncate(time.Second)
+			operationsWithoutErr.User = &models.User{Email: "advanced@mgmtportal.com", Role: models.RoleAdvanced}
+			operationsWithoutErr.ActiveElevation = &models.RoleElevation{UserID: 1, Role: models.RoleAdmin,
+				Status: models.ElevationStatusApproved, ExpiresAt: &elevationExpiresAt}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(200))
+			var tokens map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &tokens)).To(Succeed())
+			token, err := handler.tokens.ValidateJWT(tokens["access_token"].(string))
+			Expect(err).To(BeNil())
+			Expect(token.Claims.(jwt.MapClaims)[auth.JWTClaimRole]).To(Equal(models.RoleAdmin))
+			expiresAt, err := token.Claims.GetExpirationTime()
+			Expect(err).To(BeNil())
+			Expect(expiresAt.Time).To(BeTemporally("==", elevationExpiresAt))
+			misc.RemoveRole(models.RoleAdmin)
+		})
+		It("Elevation to role deleted since its approval is disregarded", func() {
+			elevationExpiresAt := time.Now().Add(time.Hour)
+			operationsWithoutErr.User = &models.User{Email: "basic@mgmtportal.com", Role: "basic"}
+			operationsWithoutErr.ActiveElevation = &models.RoleElevation{UserID: 1, Role: "auditor",
+				Status: models.ElevationStatusApproved, ExpiresAt: &elevationExpiresAt}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(200))
+			var tokens map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &tokens)).To(Succeed())
+			token, err := handler.tokens.ValidateJWT(tokens["access_token"].(string))
+			Expect(err).To(BeNil())
+			Expect(token.Claims.(jwt.MapClaims)[auth.JWTClaimRole]).To(Equal("basic"))
+		})
+		It("Elevation to role no longer granted every permission of the user's role is disregarded", func() {
+			misc.SetRole(models.UserRole{Name: "release-manager", Permissions: []string{models.PermissionServiceWrite}})
+			misc.SetRole(models.UserRole{Name: "auditor", Permissions: []string{models.PermissionUserRead}})
+			defer misc.RemoveRole("release-manager")
+			defer misc.RemoveRole("auditor")
+			elevationExpiresAt := time.Now().Add(time.Hour)
+			operationsWithoutErr.User = &models.User{Email: "release@mgmtportal.com", Role: "release-manager"}
+			operationsWithoutErr.ActiveElevation = &models.RoleElevation{UserID: 1, Role: "auditor",
+				Status: models.ElevationStatusApproved, ExpiresAt: &elevationExpiresAt}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(200))
+			var tokens map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &tokens)).To(Succeed())
+			token, err := handler.tokens.ValidateJWT(tokens["access_token"].(string))
+			Expect(err).To(BeNil())
+			Expect(token.Claims.(jwt.MapClaims)[auth.JWTClaimRole]).To(Equal("release-manager"))
+		})
+		It("Disabled user", func() {
+			operationsWithoutErr.User = &models.User{Email: "admin@mgmtportal.com", Role: "admin", Disabled: true}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrUserDisabled.Error()))
+		})
+		It("Expired temporary password", func() {
+			expiresAt := time.Now().Add(-time.Minute)
+			operationsWithoutErr.User = &models.User{Email: "admin@mgmtportal.com", Role: "admin",
+				IsTemporaryPassword: true, TemporaryPasswordExpiresAt: &expiresAt}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "xyz"})
+			handler.refreshToken(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrTemporaryPasswordExpired.Error()))
+		})
+	})
+	Context("logout", func() {
+		BeforeEach(func() {
+			ctx.Set("sub", uint(1))
+			ctx.Set("jti", "xyz")
+			ctx.Set("exp", time.Now().Add(time.Minute))
+		})
+		It("token claims not set in context", func() {
+			handler.operations = &operationsWithoutErr
+			ctx = GetTestGinContext(w)
+			handler.logout(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+		It("Invalid payload", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "abc"})
+			ctx.Request.ContentLength = 15
+			handler.logout(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Logout payload is invalid; Strictly Allowed Params"))
+		})
+		It("DB Internal error while revoking refresh tokens", func() {
+			handler.operations = &operationsInternalErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "abc"})
+			ctx.Request.ContentLength = 23
+			handler.logout(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+		It("Internal error while revoking access token", func() {
+			handler.operations = &operationsWithoutErr
+			handler.revoker = &RevocationMock{SetInternalError: true}
+			handler.logout(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+		It("Successful logout", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"refresh_token": "abc"})
+			ctx.Request.ContentLength = 23
+			handler.logout(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("Logged out successfully"))
+			Expect(handler.revoker.(*RevocationMock).RevokedTokens).To(ConsistOf("xyz"))
+		})
+	})
+	Context("createAccessToken", func() {
+		BeforeEach(func() {
+			ctx.Set("sub", uint(1))
+			ctx.Set("role", models.RoleAdvanced)
+			misc.SetRole(models.UserRole{Name: models.RoleAdvanced, Permissions: []string{models.PermissionServiceRead,
+				models.PermissionServiceWrite, models.PermissionUserRead, models.PermissionRoleRead}})
+		})
+		It("identity not set in context", func() {
+			handler.operations = &operationsWithoutErr
+			ctx = GetTestGinContext(w)
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+		It("Invalid payload", func() {
+			handler.operations = &operationsWithoutErr
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Personal access token payload is invalid; Expected JSON payload"))
+		})
+		It("Missing fields in payload", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci"})
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Personal access token payload is invalid; Strictly Allowed Params"))
+		})
+		It("Empty name", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "", "scopes": []string{"service:read"}, "expires_in_days": 30})
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("name is empty"))
+		})
+		It("Empty scopes", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "scopes": []string{}, "expires_in_days": 30})
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("scopes are empty"))
+		})
+		It("Scope beyond user's role", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "scopes": []string{"user:write"}, "expires_in_days": 30})
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Scope user:write can't be granted"))
+		})
+		It("Expiry beyond allowed lifetime", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "scopes": []string{"service:write"}, "expires_in_days": 366})
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("expires_in_days should be between 1 and 365"))
+		})
+		It("DB Internal error", func() {
+			handler.operations = &operationsInternalErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci", "scopes": []string{"service:write"}, "expires_in_days": 30})
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+		It("Successful creation", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"name": "ci",
+				"scopes": []string{"service:write", "service:read", "service:write"}, "expires_in_days": 30})
+			handler.createAccessToken(ctx)
+			Expect(w.Code).To(Equal(201))
+			var created map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &created)
+			if err != nil {
+				Fail(fmt.Sprintf("Internal error: %v", err))
+			}
+			Expect(created["token"]).To(HavePrefix("pat_"))
+			Expect(created["token"]).To(HavePrefix(created["prefix"].(string)))
+			Expect(created["scopes"]).To(ConsistOf("service:write", "service:read"))
+			Expect(created).To(Not(HaveKey("token_hash")))
+		})
+	})
+	Context("fetchAccessTokens", func() {
+		It("user ID not set in context", func() {
+			handler.operations = &operationsWithoutErr
+			handler.fetchAccessTokens(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("DB Internal error", func() {
+			handler.operations = &operationsInternalErr
+			ctx.Set("sub", uint(1))
+			handler.fetchAccessTokens(ctx)
+			Expect(w.Code).To(Equal(500))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+		})
+		It("Successful fetch", func() {
+			handler.operations = &operationsWithoutErr
+			ctx.Set("sub", uint(1))
+			handler.fetchAccessTokens(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("pat_abcdefgh"))
+		})
+	})
+	Context("revokeAccessToken", func() {
+		BeforeEach(func() {
+			ctx.Set("sub", uint(1))
+			ctx.Params = []gin.Param{{Key: "id", Value: "3"}}
+		})
+		It("Non numerical ID", func() {
+			handler.operations = &operationsWithoutErr
+			ctx.Params = []gin.Param{{Key: "id", Value: "abc"}}
+			handler.revokeAccessToken(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Personal access token ID should be numerical"))
+		})
+		It("Token doesn't exist", func() {
+			handler.operations = &UserMock{SetTokenInvalid: true}
+			handler.revokeAccessToken(ctx)
+			Expect(w.Code).To(Equal(404))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrAccessTokenDoesNotExist.Error()))
+		})
+		It("DB Internal error", func() {
+			handler.operations = &operationsInternalErr
+			handler.revokeAccessToken(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Successful revocation", func() {
+			handler.operations = &operationsWithoutErr
+			handler.revokeAccessToken(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("Personal access token revoked"))
+		})
+	})
+	Context("Sessions", func() {
+		BeforeEach(func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 2}}
+			operationsWithoutErr.Sessions = []models.Session{{ID: 4, UserID: 1, UserAgent: "Mozilla/5.0"},
+				{ID: 5, UserID: 1, UserAgent: "curl/8.0"}, {ID: 6, UserID: 2, UserAgent: "Mozilla/5.0"}}
+			handler.operations = &operationsWithoutErr
+			ctx.Set("sub", uint(1))
+			ctx.Set("sid", uint(5))
+		})
+		Context("fetchSessions", func() {
+			It("user ID not set in context", func() {
+				handler.fetchSessions(GetTestGinContext(w))
+				Expect(w.Code).To(Equal(500))
+			})
+			It("DB Internal error", func() {
+				handler.operations = &operationsInternalErr
+				handler.fetchSessions(ctx)
+				Expect(w.Code).To(Equal(500))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrFailureToProcessRequest.Error()))
+			})
+			It("Sessions of the user are listed, marking the current one", func() {
+				handler.fetchSessions(ctx)
+				Expect(w.Code).To(Equal(200))
+				var sessions []map[string]interface{}
+				Expect(json.Unmarshal(w.Body.Bytes(), &sessions)).To(Succeed())
+				Expect(sessions).To(HaveLen(2))
+				Expect(sessions[0]["id"]).To(Equal(float64(4)))
+				Expect(sessions[0]["current"]).To(BeFalse())
+				Expect(sessions[1]["current"]).To(BeTrue())
+				Expect(sessions[1]["user_agent"]).To(Equal("curl/8.0"))
+				Expect(sessions[1]).To(Not(HaveKey("family_id")))
+			})
+		})
+		Context("revokeSession", func() {
+			It("Non numerical ID", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "abc"}}
+				handler.revokeSession(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("Session ID should be numerical"))
+			})
+			It("Session of other user", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "6"}}
+				handler.revokeSession(ctx)
+				Expect(w.Code).To(Equal(404))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrSessionDoesNotExist.Error()))
+				Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(BeEmpty())
+			})
+			It("Internal error while revoking access tokens", func() {
+				handler.revoker = &RevocationMock{SetInternalError: true}
+				ctx.Params = []gin.Param{{Key: "id", Value: "4"}}
+				handler.revokeSession(ctx)
+				Expect(w.Code).To(Equal(500))
+			})
+			It("Successful revocation revokes tokens of the session alone", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "4"}}
+				handler.revokeSession(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(w.Body.String()).To(ContainSubstring("Session revoked"))
+				Expect(operationsWithoutErr.RevokedSessionIDs).To(Equal([]uint{4}))
+				Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ConsistOf("session:4"))
+			})
+		})
+		Context("fetchUserSessions", func() {
+			It("Non numerical ID", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "abc"}}
+				handler.fetchUserSessions(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("User ID should be numerical"))
+			})
+			It("User doesn't exist", func() {
+				operationsWithoutErr.SetUserDoesntExist = true
+				ctx.Params = []gin.Param{{Key: "id", Value: "3"}}
+				handler.fetchUserSessions(ctx)
+				Expect(w.Code).To(Equal(404))
+			})
+			It("Sessions of the user are listed", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+				handler.fetchUserSessions(ctx)
+				Expect(w.Code).To(Equal(200))
+				var sessions []map[string]interface{}
+				Expect(json.Unmarshal(w.Body.Bytes(), &sessions)).To(Succeed())
+				Expect(sessions).To(HaveLen(1))
+				Expect(sessions[0]["id"]).To(Equal(float64(6)))
+				Expect(sessions[0]["current"]).To(BeFalse())
+			})
+		})
+		Context("revokeUserSession", func() {
+			It("Non numerical session ID", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "2"}, {Key: "sid", Value: "abc"}}
+				handler.revokeUserSession(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("Session ID should be numerical"))
+			})
+			It("Session of other user", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "2"}, {Key: "sid", Value: "4"}}
+				handler.revokeUserSession(ctx)
+				Expect(w.Code).To(Equal(404))
+			})
+			It("DB Internal error", func() {
+				handler.operations = &operationsInternalErr
+				ctx.Params = []gin.Param{{Key: "id", Value: "2"}, {Key: "sid", Value: "6"}}
+				handler.revokeUserSession(ctx)
+				Expect(w.Code).To(Equal(500))
+			})
+			It("Successful revocation", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "2"}, {Key: "sid", Value: "6"}}
+				handler.revokeUserSession(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(operationsWithoutErr.RevokedSessionIDs).To(Equal([]uint{6}))
+				Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ConsistOf("session:6"))
+			})
+		})
+	})
+	Context("Impersonation", func() {
+		BeforeEach(func() {
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 2}, Email: "basic@mgmtportal.com",
+				Role: models.RoleBasic}
+			operationsWithoutErr.ImpersonationAudits = []models.ImpersonationAudit{
+				{ID: 3, ActorID: 1, UserID: 2, Method: http.MethodGet, Path: "/api/v1/services"},
+				{ID: 4, ActorID: 1, UserID: 5, Method: http.MethodGet, Path: "/api/v1/services"}}
+			handler.operations = &operationsWithoutErr
+			handler.runtimeConfig.ImpersonationExpirationInSeconds = 60
+			ctx.Set("sub", uint(1))
+			ctx.Set("role", models.RoleAdvanced)
+			misc.SetRole(models.UserRole{Name: models.RoleAdvanced, Permissions: []string{models.PermissionUserRead,
+				models.PermissionUserWrite}})
+			ctx.Params = []gin.Param{{Key: "id", Value: "2"}}
+		})
+		Context("impersonateUser", func() {
+			It("user ID not set in context", func() {
+				handler.impersonateUser(GetTestGinContext(w))
+				Expect(w.Code).To(Equal(500))
+			})
+			It("Non numerical ID", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "abc"}}
+				handler.impersonateUser(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("User ID should be numerical"))
+			})
+			It("User doesn't exist", func() {
+				handler.operations = &operationsUserDoesntExist
+				handler.impersonateUser(ctx)
+				Expect(w.Code).To(Equal(404))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrUserDoesNotExist.Error()))
+			})
+			It("DB Internal error", func() {
+				handler.operations = &operationsInternalErr
+				handler.impersonateUser(ctx)
+				Expect(w.Code).To(Equal(500))
+			})
+			It("User granted user:write can't be impersonated, whatever role grants it", func() {
+				misc.SetRole(models.UserRole{Name: "user-manager", Permissions: []string{models.PermissionUserWrite}})
+				defer misc.RemoveRole("user-manager")
+				operationsWithoutErr.User.Role = "user-manager"
+				handler.impersonateUser(ctx)
+				Expect(w.Code).To(Equal(403))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrImpersonatingAdmin.Error()))
+			})
+			It("User granted permissions beyond the requester's can't be impersonated", func() {
+				misc.SetRole(models.UserRole{Name: models.RoleBasic, Permissions: []string{models.PermissionServiceWrite}})
+				defer misc.SetRole(models.UserRole{Name: models.RoleBasic})
+				handler.impersonateUser(ctx)
+				Expect(w.Code).To(Equal(403))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrImpersonatingBeyondPermissions.Error()))
+			})
+			It("Token of the user is issued, carrying the admin as actor", func() {
+				handler.impersonateUser(ctx)
+				Expect(w.Code).To(Equal(200))
+				var response utils.ClientCredentialsTokenResponse
+				Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.ExpiresIn).To(Equal(int64(60)))
+				token, err := handler.tokens.ValidateJWT(response.AccessToken)
+				Expect(err).To(BeNil())
+				claims := token.Claims.(jwt.MapClaims)
+				Expect(claims["sub"]).To(Equal("2"))
+				Expect(claims["role"]).To(Equal(models.RoleBasic))
+				Expect(claims).To(Not(HaveKey("sid")))
+				actorID, err := auth.ActorUserIDFromClaim(claims["act"])
+				Expect(err).To(BeNil())
+				Expect(actorID).To(Equal(uint(1)))
+			})
+			It("Token doesn't outlive access tokens", func() {
+				handler.runtimeConfig.ImpersonationExpirationInSeconds = 3600
+				handler.impersonateUser(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(w.Body.String()).To(ContainSubstring(`"expires_in":100`))
+			})
+		})
+		Context("fetchImpersonationAudits", func() {
+			It("Non numerical ID", func() {
+				ctx.Params = []gin.Param{{Key: "id", Value: "abc"}}
+				handler.fetchImpersonationAudits(ctx)
+				Expect(w.Code).To(Equal(400))
+			})
+			It("DB Internal error", func() {
+				handler.operations = &operationsInternalErr
+				handler.fetchImpersonationAudits(ctx)
+				Expect(w.Code).To(Equal(500))
+			})
+			It("Requests made while impersonating the user are listed", func() {
+				handler.fetchImpersonationAudits(ctx)
+				Expect(w.Code).To(Equal(200))
+				var audits []models.ImpersonationAudit
+				Expect(json.Unmarshal(w.Body.Bytes(), &audits)).To(Succeed())
+				Expect(audits).To(HaveLen(1))
+				Expect(audits[0].ID).To(Equal(uint(3)))
+				Expect(audits[0].ActorID).To(Equal(uint(1)))
+			})
+		})
+	})
+	Context("MFA", func() {
+		var (
+			secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+			code   = func(t time.Time) string {
+				code, _ := auth.GenerateTOTPCode(secret, t)
+				return code
+			}
+		)
+		BeforeEach(func() {
+			handler.runtimeConfig.MFAIssuer = "userservice"
+			handler.runtimeConfig.MFARecoveryCodeCount = 10
+			handler.runtimeConfig.MFAChallengeExpirationInSeconds = 60
+			handler.runtimeConfig.LoginMaxFailedAttempts = 3
+			handler.runtimeConfig.LoginLockoutDurationInSeconds = 60
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				Role: "admin", MFAEnabled: true, MFASecret: secret}
+			ctx.Set("sub", uint(1))
+		})
+		Context("loginWithMFA", func() {
+			var challenge string
+			BeforeEach(func() {
+				challenge, _ = handler.tokens.CreateMFAChallenge(1, time.Minute)
+			})
+			It("Invalid payload", func() {
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now())})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("MFA login payload is invalid; Strictly Allowed Params:"))
+			})
+			It("Invalid challenge token", func() {
+				handler.operations = &operationsWithoutErr
+				token, _ := handler.tokens.CreateJWT(auth.TokenSubject{UserID: 1, Role: "admin"})
+				MockJsonPostOrPut(ctx, map[string]interface{}{"mfa_token": *token, "code": code(time.Now())})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredMFAToken.Error()))
+			})
+			It("MFA reset since the challenge", func() {
+				operationsWithoutErr.User.MFAEnabled = false
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"mfa_token": challenge, "code": code(time.Now())})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredMFAToken.Error()))
+			})
+			It("Wrong code is accounted as failed login attempt", func() {
+				operationsWithoutErr.User.FailedLoginAttempts = 2
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"mfa_token": challenge, "code": "000000"})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidMFACode.Error()))
+				Expect(operationsWithoutErr.LoginFailureCount).To(Equal(1))
+				Expect(operationsWithoutErr.LockedUntil).To(Not(BeNil()))
+			})
+			It("Locked account", func() {
+				lockedUntil := time.Now().Add(time.Minute)
+				operationsWithoutErr.User.LockedUntil = &lockedUntil
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"mfa_token": challenge, "code": code(time.Now())})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(429))
+				Expect(w.Header().Get("Retry-After")).To(Equal("60"))
+			})
+			It("Successful login with TOTP code, which can't be replayed", func() {
+				handler.operations = &operationsWithoutErr
+				totpCode := code(time.Now())
+				MockJsonPostOrPut(ctx, map[string]interface{}{"mfa_token": challenge, "code": totpCode})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(w.Body.String()).To(ContainSubstring("access_token"))
+				Expect(w.Body.String()).To(ContainSubstring("refresh_token"))
+
+				w = httptest.NewRecorder()
+				ctx = GetTestGinContext(w)
+				MockJsonPostOrPut(ctx, map[string]interface{}{"mfa_token": challenge, "code": totpCode})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(401))
+			})
+			It("Successful login with recovery code, which is consumed", func() {
+				operationsWithoutErr.RecoveryCodeHashes = []string{auth.HashRecoveryCode("abcde-fghjk")}
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"mfa_token": challenge, "code": "ABCDE-FGHJK"})
+				handler.loginWithMFA(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(operationsWithoutErr.RecoveryCodeHashes).To(BeEmpty())
+			})
+		})
+		Context("enrollMFA", func() {
+			It("MFA already enabled", func() {
+				handler.operations = &operationsWithoutErr
+				handler.enrollMFA(ctx)
+				Expect(w.Code).To(Equal(409))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrMFAAlreadyEnabled.Error()))
+			})
+			It("DB Internal error", func() {
+				handler.operations = &operationsInternalErr
+				handler.enrollMFA(ctx)
+				Expect(w.Code).To(Equal(500))
+			})
+			It("Successful enrollment", func() {
+				operationsWithoutErr.User.MFAEnabled = false
+				handler.operations = &operationsWithoutErr
+				handler.enrollMFA(ctx)
+				Expect(w.Code).To(Equal(200))
+				var enrollment models.MFAEnrollment
+				Expect(json.Unmarshal(w.Body.Bytes(), &enrollment)).To(Succeed())
+				Expect(enrollment.Secret).To(Equal(operationsWithoutErr.User.MFAPendingSecret))
+				Expect(enrollment.OTPAuthURI).To(HavePrefix("otpauth://totp/userservice:admin@mgmtportal.com?"))
+				Expect(operationsWithoutErr.User.MFAEnabled).To(BeFalse())
+			})
+		})
+		Context("confirmMFA", func() {
+			BeforeEach(func() {
+				operationsWithoutErr.User.MFAEnabled = false
+				operationsWithoutErr.User.MFASecret = ""
+				operationsWithoutErr.User.MFAPendingSecret = secret
+			})
+			It("Enrollment not started", func() {
+				operationsWithoutErr.User.MFAPendingSecret = ""
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now())})
+				handler.confirmMFA(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrMFAEnrollmentNotStarted.Error()))
+			})
+			It("Wrong code", func() {
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now().Add(-time.Hour))})
+				handler.confirmMFA(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidMFACode.Error()))
+				Expect(operationsWithoutErr.User.MFAEnabled).To(BeFalse())
+			})
+			It("Successful confirmation", func() {
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now())})
+				handler.confirmMFA(ctx)
+				Expect(w.Code).To(Equal(200))
+				var recoveryCodes models.MFARecoveryCodes
+				Expect(json.Unmarshal(w.Body.Bytes(), &recoveryCodes)).To(Succeed())
+				Expect(recoveryCodes.RecoveryCodes).To(HaveLen(10))
+				Expect(operationsWithoutErr.RecoveryCodeHashes).To(ContainElement(
+					auth.HashRecoveryCode(recoveryCodes.RecoveryCodes[0])))
+				Expect(operationsWithoutErr.User.MFAEnabled).To(BeTrue())
+				Expect(operationsWithoutErr.User.MFASecret).To(Equal(secret))
+				Expect(operationsWithoutErr.User.MFALastUsedStep).To(Equal(auth.TOTPStep(time.Now())))
+			})
+		})
+		Context("disableMFA", func() {
+			It("MFA not enabled", func() {
+				operationsWithoutErr.User.MFAEnabled = false
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now())})
+				handler.disableMFA(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrMFANotEnabled.Error()))
+			})
+			It("MFA demanded by role", func() {
+				handler.runtimeConfig.MFARequiredRoles = []string{"admin"}
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now())})
+				handler.disableMFA(ctx)
+				Expect(w.Code).To(Equal(403))
+				Expect(operationsWithoutErr.User.MFAEnabled).To(BeTrue())
+			})
+			It("Wrong code", func() {
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": "000000"})
+				handler.disableMFA(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(operationsWithoutErr.User.MFAEnabled).To(BeTrue())
+			})
+			It("Successfully disabled", func() {
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now())})
+				handler.disableMFA(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(operationsWithoutErr.User.MFAEnabled).To(BeFalse())
+			})
+		})
+		Context("regenerateRecoveryCodes", func() {
+			It("Successfully regenerated", func() {
+				operationsWithoutErr.RecoveryCodeHashes = []string{auth.HashRecoveryCode("abcde-fghjk")}
+				handler.operations = &operationsWithoutErr
+				MockJsonPostOrPut(ctx, map[string]interface{}{"code": code(time.Now())})
+				handler.regenerateRecoveryCodes(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(operationsWithoutErr.RecoveryCodeHashes).To(HaveLen(10))
+				Expect(operationsWithoutErr.RecoveryCodeHashes).To(Not(ContainElement(
+					auth.HashRecoveryCode("abcde-fghjk"))))
+			})
+		})
+		Context("resetUserMFA", func() {
+			It("User doesn't exist", func() {
+				handler.operations = &operationsUserDoesntExist
+				ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+				handler.resetUserMFA(ctx)
+				Expect(w.Code).To(Equal(404))
+			})
+			It("Successful reset", func() {
+				handler.operations = &operationsWithoutErr
+				ctx.Params = []gin.Param{{Key: "id", Value: "1"}}
+				handler.resetUserMFA(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(operationsWithoutErr.User.MFAEnabled).To(BeFalse())
+			})
+		})
+	})
+	Context("forgotPassword", func() {
+		BeforeEach(func() {
+			handler.runtimeConfig.PasswordResetTokenExpirationInSeconds = 1800
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Name: "admin",
+				Email: "admin@mgmtportal.com"}
+		})
+		It("Invalid payload", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com", "role": "admin"})
+			handler.forgotPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Password forgot payload is invalid; Strictly Allowed Params:"))
+		})
+		It("Invalid email", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin"})
+			handler.forgotPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+		It("DB Internal error", func() {
+			handler.operations = &operationsInternalErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com"})
+			handler.forgotPassword(ctx)
+			Expect(w.Code).To(Equal(500))
+		})
+		It("Unknown email is responded alike, without notification", func() {
+			handler.operations = &operationsEmailOrIDNotFound
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "unknown@mgmtportal.com"})
+			handler.forgotPassword(ctx)
+			handler.wg.Wait()
+			Expect(w.Code).To(Equal(202))
+			Expect(w.Body.String()).To(ContainSubstring("If the email is registered"))
+			Expect(handler.notifier.(*NotifierMock).Messages).To(BeEmpty())
+		})
+		It("Locked client IP is rejected", func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			operationsWithoutErr.LoginFailure = &models.LoginFailure{LockedUntil: &lockedUntil}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com"})
+			handler.forgotPassword(ctx)
+			handler.wg.Wait()
+			Expect(w.Code).To(Equal(429))
+			Expect(w.Header().Get("Retry-After")).To(Not(BeEmpty()))
+			Expect(handler.notifier.(*NotifierMock).Messages).To(BeEmpty())
+		})
+		It("Request counts against client IP", func() {
+			handler.operations = &operationsEmailOrIDNotFound
+			operationsEmailOrIDNotFound.LoginFailure = nil
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "unknown@mgmtportal.com"})
+			handler.forgotPassword(ctx)
+			Expect(w.Code).To(Equal(202))
+			Expect(operationsEmailOrIDNotFound.LoginFailure.FailedAttempts).To(Equal(1))
+		})
+		It("Delivery failure is responded alike", func() {
+			handler.operations = &operationsWithoutErr
+			handler.notifier = &NotifierMock{SetInternalError: true}
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com"})
+			handler.forgotPassword(ctx)
+			handler.wg.Wait()
+			Expect(w.Code).To(Equal(202))
+		})
+		It("Reset token is delivered through notifier and only its hash is persisted", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com"})
+			handler.forgotPassword(ctx)
+			handler.wg.Wait()
+			Expect(w.Code).To(Equal(202))
+			Expect(w.Body.String()).To(ContainSubstring("If the email is registered"))
+			messages := handler.notifier.(*NotifierMock).Messages
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].To).To(Equal("admin@mgmtportal.com"))
+			Expect(operationsWithoutErr.ResetToken.ExpiresAt).To(BeTemporally("~",
+				time.Now().Add(30*time.Minute), time.Second))
+			Expect(messages[0].Body).To(Not(ContainSubstring(operationsWithoutErr.ResetTokenHash)))
+		})
+		It("Reset link is delivered if reset page is configured", func() {
+			handler.runtimeConfig.PasswordResetURL = "https://mgmtportal.com/reset"
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"email": "admin@mgmtportal.com"})
+			handler.forgotPassword(ctx)
+			handler.wg.Wait()
+			Expect(w.Code).To(Equal(202))
+			Expect(handler.notifier.(*NotifierMock).Messages[0].Body).To(
+				ContainSubstring("https://mgmtportal.com/reset?token="))
+		})
+	})
+	Context("resetPassword", func() {
+		resetToken := "reset-token"
+		BeforeEach(func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Email: "admin@mgmtportal.com",
+				PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2",
+				LockedUntil:  &lockedUntil}
+			operationsWithoutErr.ResetToken = &models.PasswordResetToken{UserID: 1,
+				TokenHash: auth.HashOpaqueToken(resetToken), ExpiresAt: time.Now().Add(time.Minute)}
+		})
+		It("Invalid payload", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"password": "Mgmt-Portal-2024"})
+			handler.resetPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Password reset payload is invalid; Strictly Allowed Params:"))
+		})
+		It("Unknown token", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": "other", "password": "Mgmt-Portal-2024"})
+			handler.resetPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredResetToken.Error()))
+		})
+		It("Password violating policy doesn't consume the token", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": resetToken, "password": "short"})
+			handler.resetPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrPasswordTooShort.Error()))
+			Expect(operationsWithoutErr.ResetTokenConsumed).To(BeFalse())
+		})
+		It("Token used concurrently", func() {
+			operationsWithoutErr.SetTokenReused = true
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": resetToken, "password": "Mgmt-Portal-2024"})
+			handler.resetPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredResetToken.Error()))
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(BeEmpty())
+		})
+		It("Successful reset revokes sessions and lifts lockout", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": resetToken, "password": "Mgmt-Portal-2024"})
+			handler.resetPassword(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("Password reset successfully"))
+			Expect(operationsWithoutErr.ResetTokenConsumed).To(BeTrue())
+			Expect(operationsWithoutErr.PasswordHistory).To(HaveLen(1))
+			Expect(operationsWithoutErr.LoginFailuresReset).To(BeTrue())
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ContainElement("1"))
+
+			// token is single use
+			w = httptest.NewRecorder()
+			ctx = GetTestGinContext(w)
+			MockJsonPostOrPut(ctx, map[string]interface{}{"token": resetToken, "password": "Other-Portal-2024"})
+			handler.resetPassword(ctx)
+			Expect(w.Code).To(Equal(400))
+		})
+	})
+	Context("Single sign-on", func() {
+		var (
+			idp    *oidctest.FakeIdP
+			claims jwt.MapClaims
+			// signIn starts the login, signs in at identity provider and responds with the callback payload
+			signIn = func() map[string]interface{} {
+				handler.startOIDCLogin(ctx)
+				Expect(w.Code).To(Equal(200))
+				var response utils.AuthorizationURLResponse
+				Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(BeNil())
+				code, state, err := idp.Authorize(response.AuthorizationURL, claims)
+				Expect(err).To(BeNil())
+				w = httptest.NewRecorder()
+				ctx = GetTestGinContext(w)
+				return map[string]interface{}{"code": code, "state": state}
+			}
+		)
+		BeforeEach(func() {
+			var err error
+			idp, err = oidctest.NewFakeIdP("userservice", "secret")
+			Expect(err).To(BeNil())
+			roleMapping, _ := oidc.ParseRoleMapping([]string{"portal-admins=admin", "developers=advanced"}, "")
+			handler.oidc = oidc.NewProvider(oidc.Config{IssuerURL: idp.Issuer(), ClientID: "userservice",
+				ClientSecret: "secret", RedirectURL: "https://portal/sso/callback", GroupsClaim: "groups",
+				RoleMapping: roleMapping}, zap.NewNop().Sugar())
+			handler.runtimeConfig.OIDCStateExpirationInSeconds = 60
+			handler.runtimeConfig.MFAChallengeExpirationInSeconds = 60
+			claims = jwt.MapClaims{"sub": "idp-user-1", "email": "john@mgmtportal.com", "email_verified": true,
+				"name": "John", "groups": []string{"developers"}}
+			issuer, subject := idp.Issuer(), "idp-user-1"
+			operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Name: "John",
+				Email: "john@mgmtportal.com", Role: "advanced", OIDCIssuer: &issuer, OIDCSubject: &subject,
+				OIDCProvisioned: true}
+		})
+		AfterEach(func() {
+			idp.Close()
+		})
+		It("Not configured", func() {
+			handler.oidc = nil
+			handler.operations = &operationsWithoutErr
+			handler.startOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(404))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCNotConfigured.Error()))
+		})
+		It("Identity provider unavailable", func() {
+			idp.Close()
+			handler.operations = &operationsWithoutErr
+			handler.startOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(502))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCProviderUnavailable.Error()))
+		})
+		It("Login start records hashed state", func() {
+			handler.operations = &operationsWithoutErr
+			payload := signIn()
+			authorization, ok := operationsWithoutErr.OIDCAuthorizations[auth.HashOpaqueToken(payload["state"].(string))]
+			Expect(ok).To(BeTrue())
+			Expect(authorization.CodeVerifier).To(Not(BeEmpty()))
+			Expect(authorization.Nonce).To(Not(BeEmpty()))
+		})
+		It("Invalid payload", func() {
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, map[string]interface{}{"code": "code"})
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(400))
+			Expect(w.Body.String()).To(ContainSubstring("Single sign-on payload is invalid; Strictly Allowed Params:"))
+		})
+		It("Unknown state", func() {
+			handler.operations = &operationsWithoutErr
+			payload := signIn()
+			payload["state"] = "forged"
+			MockJsonPostOrPut(ctx, payload)
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredOIDCState.Error()))
+		})
+		It("Code rejected by identity provider", func() {
+			handler.operations = &operationsWithoutErr
+			payload := signIn()
+			payload["code"] = "forged"
+			MockJsonPostOrPut(ctx, payload)
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCLoginFailed.Error()))
+		})
+		It("Email not verified by identity provider", func() {
+			delete(claims, "email_verified")
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(401))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCLoginFailed.Error()))
+		})
+		It("User with the same email, yet to be linked, isn't signed in", func() {
+			operationsWithoutErr.User.OIDCIssuer, operationsWithoutErr.User.OIDCSubject = nil, nil
+			operationsWithoutErr.User.OIDCProvisioned = false
+			handler.runtimeConfig.OIDCJITProvisioning = true
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCUserNotLinked.Error()))
+			Expect(operationsWithoutErr.ProvisionedUser).To(BeNil())
+		})
+		It("User linked to other identity at identity provider isn't signed in", func() {
+			claims["sub"] = "idp-user-2"
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(409))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCUserNotLinked.Error()))
+		})
+		It("Role of linked user, not added by single sign-on, isn't synced", func() {
+			claims["groups"] = []string{"developers", "portal-admins"}
+			operationsWithoutErr.User.OIDCProvisioned = false
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.UpdatedRole).To(BeEmpty())
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(BeEmpty())
+		})
+		It("Groups not mapped to any role", func() {
+			claims["groups"] = []string{"everyone"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCRoleNotMapped.Error()))
+		})
+		It("Successful login, state can't be replayed", func() {
+			handler.operations = &operationsWithoutErr
+			payload := signIn()
+			MockJsonPostOrPut(ctx, payload)
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("access_token"))
+			Expect(w.Body.String()).To(ContainSubstring("refresh_token"))
+			Expect(operationsWithoutErr.UpdatedRole).To(BeEmpty())
+
+			w = httptest.NewRecorder()
+			ctx = GetTestGinContext(w)
+			MockJsonPostOrPut(ctx, payload)
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(401))
+		})
+		It("Role is synced with groups and tokens of former role are revoked", func() {
+			claims["groups"] = []string{"developers", "portal-admins"}
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(operationsWithoutErr.UpdatedRole).To(Equal("admin"))
+			Expect(handler.revoker.(*RevocationMock).RevokedSubjects).To(ContainElement("1"))
+			Expect(operationsWithoutErr.ElevationsEndedOf).To(ConsistOf(uint(1)))
+		})
+		It("User enrolled in MFA is challenged for the second factor", func() {
+			operationsWithoutErr.User.MFAEnabled = true
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("mfa_token"))
+			Expect(w.Body.String()).To(Not(ContainSubstring("access_token")))
+		})
+		It("Locked account", func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			operationsWithoutErr.User.LockedUntil = &lockedUntil
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(429))
+		})
+		It("Unknown user without just-in-time provisioning", func() {
+			operationsWithoutErr.SetUserDoesntExist = true
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(403))
+			Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCUserNotProvisioned.Error()))
+			Expect(operationsWithoutErr.ProvisionedUser).To(BeNil())
+		})
+		It("Unknown user is provisioned just-in-time", func() {
+			handler.runtimeConfig.OIDCJITProvisioning = true
+			operationsWithoutErr.SetUserDoesntExist = true
+			handler.operations = &operationsWithoutErr
+			MockJsonPostOrPut(ctx, signIn())
+			handler.completeOIDCLogin(ctx)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(ContainSubstring("access_token"))
+			Expect(operationsWithoutErr.ProvisionedUser.Email).To(Equal("john@mgmtportal.com"))
+			Expect(operationsWithoutErr.ProvisionedUser.Name).To(Equal("John"))
+			Expect(operationsWithoutErr.ProvisionedUser.Role).To(Equal("advanced"))
+			Expect(*operationsWithoutErr.ProvisionedUser.OIDCSubject).To(Equal("idp-user-1"))
+			Expect(operationsWithoutErr.ProvisionedUser.OIDCProvisioned).To(BeTrue())
+		})
+		Context("Linking", func() {
+			var (
+				// startLink starts linking as the user, signs in at identity provider and responds with the
+				// callback payload
+				startLink = func() map[string]interface{} {
+					MockJsonPostOrPut(ctx, map[string]interface{}{"current_password": "sabari123"})
+					handler.startOIDCLink(ctx)
+					Expect(w.Code).To(Equal(200))
+					var response utils.AuthorizationURLResponse
+					Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(BeNil())
+					code, state, err := idp.Authorize(response.AuthorizationURL, claims)
+					Expect(err).To(BeNil())
+					w = httptest.NewRecorder()
+					ctx = GetTestGinContext(w)
+					ctx.Set("sub", uint(1))
+					return map[string]interface{}{"code": code, "state": state}
+				}
+			)
+			BeforeEach(func() {
+				operationsWithoutErr.User = &models.User{DBModel: models.DBModel{ID: 1}, Name: "John",
+					Email: "john@mgmtportal.com", Role: "advanced",
+					PasswordHash: "$2a$10$MMMx.hCq9QXeJyOm80Cx3e0o0PR25/xF05WgM9CsJR6zlnfbllZR2"}
+				handler.operations = &operationsWithoutErr
+				ctx.Set("sub", uint(1))
+			})
+			It("Invalid payload", func() {
+				MockJsonPostOrPut(ctx, map[string]interface{}{"password": "sabari123"})
+				handler.startOIDCLink(ctx)
+				Expect(w.Code).To(Equal(400))
+				Expect(w.Body.String()).To(ContainSubstring("Single sign-on link payload is invalid"))
+			})
+			It("Wrong current password", func() {
+				MockJsonPostOrPut(ctx, map[string]interface{}{"current_password": "wrong"})
+				handler.startOIDCLink(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrCurrentPasswordMismatch.Error()))
+				Expect(operationsWithoutErr.OIDCAuthorizations).To(BeEmpty())
+			})
+			It("Identity is linked to the user, who signs in through single sign-on thereafter", func() {
+				payload := startLink()
+				authorization := operationsWithoutErr.OIDCAuthorizations[auth.HashOpaqueToken(payload["state"].(string))]
+				Expect(*authorization.UserID).To(Equal(uint(1)))
+				MockJsonPostOrPut(ctx, payload)
+				handler.completeOIDCLink(ctx)
+				Expect(w.Code).To(Equal(200))
+				Expect(operationsWithoutErr.LinkedOIDCSubject).To(Equal("idp-user-1"))
+			})
+			It("State of linking can't login", func() {
+				MockJsonPostOrPut(ctx, startLink())
+				handler.completeOIDCLogin(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrInvalidOrExpiredOIDCState.Error()))
+			})
+			It("State of login can't link", func() {
+				payload := signIn()
+				ctx.Set("sub", uint(1))
+				MockJsonPostOrPut(ctx, payload)
+				handler.completeOIDCLink(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(operationsWithoutErr.LinkedOIDCSubject).To(BeEmpty())
+			})
+			It("State of linking can't link other user", func() {
+				payload := startLink()
+				ctx.Set("sub", uint(2))
+				MockJsonPostOrPut(ctx, payload)
+				handler.completeOIDCLink(ctx)
+				Expect(w.Code).To(Equal(401))
+				Expect(operationsWithoutErr.LinkedOIDCSubject).To(BeEmpty())
+			})
+			It("Identity linked to other user", func() {
+				payload := startLink()
+				operationsWithoutErr.SetOIDCIdentityLinked = true
+				MockJsonPostOrPut(ctx, payload)
+				handler.completeOIDCLink(ctx)
+				Expect(w.Code).To(Equal(409))
+				Expect(w.Body.String()).To(ContainSubstring(appErrors.ErrOIDCIdentityAlreadyLinked.Error()))
+			})
+		})
+	})
 })
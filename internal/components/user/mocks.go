@@ -1,26 +1,56 @@
 package user
 
 import (
+	"errors"
+	"time"
 	appErrors "userservice/internal/errors"
 	"userservice/internal/models"
+	"userservice/internal/notify"
 
 	"gorm.io/gorm"
 )
 
 // UserMock...
 type UserMock struct {
-	User                 *models.User
-	SetInternalError     bool
-	SetEmailOrIDNotFound bool
-	SetDuplicateEmail    bool
-	SetUserDoesntExist   bool
+	User                  *models.User
+	SetInternalError      bool
+	SetEmailOrIDNotFound  bool
+	SetDuplicateEmail     bool
+	SetUserDoesntExist    bool
+	SetTokenInvalid       bool
+	SetTokenReused        bool
+	LoginFailure          *models.LoginFailure
+	LoginFailureCount     int
+	LockedUntil           *time.Time
+	LoginFailuresReset    bool
+	PasswordHistory       []string
+	RecoveryCodeHashes    []string
+	ResetToken            *models.PasswordResetToken
+	ResetTokenHash        string
+	ResetTokenConsumed    bool
+	TempPassExpiresAt     *time.Time
+	RehashedPassword      string
+	UpdatedRole           string
+	ProvisionedUser       *models.User
+	LinkedOIDCSubject     string
+	SetOIDCIdentityLinked bool
+	OIDCAuthorizations    map[string]models.OIDCAuthorization
+	Sessions              []models.Session
+	RevokedSessionIDs     []uint
+	ImpersonationAudits   []models.ImpersonationAudit
+	ActiveElevation       *models.RoleElevation
+	// ElevationsEndedOf are the users whose role elevations were ended
+	ElevationsEndedOf []uint
+	// DeletedUserIDs and RefreshTokensRevokedOf are the users deleted and whose refresh tokens were revoked
+	DeletedUserIDs         []uint
+	RefreshTokensRevokedOf []uint
 }
 
 // GetUserByEmail...
 func (m *UserMock) GetUserByEmail(string) (*models.User, error) {
 	if m.SetInternalError {
 		return nil, appErrors.ErrInternal
-	} else if m.SetEmailOrIDNotFound {
+	} else if m.SetEmailOrIDNotFound || m.SetUserDoesntExist {
 		return nil, gorm.ErrRecordNotFound
 	}
 	return m.User, nil
@@ -30,24 +60,25 @@ func (m *UserMock) GetUserByEmail(string) (*models.User, error) {
 func (m *UserMock) GetUser(uint) (*models.User, error) {
 	if m.SetInternalError {
 		return nil, appErrors.ErrInternal
-	} else if m.SetEmailOrIDNotFound {
+	} else if m.SetEmailOrIDNotFound || m.SetUserDoesntExist {
 		return nil, gorm.ErrRecordNotFound
 	}
 	return m.User, nil
 }
 
 // CreateUser...
-func (m *UserMock) CreateUser(string, string, string, string) error {
+func (m *UserMock) CreateUser(_, _, _, _ string, temporaryPasswordExpiresAt *time.Time) error {
 	if m.SetInternalError {
 		return appErrors.ErrInternal
 	} else if m.SetDuplicateEmail {
 		return appErrors.ErrUserWithSameEmailAlreadyExists
 	}
+	m.TempPassExpiresAt = temporaryPasswordExpiresAt
 	return nil
 }
 
 // UpdateUser
-func (m *UserMock) UpdateUser(uint, string, string, string) (*models.User, error) {
+func (m *UserMock) UpdateUser(_ uint, _ string, _ string, role string) (*models.User, error) {
 	if m.SetInternalError {
 		return nil, appErrors.ErrInternal
 	} else if m.SetDuplicateEmail {
@@ -55,16 +86,20 @@ func (m *UserMock) UpdateUser(uint, string, string, string) (*models.User, error
 	} else if m.SetUserDoesntExist {
 		return nil, appErrors.ErrUserDoesNotExist
 	}
-	return m.User, nil
+	m.UpdatedRole = role
+	updatedUser := *m.User
+	updatedUser.Role = role
+	return &updatedUser, nil
 }
 
 // DeleteUser
-func (m *UserMock) DeleteUser(uint) error {
+func (m *UserMock) DeleteUser(id uint) error {
 	if m.SetInternalError {
 		return appErrors.ErrInternal
 	} else if m.SetUserDoesntExist {
 		return appErrors.ErrUserDoesNotExist
 	}
+	m.DeletedUserIDs = append(m.DeletedUserIDs, id)
 	return nil
 }
 
@@ -97,3 +132,464 @@ func (m *UserMock) ChangePassword(string, string) error {
 	}
 	return nil
 }
+
+// CreateRefreshToken...
+func (m *UserMock) CreateRefreshToken(uint, string, string, time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RotateRefreshToken...
+func (m *UserMock) RotateRefreshToken(string, string, time.Time) (*models.RefreshToken, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetTokenInvalid {
+		return nil, appErrors.ErrInvalidOrExpiredRefreshToken
+	} else if m.SetTokenReused {
+		return nil, appErrors.ErrRefreshTokenReused
+	}
+	return &models.RefreshToken{UserID: 1, FamilyID: "family"}, nil
+}
+
+// RevokeRefreshTokenFamily...
+func (m *UserMock) RevokeRefreshTokenFamily(uint, string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens...
+func (m *UserMock) RevokeUserRefreshTokens(id uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RefreshTokensRevokedOf = append(m.RefreshTokensRevokedOf, id)
+	return nil
+}
+
+// CreateAccessToken...
+func (m *UserMock) CreateAccessToken(*models.PersonalAccessToken) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// FetchAccessTokens...
+func (m *UserMock) FetchAccessTokens(userID uint) ([]models.PersonalAccessToken, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	return []models.PersonalAccessToken{{UserID: userID, Name: "ci", Prefix: "pat_abcdefgh",
+		Scopes: []string{models.PermissionServiceRead}}}, nil
+}
+
+// RevokeAccessToken...
+func (m *UserMock) RevokeAccessToken(uint, uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetTokenInvalid {
+		return appErrors.ErrAccessTokenDoesNotExist
+	}
+	return nil
+}
+
+// RecordLoginFailure...
+func (m *UserMock) RecordLoginFailure(uint) (int, error) {
+	if m.SetInternalError {
+		return 0, appErrors.ErrInternal
+	}
+	m.LoginFailureCount++
+	if m.User == nil {
+		return m.LoginFailureCount, nil
+	}
+	return m.User.FailedLoginAttempts + m.LoginFailureCount, nil
+}
+
+// LockUser...
+func (m *UserMock) LockUser(_ uint, lockedUntil time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.LockedUntil = &lockedUntil
+	return nil
+}
+
+// RehashPassword...
+func (m *UserMock) RehashPassword(_ uint, _ string, newPasswordHash string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RehashedPassword = newPasswordHash
+	return nil
+}
+
+// ResetLoginFailures...
+func (m *UserMock) ResetLoginFailures(uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetUserDoesntExist {
+		return appErrors.ErrUserDoesNotExist
+	}
+	m.LoginFailuresReset = true
+	return nil
+}
+
+// GetLoginFailure...
+func (m *UserMock) GetLoginFailure(ip string) (*models.LoginFailure, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	if m.LoginFailure == nil {
+		m.LoginFailure = &models.LoginFailure{IP: ip}
+	}
+	return m.LoginFailure, nil
+}
+
+// RecordIPLoginFailure...
+func (m *UserMock) RecordIPLoginFailure(ip string, now time.Time, _ time.Time) (int, error) {
+	if m.SetInternalError {
+		return 0, appErrors.ErrInternal
+	}
+	if m.LoginFailure == nil {
+		m.LoginFailure = &models.LoginFailure{IP: ip}
+	}
+	m.LoginFailure.FailedAttempts++
+	m.LoginFailure.LastFailedAt = now
+	return m.LoginFailure.FailedAttempts, nil
+}
+
+// LockIP...
+func (m *UserMock) LockIP(_ string, lockedUntil time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.LoginFailure.FailedAttempts = 0
+	m.LoginFailure.LockedUntil = &lockedUntil
+	return nil
+}
+
+// FetchPasswordHistory...
+func (m *UserMock) FetchPasswordHistory(uint, int) ([]string, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	return m.PasswordHistory, nil
+}
+
+// RecordPasswordHistory...
+func (m *UserMock) RecordPasswordHistory(_ uint, passwordHash string, _ int) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.PasswordHistory = append([]string{passwordHash}, m.PasswordHistory...)
+	return nil
+}
+
+// SetPendingMFASecret...
+func (m *UserMock) SetPendingMFASecret(_ uint, secret string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetUserDoesntExist {
+		return appErrors.ErrUserDoesNotExist
+	}
+	m.User.MFAPendingSecret = secret
+	return nil
+}
+
+// EnableMFA...
+func (m *UserMock) EnableMFA(_ uint, secret string, recoveryCodeHashes []string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetUserDoesntExist {
+		return appErrors.ErrUserDoesNotExist
+	}
+	m.User.MFAEnabled, m.User.MFASecret, m.User.MFAPendingSecret = true, secret, ""
+	m.RecoveryCodeHashes = recoveryCodeHashes
+	return nil
+}
+
+// DisableMFA...
+func (m *UserMock) DisableMFA(uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetUserDoesntExist {
+		return appErrors.ErrUserDoesNotExist
+	}
+	if m.User != nil {
+		m.User.MFAEnabled, m.User.MFASecret, m.User.MFAPendingSecret = false, "", ""
+	}
+	m.RecoveryCodeHashes = nil
+	return nil
+}
+
+// RecordMFAStep...
+func (m *UserMock) RecordMFAStep(_ uint, step int64) (bool, error) {
+	if m.SetInternalError {
+		return false, appErrors.ErrInternal
+	}
+	if step <= m.User.MFALastUsedStep {
+		return false, nil
+	}
+	m.User.MFALastUsedStep = step
+	return true, nil
+}
+
+// ConsumeRecoveryCode...
+func (m *UserMock) ConsumeRecoveryCode(_ uint, codeHash string) (bool, error) {
+	if m.SetInternalError {
+		return false, appErrors.ErrInternal
+	}
+	for i, recoveryCodeHash := range m.RecoveryCodeHashes {
+		if recoveryCodeHash == codeHash {
+			m.RecoveryCodeHashes = append(m.RecoveryCodeHashes[:i], m.RecoveryCodeHashes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReplaceRecoveryCodes...
+func (m *UserMock) ReplaceRecoveryCodes(_ uint, recoveryCodeHashes []string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RecoveryCodeHashes = recoveryCodeHashes
+	return nil
+}
+
+// CreatePasswordResetToken...
+func (m *UserMock) CreatePasswordResetToken(userID uint, tokenHash string, expiresAt time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.ResetTokenHash = tokenHash
+	m.ResetToken = &models.PasswordResetToken{UserID: userID, TokenHash: tokenHash, ExpiresAt: expiresAt}
+	return nil
+}
+
+// GetPasswordResetToken...
+func (m *UserMock) GetPasswordResetToken(tokenHash string) (*models.PasswordResetToken, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetTokenInvalid || m.ResetToken == nil || m.ResetToken.TokenHash != tokenHash {
+		return nil, appErrors.ErrInvalidOrExpiredResetToken
+	}
+	return m.ResetToken, nil
+}
+
+// ConsumePasswordResetToken...
+func (m *UserMock) ConsumePasswordResetToken(string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetTokenReused || m.ResetTokenConsumed {
+		return appErrors.ErrInvalidOrExpiredResetToken
+	}
+	m.ResetTokenConsumed = true
+	return nil
+}
+
+// ProvisionUser...
+func (m *UserMock) ProvisionUser(name string, email string, role string, issuer string,
+	subject string) (*models.User, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.SetDuplicateEmail {
+		return nil, appErrors.ErrUserWithSameEmailAlreadyExists
+	}
+	m.ProvisionedUser = &models.User{DBModel: models.DBModel{ID: 2}, Name: name, Email: email, Role: role,
+		OIDCIssuer: &issuer, OIDCSubject: &subject, OIDCProvisioned: true}
+	return m.ProvisionedUser, nil
+}
+
+// GetUserByOIDCIdentity responds with the user, only if it is linked to the identity.
+func (m *UserMock) GetUserByOIDCIdentity(issuer string, subject string) (*models.User, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	if m.SetUserDoesntExist || m.User == nil || m.User.OIDCIssuer == nil || *m.User.OIDCIssuer != issuer ||
+		*m.User.OIDCSubject != subject {
+		return nil, appErrors.ErrUserDoesNotExist
+	}
+	return m.User, nil
+}
+
+// LinkOIDCIdentity...
+func (m *UserMock) LinkOIDCIdentity(_ uint, issuer string, subject string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	} else if m.SetOIDCIdentityLinked {
+		return appErrors.ErrOIDCIdentityAlreadyLinked
+	}
+	m.LinkedOIDCSubject = subject
+	return nil
+}
+
+// CreateOIDCAuthorization...
+func (m *UserMock) CreateOIDCAuthorization(authorization *models.OIDCAuthorization) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	if m.OIDCAuthorizations == nil {
+		m.OIDCAuthorizations = make(map[string]models.OIDCAuthorization)
+	}
+	m.OIDCAuthorizations[authorization.StateHash] = *authorization
+	return nil
+}
+
+// ConsumeOIDCAuthorization...
+func (m *UserMock) ConsumeOIDCAuthorization(stateHash string, now time.Time) (*models.OIDCAuthorization, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	authorization, ok := m.OIDCAuthorizations[stateHash]
+	if !ok || !authorization.ExpiresAt.After(now) {
+		return nil, appErrors.ErrInvalidOrExpiredOIDCState
+	}
+	delete(m.OIDCAuthorizations, stateHash)
+	return &authorization, nil
+}
+
+// CreateSession...
+func (m *UserMock) CreateSession(session *models.Session) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	session.ID = uint(len(m.Sessions) + 1)
+	m.Sessions = append(m.Sessions, *session)
+	return nil
+}
+
+// TouchSession...
+func (m *UserMock) TouchSession(familyID string, lastSeenAt time.Time) (*models.Session, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	for i := range m.Sessions {
+		if m.Sessions[i].FamilyID == familyID {
+			m.Sessions[i].LastSeenAt = lastSeenAt
+			return &m.Sessions[i], nil
+		}
+	}
+	return nil, appErrors.ErrSessionDoesNotExist
+}
+
+// FetchSessions...
+func (m *UserMock) FetchSessions(userID uint) ([]models.Session, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	sessions := []models.Session{}
+	for _, session := range m.Sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession...
+func (m *UserMock) RevokeSession(userID uint, sessionID uint) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	for i, session := range m.Sessions {
+		if session.ID == sessionID && session.UserID == userID {
+			m.Sessions = append(m.Sessions[:i], m.Sessions[i+1:]...)
+			m.RevokedSessionIDs = append(m.RevokedSessionIDs, sessionID)
+			return nil
+		}
+	}
+	return appErrors.ErrSessionDoesNotExist
+}
+
+// FetchImpersonationAudits...
+func (m *UserMock) FetchImpersonationAudits(userID uint) ([]models.ImpersonationAudit, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	}
+	audits := []models.ImpersonationAudit{}
+	for _, audit := range m.ImpersonationAudits {
+		if audit.UserID == userID {
+			audits = append(audits, audit)
+		}
+	}
+	return audits, nil
+}
+
+// GetActiveElevation...
+func (m *UserMock) GetActiveElevation(uint, time.Time) (*models.RoleElevation, error) {
+	if m.SetInternalError {
+		return nil, appErrors.ErrInternal
+	} else if m.ActiveElevation == nil {
+		return nil, appErrors.ErrElevationDoesNotExist
+	}
+	return m.ActiveElevation, nil
+}
+
+// EndElevations...
+func (m *UserMock) EndElevations(userID uint, _ time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.ElevationsEndedOf = append(m.ElevationsEndedOf, userID)
+	return nil
+}
+
+// RevocationMock...
+type RevocationMock struct {
+	SetInternalError bool
+	RevokedTokens    []string
+	RevokedSubjects  []string
+}
+
+// RevokeToken...
+func (m *RevocationMock) RevokeToken(jti string, _ time.Time) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RevokedTokens = append(m.RevokedTokens, jti)
+	return nil
+}
+
+// RevokeSubjectTokens...
+func (m *RevocationMock) RevokeSubjectTokens(subject string) error {
+	if m.SetInternalError {
+		return appErrors.ErrInternal
+	}
+	m.RevokedSubjects = append(m.RevokedSubjects, subject)
+	return nil
+}
+
+// IsRevoked...
+func (m *RevocationMock) IsRevoked(jti string, subject string, _ time.Time) bool {
+	for _, revokedToken := range m.RevokedTokens {
+		if revokedToken == jti {
+			return true
+		}
+	}
+	for _, revokedSubject := range m.RevokedSubjects {
+		if revokedSubject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifierMock...
+type NotifierMock struct {
+	SetInternalError bool
+	Messages         []notify.Message
+}
+
+// Notify...
+func (m *NotifierMock) Notify(message notify.Message) error {
+	if m.SetInternalError {
+		return errors.New("delivery failed")
+	}
+	m.Messages = append(m.Messages, message)
+	return nil
+}
@@ -3,11 +3,13 @@ package user
 import (
 	"errors"
 	"strings"
+	"time"
 	appErrors "userservice/internal/errors"
 	"userservice/internal/models"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // operations...
@@ -21,6 +23,11 @@ func newOperations(db *gorm.DB, log *zap.SugaredLogger) *operations {
 	return &operations{db: db, log: log}
 }
 
+// NewOperations initializes user operations for other components acting on users, such as SCIM provisioning.
+func NewOperations(db *gorm.DB, log *zap.SugaredLogger) models.UserOperations {
+	return newOperations(db, log)
+}
+
 // GetUserByEmail fetches user record in DB for the given email
 func (ops *operations) GetUserByEmail(email string) (user *models.User, returnErr error) {
 	user = new(models.User)
@@ -58,10 +65,13 @@ func (ops *operations) GetUser(id uint) (user *models.User, returnErr error) {
 // CreateUser creates user record in DB  with necessary metadata.
 // Since user creation happens seldom, we have additional DB call
 // to check if record exist with same email rather than waiting for DB to report uniqueKey constrain.
-// We still need to handle duplicate record constrain gracefully if create request happens at once
-func (ops *operations) CreateUser(name string, email string, role string, passwordHash string) error {
+// We still need to handle duplicate record constrain gracefully if create request happens at once.
+// User is created with temporary password, which expires at the given time unless it is nil.
+func (ops *operations) CreateUser(name string, email string, role string, passwordHash string,
+	temporaryPasswordExpiresAt *time.Time) error {
 
-	newUser := models.User{Name: name, Email: email, Role: role, PasswordHash: passwordHash, IsTemporaryPassword: true}
+	newUser := models.User{Name: name, Email: email, Role: role, PasswordHash: passwordHash, IsTemporaryPassword: true,
+		TemporaryPasswordExpiresAt: temporaryPasswordExpiresAt}
 	var userWithSameEmail int64 = 0
 	if gormErr := ops.db.Model(&models.User{}).Where("email = ?", email).Count(&userWithSameEmail).Error; gormErr != nil {
 		return appErrors.ErrInternal
@@ -176,7 +186,8 @@ func (ops *operations) ChangePassword(email string, passwordHash string) (return
 	if userCount == 0 {
 		return appErrors.ErrUserDoesNotExist
 	}
-	userToUpdate := map[string]interface{}{"email": email, "password_hash": passwordHash, "temp_password": false}
+	userToUpdate := map[string]interface{}{"email": email, "password_hash": passwordHash, "temp_password": false,
+		"temp_password_expires_at": nil}
 	if err := ops.db.Model(&models.User{}).Where("email = ?", email).Updates(userToUpdate).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// Handle any concurrent deletion as well
@@ -187,3 +198,573 @@ func (ops *operations) ChangePassword(email string, passwordHash string) (return
 	}
 	return nil
 }
+
+// CreateRefreshToken persists hash of newly issued refresh token which starts a new token family.
+func (ops *operations) CreateRefreshToken(userID uint, familyID string, tokenHash string, expiresAt time.Time) error {
+	refreshToken := models.RefreshToken{UserID: userID, FamilyID: familyID, TokenHash: tokenHash, ExpiresAt: expiresAt}
+	if err := ops.db.Model(&models.RefreshToken{}).Create(&refreshToken).Error; err != nil {
+		ops.log.Errorf("Failed to create refresh token for user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RotateRefreshToken marks the presented refresh token as used and persists its successor in the same family.
+// Presenting an already used token indicates the token is replayed(possibly stolen),
+// hence the whole family gets revoked forcing the legitimate user to login again.
+// Row is locked during rotation, so concurrent refresh requests with same token are serialized.
+func (ops *operations) RotateRefreshToken(tokenHash string, newTokenHash string,
+	expiresAt time.Time) (*models.RefreshToken, error) {
+
+	var (
+		presentedToken models.RefreshToken
+		reuseDetected  bool
+	)
+	txErr := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).First(&presentedToken).Error; err != nil {
+			return err
+		}
+		if presentedToken.Revoked || presentedToken.ExpiresAt.Before(time.Now()) {
+			return appErrors.ErrInvalidOrExpiredRefreshToken
+		}
+		if presentedToken.Used {
+			reuseDetected = true
+			// commit the family revocation, caller is still informed about the reuse
+			return tx.Model(&models.RefreshToken{}).Where("family_id = ?", presentedToken.FamilyID).
+				Update("revoked", true).Error
+		}
+		if err := tx.Model(&models.RefreshToken{}).Where("id = ?", presentedToken.ID).
+			Update("used", true).Error; err != nil {
+			return err
+		}
+		successor := models.RefreshToken{UserID: presentedToken.UserID, FamilyID: presentedToken.FamilyID,
+			TokenHash: newTokenHash, ExpiresAt: expiresAt}
+		return tx.Model(&models.RefreshToken{}).Create(&successor).Error
+	})
+	if txErr != nil {
+		if errors.Is(txErr, gorm.ErrRecordNotFound) || txErr == appErrors.ErrInvalidOrExpiredRefreshToken {
+			return nil, appErrors.ErrInvalidOrExpiredRefreshToken
+		}
+		ops.log.Errorf("Failed to rotate refresh token: %v", txErr)
+		return nil, appErrors.ErrInternal
+	}
+	if reuseDetected {
+		ops.log.Warnf("Refresh token reuse detected for user with id %d, revoked token family", presentedToken.UserID)
+		return nil, appErrors.ErrRefreshTokenReused
+	}
+	return &presentedToken, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token descended from same login as the given token.
+// Family is revoked only if the token is owned by the given user.
+func (ops *operations) RevokeRefreshTokenFamily(userID uint, tokenHash string) error {
+	familyIDs := ops.db.Model(&models.RefreshToken{}).Select("family_id").
+		Where("token_hash = ? AND user_id = ?", tokenHash, userID)
+	if err := ops.db.Model(&models.RefreshToken{}).Where("family_id IN (?)", familyIDs).
+		Update("revoked", true).Error; err != nil {
+		ops.log.Errorf("Failed to revoke refresh token family of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token issued to the user.
+func (ops *operations) RevokeUserRefreshTokens(userID uint) error {
+	if err := ops.db.Model(&models.RefreshToken{}).Where("user_id = ?", userID).
+		Update("revoked", true).Error; err != nil {
+		ops.log.Errorf("Failed to revoke refresh tokens of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// CreateAccessToken persists personal access token, of which only the hash is known.
+func (ops *operations) CreateAccessToken(accessToken *models.PersonalAccessToken) error {
+	if err := ops.db.Model(&models.PersonalAccessToken{}).Create(accessToken).Error; err != nil {
+		ops.log.Errorf("Failed to create personal access token for user with id %d: %v", accessToken.UserID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// FetchAccessTokens lists personal access tokens owned by the user, including the expired ones.
+func (ops *operations) FetchAccessTokens(userID uint) ([]models.PersonalAccessToken, error) {
+	accessTokens := []models.PersonalAccessToken{}
+	if err := ops.db.Where("user_id = ?", userID).Order("id").Find(&accessTokens).Error; err != nil {
+		ops.log.Errorf("Failed to fetch personal access tokens of user with id %d: %v", userID, err)
+		return nil, appErrors.ErrInternal
+	}
+	return accessTokens, nil
+}
+
+// RevokeAccessToken deletes personal access token, only if it is owned by the user.
+func (ops *operations) RevokeAccessToken(userID uint, tokenID uint) error {
+	result := ops.db.Unscoped().Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		ops.log.Errorf("Failed to revoke personal access token %d of user with id %d: %v", tokenID, userID, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrAccessTokenDoesNotExist
+	}
+	return nil
+}
+
+// RecordLoginFailure accounts a failed login attempt of the user, responding with the consecutive failures.
+// Counter is incremented in DB, such that concurrent failures are all accounted.
+func (ops *operations) RecordLoginFailure(userID uint) (int, error) {
+	var user models.User
+	if err := ops.db.Model(&user).Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}}}).
+		Where("id = ?", userID).UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).
+		Error; err != nil {
+		ops.log.Errorf("Failed to record login failure of user with id %d: %v", userID, err)
+		return 0, appErrors.ErrInternal
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+// LockUser locks the account till lockedUntil, and the failure counter restarts, such that the user gets
+// full set of attempts after the lockout.
+func (ops *operations) LockUser(userID uint, lockedUntil time.Time) error {
+	if err := ops.db.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{"failed_login_attempts": 0, "locked_until": lockedUntil}).
+		Error; err != nil {
+		ops.log.Errorf("Failed to lock user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// ResetLoginFailures clears failed login attempts and lockout of the user.
+func (ops *operations) ResetLoginFailures(userID uint) error {
+	result := ops.db.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{"failed_login_attempts": 0, "locked_until": nil})
+	if result.Error != nil {
+		ops.log.Errorf("Failed to reset login failures of user with id %d: %v", userID, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrUserDoesNotExist
+	}
+	return nil
+}
+
+// RehashPassword replaces password hash of the user with the one of current algorithm and parameters,
+// provided the password isn't changed meanwhile.
+func (ops *operations) RehashPassword(userID uint, passwordHash string, newPasswordHash string) error {
+	if err := ops.db.Model(&models.User{}).Where("id = ? AND password_hash = ?", userID, passwordHash).
+		UpdateColumn("password_hash", newPasswordHash).Error; err != nil {
+		ops.log.Errorf("Failed to rehash password of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// GetLoginFailure fetches failed login attempts from the client IP, a fresh record if there is none.
+func (ops *operations) GetLoginFailure(ip string) (*models.LoginFailure, error) {
+	failure := &models.LoginFailure{IP: ip}
+	if err := ops.db.Where("ip = ?", ip).First(failure).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return failure, nil
+		}
+		ops.log.Errorf("Failed to fetch login failures of ip %s: %v", ip, err)
+		return nil, appErrors.ErrInternal
+	}
+	return failure, nil
+}
+
+// RecordIPLoginFailure accounts a failed login attempt from the client IP, responding with the failures
+// accounted so far. Failures last accounted before forgetBefore are forgotten. Counter is incremented
+// through upsert, such that concurrent failures are all accounted.
+func (ops *operations) RecordIPLoginFailure(ip string, now time.Time, forgetBefore time.Time) (int, error) {
+	failure := models.LoginFailure{IP: ip, FailedAttempts: 1, LastFailedAt: now}
+	if err := ops.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "ip"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failed_attempts": gorm.Expr("CASE WHEN login_failure.last_failed_at < ? THEN 1 "+
+				"ELSE login_failure.failed_attempts + 1 END", forgetBefore),
+			"last_failed_at": now,
+		})}, clause.Returning{Columns: []clause.Column{{Name: "failed_attempts"}}}).
+		Create(&failure).Error; err != nil {
+		ops.log.Errorf("Failed to record login failure of ip %s: %v", ip, err)
+		return 0, appErrors.ErrInternal
+	}
+	return failure.FailedAttempts, nil
+}
+
+// LockIP locks the client IP out of login till lockedUntil, and the failure counter restarts.
+func (ops *operations) LockIP(ip string, lockedUntil time.Time) error {
+	if err := ops.db.Model(&models.LoginFailure{}).Where("ip = ?", ip).
+		UpdateColumns(map[string]interface{}{"failed_attempts": 0, "locked_until": lockedUntil}).
+		Error; err != nil {
+		ops.log.Errorf("Failed to lock ip %s: %v", ip, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// FetchPasswordHistory responds with password hashes recently chosen by the user, the latest first.
+func (ops *operations) FetchPasswordHistory(userID uint, limit int) ([]string, error) {
+	var passwordHashes []string
+	if err := ops.db.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).Order("id desc").
+		Limit(limit).Pluck("password_hash", &passwordHashes).Error; err != nil {
+		ops.log.Errorf("Failed to fetch password history of user with id %d: %v", userID, err)
+		return nil, appErrors.ErrInternal
+	}
+	return passwordHashes, nil
+}
+
+// RecordPasswordHistory persists newly chosen password hash of the user, and prunes history beyond retain entries.
+func (ops *operations) RecordPasswordHistory(userID uint, passwordHash string, retain int) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+			return err
+		}
+		retained := tx.Model(&models.PasswordHistory{}).Select("id").Where("user_id = ?", userID).
+			Order("id desc").Limit(retain)
+		return tx.Where("user_id = ? AND id NOT IN (?)", userID, retained).Delete(&models.PasswordHistory{}).Error
+	})
+	if err != nil {
+		ops.log.Errorf("Failed to record password history of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// SetPendingMFASecret persists TOTP secret of MFA enrollment, which awaits confirmation by the user.
+func (ops *operations) SetPendingMFASecret(userID uint, secret string) error {
+	result := ops.db.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("mfa_pending_secret", secret)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to set pending MFA secret of user with id %d: %v", userID, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrUserDoesNotExist
+	}
+	return nil
+}
+
+// EnableMFA confirms MFA enrollment of the user with the secret, and replaces recovery codes of the user.
+func (ops *operations) EnableMFA(userID uint, secret string, recoveryCodeHashes []string) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+			"mfa_enabled": true, "mfa_secret": secret, "mfa_pending_secret": "", "mfa_last_used_step": 0})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return appErrors.ErrUserDoesNotExist
+		}
+		return replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+	if err != nil {
+		if err == appErrors.ErrUserDoesNotExist {
+			return err
+		}
+		ops.log.Errorf("Failed to enable MFA of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// DisableMFA clears MFA enrollment and recovery codes of the user.
+func (ops *operations) DisableMFA(userID uint) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+			"mfa_enabled": false, "mfa_secret": "", "mfa_pending_secret": "", "mfa_last_used_step": 0})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return appErrors.ErrUserDoesNotExist
+		}
+		return replaceRecoveryCodes(tx, userID, nil)
+	})
+	if err != nil {
+		if err == appErrors.ErrUserDoesNotExist {
+			return err
+		}
+		ops.log.Errorf("Failed to disable MFA of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// RecordMFAStep records TOTP time step the user signed in with. Step is only accepted if it is later than
+// the last recorded one, such that a code can't be replayed, not even by concurrent requests.
+func (ops *operations) RecordMFAStep(userID uint, step int64) (bool, error) {
+	result := ops.db.Model(&models.User{}).Where("id = ? AND mfa_last_used_step < ?", userID, step).
+		UpdateColumn("mfa_last_used_step", step)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to record MFA step of user with id %d: %v", userID, result.Error)
+		return false, appErrors.ErrInternal
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ConsumeRecoveryCode deletes the recovery code of the user, reporting whether there was such an unused code.
+func (ops *operations) ConsumeRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := ops.db.Where("user_id = ? AND code_hash = ?", userID, codeHash).Delete(&models.MFARecoveryCode{})
+	if result.Error != nil {
+		ops.log.Errorf("Failed to consume recovery code of user with id %d: %v", userID, result.Error)
+		return false, appErrors.ErrInternal
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of the user with the new ones.
+func (ops *operations) ReplaceRecoveryCodes(userID uint, recoveryCodeHashes []string) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+	if err != nil {
+		ops.log.Errorf("Failed to replace recovery codes of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// replaceRecoveryCodes replaces recovery codes of the user within the transaction.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, recoveryCodeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(recoveryCodeHashes) == 0 {
+		return nil
+	}
+	recoveryCodes := make([]models.MFARecoveryCode, 0, len(recoveryCodeHashes))
+	for _, codeHash := range recoveryCodeHashes {
+		recoveryCodes = append(recoveryCodes, models.MFARecoveryCode{UserID: userID, CodeHash: codeHash})
+	}
+	return tx.Create(&recoveryCodes).Error
+}
+
+// CreatePasswordResetToken persists hash of newly issued password reset token of the user.
+// Formerly issued tokens of the user, which aren't used yet, are discarded such that only the latest one is valid.
+func (ops *operations) CreatePasswordResetToken(userID uint, tokenHash string, expiresAt time.Time) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", userID).
+			Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{UserID: userID, TokenHash: tokenHash, ExpiresAt: expiresAt}).Error
+	})
+	if err != nil {
+		ops.log.Errorf("Failed to create password reset token of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// GetPasswordResetToken fetches password reset token by its hash, only if it is neither used nor expired.
+func (ops *operations) GetPasswordResetToken(tokenHash string) (*models.PasswordResetToken, error) {
+	var resetToken models.PasswordResetToken
+	if err := ops.db.Where("token_hash = ?", tokenHash).First(&resetToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrInvalidOrExpiredResetToken
+		}
+		ops.log.Errorf("Failed to fetch password reset token: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	if !resetToken.IsValid(time.Now()) {
+		return nil, appErrors.ErrInvalidOrExpiredResetToken
+	}
+	return &resetToken, nil
+}
+
+// ConsumePasswordResetToken marks password reset token as used. Token is consumed only if it is neither used
+// nor expired at the moment, such that concurrent requests can't use the same token.
+func (ops *operations) ConsumePasswordResetToken(tokenHash string) error {
+	now := time.Now()
+	result := ops.db.Model(&models.PasswordResetToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		UpdateColumn("used_at", now)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to consume password reset token: %v", result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrInvalidOrExpiredResetToken
+	}
+	return nil
+}
+
+// ProvisionUser adds user authenticated by identity provider upon first single sign-on, identified by issuer and
+// subject at the provider. User is added without password, hence can only login through single sign-on,
+// unless password is reset.
+func (ops *operations) ProvisionUser(name string, email string, role string, issuer string,
+	subject string) (*models.User, error) {
+	user := &models.User{Name: name, Email: email, Role: role, OIDCIssuer: &issuer, OIDCSubject: &subject,
+		OIDCProvisioned: true}
+	if err := ops.db.Create(user).Error; err != nil {
+		if strings.Contains(err.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return nil, appErrors.ErrUserWithSameEmailAlreadyExists
+		}
+		ops.log.Errorf("Failed to provision user with email %s: %v", email, err)
+		return nil, appErrors.ErrInternal
+	}
+	return user, nil
+}
+
+// GetUserByOIDCIdentity fetches the user identified by issuer and subject at identity provider.
+func (ops *operations) GetUserByOIDCIdentity(issuer string, subject string) (*models.User, error) {
+	user := new(models.User)
+	if err := ops.db.Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).Take(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrUserDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch user by single sign-on identity %s of %s: %v", subject, issuer, err)
+		return nil, appErrors.ErrInternal
+	}
+	return user, nil
+}
+
+// LinkOIDCIdentity links the user to the identity of issuer and subject at identity provider, replacing
+// the identity linked earlier. Identity can be linked to one user only.
+func (ops *operations) LinkOIDCIdentity(userID uint, issuer string, subject string) error {
+	result := ops.db.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"oidc_issuer": issuer, "oidc_subject": subject})
+	if result.Error != nil {
+		if strings.Contains(result.Error.Error(), appErrors.ErrUniqueKeyConstrainViolation.Error()) {
+			return appErrors.ErrOIDCIdentityAlreadyLinked
+		}
+		ops.log.Errorf("Failed to link single sign-on identity %s of %s to user with id %d: %v", subject, issuer,
+			userID, result.Error)
+		return appErrors.ErrInternal
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.ErrUserDoesNotExist
+	}
+	return nil
+}
+
+// CreateOIDCAuthorization records single sign-on login awaiting the user to return from identity provider.
+// Authorizations which expired without the user returning are purged along.
+func (ops *operations) CreateOIDCAuthorization(authorization *models.OIDCAuthorization) error {
+	err := ops.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", time.Now()).Delete(&models.OIDCAuthorization{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(authorization).Error
+	})
+	if err != nil {
+		ops.log.Errorf("Failed to create single sign-on authorization: %v", err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// ConsumeOIDCAuthorization deletes the authorization of the state and returns it, only if it is not expired,
+// such that the state can't be used again.
+func (ops *operations) ConsumeOIDCAuthorization(stateHash string, now time.Time) (*models.OIDCAuthorization, error) {
+	var authorizations []models.OIDCAuthorization
+	if err := ops.db.Clauses(clause.Returning{}).Where("state_hash = ? AND expires_at > ?", stateHash, now).
+		Delete(&authorizations).Error; err != nil {
+		ops.log.Errorf("Failed to consume single sign-on authorization: %v", err)
+		return nil, appErrors.ErrInternal
+	}
+	if len(authorizations) == 0 {
+		return nil, appErrors.ErrInvalidOrExpiredOIDCState
+	}
+	return &authorizations[0], nil
+}
+
+// CreateSession records login of the user, along with the device it is made from.
+func (ops *operations) CreateSession(session *models.Session) error {
+	if err := ops.db.Model(&models.Session{}).Create(session).Error; err != nil {
+		ops.log.Errorf("Failed to create session for user with id %d: %v", session.UserID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// TouchSession records the instant session of the refresh token family is last seen, and returns the session.
+func (ops *operations) TouchSession(familyID string, lastSeenAt time.Time) (*models.Session, error) {
+	var sessions []models.Session
+	result := ops.db.Model(&sessions).Clauses(clause.Returning{}).Where("family_id = ?", familyID).
+		UpdateColumn("last_seen_at", lastSeenAt)
+	if result.Error != nil {
+		ops.log.Errorf("Failed to touch session: %v", result.Error)
+		return nil, appErrors.ErrInternal
+	}
+	if len(sessions) == 0 {
+		return nil, appErrors.ErrSessionDoesNotExist
+	}
+	return &sessions[0], nil
+}
+
+// activeFamilies selects refresh token families having a refresh token which is still usable.
+func (ops *operations) activeFamilies() *gorm.DB {
+	return ops.db.Model(&models.RefreshToken{}).Select("family_id").
+		Where("used = ? AND revoked = ? AND expires_at > ?", false, false, time.Now())
+}
+
+// FetchSessions lists active sessions of the user, i.e. the ones which are neither logged out, revoked nor expired.
+func (ops *operations) FetchSessions(userID uint) ([]models.Session, error) {
+	sessions := []models.Session{}
+	if err := ops.db.Where("user_id = ? AND family_id IN (?)", userID, ops.activeFamilies()).
+		Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		ops.log.Errorf("Failed to fetch sessions of user with id %d: %v", userID, err)
+		return nil, appErrors.ErrInternal
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes refresh tokens of the session, only if the session is active and owned by the user.
+func (ops *operations) RevokeSession(userID uint, sessionID uint) error {
+	txErr := ops.db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		if err := tx.Where("id = ? AND user_id = ? AND family_id IN (?)", sessionID, userID, ops.activeFamilies()).
+			First(&session).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).Where("family_id = ?", session.FamilyID).
+			Update("revoked", true).Error
+	})
+	if txErr != nil {
+		if errors.Is(txErr, gorm.ErrRecordNotFound) {
+			return appErrors.ErrSessionDoesNotExist
+		}
+		ops.log.Errorf("Failed to revoke session %d of user with id %d: %v", sessionID, userID, txErr)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
+
+// FetchImpersonationAudits lists requests made while impersonating the user, most recent first.
+func (ops *operations) FetchImpersonationAudits(userID uint) ([]models.ImpersonationAudit, error) {
+	audits := []models.ImpersonationAudit{}
+	if err := ops.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&audits).Error; err != nil {
+		ops.log.Errorf("Failed to fetch impersonation audits of user with id %d: %v", userID, err)
+		return nil, appErrors.ErrInternal
+	}
+	return audits, nil
+}
+
+// GetActiveElevation fetches approved role elevation of the user, window of which hasn't ended as of now.
+// Elevation ending last is preferred, if several are active.
+func (ops *operations) GetActiveElevation(userID uint, now time.Time) (*models.RoleElevation, error) {
+	elevation := new(models.RoleElevation)
+	if err := ops.db.Where("user_id = ? AND status = ? AND expires_at > ?", userID, models.ElevationStatusApproved,
+		now).Order("expires_at DESC").First(elevation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appErrors.ErrElevationDoesNotExist
+		}
+		ops.log.Errorf("Failed to fetch active role elevation of user with id %d: %v", userID, err)
+		return nil, appErrors.ErrInternal
+	}
+	return elevation, nil
+}
+
+// EndElevations ends pending and active role elevations of the user as of now, since they were requested and
+// reviewed against the role the user held then.
+func (ops *operations) EndElevations(userID uint, now time.Time) error {
+	if err := ops.db.Model(&models.RoleElevation{}).Where("user_id = ? AND status IN ? AND expires_at > ?", userID,
+		[]string{models.ElevationStatusPending, models.ElevationStatusApproved}, now).
+		Updates(map[string]interface{}{"status": models.ElevationStatusRevoked, "revoked_at": now,
+			"expires_at": now}).Error; err != nil {
+		ops.log.Errorf("Failed to end role elevations of user with id %d: %v", userID, err)
+		return appErrors.ErrInternal
+	}
+	return nil
+}
@@ -125,14 +125,14 @@ var _ = Describe("Users [operations]", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE email = $1`)).
 				WillReturnError(errors.New("connection is already closed"))
 
-			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash")
+			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash", nil)
 			Expect(err).To(Not(BeNil()))
 			Expect(err).To(MatchError(appErrors.ErrInternal))
 		})
 		It("user already exist with desired email", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user" WHERE email = $1`)).
 				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
-			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash")
+			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash", nil)
 			Expect(err).To(Not(BeNil()))
 			Expect(err).To(MatchError(appErrors.ErrUserWithSameEmailAlreadyExists))
 		})
@@ -152,9 +152,20 @@ var _ = Describe("Users [operations]", func() {
 					"basic",
 					"hash",
 					true,
+					nil,
+					0,
+					nil,
+					false,
+					"",
+					"",
+					0,
+					nil,
+					nil,
+					false,
+					false,
 				).WillReturnError(appErrors.ErrUniqueKeyConstrainViolation)
 			mock.ExpectRollback()
-			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash")
+			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash", nil)
 			Expect(err).To(MatchError(appErrors.ErrUserWithSameEmailAlreadyExists))
 		})
 		It("In distributed/concurrent env, while proceeding to create email, we experience Internal error", func() {
@@ -172,9 +183,20 @@ var _ = Describe("Users [operations]", func() {
 					"basic",
 					"hash",
 					true,
+					nil,
+					0,
+					nil,
+					false,
+					"",
+					"",
+					0,
+					nil,
+					nil,
+					false,
+					false,
 				).WillReturnError(errors.New("connection error"))
 			mock.ExpectRollback()
-			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash")
+			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash", nil)
 			Expect(err).To(MatchError(appErrors.ErrInternal))
 		})
 		It("successfully create user", func() {
@@ -192,9 +214,20 @@ var _ = Describe("Users [operations]", func() {
 					"basic",
 					"hash",
 					true,
+					nil,
+					0,
+					nil,
+					false,
+					"",
+					"",
+					0,
+					nil,
+					nil,
+					false,
+					false,
 				).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
 			mock.ExpectCommit()
-			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash")
+			err := ops.CreateUser("admin", "admin@mgmtportal.com", "basic", "hash", nil)
 			Expect(err).To(BeNil())
 		})
 	})
@@ -433,4 +466,735 @@ var _ = Describe("Users [operations]", func() {
 			Expect(err).To(BeNil())
 		})
 	})
+	Context("Create refresh token", func() {
+		It("Internal error while persisting token", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_token"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.CreateRefreshToken(1, "family", "hash", time.Now().Add(time.Hour))
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully persist token", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_token"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+			mock.ExpectCommit()
+			err := ops.CreateRefreshToken(1, "family", "hash", time.Now().Add(time.Hour))
+			Expect(err).To(BeNil())
+		})
+	})
+	Context("Rotate refresh token", func() {
+		columns := []string{"id", "created_at", "updated_at", "deleted_at", "user_id", "family_id", "token_hash",
+			"expires_at", "used", "revoked"}
+		It("Unknown refresh token", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns))
+			mock.ExpectRollback()
+			token, err := ops.RotateRefreshToken("hash", "newHash", time.Now().Add(time.Hour))
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredRefreshToken))
+			Expect(token).To(BeNil())
+		})
+		It("Expired refresh token", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(1, time.Time{}, time.Time{}, nil, 1, "family", "hash",
+					time.Now().Add(-time.Hour), false, false))
+			mock.ExpectRollback()
+			token, err := ops.RotateRefreshToken("hash", "newHash", time.Now().Add(time.Hour))
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredRefreshToken))
+			Expect(token).To(BeNil())
+		})
+		It("Replayed refresh token revokes the family", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(1, time.Time{}, time.Time{}, nil, 1, "family", "hash",
+					time.Now().Add(time.Hour), true, false))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "revoked"=$1,"updated_at"=$2 WHERE family_id = $3`)).
+				WithArgs(true, sqlmock.AnyArg(), "family").
+				WillReturnResult(sqlmock.NewResult(1, 2))
+			mock.ExpectCommit()
+			token, err := ops.RotateRefreshToken("hash", "newHash", time.Now().Add(time.Hour))
+			Expect(err).To(MatchError(appErrors.ErrRefreshTokenReused))
+			Expect(token).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+		It("Internal error while persisting successor", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(1, time.Time{}, time.Time{}, nil, 1, "family", "hash",
+					time.Now().Add(time.Hour), false, false))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "used"=$1`)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_token"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			token, err := ops.RotateRefreshToken("hash", "newHash", time.Now().Add(time.Hour))
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+			Expect(token).To(BeNil())
+		})
+		It("Successful rotation", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(1, time.Time{}, time.Time{}, nil, 7, "family", "hash",
+					time.Now().Add(time.Hour), false, false))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "used"=$1`)).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_token"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("2"))
+			mock.ExpectCommit()
+			token, err := ops.RotateRefreshToken("hash", "newHash", time.Now().Add(time.Hour))
+			Expect(err).To(BeNil())
+			Expect(token.UserID).To(Equal(uint(7)))
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("Revoke refresh tokens", func() {
+		It("Internal error while revoking token family", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "revoked"=$1,"updated_at"=$2 WHERE family_id IN ` +
+				`(SELECT "family_id" FROM "refresh_token" WHERE token_hash = $3 AND user_id = $4)`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.RevokeRefreshTokenFamily(1, "hash")
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully revoke token family", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "revoked"=$1`)).
+				WithArgs(true, sqlmock.AnyArg(), "hash", 1).
+				WillReturnResult(sqlmock.NewResult(1, 2))
+			mock.ExpectCommit()
+			err := ops.RevokeRefreshTokenFamily(1, "hash")
+			Expect(err).To(BeNil())
+		})
+		It("Internal error while revoking user tokens", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "revoked"=$1,"updated_at"=$2 WHERE user_id = $3`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.RevokeUserRefreshTokens(1)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully revoke user tokens", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "revoked"=$1,"updated_at"=$2 WHERE user_id = $3`)).
+				WithArgs(true, sqlmock.AnyArg(), 1).
+				WillReturnResult(sqlmock.NewResult(1, 3))
+			mock.ExpectCommit()
+			err := ops.RevokeUserRefreshTokens(1)
+			Expect(err).To(BeNil())
+		})
+	})
+	Context("Personal access tokens", func() {
+		It("Internal error while creating token", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "personal_access_token"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.CreateAccessToken(&models.PersonalAccessToken{UserID: 1, Name: "ci", Scopes: []string{"service:read"}})
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully create token", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "personal_access_token"`)).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 1, "ci", "pat_abcdefgh", "hash",
+					`["service:read"]`, sqlmock.AnyArg(), nil).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+			mock.ExpectCommit()
+			accessToken := &models.PersonalAccessToken{UserID: 1, Name: "ci", Prefix: "pat_abcdefgh", TokenHash: "hash",
+				Scopes: []string{"service:read"}, ExpiresAt: time.Now().Add(time.Hour)}
+			err := ops.CreateAccessToken(accessToken)
+			Expect(err).To(BeNil())
+			Expect(accessToken.ID).To(Equal(uint(3)))
+		})
+		It("Internal error while fetching tokens", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token" WHERE user_id = $1`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.FetchAccessTokens(1)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully fetch tokens", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personal_access_token" WHERE user_id = $1 AND ` +
+				`"personal_access_token"."deleted_at" IS NULL ORDER BY id`)).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "scopes"}).
+					AddRow(3, 1, "ci", `["service:read","service:write"]`))
+			accessTokens, err := ops.FetchAccessTokens(1)
+			Expect(err).To(BeNil())
+			Expect(accessTokens).To(HaveLen(1))
+			Expect(accessTokens[0].Scopes).To(Equal([]string{"service:read", "service:write"}))
+		})
+		It("Internal error while revoking token", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "personal_access_token" WHERE id = $1 AND user_id = $2`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.RevokeAccessToken(1, 3)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Revoking token of other user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "personal_access_token" WHERE id = $1 AND user_id = $2`)).
+				WithArgs(3, 1).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.RevokeAccessToken(1, 3)
+			Expect(err).To(MatchError(appErrors.ErrAccessTokenDoesNotExist))
+		})
+		It("successfully revoke token", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "personal_access_token" WHERE id = $1 AND user_id = $2`)).
+				WithArgs(3, 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.RevokeAccessToken(1, 3)
+			Expect(err).To(BeNil())
+		})
+	})
+	Context("login failures", func() {
+		It("Record login failure increments counter", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`UPDATE "user" SET "failed_login_attempts"=failed_login_attempts + 1 WHERE id = $1 AND ` +
+					`"user"."deleted_at" IS NULL RETURNING "failed_login_attempts"`)).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"failed_login_attempts"}).AddRow(3))
+			mock.ExpectCommit()
+			failedAttempts, err := ops.RecordLoginFailure(1)
+			Expect(err).To(BeNil())
+			Expect(failedAttempts).To(Equal(3))
+		})
+		It("Internal error while recording login failure", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "user"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			_, err := ops.RecordLoginFailure(1)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Lock user", func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user" SET "failed_login_attempts"=$1,"locked_until"=$2 WHERE id = $3`)).
+				WithArgs(0, lockedUntil, 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.LockUser(1, lockedUntil)
+			Expect(err).To(BeNil())
+		})
+		It("Reset login failures of unknown user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user" SET "failed_login_attempts"=$1,"locked_until"=$2 WHERE id = $3`)).
+				WithArgs(0, nil, 1).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.ResetLoginFailures(1)
+			Expect(err).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("successfully reset login failures", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user" SET "failed_login_attempts"=$1,"locked_until"=$2 WHERE id = $3`)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.ResetLoginFailures(1)
+			Expect(err).To(BeNil())
+		})
+		It("Internal error while rehashing password", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user" SET "password_hash"=$1 WHERE (id = $2 AND password_hash = $3) AND "user"."deleted_at" IS NULL`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.RehashPassword(1, "old-hash", "new-hash")
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("successfully rehash password", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user" SET "password_hash"=$1 WHERE (id = $2 AND password_hash = $3) AND "user"."deleted_at" IS NULL`)).
+				WithArgs("new-hash", 1, "old-hash").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.RehashPassword(1, "old-hash", "new-hash")
+			Expect(err).To(BeNil())
+		})
+		It("No login failures from client IP", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "login_failure" WHERE ip = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"ip", "failed_attempts"}))
+			failure, err := ops.GetLoginFailure("10.0.0.1")
+			Expect(err).To(BeNil())
+			Expect(failure.IP).To(Equal("10.0.0.1"))
+			Expect(failure.FailedAttempts).To(Equal(0))
+		})
+		It("Login failures from client IP", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "login_failure" WHERE ip = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"ip", "failed_attempts"}).AddRow("10.0.0.1", 4))
+			failure, err := ops.GetLoginFailure("10.0.0.1")
+			Expect(err).To(BeNil())
+			Expect(failure.FailedAttempts).To(Equal(4))
+		})
+		It("Internal error while fetching login failures", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "login_failure" WHERE ip = $1`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.GetLoginFailure("10.0.0.1")
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Record login failure of client IP increments counter", func() {
+			now := time.Now()
+			forgetBefore := now.Add(-time.Minute)
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "login_failure" ("ip","failed_attempts","last_failed_at",`+
+				`"locked_until") VALUES ($1,$2,$3,$4) ON CONFLICT ("ip") DO UPDATE SET "failed_attempts"=CASE WHEN `+
+				`login_failure.last_failed_at < $5 THEN 1 ELSE login_failure.failed_attempts + 1 END,`+
+				`"last_failed_at"=$6 RETURNING "failed_attempts"`)).
+				WithArgs("10.0.0.1", 1, now, nil, forgetBefore, now).
+				WillReturnRows(sqlmock.NewRows([]string{"failed_attempts"}).AddRow(4))
+			mock.ExpectCommit()
+			failedAttempts, err := ops.RecordIPLoginFailure("10.0.0.1", now, forgetBefore)
+			Expect(err).To(BeNil())
+			Expect(failedAttempts).To(Equal(4))
+		})
+		It("Lock client IP", func() {
+			lockedUntil := time.Now().Add(time.Minute)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "login_failure" SET "failed_attempts"=$1,"locked_until"=$2 WHERE ip = $3`)).
+				WithArgs(0, lockedUntil, "10.0.0.1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.LockIP("10.0.0.1", lockedUntil)
+			Expect(err).To(BeNil())
+		})
+	})
+	Context("password history", func() {
+		It("Fetch password history", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(
+				`SELECT "password_hash" FROM "password_history" WHERE user_id = $1 ORDER BY id desc LIMIT $2`)).
+				WithArgs(1, 3).
+				WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow("hash2").AddRow("hash1"))
+			passwordHashes, err := ops.FetchPasswordHistory(1, 3)
+			Expect(err).To(BeNil())
+			Expect(passwordHashes).To(Equal([]string{"hash2", "hash1"}))
+		})
+		It("Internal error while fetching password history", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT "password_hash" FROM "password_history"`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.FetchPasswordHistory(1, 3)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Record password history and prune older entries", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "password_history"`)).
+				WithArgs(sqlmock.AnyArg(), 1, "hash").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "password_history" WHERE user_id = $1 AND id NOT IN `+
+				`(SELECT "id" FROM "password_history" WHERE user_id = $2 ORDER BY id desc LIMIT $3)`)).
+				WithArgs(1, 1, 3).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.RecordPasswordHistory(1, "hash", 3)
+			Expect(err).To(BeNil())
+		})
+		It("Internal error while recording password history", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "password_history"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.RecordPasswordHistory(1, "hash", 3)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+	})
+	Context("MFA", func() {
+		It("Set pending MFA secret of unknown user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "mfa_pending_secret"=$1 WHERE id = $2`)).
+				WithArgs("SECRET", 1).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.SetPendingMFASecret(1, "SECRET")
+			Expect(err).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("Enable MFA and replace recovery codes", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "mfa_enabled"=$1,"mfa_last_used_step"=$2,`+
+				`"mfa_pending_secret"=$3,"mfa_secret"=$4 WHERE id = $5`)).
+				WithArgs(true, 0, "", "SECRET", 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mfa_recovery_code" WHERE user_id = $1`)).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "mfa_recovery_code" ("created_at","user_id","code_hash") `+
+				`VALUES ($1,$2,$3),($4,$5,$6) RETURNING "id"`)).
+				WithArgs(sqlmock.AnyArg(), 1, "hash1", sqlmock.AnyArg(), 1, "hash2").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+			mock.ExpectCommit()
+			err := ops.EnableMFA(1, "SECRET", []string{"hash1", "hash2"})
+			Expect(err).To(BeNil())
+		})
+		It("Enable MFA of unknown user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user"`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+			err := ops.EnableMFA(1, "SECRET", []string{"hash1"})
+			Expect(err).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("Disable MFA clears recovery codes", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "mfa_enabled"=$1,"mfa_last_used_step"=$2,`+
+				`"mfa_pending_secret"=$3,"mfa_secret"=$4 WHERE id = $5`)).
+				WithArgs(false, 0, "", "", 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mfa_recovery_code" WHERE user_id = $1`)).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectCommit()
+			err := ops.DisableMFA(1)
+			Expect(err).To(BeNil())
+		})
+		It("Internal error while disabling MFA", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.DisableMFA(1)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Record later MFA step", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`UPDATE "user" SET "mfa_last_used_step"=$1 WHERE (id = $2 AND mfa_last_used_step < $3)`)).
+				WithArgs(100, 1, 100).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			accepted, err := ops.RecordMFAStep(1, 100)
+			Expect(err).To(BeNil())
+			Expect(accepted).To(BeTrue())
+		})
+		It("Replayed MFA step is rejected", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "mfa_last_used_step"=$1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			accepted, err := ops.RecordMFAStep(1, 100)
+			Expect(err).To(BeNil())
+			Expect(accepted).To(BeFalse())
+		})
+		It("Consume recovery code", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mfa_recovery_code" WHERE user_id = $1 AND code_hash = $2`)).
+				WithArgs(1, "hash1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			consumed, err := ops.ConsumeRecoveryCode(1, "hash1")
+			Expect(err).To(BeNil())
+			Expect(consumed).To(BeTrue())
+		})
+		It("Unknown or used recovery code", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mfa_recovery_code"`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			consumed, err := ops.ConsumeRecoveryCode(1, "hash1")
+			Expect(err).To(BeNil())
+			Expect(consumed).To(BeFalse())
+		})
+		It("Internal error while replacing recovery codes", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "mfa_recovery_code"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.ReplaceRecoveryCodes(1, []string{"hash1"})
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+	})
+	Context("password reset tokens", func() {
+		It("Create reset token discards formerly issued ones", func() {
+			expiresAt := time.Now().Add(time.Minute)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(
+				`DELETE FROM "password_reset_token" WHERE user_id = $1 AND used_at IS NULL`)).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "password_reset_token"`)).
+				WithArgs(sqlmock.AnyArg(), 1, "hash", expiresAt, nil).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectCommit()
+			err := ops.CreatePasswordResetToken(1, "hash", expiresAt)
+			Expect(err).To(BeNil())
+		})
+		It("Internal error while creating reset token", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "password_reset_token"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.CreatePasswordResetToken(1, "hash", time.Now())
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Fetch valid reset token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "password_reset_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token_hash", "expires_at", "used_at"}).
+					AddRow(1, 3, "hash", time.Now().Add(time.Minute), nil))
+			resetToken, err := ops.GetPasswordResetToken("hash")
+			Expect(err).To(BeNil())
+			Expect(resetToken.UserID).To(Equal(uint(3)))
+		})
+		It("Unknown reset token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "password_reset_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, err := ops.GetPasswordResetToken("hash")
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredResetToken))
+		})
+		It("Expired reset token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "password_reset_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token_hash", "expires_at", "used_at"}).
+					AddRow(1, 3, "hash", time.Now().Add(-time.Minute), nil))
+			_, err := ops.GetPasswordResetToken("hash")
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredResetToken))
+		})
+		It("Used reset token", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "password_reset_token" WHERE token_hash = $1`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token_hash", "expires_at", "used_at"}).
+					AddRow(1, 3, "hash", time.Now().Add(time.Minute), time.Now()))
+			_, err := ops.GetPasswordResetToken("hash")
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredResetToken))
+		})
+		It("Consume reset token", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "password_reset_token" SET "used_at"=$1 WHERE `+
+				`token_hash = $2 AND used_at IS NULL AND expires_at > $3`)).
+				WithArgs(sqlmock.AnyArg(), "hash", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			err := ops.ConsumePasswordResetToken("hash")
+			Expect(err).To(BeNil())
+		})
+		It("Reset token consumed already", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "password_reset_token" SET "used_at"=$1`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			err := ops.ConsumePasswordResetToken("hash")
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredResetToken))
+		})
+	})
+	Context("single sign-on", func() {
+		It("Provision user without password", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user"`)).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "John", "john@mgmtportal.com",
+					"advanced", "", false, nil, 0, nil, false, "", "", 0, "https://idp", "idp-user-1", true,
+					false).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			mock.ExpectCommit()
+			user, err := ops.ProvisionUser("John", "john@mgmtportal.com", "advanced", "https://idp", "idp-user-1")
+			Expect(err).To(BeNil())
+			Expect(user.ID).To(Equal(uint(2)))
+		})
+		It("Provision user concurrently provisioned", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user"`)).
+				WillReturnError(appErrors.ErrUniqueKeyConstrainViolation)
+			mock.ExpectRollback()
+			_, err := ops.ProvisionUser("John", "john@mgmtportal.com", "advanced", "https://idp", "idp-user-1")
+			Expect(err).To(MatchError(appErrors.ErrUserWithSameEmailAlreadyExists))
+		})
+		It("Fetch user linked to identity", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE (oidc_issuer = $1 AND oidc_subject = $2) `+
+				`AND "user"."deleted_at" IS NULL LIMIT $3`)).
+				WithArgs("https://idp", "idp-user-1", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "oidc_provisioned"}).AddRow(2, true))
+			user, err := ops.GetUserByOIDCIdentity("https://idp", "idp-user-1")
+			Expect(err).To(BeNil())
+			Expect(user.ID).To(Equal(uint(2)))
+			Expect(user.OIDCProvisioned).To(BeTrue())
+		})
+		It("No user linked to identity", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE (oidc_issuer = $1 AND oidc_subject = $2)`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, err := ops.GetUserByOIDCIdentity("https://idp", "idp-user-1")
+			Expect(err).To(MatchError(appErrors.ErrUserDoesNotExist))
+		})
+		It("Link identity to user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "oidc_issuer"=$1,"oidc_subject"=$2,"updated_at"=$3 `+
+				`WHERE id = $4 AND "user"."deleted_at" IS NULL`)).
+				WithArgs("https://idp", "idp-user-1", sqlmock.AnyArg(), 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			Expect(ops.LinkOIDCIdentity(1, "https://idp", "idp-user-1")).To(Succeed())
+		})
+		It("Link identity linked to other user", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "oidc_issuer"=$1`)).
+				WillReturnError(appErrors.ErrUniqueKeyConstrainViolation)
+			mock.ExpectRollback()
+			err := ops.LinkOIDCIdentity(1, "https://idp", "idp-user-1")
+			Expect(err).To(MatchError(appErrors.ErrOIDCIdentityAlreadyLinked))
+		})
+		It("Create authorization purges expired ones", func() {
+			expiresAt := time.Now().Add(time.Minute)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "oidc_authorization" WHERE expires_at <= $1`)).
+				WithArgs(sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "oidc_authorization"`)).
+				WithArgs(sqlmock.AnyArg(), "state-hash", "verifier", "nonce", expiresAt, nil).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectCommit()
+			err := ops.CreateOIDCAuthorization(&models.OIDCAuthorization{StateHash: "state-hash",
+				CodeVerifier: "verifier", Nonce: "nonce", ExpiresAt: expiresAt})
+			Expect(err).To(BeNil())
+		})
+		It("Internal error while creating authorization", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "oidc_authorization"`)).
+				WillReturnError(errors.New("connection error"))
+			mock.ExpectRollback()
+			err := ops.CreateOIDCAuthorization(&models.OIDCAuthorization{StateHash: "state-hash"})
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Consume authorization", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM "oidc_authorization" WHERE `+
+				`state_hash = $1 AND expires_at > $2 RETURNING *`)).
+				WithArgs("state-hash", sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "state_hash", "code_verifier", "nonce", "expires_at"}).
+					AddRow(1, "state-hash", "verifier", "nonce", time.Now().Add(time.Minute)))
+			mock.ExpectCommit()
+			authorization, err := ops.ConsumeOIDCAuthorization("state-hash", time.Now())
+			Expect(err).To(BeNil())
+			Expect(authorization.CodeVerifier).To(Equal("verifier"))
+			Expect(authorization.Nonce).To(Equal("nonce"))
+		})
+		It("Unknown, expired or consumed authorization", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM "oidc_authorization"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectCommit()
+			_, err := ops.ConsumeOIDCAuthorization("state-hash", time.Now())
+			Expect(err).To(MatchError(appErrors.ErrInvalidOrExpiredOIDCState))
+		})
+	})
+	Context("impersonation audits", func() {
+		It("Fetch audits of the user", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "impersonation_audit" WHERE user_id = $1 ` +
+				`ORDER BY created_at DESC`)).
+				WithArgs(2).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "actor_id", "user_id"}).AddRow(4, 1, 2).AddRow(3, 1, 2))
+			audits, err := ops.FetchImpersonationAudits(2)
+			Expect(err).To(BeNil())
+			Expect(audits).To(HaveLen(2))
+		})
+		It("Internal error", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "impersonation_audit"`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.FetchImpersonationAudits(2)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+	})
+	Context("role elevations", func() {
+		It("Active elevation of the user", func() {
+			now := time.Now()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "role_elevation" WHERE user_id = $1 AND status = $2 `+
+				`AND expires_at > $3 ORDER BY expires_at DESC,"role_elevation"."id" LIMIT $4`)).
+				WithArgs(2, models.ElevationStatusApproved, now, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "role", "status", "expires_at"}).
+					AddRow(3, 2, "admin", models.ElevationStatusApproved, now.Add(time.Hour)))
+			elevation, err := ops.GetActiveElevation(2, now)
+			Expect(err).To(BeNil())
+			Expect(elevation.Role).To(Equal("admin"))
+		})
+		It("User holds no active elevation", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "role_elevation"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			_, err := ops.GetActiveElevation(2, time.Now())
+			Expect(err).To(MatchError(appErrors.ErrElevationDoesNotExist))
+		})
+		It("End pending and active elevations of the user", func() {
+			now := time.Now()
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "role_elevation" SET "expires_at"=$1,"revoked_at"=$2,"status"=$3 `+
+				`WHERE user_id = $4 AND status IN ($5,$6) AND expires_at > $7`)).
+				WithArgs(now, now, models.ElevationStatusRevoked, 2, models.ElevationStatusPending,
+					models.ElevationStatusApproved, now).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectCommit()
+			Expect(ops.EndElevations(2, now)).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(BeNil())
+		})
+	})
+	Context("sessions", func() {
+		It("Create session", func() {
+			lastSeenAt := time.Now()
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "session"`)).
+				WithArgs(sqlmock.AnyArg(), 1, "family", "Mozilla/5.0", "10.0.0.7", lastSeenAt).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+			mock.ExpectCommit()
+			session := &models.Session{UserID: 1, FamilyID: "family", UserAgent: "Mozilla/5.0", IP: "10.0.0.7",
+				LastSeenAt: lastSeenAt}
+			Expect(ops.CreateSession(session)).To(BeNil())
+			Expect(session.ID).To(Equal(uint(4)))
+		})
+		It("Touch session", func() {
+			lastSeenAt := time.Now()
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "session" SET "last_seen_at"=$1 WHERE family_id = $2 RETURNING *`)).
+				WithArgs(lastSeenAt, "family").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id"}).AddRow(4, 1, "family"))
+			mock.ExpectCommit()
+			session, err := ops.TouchSession("family", lastSeenAt)
+			Expect(err).To(BeNil())
+			Expect(session.ID).To(Equal(uint(4)))
+		})
+		It("Touch session unknown to the family", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "session"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectCommit()
+			_, err := ops.TouchSession("family", time.Now())
+			Expect(err).To(MatchError(appErrors.ErrSessionDoesNotExist))
+		})
+		It("Fetch active sessions", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "session" WHERE user_id = $1 AND family_id IN `+
+				`(SELECT "family_id" FROM "refresh_token" WHERE (used = $2 AND revoked = $3 AND expires_at > $4) `+
+				`AND "refresh_token"."deleted_at" IS NULL) ORDER BY last_seen_at DESC`)).
+				WithArgs(1, false, false, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(4, 1).AddRow(5, 1))
+			sessions, err := ops.FetchSessions(1)
+			Expect(err).To(BeNil())
+			Expect(sessions).To(HaveLen(2))
+		})
+		It("Internal error while fetching sessions", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "session"`)).
+				WillReturnError(errors.New("connection error"))
+			_, err := ops.FetchSessions(1)
+			Expect(err).To(MatchError(appErrors.ErrInternal))
+		})
+		It("Revoke session", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "session" WHERE id = $1 AND user_id = $2 AND family_id IN `)).
+				WithArgs(4, 1, false, false, sqlmock.AnyArg(), 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id"}).AddRow(4, 1, "family"))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_token" SET "revoked"=$1,"updated_at"=$2 `+
+				`WHERE family_id = $3 AND "refresh_token"."deleted_at" IS NULL`)).
+				WithArgs(true, sqlmock.AnyArg(), "family").
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectCommit()
+			Expect(ops.RevokeSession(1, 4)).To(BeNil())
+		})
+		It("Revoke session of other user or already revoked", func() {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "session"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectRollback()
+			Expect(ops.RevokeSession(1, 4)).To(MatchError(appErrors.ErrSessionDoesNotExist))
+		})
+	})
 })
@@ -1,9 +1,13 @@
 package user
 
 import (
+	"sync"
+	"userservice/internal/auth"
 	"userservice/internal/configs"
 	"userservice/internal/middleware"
 	"userservice/internal/models"
+	"userservice/internal/notify"
+	"userservice/internal/oidc"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -12,13 +16,27 @@ import (
 
 // Handler for user management.
 type Handler struct {
-	runtimeConfig *configs.Config
-	operations    models.UserOperations
+	runtimeConfig  *configs.Config
+	operations     models.UserOperations
+	tokens         *auth.TokenIssuer
+	revoker        models.TokenRevocationOperations
+	passwordPolicy *auth.PasswordPolicy
+	passwordHasher auth.PasswordHasher
+	notifier       notify.Notifier
+	// oidc is the identity provider of single sign-on, nil if single sign-on is not configured
+	oidc *oidc.Provider
+	// wg tracks notifications delivered in background, such that shutdown waits for them
+	wg  *sync.WaitGroup
+	log *zap.SugaredLogger
 }
 
 // NewHandler initializes user handler context with desired parameters.
-func NewHandler(log *zap.SugaredLogger, config *configs.Config, db *gorm.DB) *Handler {
-	return &Handler{runtimeConfig: config, operations: newOperations(db, log)}
+func NewHandler(wg *sync.WaitGroup, log *zap.SugaredLogger, config *configs.Config, db *gorm.DB,
+	tokens *auth.TokenIssuer, revoker models.TokenRevocationOperations, passwordPolicy *auth.PasswordPolicy,
+	passwordHasher auth.PasswordHasher, notifier notify.Notifier, oidcProvider *oidc.Provider) *Handler {
+	return &Handler{runtimeConfig: config, operations: newOperations(db, log), tokens: tokens, revoker: revoker,
+		passwordPolicy: passwordPolicy, passwordHasher: passwordHasher, notifier: notifier, oidc: oidcProvider,
+		wg: wg, log: log}
 }
 
 // RegisterRoutes has sent of route endpoints categorized as per authz roles using middleware.
@@ -26,23 +44,60 @@ func (h *Handler) RegisterRoutes(routers *gin.RouterGroup) {
 
 	// Authorized routes for all user roles.
 	routers.POST("/login", h.login)
-	routers.PUT("/user/self/password", h.changeUserPassword)
+	routers.POST("/login/mfa", h.loginWithMFA)
+	routers.GET("/login/oidc", h.startOIDCLogin)
+	routers.POST("/login/oidc/callback", h.completeOIDCLogin)
+	routers.POST("/token/refresh", h.refreshToken)
+	routers.POST("/password/forgot", h.forgotPassword)
+	routers.POST("/password/reset", h.resetPassword)
 
-	// Authorized routes for advanced, and admin users.
-	advancedAndAdminUserRoutes := routers.Group("/")
-	advancedAndAdminUserRoutes.Use(middleware.AuthzRoles(models.RoleAdvanced, models.RoleAdmin))
+	// Authorized routes for all user roles, only through user session, and not through personal access tokens.
+	userSessionRoutes := routers.Group("/")
+	userSessionRoutes.Use(middleware.RequireUserSession())
 	{
-		advancedAndAdminUserRoutes.GET("/user/:id", h.getUserByID)
-		advancedAndAdminUserRoutes.GET("/users", h.fetchUsers)
+		userSessionRoutes.PUT("/user/self/password", h.changeUserPassword)
+		userSessionRoutes.POST("/logout", h.logout)
+		userSessionRoutes.POST("/user/self/tokens", h.createAccessToken)
+		userSessionRoutes.GET("/user/self/tokens", h.fetchAccessTokens)
+		userSessionRoutes.DELETE("/user/self/tokens/:id", h.revokeAccessToken)
+		userSessionRoutes.GET("/user/self/sessions", h.fetchSessions)
+		userSessionRoutes.DELETE("/user/self/sessions/:id", h.revokeSession)
+		userSessionRoutes.POST("/user/self/mfa", h.enrollMFA)
+		userSessionRoutes.POST("/user/self/mfa/confirm", h.confirmMFA)
+		userSessionRoutes.POST("/user/self/mfa/disable", h.disableMFA)
+		userSessionRoutes.POST("/user/self/mfa/recovery-codes", h.regenerateRecoveryCodes)
+		userSessionRoutes.POST("/user/self/oidc", h.startOIDCLink)
+		userSessionRoutes.POST("/user/self/oidc/callback", h.completeOIDCLink)
 	}
 
-	// Authorized routes only for admin roles.
-	adminUserOnlyRoutes := routers.Group("/")
-	adminUserOnlyRoutes.Use(middleware.AuthzRoles(models.RoleAdmin))
+	// Authorized routes for roles permitted to view users.
+	userReadRoutes := routers.Group("/")
+	userReadRoutes.Use(middleware.RequirePermission(models.PermissionUserRead))
 	{
-		adminUserOnlyRoutes.POST("/user", h.addUser)
-		adminUserOnlyRoutes.PUT("/user/:id", h.updateUser)
-		adminUserOnlyRoutes.DELETE("/user/:id", h.deleteUser)
+		userReadRoutes.GET("/user/:id", h.getUserByID)
+		userReadRoutes.GET("/users", h.fetchUsers)
+	}
+
+	// Authorized routes for roles permitted to manage users.
+	userWriteRoutes := routers.Group("/")
+	userWriteRoutes.Use(middleware.RequirePermission(models.PermissionUserWrite))
+	{
+		userWriteRoutes.POST("/user", h.addUser)
+		userWriteRoutes.PUT("/user/:id", h.updateUser)
+		userWriteRoutes.DELETE("/user/:id", h.deleteUser)
+		userWriteRoutes.POST("/user/:id/unlock", h.unlockUser)
+		userWriteRoutes.DELETE("/user/:id/mfa", h.resetUserMFA)
+		userWriteRoutes.GET("/user/:id/sessions", h.fetchUserSessions)
+		userWriteRoutes.DELETE("/user/:id/sessions/:sid", h.revokeUserSession)
+		userWriteRoutes.GET("/user/:id/impersonations", h.fetchImpersonationAudits)
+	}
+
+	// Authorized routes for roles permitted to manage users, only through user session, such that impersonation
+	// is neither started by personal access tokens nor nested.
+	userWriteSessionRoutes := routers.Group("/")
+	userWriteSessionRoutes.Use(middleware.RequirePermission(models.PermissionUserWrite), middleware.RequireUserSession())
+	{
+		userWriteSessionRoutes.POST("/user/:id/impersonate", h.impersonateUser)
 	}
 
 }
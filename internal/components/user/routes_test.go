@@ -9,12 +9,12 @@ import (
 var _ = Describe("User [Handler]", func() {
 
 	It("Initializer Handler, list and ensure expected number of routes", func() {
-		h := NewHandler(nil, nil, nil)
+		h := NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 		gin.SetMode(gin.TestMode)
 		router := gin.New()
 		apiGroup := router.Group("/api/v1")
 		h.RegisterRoutes(apiGroup)
 		routes := router.Routes()
-		Expect(routes).To(HaveLen(7))
+		Expect(routes).To(HaveLen(31))
 	})
 })
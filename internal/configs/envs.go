@@ -4,25 +4,86 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 // Config represents runtime config accessible by application modules.
 type Config struct {
-	ServerPort              string
-	DBHost                  string
-	DBPort                  int64
-	DBUser                  string
-	DBName                  string
-	DBPassword              string
-	DBConnTimeout           int64
-	DBSlowQueryLogThreshold int64
-	DBMaxConnIdleTime       int64
-	DBMaxOpenConn           int64
-	LogLevel                string
-	JWTSecret               string
-	JWTExpirationInSeconds  int64
+	ServerPort                            string
+	TrustedProxies                        []string
+	DBHost                                string
+	DBPort                                int64
+	DBUser                                string
+	DBName                                string
+	DBPassword                            string
+	DBConnTimeout                         int64
+	DBSlowQueryLogThreshold               int64
+	DBMaxConnIdleTime                     int64
+	DBMaxOpenConn                         int64
+	LogLevel                              string
+	JWTSecret                             string
+	JWTSigningAlgorithm                   string
+	JWTSigningKeyFile                     string
+	JWTVerificationKeyFiles               []string
+	JWTExpirationInSeconds                int64
+	JWTIssuer                             string
+	JWTAudience                           string
+	JWTLeewayInSeconds                    int64
+	RefreshTokenExpirationInSeconds       int64
+	TokenRevocationSyncIntervalInSeconds  int64
+	AccessTokenMaxLifetimeInDays          int64
+	LoginMaxFailedAttempts                int64
+	LoginMaxFailedAttemptsPerIP           int64
+	LoginLockoutDurationInSeconds         int64
+	LoginFailureDelayInMilliseconds       int64
+	LoginFailureMaxDelayInMilliseconds    int64
+	PasswordMinLength                     int64
+	PasswordMaxLength                     int64
+	PasswordRequiredCharacterClasses      []string
+	PasswordHistorySize                   int64
+	PasswordHashAlgorithm                 string
+	BcryptCost                            int64
+	Argon2MemoryInKiB                     int64
+	Argon2Iterations                      int64
+	Argon2Parallelism                     int64
+	MFAIssuer                             string
+	MFARequiredRoles                      []string
+	MFAChallengeExpirationInSeconds       int64
+	MFARecoveryCodeCount                  int64
+	PasswordResetTokenExpirationInSeconds int64
+	PasswordResetURL                      string
+	NotifierDriver                        string
+	NotifierFrom                          string
+	NotifierOutboxDir                     string
+	SMTPHost                              string
+	SMTPPort                              int64
+	SMTPUsername                          string
+	SMTPPassword                          string
+	SMTPTLSMode                           string
+	NotifyTemporaryPassword               bool
+	TemporaryPasswordExpirationInSeconds  int64
+	InvitationExpirationInSeconds         int64
+	InvitationCleanupIntervalInSeconds    int64
+	InvitationURL                         string
+	OIDCIssuerURL                         string
+	OIDCClientID                          string
+	OIDCClientSecret                      string
+	OIDCRedirectURL                       string
+	OIDCScopes                            []string
+	OIDCGroupsClaim                       string
+	OIDCRoleMapping                       []string
+	OIDCDefaultRole                       string
+	OIDCJITProvisioning                   bool
+	OIDCStateExpirationInSeconds          int64
+	SCIMBearerToken                       string
+	SCIMDefaultRole                       string
+	SCIMMaxResults                        int64
+	ImpersonationExpirationInSeconds      int64
+	PolicyFile                            string
+	RoleElevationMaxDurationInMinutes     int64
+	RoleElevationPendingExpiryInMinutes   int64
 }
 
 // InitConfig initializes runtime config.
@@ -37,6 +98,9 @@ func InitConfig(envFile string) (*Config, error) {
 
 	return &Config{
 		ServerPort: getEnv("PORT", "8080"),
+		// Client IP is taken from X-Forwarded-For only when the request is relayed through these proxies.
+		TrustedProxies: getEnvAsList("TRUSTED_PROXIES", nil),
+
 		DBHost:     getEnv("DB_HOST", "127.0.0.1"),
 		DBPort:     getEnvAsInt("DB_PORT", 5432),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -53,6 +117,98 @@ func InitConfig(envFile string) (*Config, error) {
 		// Secret is preferred to be sent as environment variable.
 		JWTSecret:              getEnv("JWT_SECRET", "userservice123"),
 		JWTExpirationInSeconds: getEnvAsInt("JWT_EXPIRATION_IN_SECONDS", 900),
+		// Tokens minted for other issuer or audience are rejected, even if signed with the same key.
+		JWTIssuer:   getEnv("JWT_ISSUER", "userservice"),
+		JWTAudience: getEnv("JWT_AUDIENCE", "userservice"),
+		// Clock skew across hosts tolerated while validating exp, nbf and iat.
+		JWTLeewayInSeconds: getEnvAsInt("JWT_LEEWAY_IN_SECONDS", 30),
+		// Asymmetric algorithms let other services verify tokens through JWKS, without holding the secret.
+		JWTSigningAlgorithm: getEnv("JWT_SIGNING_ALGORITHM", "HS256"),
+		JWTSigningKeyFile:   getEnv("JWT_SIGNING_KEY_FILE", ""),
+		// Public keys of former signing keys are retained here during rotation.
+		JWTVerificationKeyFiles: getEnvAsList("JWT_VERIFICATION_KEY_FILES", nil),
+		// Refresh token outlives access token, user login is demanded only after its expiry.
+		RefreshTokenExpirationInSeconds: getEnvAsInt("REFRESH_TOKEN_EXPIRATION_IN_SECONDS", 604800),
+		// Revocations done by other app instances are honoured within this interval.
+		TokenRevocationSyncIntervalInSeconds: getEnvAsInt("TOKEN_REVOCATION_SYNC_INTERVAL_SEC", 30),
+		// Personal access tokens for automation are long-lived, yet are bound to expire.
+		AccessTokenMaxLifetimeInDays: getEnvAsInt("ACCESS_TOKEN_MAX_LIFETIME_IN_DAYS", 365),
+		// Consecutive failed logins lock the account, and failures from a client IP lock the IP, for the duration.
+		LoginMaxFailedAttempts:        getEnvAsInt("LOGIN_MAX_FAILED_ATTEMPTS", 5),
+		LoginMaxFailedAttemptsPerIP:   getEnvAsInt("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", 20),
+		LoginLockoutDurationInSeconds: getEnvAsInt("LOGIN_LOCKOUT_DURATION_IN_SECONDS", 900),
+		// Failed login is answered after a delay, doubling with every consecutive failure.
+		LoginFailureDelayInMilliseconds:    getEnvAsInt("LOGIN_FAILURE_DELAY_IN_MILLISECONDS", 250),
+		LoginFailureMaxDelayInMilliseconds: getEnvAsInt("LOGIN_FAILURE_MAX_DELAY_IN_MILLISECONDS", 4000),
+		// Password chosen by user must satisfy the policy, common passwords and email are always denied.
+		PasswordMinLength: getEnvAsInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordMaxLength: getEnvAsInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequiredCharacterClasses: getEnvAsList("PASSWORD_REQUIRED_CHARACTER_CLASSES",
+			[]string{"lower", "upper", "digit"}),
+		// Last few passwords of the user can't be chosen again, zero permits reuse.
+		PasswordHistorySize: getEnvAsInt("PASSWORD_HISTORY_SIZE", 5),
+		// Passwords are hashed with argon2id or bcrypt, hashes of other algorithm or parameters are rehashed at login.
+		PasswordHashAlgorithm: getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		BcryptCost:            getEnvAsInt("BCRYPT_COST", 12),
+		Argon2MemoryInKiB:     getEnvAsInt("ARGON2_MEMORY_IN_KIB", 19456),
+		Argon2Iterations:      getEnvAsInt("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism:     getEnvAsInt("ARGON2_PARALLELISM", 1),
+		// Issuer shown against the account in authenticator apps.
+		MFAIssuer: getEnv("MFA_ISSUER", "userservice"),
+		// Users of these roles have to enroll MFA, before accessing anything else.
+		MFARequiredRoles: getEnvAsList("MFA_REQUIRED_ROLES", nil),
+		// Time window to present the second factor, after the password is verified.
+		MFAChallengeExpirationInSeconds: getEnvAsInt("MFA_CHALLENGE_EXPIRATION_IN_SECONDS", 300),
+		MFARecoveryCodeCount:            getEnvAsInt("MFA_RECOVERY_CODE_COUNT", 10),
+		// Password reset token is sent to the user, appended to the URL of reset page if configured.
+		PasswordResetTokenExpirationInSeconds: getEnvAsInt("PASSWORD_RESET_TOKEN_EXPIRATION_IN_SECONDS", 1800),
+		PasswordResetURL:                      getEnv("PASSWORD_RESET_URL", ""),
+		// Notifications are written to the log (local testing only), dropped as files into the outbox, or e-mailed.
+		NotifierDriver:    getEnv("NOTIFIER_DRIVER", "log"),
+		NotifierFrom:      getEnv("NOTIFIER_FROM", "userservice@localhost"),
+		NotifierOutboxDir: getEnv("NOTIFIER_OUTBOX_DIR", "outbox"),
+		SMTPHost:          getEnv("SMTP_HOST", ""),
+		SMTPPort:          getEnvAsInt("SMTP_PORT", 587),
+		// Password is preferred to be sent as environment variable.
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		// Connection is secured with TLS through STARTTLS, or implicitly; opportunistic falls back to plain text.
+		SMTPTLSMode: getEnv("SMTP_TLS_MODE", "starttls"),
+		// Temporary password of the added user is sent to the user, instead of being shown to the admin.
+		NotifyTemporaryPassword: getEnvAsBool("NOTIFY_TEMPORARY_PASSWORD", false),
+		// Temporary password of added user has to be replaced within this duration, zero never expires it.
+		TemporaryPasswordExpirationInSeconds: getEnvAsInt("TEMPORARY_PASSWORD_EXPIRATION_IN_SECONDS", 259200),
+		// Invitation token is sent to the invitee, appended to the URL of invitation page if configured.
+		InvitationExpirationInSeconds: getEnvAsInt("INVITATION_EXPIRATION_IN_SECONDS", 259200),
+		InvitationURL:                 getEnv("INVITATION_URL", ""),
+		// Expired invitations are deleted periodically, freeing up the email to be invited again.
+		InvitationCleanupIntervalInSeconds: getEnvAsInt("INVITATION_CLEANUP_INTERVAL_SEC", 3600),
+		// Single sign-on through OpenID Connect provider, disabled unless issuer is configured.
+		OIDCIssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:       getEnvAsList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+		// Groups of the user are mapped to role as group=role, first matching mapping wins.
+		OIDCGroupsClaim: getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping: getEnvAsList("OIDC_ROLE_MAPPING", nil),
+		OIDCDefaultRole: getEnv("OIDC_DEFAULT_ROLE", ""),
+		// Users unknown to the service are added upon their first single sign-on, if enabled.
+		OIDCJITProvisioning:          getEnvAsBool("OIDC_JIT_PROVISIONING", false),
+		OIDCStateExpirationInSeconds: getEnvAsInt("OIDC_STATE_EXPIRATION_IN_SECONDS", 600),
+		// SCIM provisioning is authenticated with the bearer token, disabled unless token is configured.
+		SCIMBearerToken: getEnv("SCIM_BEARER_TOKEN", ""),
+		// Role of users provisioned without any role.
+		SCIMDefaultRole: getEnv("SCIM_DEFAULT_ROLE", "basic"),
+		SCIMMaxResults:  getEnvAsInt("SCIM_MAX_RESULTS", 100),
+		// Impersonation tokens are never refreshed, and don't outlive regular access tokens either.
+		ImpersonationExpirationInSeconds: getEnvAsInt("IMPERSONATION_EXPIRATION_IN_SECONDS", 300),
+		// Authorization policy file further restricting requests, disabled unless path is configured.
+		PolicyFile: getEnv("POLICY_FILE", ""),
+		// Longest window users can request to hold elevated role for.
+		RoleElevationMaxDurationInMinutes: getEnvAsInt("ROLE_ELEVATION_MAX_DURATION_IN_MINUTES", 240),
+		// Window pending role elevation can be reviewed in, it expires afterwards.
+		RoleElevationPendingExpiryInMinutes: getEnvAsInt("ROLE_ELEVATION_PENDING_EXPIRY_IN_MINUTES", 1440),
 	}, nil
 }
 
@@ -75,3 +231,30 @@ func getEnvAsInt(key string, defaultVal int64) int64 {
 	}
 	return defaultVal
 }
+
+// getEnvAsBool gets the env by key or use the default and convert into bool.
+func getEnvAsBool(key string, defaultVal bool) bool {
+	if strValue, ok := os.LookupEnv(key); ok {
+		boolValue, err := strconv.ParseBool(strValue)
+		if err != nil {
+			return defaultVal
+		}
+		return boolValue
+	}
+	return defaultVal
+}
+
+// getEnvAsList gets the env by key as comma separated list or use the default.
+func getEnvAsList(key string, defaultVal []string) []string {
+	strValue, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(strValue) == "" {
+		return defaultVal
+	}
+	var values []string
+	for _, value := range strings.Split(strValue, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
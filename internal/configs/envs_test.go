@@ -38,6 +38,29 @@ var _ = Describe("Payload Validator", func() {
 			res := getEnvAsInt("testVar", 0)
 			Expect(res).To(Equal(int64(0)))
 		})
+		It("get valid bool env variable", func() {
+			os.Setenv("testVar", "true")
+			res := getEnvAsBool("testVar", false)
+			Expect(res).To(BeTrue())
+		})
+		It("get invalid bool env variable", func() {
+			os.Setenv("testVar", "yes please")
+			res := getEnvAsBool("testVar", false)
+			Expect(res).To(BeFalse())
+		})
+		It("default bool env variable", func() {
+			res := getEnvAsBool("testVar", true)
+			Expect(res).To(BeTrue())
+		})
+		It("get list env variable", func() {
+			os.Setenv("testVar", "a.pem, ,b.pem ")
+			res := getEnvAsList("testVar", nil)
+			Expect(res).To(Equal([]string{"a.pem", "b.pem"}))
+		})
+		It("default list env variable", func() {
+			res := getEnvAsList("testVar", []string{"default"})
+			Expect(res).To(Equal([]string{"default"}))
+		})
 	})
 	Context("Init config", func() {
 		It("load valid env file", func() {
@@ -13,8 +13,22 @@ var (
 	ErrEmailNotValid = errors.New("email is invalid")
 	// ErrPasswordMissingOrEmpty password is missing or empty
 	ErrPasswordMissingOrEmpty = errors.New("password is missing or empty")
-	// ErrPasswordTooLong password exceeds character length of 72 Bytes
-	ErrPasswordTooLong = errors.New("password exceeds character length of 72 Bytes")
+	// ErrPasswordTooLong password is too long
+	ErrPasswordTooLong = errors.New("password is too long")
+	// ErrPasswordTooShort password is too short
+	ErrPasswordTooShort = errors.New("password is too short")
+	// ErrPasswordMissingCharacterClass password lacks required character class
+	ErrPasswordMissingCharacterClass = errors.New("password lacks required character class")
+	// ErrPasswordTooCommon password is too common
+	ErrPasswordTooCommon = errors.New("password is too common, choose a less predictable one")
+	// ErrPasswordSameAsEmail password must not be same as email
+	ErrPasswordSameAsEmail = errors.New("password must not be same as email")
+	// ErrPasswordReused password was used recently
+	ErrPasswordReused = errors.New("password was used recently, choose a different one")
+	// ErrCurrentPasswordMissing current password is required
+	ErrCurrentPasswordMissing = errors.New("current password is required")
+	// ErrCurrentPasswordMismatch current password is incorrect
+	ErrCurrentPasswordMismatch = errors.New("current password is incorrect")
 	// ErrServiceNameEmpty service name is missing or empty
 	ErrServiceNameEmpty = errors.New("service name is empty")
 	// ErrVersionTagEmpty version tag is missing or empty
@@ -53,6 +67,139 @@ var (
 	ErrTokenClaimMissing = errors.New("token lacks desired claims")
 	// ErrInvalidOrExpiredToken invalid or expired JWT
 	ErrInvalidOrExpiredToken = errors.New("invalid or expired JWT")
+	// ErrTokenRevoked token has been revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
 	// ErrUserNotAuthorized user not authorized
 	ErrUserNotAuthorized = errors.New("user not authorized")
+	// ErrInvalidOrExpiredRefreshToken invalid or expired refresh token
+	ErrInvalidOrExpiredRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused refresh token already used
+	ErrRefreshTokenReused = errors.New("refresh token already used")
+	// ErrInvalidOrExpiredAccessToken invalid or expired personal access token
+	ErrInvalidOrExpiredAccessToken = errors.New("invalid or expired personal access token")
+	// ErrAccessTokenScopeMissing personal access token lacks scope for the request
+	ErrAccessTokenScopeMissing = errors.New("personal access token lacks scope for the request")
+	// ErrUserSessionRequired request demands user session
+	ErrUserSessionRequired = errors.New("request demands user session")
+	// ErrServiceAccountAlreadyExists service account already exists
+	ErrServiceAccountAlreadyExists = errors.New("service account already exists")
+	// ErrServiceAccountDoesNotExist service account doesn't exist
+	ErrServiceAccountDoesNotExist = errors.New("service account doesn't exist")
+	// ErrInvalidClientCredentials invalid client credentials
+	ErrInvalidClientCredentials = errors.New("invalid client credentials")
+	// ErrUnsupportedGrantType unsupported grant type
+	ErrUnsupportedGrantType = errors.New("unsupported grant type")
+	// ErrAccountLocked account is temporarily locked due to failed login attempts
+	ErrAccountLocked = errors.New("account is temporarily locked due to failed login attempts")
+	// ErrTooManyLoginAttempts too many failed login attempts, retry later
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts, retry later")
+	// ErrAccessTokenDoesNotExist personal access token doesn't exist
+	ErrAccessTokenDoesNotExist = errors.New("personal access token doesn't exist")
+	// ErrInvalidOrExpiredMFAToken invalid or expired MFA challenge token
+	ErrInvalidOrExpiredMFAToken = errors.New("invalid or expired MFA challenge token")
+	// ErrInvalidMFACode invalid MFA code
+	ErrInvalidMFACode = errors.New("invalid MFA code")
+	// ErrMFAAlreadyEnabled MFA is already enabled
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled")
+	// ErrMFANotEnabled MFA isn't enabled
+	ErrMFANotEnabled = errors.New("MFA isn't enabled")
+	// ErrMFAEnrollmentNotStarted MFA enrollment isn't started
+	ErrMFAEnrollmentNotStarted = errors.New("MFA enrollment isn't started")
+	// ErrMFAEnrollmentRequired MFA enrollment is required for user's role
+	ErrMFAEnrollmentRequired = errors.New("MFA enrollment is required for user's role")
+	// ErrInvalidOrExpiredResetToken invalid, expired or already used password reset token
+	ErrInvalidOrExpiredResetToken = errors.New("invalid, expired or already used password reset token")
+	// ErrTemporaryPasswordNotDelivered user is added, but temporary password couldn't be delivered to the user
+	ErrTemporaryPasswordNotDelivered = errors.New(
+		"user is added, but temporary password couldn't be delivered; user can reset the password once delivery recovers")
+	// ErrPasswordChangeRequired temporary password has to be changed
+	ErrPasswordChangeRequired = errors.New("temporary password has to be changed")
+	// ErrTemporaryPasswordExpired temporary password is expired
+	ErrTemporaryPasswordExpired = errors.New("temporary password is expired; reset the password to regain access")
+	// ErrInvitationAlreadyExists invitation is already pending for the email
+	ErrInvitationAlreadyExists = errors.New("invitation is already pending for the email")
+	// ErrInvitationDoesNotExist invitation doesn't exist
+	ErrInvitationDoesNotExist = errors.New("invitation doesn't exist")
+	// ErrInvalidOrExpiredInvitation invalid, expired or already accepted invitation
+	ErrInvalidOrExpiredInvitation = errors.New("invalid, expired or already accepted invitation")
+	// ErrInvitationNotDelivered invitation is saved, but couldn't be delivered to the invitee
+	ErrInvitationNotDelivered = errors.New("invitation is saved, but couldn't be delivered; resend it once delivery recovers")
+	// ErrOIDCNotConfigured single sign-on is not configured
+	ErrOIDCNotConfigured = errors.New("single sign-on is not configured")
+	// ErrOIDCProviderUnavailable identity provider is unavailable
+	ErrOIDCProviderUnavailable = errors.New("identity provider is unavailable")
+	// ErrInvalidOrExpiredOIDCState single sign-on state is invalid, expired or already used
+	ErrInvalidOrExpiredOIDCState = errors.New("single sign-on state is invalid, expired or already used")
+	// ErrOIDCLoginFailed identity provider didn't authenticate the user
+	ErrOIDCLoginFailed = errors.New("single sign-on failed; identity provider didn't authenticate the user")
+	// ErrOIDCRoleNotMapped none of the groups of the user is granted a role
+	ErrOIDCRoleNotMapped = errors.New("none of the groups of the user is granted a role")
+	// ErrOIDCUserNotProvisioned user signed in through identity provider is not added to the system
+	ErrOIDCUserNotProvisioned = errors.New("user is not added to the system; ask admin to add the user")
+	// ErrOIDCUserNotLinked user with the same email isn't linked to the identity provider
+	ErrOIDCUserNotLinked = errors.New("user with the same email exists; login with password and link single sign-on first")
+	// ErrOIDCIdentityAlreadyLinked identity at identity provider is linked to other user
+	ErrOIDCIdentityAlreadyLinked = errors.New("identity is already linked to other user")
+	// ErrInvalidSCIMToken SCIM bearer token is missing or invalid
+	ErrInvalidSCIMToken = errors.New("SCIM bearer token is missing or invalid")
+	// ErrInvalidSCIMFilter SCIM filter is malformed or not supported
+	ErrInvalidSCIMFilter = errors.New("SCIM filter is malformed or not supported")
+	// ErrUserDisabled user is deactivated through SCIM
+	ErrUserDisabled = errors.New("user is disabled; contact the administrator to regain access")
+	// ErrSessionDoesNotExist session doesn't exist or is already revoked
+	ErrSessionDoesNotExist = errors.New("session doesn't exist or is already revoked")
+	// ErrImpersonatingAdmin admin users, the ones granted user:write, can't be impersonated
+	ErrImpersonatingAdmin = errors.New("admin users, the ones granted user:write, can't be impersonated")
+	// ErrImpersonatingBeyondPermissions users granted permissions the requester lacks can't be impersonated
+	ErrImpersonatingBeyondPermissions = errors.New("users granted permissions beyond yours can't be impersonated")
+	// ErrGrantingBeyondPermissions role granted permissions the requester lacks can't be assigned
+	ErrGrantingBeyondPermissions = errors.New("role granted permissions beyond yours can't be assigned")
+	// ErrImpersonationNotAllowed request isn't allowed under impersonation
+	ErrImpersonationNotAllowed = errors.New("request isn't allowed under impersonation")
+	// ErrRoleAlreadyExists role already exists with same name
+	ErrRoleAlreadyExists = errors.New("role already exists with same name")
+	// ErrRoleDoesNotExist role doesn't exist
+	ErrRoleDoesNotExist = errors.New("role doesn't exist")
+	// ErrRoleInUse role is still assigned to users, service accounts or pending invitations
+	ErrRoleInUse = errors.New("role is still assigned to users, service accounts or pending invitations")
+	// ErrBuiltInRole built-in roles can't be deleted
+	ErrBuiltInRole = errors.New("built-in roles can't be deleted")
+	// ErrAdminPermissionsRequired admin role should retain the permissions to manage users and roles
+	ErrAdminPermissionsRequired = errors.New("admin role can't be stripped of role:write and user:write")
+	// ErrParentRoleDoesNotExist role can't inherit a role which doesn't exist
+	ErrParentRoleDoesNotExist = errors.New("parent role doesn't exist")
+	// ErrRoleInheritanceCycle role can't inherit itself, directly or through its ancestors
+	ErrRoleInheritanceCycle = errors.New("role can't inherit itself, directly or through its ancestors")
+	// ErrRoleInherited role is still the parent of other roles
+	ErrRoleInherited = errors.New("role is still inherited by other roles")
+	// ErrElevationDoesNotExist role elevation doesn't exist
+	ErrElevationDoesNotExist = errors.New("role elevation doesn't exist")
+	// ErrElevationAlreadyPending user already awaits review of elevation to the role
+	ErrElevationAlreadyPending = errors.New("elevation to the role is already pending review")
+	// ErrElevationAlreadyReviewed role elevation is already approved or denied
+	ErrElevationAlreadyReviewed = errors.New("role elevation is already reviewed")
+	// ErrElevationExpired pending role elevation wasn't reviewed in time
+	ErrElevationExpired = errors.New("role elevation expired before being reviewed")
+	// ErrElevationSelfReview role elevation has to be reviewed by someone other than the requester
+	ErrElevationSelfReview = errors.New("role elevation can't be reviewed by its requester")
+	// ErrElevationBeyondReviewer reviewer can't approve elevation to role granted permissions reviewer isn't granted
+	ErrElevationBeyondReviewer = errors.New("role elevation grants permissions beyond the ones of reviewer")
+	// ErrElevationNotActive role elevation isn't approved, or its window has ended
+	ErrElevationNotActive = errors.New("role elevation isn't active")
+	// ErrTeamAlreadyExists team already exists with same name
+	ErrTeamAlreadyExists = errors.New("team already exists with same name")
+	// ErrTeamDoesNotExist team doesn't exist
+	ErrTeamDoesNotExist = errors.New("team doesn't exist")
+	// ErrTeamOwnsServices team still owns services
+	ErrTeamOwnsServices = errors.New("team still owns services; transfer them to another team ahead of deleting it")
+	// ErrTeamMemberAlreadyExists user is already a member of the team
+	ErrTeamMemberAlreadyExists = errors.New("user is already a member of the team")
+	// ErrTeamMemberDoesNotExist user isn't a member of the team
+	ErrTeamMemberDoesNotExist = errors.New("user isn't a member of the team")
+	// ErrServiceNotOwned requester isn't a member of the team owning the service
+	ErrServiceNotOwned = errors.New("only members of the team owning the service can modify it")
+	// ErrServiceOwnerNotPermitted requester isn't permitted to assign the owner of service yet to be owned
+	ErrServiceOwnerNotPermitted = errors.New("only service admins can assign the owner of service yet to be owned")
+	// ErrPolicyDenied request isn't permitted by authorization policy
+	ErrPolicyDenied = errors.New("request isn't permitted by authorization policy")
 )
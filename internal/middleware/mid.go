@@ -2,9 +2,13 @@ package middleware
 
 import (
 	"net/http"
+	"net/url"
 	"strings"
 	"userservice/internal/auth"
 	"userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+	"userservice/internal/policy"
 	"userservice/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -12,14 +16,43 @@ import (
 	"go.uber.org/zap"
 )
 
-// Authenticate validates JWT Token and checks for existence of desired claims
-func Authenticate(apiPrefix string, log *zap.SugaredLogger, secret []byte) gin.HandlerFunc {
+// unauthenticatedRoutes are the routes through which user obtains token, regains access or joins upon invitation,
+// hence can't demand one.
+var unauthenticatedRoutes = []string{"/login", "/login/mfa", "/login/oidc", "/login/oidc/callback", "/token/refresh",
+	"/oauth/token", "/password/forgot", "/password/reset", "/invitation/accept"}
+
+// mfaEnrollmentRoutes are the only routes accessible with token restricted to MFA enrollment.
+// Temporary password can be replaced as well, without having to enroll first.
+var mfaEnrollmentRoutes = []string{"/user/self/mfa", "/user/self/mfa/confirm", "/user/self/password", "/logout"}
+
+// passwordChangeRoute is the only route accessible with token restricted to password change.
+const passwordChangeRoute = "/user/self/password"
+
+// AccessTokenHeader carries personal access token, alternatively it can be sent as Bearer token.
+const AccessTokenHeader = "X-API-Key"
+
+// ImpersonatedByHeader is set on responses to requests made under impersonation, carrying the impersonating admin.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// Authenticate validates JWT Token, checks for existence of desired claims
+// and rejects the tokens which are revoked ahead of their expiry.
+// Personal access tokens are accepted as well, and are limited to their scopes.
+func Authenticate(apiPrefix string, log *zap.SugaredLogger, tokens *auth.TokenIssuer,
+	revoker models.TokenRevocationOperations, accessTokens models.AccessTokenAuthenticator) gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 
-		// skip Token validation for login endpoint
-		if c.Request.URL != nil && (c.Request.URL.Path == apiPrefix+"/login") {
-			c.Next()
+		// skip Token validation for login and token refresh endpoints
+		if c.Request.URL != nil {
+			for _, route := range unauthenticatedRoutes {
+				if c.Request.URL.Path == apiPrefix+route {
+					c.Next()
+					return
+				}
+			}
+		}
+		if accessToken := c.GetHeader(AccessTokenHeader); accessToken != "" {
+			authenticateAccessToken(c, log, accessTokens, accessToken)
 			return
 		}
 		authHeader := c.GetHeader("Authorization")
@@ -36,8 +69,12 @@ func Authenticate(apiPrefix string, log *zap.SugaredLogger, secret []byte) gin.H
 			return
 		}
 		tokenString := parts[1]
+		if auth.IsAccessToken(tokenString) {
+			authenticateAccessToken(c, log, accessTokens, tokenString)
+			return
+		}
 
-		token, err := auth.ValidateJWT(secret, tokenString)
+		token, err := tokens.ValidateJWT(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrInvalidOrExpiredToken.Error()))
 			c.Abort()
@@ -56,42 +93,327 @@ func Authenticate(apiPrefix string, log *zap.SugaredLogger, secret []byte) gin.H
 			c.Abort()
 			return
 		}
+		subject, err := claims.GetSubject()
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
+			c.Abort()
+			return
+		}
+		// token is either issued to a service account, or to an user who is identified by email as well
+		var (
+			userID           uint
+			serviceAccountID uint
+			email            string
+		)
+		if serviceAccountID, err = auth.ServiceAccountIDFromSubject(subject); err != nil {
+			if userID, err = auth.UserIDFromSubject(subject); err != nil {
+				c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
+				c.Abort()
+				return
+			}
+			if email, ok = claims[auth.JWTClaimEmail].(string); !ok {
+				c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
+				c.Abort()
+				return
+			}
+		}
 		role, ok := claims[auth.JWTClaimRole].(string)
 		if !ok {
 			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
 			c.Abort()
 			return
 		}
-		email, ok := claims[auth.JWTClaimEmail].(string)
+		tokenID, ok := claims[auth.JWTClaimID].(string)
 		if !ok {
 			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
 			c.Abort()
 			return
 		}
+		issuedAt, err := auth.IssuedAtFromClaims(claims)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
+			c.Abort()
+			return
+		}
+		expiresAt, err := claims.GetExpirationTime()
+		if err != nil || expiresAt == nil {
+			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
+			c.Abort()
+			return
+		}
+		if revoker.IsRevoked(tokenID, subject, issuedAt) {
+			log.Debugf("Rejected revoked token %s of %s", tokenID, subject)
+			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenRevoked.Error()))
+			c.Abort()
+			return
+		}
+		// tokens issued within a user session are revoked along with the session
+		var sessionID uint
+		if sessionClaim, ok := claims[auth.JWTClaimSessionID]; ok {
+			sessionIDClaim, _ := sessionClaim.(string)
+			if sessionID, err = auth.SessionIDFromClaim(sessionIDClaim); err != nil {
+				c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
+				c.Abort()
+				return
+			}
+			if revoker.IsRevoked(tokenID, auth.SubjectFromSessionID(sessionID), issuedAt) {
+				log.Debugf("Rejected token %s of revoked session %d", tokenID, sessionID)
+				c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenRevoked.Error()))
+				c.Abort()
+				return
+			}
+		}
+		// impersonation tokens are revoked along with the tokens of impersonating admin as well
+		var actorUserID uint
+		if actorClaim, ok := claims[auth.JWTClaimActor]; ok {
+			if actorUserID, err = auth.ActorUserIDFromClaim(actorClaim); err != nil || userID == 0 {
+				c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenClaimMissing.Error()))
+				c.Abort()
+				return
+			}
+			if revoker.IsRevoked(tokenID, auth.SubjectFromUserID(actorUserID), issuedAt) {
+				log.Debugf("Rejected impersonation token %s of revoked actor %d", tokenID, actorUserID)
+				c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrTokenRevoked.Error()))
+				c.Abort()
+				return
+			}
+		}
+		if passwordChangeRequired, _ := claims[auth.JWTClaimPasswordChangeRequired].(bool); passwordChangeRequired &&
+			!isPasswordChangeRoute(apiPrefix, c.Request) {
+			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrPasswordChangeRequired.Error()))
+			c.Abort()
+			return
+		}
+		if mfaEnrollmentRequired, _ := claims[auth.JWTClaimMFAEnrollmentRequired].(bool); mfaEnrollmentRequired &&
+			!isMFAEnrollmentRoute(apiPrefix, c.Request.URL) {
+			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrMFAEnrollmentRequired.Error()))
+			c.Abort()
+			return
+		}
 
 		// set the parameters for endpoints to access
+		if serviceAccountID != 0 {
+			c.Set(auth.ContextKeyServiceAccount, serviceAccountID)
+		} else {
+			c.Set(auth.JWTClaimSubject, userID)
+			c.Set(auth.JWTClaimEmail, email)
+		}
+		if sessionID != 0 {
+			c.Set(auth.JWTClaimSessionID, sessionID)
+		}
+		if actorUserID != 0 {
+			c.Set(auth.JWTClaimActor, actorUserID)
+			c.Header(ImpersonatedByHeader, auth.SubjectFromUserID(actorUserID))
+		}
 		c.Set(auth.JWTClaimRole, role)
-		c.Set(auth.JWTClaimEmail, email)
+		c.Set(auth.JWTClaimID, tokenID)
+		c.Set(auth.JWTClaimExpiresAt, expiresAt.Time)
+		c.Next()
+	}
+}
+
+// isMFAEnrollmentRoute...
+func isMFAEnrollmentRoute(apiPrefix string, url *url.URL) bool {
+	if url == nil {
+		return false
+	}
+	for _, route := range mfaEnrollmentRoutes {
+		if url.Path == apiPrefix+route {
+			return true
+		}
+	}
+	return false
+}
+
+// isPasswordChangeRoute...
+func isPasswordChangeRoute(apiPrefix string, request *http.Request) bool {
+	return request.URL != nil && request.Method == http.MethodPut && request.URL.Path == apiPrefix+passwordChangeRoute
+}
+
+// authenticateAccessToken validates personal access token, and sets the owner identity for endpoints to access.
+// Granted scopes are narrowed down to the permissions owner's current role is granted.
+func authenticateAccessToken(c *gin.Context, log *zap.SugaredLogger,
+	accessTokens models.AccessTokenAuthenticator, token string) {
+
+	accessToken, owner, err := accessTokens.AuthenticateAccessToken(token)
+	if err != nil {
+		if err == errors.ErrInternal {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(errors.ErrFailureToProcessRequest.Error()))
+			c.Abort()
+			return
+		}
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrInvalidOrExpiredAccessToken.Error()))
+		c.Abort()
+		return
+	}
+	// personal access token can't be used to bypass the password change either
+	if owner.IsTemporaryPassword {
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrPasswordChangeRequired.Error()))
+		c.Abort()
+		return
+	}
+	scopes := make([]string, 0, len(accessToken.Scopes))
+	for _, scope := range accessToken.Scopes {
+		if misc.HasPermission(owner.Role, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	log.Debugf("Authenticated personal access token %d of user %d", accessToken.ID, owner.ID)
+
+	// set the parameters for endpoints to access
+	c.Set(auth.JWTClaimSubject, owner.ID)
+	c.Set(auth.JWTClaimRole, owner.Role)
+	c.Set(auth.JWTClaimEmail, owner.Email)
+	c.Set(models.AttributeScopes, scopes)
+	c.Next()
+}
+
+// RequireScope middleware checks if the personal access token used for the request is granted the scope.
+// Requests authenticated with JWT aren't scoped, and are left to role based authorization.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		grantedScopes, ok := c.Get(models.AttributeScopes)
+		if !ok {
+			c.Next()
+			return
+		}
+		for _, grantedScope := range grantedScopes.([]string) {
+			if grantedScope == scope {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrAccessTokenScopeMissing.Error()))
+		c.Abort()
+	}
+}
+
+// RequireUserSession middleware rejects requests authenticated with personal access token,
+// as service account or under impersonation, for the routes which are meant for interactive user session.
+func RequireUserSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, isAccessToken := c.Get(models.AttributeScopes)
+		_, isServiceAccount := c.Get(auth.ContextKeyServiceAccount)
+		if isAccessToken || isServiceAccount {
+			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrUserSessionRequired.Error()))
+			c.Abort()
+			return
+		}
+		// impersonating admin can see what user sees, but can't act on user's credentials
+		if _, isImpersonation := c.Get(auth.JWTClaimActor); isImpersonation {
+			c.JSON(http.StatusForbidden, utils.FormatErrorResponse(errors.ErrImpersonationNotAllowed.Error()))
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
-// AuthzRoles middleware checks if the user request comply with associated endpoint request roles
-func AuthzRoles(allowedRolesForRoute ...string) gin.HandlerFunc {
+// AuditImpersonation middleware records every request made under impersonation ahead of serving it.
+// Request is rejected if it can't be audited.
+func AuditImpersonation(auditor models.ImpersonationAuditor) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, ok := c.Get(auth.JWTClaimRole)
+		actorUserID, ok := c.Get(auth.JWTClaimActor)
 		if !ok {
+			c.Next()
+			return
+		}
+		audit := models.ImpersonationAudit{
+			ActorID: actorUserID.(uint),
+			UserID:  c.GetUint(auth.JWTClaimSubject),
+			TokenID: c.GetString(auth.JWTClaimID),
+			Method:  c.Request.Method,
+			IP:      c.ClientIP(),
+		}
+		if c.Request.URL != nil {
+			audit.Path = c.Request.URL.RequestURI()
+		}
+		if err := auditor.RecordImpersonatedRequest(&audit); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(errors.ErrFailureToProcessRequest.Error()))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission middleware checks if the role of the request is granted the permission.
+// Requests authenticated with personal access token have to be granted the permission as scope as well.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := c.Get(auth.JWTClaimRole)
+		if !ok || !misc.HasPermission(role.(string), permission) {
 			c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrUserNotAuthorized.Error()))
 			c.Abort()
 			return
 		}
-		for _, role := range allowedRolesForRoute {
-			if role == userRole {
+		grantedScopes, ok := c.Get(models.AttributeScopes)
+		if !ok {
+			c.Next()
+			return
+		}
+		for _, grantedScope := range grantedScopes.([]string) {
+			if grantedScope == permission {
 				c.Next()
 				return
 			}
 		}
-		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrUserNotAuthorized.Error()))
+		c.JSON(http.StatusUnauthorized, utils.FormatErrorResponse(errors.ErrAccessTokenScopeMissing.Error()))
 		c.Abort()
 	}
 }
+
+// IsPermitted reports whether the role of the request is granted the permission, for handlers authorizing
+// beyond their routes. Requests authenticated with personal access token have to be granted the permission
+// as scope as well.
+func IsPermitted(c *gin.Context, permission string) bool {
+	if !misc.HasPermission(c.GetString(auth.JWTClaimRole), permission) {
+		return false
+	}
+	grantedScopes, ok := c.Get(models.AttributeScopes)
+	if !ok {
+		return true
+	}
+	for _, grantedScope := range grantedScopes.([]string) {
+		if grantedScope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// EnforcePolicy middleware authorizes authenticated requests against the declarative policy, on top of
+// the permissions of the role. Rules for the roles inherited by the role apply as well. Resource is the
+// request path under apiPrefix, e.g. service/1/version/v1, and action is derived from the request method.
+// Unauthenticated routes are left to themselves.
+func EnforcePolicy(apiPrefix string, log *zap.SugaredLogger, enforcer *policy.Enforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := c.Get(auth.JWTClaimRole)
+		if !ok {
+			c.Next()
+			return
+		}
+		request := policy.Request{
+			Role:     role.(string),
+			Action:   policy.ActionFromMethod(c.Request.Method),
+			Resource: strings.Trim(strings.TrimPrefix(c.Request.URL.Path, apiPrefix), "/"),
+			UserID:   c.GetUint(auth.JWTClaimSubject),
+		}
+		if roleLineage := misc.RoleLineage(request.Role); len(roleLineage) > 1 {
+			request.InheritedRoles = roleLineage[1:]
+		}
+		allowed, err := enforcer.Authorize(request)
+		if err != nil {
+			log.Errorf("Failed to authorize %s on %s against policy: %v", request.Action, request.Resource, err)
+			c.JSON(http.StatusInternalServerError, utils.FormatErrorResponse(errors.ErrFailureToProcessRequest.Error()))
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, utils.FormatErrorResponse(errors.ErrPolicyDenied.Error()))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,80 +1,281 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
 	"userservice/internal/auth"
 	"userservice/internal/errors"
+	"userservice/internal/misc"
+	"userservice/internal/models"
+	"userservice/internal/policy"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// ownershipMock...
+type ownershipMock struct {
+	isOwner bool
+	err     error
+}
+
+// IsServiceOwner...
+func (m *ownershipMock) IsServiceOwner(uint, uint) (bool, error) {
+	return m.isOwner, m.err
+}
+
+// revocationMock...
+type revocationMock struct {
+	revokedTokens   map[string]struct{}
+	revokedSubjects map[string]struct{}
+}
+
+// RevokeToken...
+func (m *revocationMock) RevokeToken(jti string, _ time.Time) error {
+	m.revokedTokens[jti] = struct{}{}
+	return nil
+}
+
+// RevokeSubjectTokens...
+func (m *revocationMock) RevokeSubjectTokens(subject string) error {
+	m.revokedSubjects[subject] = struct{}{}
+	return nil
+}
+
+// IsRevoked...
+func (m *revocationMock) IsRevoked(jti string, subject string, _ time.Time) bool {
+	_, tokenRevoked := m.revokedTokens[jti]
+	_, subjectRevoked := m.revokedSubjects[subject]
+	return tokenRevoked || subjectRevoked
+}
+
+// accessTokenMock...
+type accessTokenMock struct {
+	setInternalError bool
+	scopes           []string
+	ownerRole        string
+	ownerTempPass    bool
+}
+
+// AuthenticateAccessToken...
+func (m *accessTokenMock) AuthenticateAccessToken(token string) (*models.PersonalAccessToken, *models.User, error) {
+	if m.setInternalError {
+		return nil, nil, errors.ErrInternal
+	}
+	if token != "pat_valid" {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return &models.PersonalAccessToken{DBModel: models.DBModel{ID: 3}, UserID: 7, Scopes: m.scopes},
+		&models.User{DBModel: models.DBModel{ID: 7}, Email: "ci@gmail.com", Role: m.ownerRole,
+			IsTemporaryPassword: m.ownerTempPass}, nil
+}
+
+// auditorMock...
+type auditorMock struct {
+	setInternalError bool
+	audits           []models.ImpersonationAudit
+}
+
+// RecordImpersonatedRequest...
+func (m *auditorMock) RecordImpersonatedRequest(audit *models.ImpersonationAudit) error {
+	if m.setInternalError {
+		return errors.ErrInternal
+	}
+	m.audits = append(m.audits, *audit)
+	return nil
+}
+
 var _ = Describe("Middleware Tests", func() {
 
 	var router *gin.Engine
 	gin.SetMode(gin.TestMode)
-	Context("Test Authz middleware", func() {
+	Context("Permission middleware", func() {
 		BeforeEach(func() {
 			router = gin.Default()
+			misc.SetRole(models.UserRole{Name: "auditor", Permissions: []string{models.PermissionServiceRead,
+				models.PermissionUserRead}})
 		})
-		It("user having expected role for the route in his request", func() {
+		serve := func(method, path string, setContext func(c *gin.Context)) *httptest.ResponseRecorder {
 			router.Use(func(c *gin.Context) {
-				c.Set("role", "admin")
+				setContext(c)
 				c.Next()
 			})
-			router.GET("/test", AuthzRoles("admin", "basic"), func(c *gin.Context) {
+			router.GET("/users", RequirePermission(models.PermissionUserRead), func(c *gin.Context) {
 				c.String(http.StatusOK, "OK")
 			})
-
-			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			router.POST("/user", RequirePermission(models.PermissionUserWrite), func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			req, _ := http.NewRequest(method, path, nil)
 			recorder := httptest.NewRecorder()
 			router.ServeHTTP(recorder, req)
-
+			return recorder
+		}
+		It("role granted the permission", func() {
+			recorder := serve(http.MethodGet, "/users", func(c *gin.Context) { c.Set("role", "auditor") })
 			Expect(recorder.Code).To(Equal(http.StatusOK))
-			Expect(recorder.Body.String()).To(Equal("OK"))
 		})
+		It("role not granted the permission", func() {
+			recorder := serve(http.MethodPost, "/user", func(c *gin.Context) { c.Set("role", "auditor") })
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrUserNotAuthorized.Error()))
+		})
+		It("unknown role", func() {
+			recorder := serve(http.MethodGet, "/users", func(c *gin.Context) { c.Set("role", "root") })
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+		It("user role is missing for the user request", func() {
+			recorder := serve(http.MethodGet, "/users", func(c *gin.Context) {})
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+		It("personal access token granted the permission as scope", func() {
+			recorder := serve(http.MethodGet, "/users", func(c *gin.Context) {
+				c.Set("role", "auditor")
+				c.Set(models.AttributeScopes, []string{models.PermissionUserRead})
+			})
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("personal access token lacking the permission as scope", func() {
+			recorder := serve(http.MethodGet, "/users", func(c *gin.Context) {
+				c.Set("role", "auditor")
+				c.Set(models.AttributeScopes, []string{models.PermissionServiceRead})
+			})
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrAccessTokenScopeMissing.Error()))
+		})
+		It("handlers check the permission alike", func() {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			Expect(IsPermitted(c, models.PermissionUserRead)).To(BeFalse())
+			c.Set("role", "auditor")
+			Expect(IsPermitted(c, models.PermissionUserRead)).To(BeTrue())
+			Expect(IsPermitted(c, models.PermissionUserWrite)).To(BeFalse())
+			c.Set(models.AttributeScopes, []string{models.PermissionServiceRead})
+			Expect(IsPermitted(c, models.PermissionUserRead)).To(BeFalse())
+			Expect(IsPermitted(c, models.PermissionServiceRead)).To(BeTrue())
+		})
+	})
 
-		It("user having undesired role for the route in his request", func() {
+	Context("Policy middleware", func() {
+		var (
+			policyDir string
+			enforcer  *policy.Enforcer
+			ownership *ownershipMock
+		)
+		BeforeEach(func() {
+			var err error
+			policyDir, err = os.MkdirTemp("", "policy")
+			Expect(err).To(BeNil())
+			path := filepath.Join(policyDir, "policy.yaml")
+			Expect(os.WriteFile(path, []byte(`
+version: 1
+rules:
+  - roles: [basic, advanced]
+    actions: [read]
+    resources: ["service/**"]
+  - roles: [advanced]
+    actions: [update]
+    resources: ["service/*/version/*"]
+    conditions: [owner]
+`), 0600)).To(Succeed())
+			ownership = &ownershipMock{isOwner: true}
+			enforcer, err = policy.NewEnforcer(path, ownership, misc.IsRoleConfigured, zap.NewExample().Sugar())
+			Expect(err).To(BeNil())
+			router = gin.Default()
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(policyDir)
+		})
+		serve := func(method, path string, setContext func(c *gin.Context)) *httptest.ResponseRecorder {
 			router.Use(func(c *gin.Context) {
-				c.Set("role", "basic")
+				setContext(c)
 				c.Next()
 			})
-			router.GET("/test", AuthzRoles("admin"), func(c *gin.Context) {
+			router.Use(EnforcePolicy("/api/v1", zap.NewExample().Sugar(), enforcer))
+			router.Any("/api/v1/*path", func(c *gin.Context) {
 				c.String(http.StatusOK, "OK")
 			})
-
-			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req, _ := http.NewRequest(method, path, nil)
 			recorder := httptest.NewRecorder()
 			router.ServeHTTP(recorder, req)
-			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
-			Expect(recorder.Body.String()).To(ContainSubstring("user not authorized"))
+			return recorder
+		}
+		It("request permitted by policy", func() {
+			recorder := serve(http.MethodGet, "/api/v1/service/1/versions", func(c *gin.Context) {
+				c.Set("role", "basic")
+			})
+			Expect(recorder.Code).To(Equal(http.StatusOK))
 		})
-
-		It("user role is missing for the user request", func() {
-			router.GET("/test", AuthzRoles("admin", "user"), func(c *gin.Context) {
-				c.String(http.StatusOK, "OK")
+		It("request not permitted by policy", func() {
+			recorder := serve(http.MethodDelete, "/api/v1/service/1", func(c *gin.Context) {
+				c.Set("role", "advanced")
 			})
-			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
-			recorder := httptest.NewRecorder()
-			router.ServeHTTP(recorder, req)
-			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
-			Expect(recorder.Body.String()).To(ContainSubstring("user not authorized"))
+			Expect(recorder.Code).To(Equal(http.StatusForbidden))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrPolicyDenied.Error()))
+		})
+		It("request permitted by policy for inherited role", func() {
+			misc.SetRole(models.UserRole{Name: "release-manager", Description: "release", Parent: models.RoleAdvanced})
+			misc.SetRole(models.UserRole{Name: models.RoleAdvanced, Description: "advanced"})
+			recorder := serve(http.MethodPut, "/api/v1/service/1/version/v1", func(c *gin.Context) {
+				c.Set("role", "release-manager")
+				c.Set("sub", uint(3))
+			})
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			misc.RemoveRole("release-manager")
+		})
+		It("condition of the rule doesn't hold", func() {
+			ownership.isOwner = false
+			recorder := serve(http.MethodPut, "/api/v1/service/1/version/v1", func(c *gin.Context) {
+				c.Set("role", "advanced")
+				c.Set("sub", uint(3))
+			})
+			Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		})
+		It("failure to evaluate condition of the rule", func() {
+			ownership.err = errors.ErrInternal
+			recorder := serve(http.MethodPut, "/api/v1/service/1/version/v1", func(c *gin.Context) {
+				c.Set("role", "advanced")
+				c.Set("sub", uint(3))
+			})
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+		It("unauthenticated routes are left to themselves", func() {
+			recorder := serve(http.MethodPost, "/api/v1/login", func(c *gin.Context) {})
+			Expect(recorder.Code).To(Equal(http.StatusOK))
 		})
 	})
 
 	Context("Authenticate middleware", func() {
 		var mockLog = zap.NewExample().Sugar()
 		secret := []byte("secret")
-		var token *string
+		tokens := auth.NewTokenIssuer(auth.NewHMACKeySet(secret), "userservice", "mgmtportal", 5, 0)
+		// withRegisteredClaims adds registered claims expected from tokens of the issuer.
+		withRegisteredClaims := func(claims jwt.MapClaims) jwt.MapClaims {
+			claims["iss"] = "userservice"
+			claims["aud"] = "mgmtportal"
+			claims["exp"] = time.Now().Add(time.Minute).Unix()
+			return claims
+		}
+		var (
+			token        *string
+			revoker      *revocationMock
+			accessTokens *accessTokenMock
+		)
 		BeforeEach(func() {
 			gin.SetMode(gin.TestMode)
 			router = gin.Default()
-			router.Use(Authenticate("", mockLog, secret))
-			token, _ = auth.CreateJWT(secret, 5, "test@gmail.com", "admin")
+			revoker = &revocationMock{revokedTokens: make(map[string]struct{}), revokedSubjects: make(map[string]struct{})}
+			accessTokens = &accessTokenMock{scopes: []string{models.PermissionServiceRead, models.PermissionUserWrite},
+				ownerRole: models.RoleAdvanced}
+			router.Use(Authenticate("", mockLog, tokens, revoker, accessTokens))
+			token, _ = tokens.CreateJWT(auth.TokenSubject{UserID: 1, Email: "test@gmail.com", Role: "admin"})
 			_ = token
 
 		})
@@ -90,6 +291,16 @@ var _ = Describe("Middleware Tests", func() {
 			Expect(recorder.Body.String()).To(Equal("OK"))
 
 		})
+		It("ensure not authn for token refresh request", func() {
+			router.POST("/token/refresh", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			req, _ := http.NewRequest(http.MethodPost, "/token/refresh", nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("OK"))
+		})
 		It("No Authorization header", func() {
 			router.GET("/test", func(c *gin.Context) {
 				c.String(http.StatusOK, "OK")
@@ -135,13 +346,28 @@ var _ = Describe("Middleware Tests", func() {
 			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
 			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrInvalidOrExpiredToken.Error()))
 		})
+		It("Token signed with unexpected algorithm", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			token := jwt.NewWithClaims(jwt.SigningMethodHS512, withRegisteredClaims(jwt.MapClaims{
+				"sub": "1", "role": "admin", "email": "test@gmail.com", "jti": "id", "iat": time.Now().Unix(),
+			}))
+			tokenString, _ := token.SignedString(secret)
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrInvalidOrExpiredToken.Error()))
+		})
 		It("Only role claim present", func() {
 			router.GET("/test", func(c *gin.Context) {
 				c.String(http.StatusOK, "OK")
 			})
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, withRegisteredClaims(jwt.MapClaims{
 				"role": "basic",
-			})
+			}))
 			tokenString, _ := token.SignedString(secret)
 			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
 			req.Header.Set("Authorization", "Bearer "+tokenString)
@@ -154,9 +380,9 @@ var _ = Describe("Middleware Tests", func() {
 			router.GET("/test", func(c *gin.Context) {
 				c.String(http.StatusOK, "OK")
 			})
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, withRegisteredClaims(jwt.MapClaims{
 				"email": "p.sabari",
-			})
+			}))
 			tokenString, _ := token.SignedString(secret)
 			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
 			req.Header.Set("Authorization", "Bearer "+tokenString)
@@ -169,10 +395,13 @@ var _ = Describe("Middleware Tests", func() {
 			router.GET("/test", func(c *gin.Context) {
 				c.String(http.StatusOK, "OK")
 			})
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, withRegisteredClaims(jwt.MapClaims{
+				"sub":   "1",
 				"role":  "basic",
 				"email": "sabari@gmail.com",
-			})
+				"jti":   "xyz",
+				"iat":   time.Now().Unix(),
+			}))
 			tokenString, _ := token.SignedString(secret)
 			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
 			req.Header.Set("Authorization", "Bearer "+tokenString)
@@ -181,6 +410,476 @@ var _ = Describe("Middleware Tests", func() {
 			Expect(recorder.Code).To(Equal(http.StatusOK))
 			Expect(recorder.Body.String()).To(ContainSubstring("OK"))
 		})
+		It("Token without jti claim", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, withRegisteredClaims(jwt.MapClaims{
+				"sub":   "1",
+				"role":  "basic",
+				"email": "sabari@gmail.com",
+				"iat":   time.Now().Unix(),
+			}))
+			tokenString, _ := token.SignedString(secret)
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenClaimMissing.Error()))
+		})
+		It("Revoked token", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			revoker.revokedTokens["xyz"] = struct{}{}
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, withRegisteredClaims(jwt.MapClaims{
+				"sub":   "1",
+				"role":  "basic",
+				"email": "sabari@gmail.com",
+				"jti":   "xyz",
+				"iat":   time.Now().Unix(),
+			}))
+			tokenString, _ := token.SignedString(secret)
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenRevoked.Error()))
+		})
+		It("Token without subject claim", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, withRegisteredClaims(jwt.MapClaims{
+				"role":  "basic",
+				"email": "sabari@gmail.com",
+				"jti":   "xyz",
+				"iat":   time.Now().Unix(),
+			}))
+			tokenString, _ := token.SignedString(secret)
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenClaimMissing.Error()))
+		})
+		It("Token minted for other audience", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			claims := withRegisteredClaims(jwt.MapClaims{
+				"sub":   "1",
+				"role":  "admin",
+				"email": "sabari@gmail.com",
+				"jti":   "xyz",
+				"iat":   time.Now().Unix(),
+			})
+			claims["aud"] = "otherservice"
+			tokenString, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrInvalidOrExpiredToken.Error()))
+		})
+		It("Token revoked by subject", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			revoker.revokedSubjects["1"] = struct{}{}
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+*token)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenRevoked.Error()))
+		})
+		It("Session ID is set for endpoints, and tokens of revoked session are rejected", func() {
+			router.GET("/test", func(c *gin.Context) {
+				sessionID, _ := c.Get(auth.JWTClaimSessionID)
+				c.JSON(http.StatusOK, sessionID)
+			})
+			sessionToken, _ := tokens.CreateJWT(auth.TokenSubject{UserID: 1, Email: "test@gmail.com", Role: "admin",
+				SessionID: 4})
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+*sessionToken)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("4"))
+
+			revoker.revokedSubjects["session:4"] = struct{}{}
+			recorder = httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenRevoked.Error()))
+
+			// tokens of other sessions of the user remain valid
+			req.Header.Set("Authorization", "Bearer "+*token)
+			recorder = httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("Token with malformed session ID claim", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, withRegisteredClaims(jwt.MapClaims{
+				"sub":   "1",
+				"role":  "basic",
+				"email": "sabari@gmail.com",
+				"jti":   "xyz",
+				"iat":   time.Now().Unix(),
+				"sid":   4,
+			}))
+			tokenString, _ := token.SignedString(secret)
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenClaimMissing.Error()))
+		})
+		It("ensure not authn for client credentials grant", func() {
+			router.POST("/oauth/token", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			req, _ := http.NewRequest(http.MethodPost, "/oauth/token", nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("Service account token is accepted without email", func() {
+			router.GET("/test", func(c *gin.Context) {
+				serviceAccountID, _ := c.Get(auth.ContextKeyServiceAccount)
+				_, hasUserID := c.Get(auth.JWTClaimSubject)
+				c.String(http.StatusOK, fmt.Sprintf("%v:%v", serviceAccountID, hasUserID))
+			})
+			serviceAccountToken, _ := tokens.CreateJWT(auth.TokenSubject{ServiceAccountID: 3, Role: "advanced"})
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+*serviceAccountToken)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("3:false"))
+		})
+		It("Service account token can't reach user session routes", func() {
+			router.PUT("/user/self/password", RequireUserSession(), func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			serviceAccountToken, _ := tokens.CreateJWT(auth.TokenSubject{ServiceAccountID: 3, Role: "advanced"})
+			req, _ := http.NewRequest(http.MethodPut, "/user/self/password", nil)
+			req.Header.Set("Authorization", "Bearer "+*serviceAccountToken)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrUserSessionRequired.Error()))
+		})
+		It("User token without email claim", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			userToken, _ := tokens.CreateJWT(auth.TokenSubject{UserID: 3, Role: "advanced"})
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+*userToken)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenClaimMissing.Error()))
+		})
+		It("Token issued by CreateJWT is accepted", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+*token)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("Authenticated user ID is set for endpoints", func() {
+			router.GET("/test", func(c *gin.Context) {
+				userID, _ := c.Get(auth.JWTClaimSubject)
+				c.JSON(http.StatusOK, userID)
+			})
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+*token)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("1"))
+		})
+		It("ensure not authn for MFA login request", func() {
+			router.POST("/login/mfa", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			req, _ := http.NewRequest(http.MethodPost, "/login/mfa", nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("ensure not authn for single sign-on login requests", func() {
+			router.GET("/login/oidc", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			router.POST("/login/oidc/callback", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			for _, request := range []struct{ method, path string }{{http.MethodGet, "/login/oidc"},
+				{http.MethodPost, "/login/oidc/callback"}} {
+				req, _ := http.NewRequest(request.method, request.path, nil)
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			}
+		})
+		It("ensure not authn for password reset requests", func() {
+			router.POST("/password/forgot", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			router.POST("/password/reset", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			for _, route := range []string{"/password/forgot", "/password/reset"} {
+				req, _ := http.NewRequest(http.MethodPost, route, nil)
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+			}
+		})
+		It("ensure not authn for invitation acceptance", func() {
+			router.POST("/invitation/accept", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			req, _ := http.NewRequest(http.MethodPost, "/invitation/accept", nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("MFA challenge token isn't accepted as access token", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			challenge, _ := tokens.CreateMFAChallenge(1, time.Minute)
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+challenge)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrInvalidOrExpiredToken.Error()))
+		})
+		It("Token restricted to MFA enrollment only reaches enrollment routes", func() {
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			router.POST("/user/self/mfa", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			restricted, _ := tokens.CreateJWT(auth.TokenSubject{UserID: 1, Email: "test@gmail.com", Role: "admin",
+				MFAEnrollmentRequired: true})
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+*restricted)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrMFAEnrollmentRequired.Error()))
+
+			req, _ = http.NewRequest(http.MethodPost, "/user/self/mfa", nil)
+			req.Header.Set("Authorization", "Bearer "+*restricted)
+			recorder = httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("Token restricted to password change only reaches password change route", func() {
+			router.GET("/user/self/password", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			router.PUT("/user/self/password", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			router.POST("/logout", func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			restricted, _ := tokens.CreateJWT(auth.TokenSubject{UserID: 1, Email: "test@gmail.com", Role: "admin",
+				PasswordChangeRequired: true, MFAEnrollmentRequired: true})
+			for _, route := range []struct{ method, path string }{
+				{http.MethodGet, "/user/self/password"}, {http.MethodPost, "/logout"}} {
+				req, _ := http.NewRequest(route.method, route.path, nil)
+				req.Header.Set("Authorization", "Bearer "+*restricted)
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+				Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrPasswordChangeRequired.Error()))
+			}
+
+			req, _ := http.NewRequest(http.MethodPut, "/user/self/password", nil)
+			req.Header.Set("Authorization", "Bearer "+*restricted)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
 	})
 
+	Context("Personal access tokens", func() {
+		var mockLog = zap.NewExample().Sugar()
+		var accessTokens *accessTokenMock
+		BeforeEach(func() {
+			gin.SetMode(gin.TestMode)
+			router = gin.Default()
+			misc.SetRole(models.UserRole{Name: models.RoleAdvanced, Permissions: []string{models.PermissionServiceRead,
+				models.PermissionServiceWrite, models.PermissionUserRead, models.PermissionRoleRead}})
+			accessTokens = &accessTokenMock{scopes: []string{models.PermissionServiceRead, models.PermissionUserWrite},
+				ownerRole: models.RoleAdvanced}
+			tokens := auth.NewTokenIssuer(auth.NewHMACKeySet([]byte("secret")), "userservice", "mgmtportal", 5, 0)
+			router.Use(Authenticate("", mockLog, tokens, &revocationMock{}, accessTokens))
+			router.GET("/service", RequireScope(models.PermissionServiceRead), func(c *gin.Context) {
+				userID, _ := c.Get(auth.JWTClaimSubject)
+				role, _ := c.Get(auth.JWTClaimRole)
+				c.String(http.StatusOK, fmt.Sprintf("%v:%v", userID, role))
+			})
+			router.POST("/service", RequireScope(models.PermissionServiceWrite), func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			router.POST("/user", RequireScope(models.PermissionUserWrite), func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			router.PUT("/user/self/password", RequireUserSession(), func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+		})
+		serve := func(method, path string, header string, value string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest(method, path, nil)
+			req.Header.Set(header, value)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		It("Access token through dedicated header", func() {
+			recorder := serve(http.MethodGet, "/service", AccessTokenHeader, "pat_valid")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("7:advanced"))
+		})
+		It("Access token as Bearer token", func() {
+			recorder := serve(http.MethodGet, "/service", "Authorization", "Bearer pat_valid")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+		It("Unknown access token", func() {
+			recorder := serve(http.MethodGet, "/service", AccessTokenHeader, "pat_unknown")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrInvalidOrExpiredAccessToken.Error()))
+		})
+		It("Internal error while authenticating access token", func() {
+			accessTokens.setInternalError = true
+			recorder := serve(http.MethodGet, "/service", AccessTokenHeader, "pat_valid")
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+		It("Access token lacking scope", func() {
+			recorder := serve(http.MethodPost, "/service", AccessTokenHeader, "pat_valid")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrAccessTokenScopeMissing.Error()))
+		})
+		It("Scope beyond owner's current role is not honoured", func() {
+			recorder := serve(http.MethodPost, "/user", AccessTokenHeader, "pat_valid")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrAccessTokenScopeMissing.Error()))
+		})
+		It("Access token of user yet to change temporary password", func() {
+			accessTokens.ownerTempPass = true
+			recorder := serve(http.MethodGet, "/service", AccessTokenHeader, "pat_valid")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrPasswordChangeRequired.Error()))
+		})
+		It("Access token on route meant for user session", func() {
+			recorder := serve(http.MethodPut, "/user/self/password", AccessTokenHeader, "pat_valid")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrUserSessionRequired.Error()))
+		})
+	})
+
+	Context("Impersonation", func() {
+		var (
+			tokens   = auth.NewTokenIssuer(auth.NewHMACKeySet([]byte("secret")), "userservice", "mgmtportal", 60, 0)
+			revoker  *revocationMock
+			auditor  *auditorMock
+			token    string
+			serve    func(method string, path string) *httptest.ResponseRecorder
+			basicJWT = auth.TokenSubject{UserID: 7, Email: "basic@gmail.com", Role: models.RoleBasic}
+		)
+		BeforeEach(func() {
+			router = gin.Default()
+			revoker = &revocationMock{revokedTokens: make(map[string]struct{}), revokedSubjects: make(map[string]struct{})}
+			auditor = &auditorMock{}
+			router.Use(Authenticate("", zap.NewExample().Sugar(), tokens, revoker, &accessTokenMock{}),
+				AuditImpersonation(auditor))
+			router.GET("/service", func(c *gin.Context) {
+				c.String(http.StatusOK, fmt.Sprintf("%v", c.GetUint(auth.JWTClaimSubject)))
+			})
+			router.PUT("/user/self/password", RequireUserSession(), func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			token, _, _ = tokens.CreateImpersonationJWT(basicJWT, 1, time.Minute)
+			serve = func(method string, path string) *httptest.ResponseRecorder {
+				req, _ := http.NewRequest(method, path, nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				return recorder
+			}
+		})
+		It("Request is served as the impersonated user, and is audited", func() {
+			recorder := serve(http.MethodGet, "/service?page=2")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("7"))
+			Expect(recorder.Header().Get(ImpersonatedByHeader)).To(Equal("1"))
+			Expect(auditor.audits).To(HaveLen(1))
+			Expect(auditor.audits[0].ActorID).To(Equal(uint(1)))
+			Expect(auditor.audits[0].UserID).To(Equal(uint(7)))
+			Expect(auditor.audits[0].Method).To(Equal(http.MethodGet))
+			Expect(auditor.audits[0].Path).To(Equal("/service?page=2"))
+			Expect(auditor.audits[0].TokenID).To(Not(BeEmpty()))
+		})
+		It("Request which can't be audited is rejected", func() {
+			auditor.setInternalError = true
+			recorder := serve(http.MethodGet, "/service")
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+		It("Requests of regular tokens aren't audited", func() {
+			regularToken, _ := tokens.CreateJWT(basicJWT)
+			token = *regularToken
+			recorder := serve(http.MethodGet, "/service")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Header().Get(ImpersonatedByHeader)).To(BeEmpty())
+			Expect(auditor.audits).To(BeEmpty())
+		})
+		It("Impersonation token is revoked along with the tokens of actor", func() {
+			revoker.revokedSubjects["1"] = struct{}{}
+			recorder := serve(http.MethodGet, "/service")
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrTokenRevoked.Error()))
+			Expect(auditor.audits).To(BeEmpty())
+		})
+		It("Impersonation token can't reach user session routes", func() {
+			recorder := serve(http.MethodPut, "/user/self/password")
+			Expect(recorder.Code).To(Equal(http.StatusForbidden))
+			Expect(recorder.Body.String()).To(ContainSubstring(errors.ErrImpersonationNotAllowed.Error()))
+		})
+	})
+
+	Context("Scope middleware for JWT", func() {
+		It("JWT authenticated requests aren't scoped", func() {
+			router = gin.Default()
+			router.POST("/service", RequireScope(models.PermissionServiceWrite), RequireUserSession(), func(c *gin.Context) {
+				c.String(http.StatusOK, "OK")
+			})
+			req, _ := http.NewRequest(http.MethodPost, "/service", nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+	})
 })
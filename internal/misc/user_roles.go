@@ -2,17 +2,38 @@ package misc
 
 import (
 	"fmt"
+	"sync"
 	"userservice/internal/models"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// configuredRole holds the permissions of role as set, for the lookup of every authorized request.
+// Permissions inherited from the parent role are resolved on lookup, such that updates of the parent
+// are honoured by its descendants right away.
+type configuredRole struct {
+	description string
+	parent      string
+	permissions map[string]struct{}
+}
+
 var (
 	// app(s) require high performant fast concurrent reads, hence opting for Map.
-	Roles = make(map[string]string)
+	// Roles are managed at runtime as well, hence the map is guarded for the rare writes.
+	roles     = make(map[string]configuredRole)
+	rolesLock sync.RWMutex
 )
 
+// newConfiguredRole...
+func newConfiguredRole(userRole models.UserRole) configuredRole {
+	permissions := make(map[string]struct{}, len(userRole.Permissions))
+	for _, permission := range userRole.Permissions {
+		permissions[permission] = struct{}{}
+	}
+	return configuredRole{description: userRole.Description, parent: userRole.Parent, permissions: permissions}
+}
+
 // LoadUserRoles records pre-configured user roles from database into memory.
 func LoadUserRoles(log *zap.SugaredLogger, db *gorm.DB) error {
 	var userRoles []models.UserRole
@@ -23,8 +44,124 @@ func LoadUserRoles(log *zap.SugaredLogger, db *gorm.DB) error {
 	if len(userRoles) == 0 {
 		return fmt.Errorf("no User roles configured; Run Migration")
 	}
+	loadedRoles := make(map[string]configuredRole, len(userRoles))
 	for _, userRole := range userRoles {
-		Roles[userRole.Name] = userRole.Description
+		loadedRoles[userRole.Name] = newConfiguredRole(userRole)
 	}
+	rolesLock.Lock()
+	roles = loadedRoles
+	rolesLock.Unlock()
 	return nil
 }
+
+// IsRoleConfigured reports whether the role is one of the configured user roles.
+func IsRoleConfigured(role string) bool {
+	rolesLock.RLock()
+	defer rolesLock.RUnlock()
+	_, ok := roles[role]
+	return ok
+}
+
+// ConfiguredRoles responds with a copy of configured user roles along with their description.
+func ConfiguredRoles() map[string]string {
+	rolesLock.RLock()
+	defer rolesLock.RUnlock()
+	configuredRoles := make(map[string]string, len(roles))
+	for role, configured := range roles {
+		configuredRoles[role] = configured.description
+	}
+	return configuredRoles
+}
+
+// lineage responds with the role followed by the roles it inherits, nearest first.
+// Walk stops at the first role seen twice, guarding against cycles introduced directly in DB.
+// Caller must hold rolesLock.
+func lineage(role string) []string {
+	var roleLineage []string
+	visited := make(map[string]struct{})
+	for role != "" {
+		configured, ok := roles[role]
+		if !ok {
+			break
+		}
+		if _, ok := visited[role]; ok {
+			break
+		}
+		visited[role] = struct{}{}
+		roleLineage = append(roleLineage, role)
+		role = configured.parent
+	}
+	return roleLineage
+}
+
+// RoleLineage responds with the role followed by the roles it inherits, nearest first.
+// Responds with empty list if the role isn't configured.
+func RoleLineage(role string) []string {
+	rolesLock.RLock()
+	defer rolesLock.RUnlock()
+	return lineage(role)
+}
+
+// HasPermission reports whether the role is granted the permission, either directly or through
+// the roles it inherits.
+func HasPermission(role string, permission string) bool {
+	rolesLock.RLock()
+	defer rolesLock.RUnlock()
+	return hasPermission(role, permission)
+}
+
+// hasPermission...
+// Caller must hold rolesLock.
+func hasPermission(role string, permission string) bool {
+	for _, ancestor := range lineage(role) {
+		if _, ok := roles[ancestor].permissions[permission]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversPermissions reports whether the role is granted every effective permission of the other role,
+// either directly or through the roles it inherits.
+func CoversPermissions(role string, other string) bool {
+	rolesLock.RLock()
+	defer rolesLock.RUnlock()
+	for _, permission := range models.Permissions {
+		if hasPermission(other, permission) && !hasPermission(role, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// RolePermissions responds with the effective permissions of the role, including the ones inherited,
+// in the order they are listed.
+func RolePermissions(role string) []string {
+	rolesLock.RLock()
+	defer rolesLock.RUnlock()
+	roleLineage := lineage(role)
+	permissions := make([]string, 0, len(models.Permissions))
+	for _, permission := range models.Permissions {
+		for _, ancestor := range roleLineage {
+			if _, ok := roles[ancestor].permissions[permission]; ok {
+				permissions = append(permissions, permission)
+				break
+			}
+		}
+	}
+	return permissions
+}
+
+// SetRole records role created or updated at runtime, such that it is honoured without a restart.
+func SetRole(userRole models.UserRole) {
+	rolesLock.Lock()
+	defer rolesLock.Unlock()
+	roles[userRole.Name] = newConfiguredRole(userRole)
+}
+
+// RemoveRole forgets role deleted at runtime.
+func RemoveRole(role string) {
+	rolesLock.Lock()
+	defer rolesLock.Unlock()
+	delete(roles, role)
+}
@@ -3,6 +3,7 @@ package misc
 import (
 	"database/sql"
 	"errors"
+	"userservice/internal/models"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	. "github.com/onsi/ginkgo"
@@ -44,9 +45,9 @@ var _ = Describe("Roles Suite Test", func() {
 			mock.ExpectQuery(`SELECT`).WillReturnRows(rows)
 			err := LoadUserRoles(mockLog, db)
 			Expect(err).To(BeNil())
-			Expect(Roles).To(HaveKeyWithValue("basic", "<basic-perm>"))
-			Expect(Roles).To(HaveKeyWithValue("admin", "<admin-perm>"))
-			Expect(Roles).To(HaveKeyWithValue("advanced", "<advanced-perm>"))
+			Expect(ConfiguredRoles()).To(HaveKeyWithValue("basic", "<basic-perm>"))
+			Expect(ConfiguredRoles()).To(HaveKeyWithValue("admin", "<admin-perm>"))
+			Expect(ConfiguredRoles()).To(HaveKeyWithValue("advanced", "<advanced-perm>"))
 			Expect(mock.ExpectationsWereMet()).To(BeNil())
 		})
 		It("Behavior of zero rows fetch", func() {
@@ -65,4 +66,36 @@ var _ = Describe("Roles Suite Test", func() {
 			Expect(mock.ExpectationsWereMet()).To(BeNil())
 		})
 	})
+	Context("Role inheritance", func() {
+		AfterEach(func() {
+			RemoveRole("reviewer")
+			RemoveRole("auditor")
+		})
+		It("Permissions of ancestors are effective", func() {
+			SetRole(models.UserRole{Name: "reviewer", Permissions: []string{models.PermissionRoleRead}})
+			SetRole(models.UserRole{Name: "auditor", Permissions: []string{models.PermissionServiceRead},
+				Parent: "reviewer"})
+			Expect(RoleLineage("auditor")).To(Equal([]string{"auditor", "reviewer"}))
+			Expect(HasPermission("auditor", models.PermissionRoleRead)).To(BeTrue())
+			Expect(HasPermission("reviewer", models.PermissionServiceRead)).To(BeFalse())
+			Expect(RolePermissions("auditor")).To(
+				Equal([]string{models.PermissionServiceRead, models.PermissionRoleRead}))
+		})
+		It("Inheriting role covers permissions of its ancestors", func() {
+			SetRole(models.UserRole{Name: "reviewer", Permissions: []string{models.PermissionRoleRead}})
+			SetRole(models.UserRole{Name: "auditor", Permissions: []string{models.PermissionServiceRead},
+				Parent: "reviewer"})
+			Expect(CoversPermissions("auditor", "reviewer")).To(BeTrue())
+			Expect(CoversPermissions("auditor", "auditor")).To(BeTrue())
+			Expect(CoversPermissions("reviewer", "auditor")).To(BeFalse())
+		})
+		It("Cycles introduced in DB don't hang the lookup", func() {
+			SetRole(models.UserRole{Name: "reviewer", Parent: "auditor"})
+			SetRole(models.UserRole{Name: "auditor", Permissions: []string{models.PermissionServiceRead},
+				Parent: "reviewer"})
+			Expect(RoleLineage("auditor")).To(Equal([]string{"auditor", "reviewer"}))
+			Expect(HasPermission("reviewer", models.PermissionServiceRead)).To(BeTrue())
+			Expect(HasPermission("auditor", models.PermissionUserRead)).To(BeFalse())
+		})
+	})
 })
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeScopes        = "scopes"
+	AttributeExpiresInDays = "expires_in_days"
+)
+
+// PersonalAccessToken represent long-lived user owned token for automation with GORM field representation.
+// Token is scoped to permissions, which are narrowed down to the ones owner's current role is granted.
+// Only the hash of the token is persisted, Prefix is retained such that owner can identify the token.
+type PersonalAccessToken struct {
+	DBModel
+	UserID     uint       `json:"-" gorm:"column:user_id;index;not null"`
+	Name       string     `json:"name" gorm:"column:name;not null"`
+	Prefix     string     `json:"prefix" gorm:"column:prefix;not null"`
+	TokenHash  string     `json:"-" gorm:"column:token_hash;unique;not null"`
+	Scopes     []string   `json:"scopes" gorm:"column:scopes;serializer:json;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+}
+
+// TableName...
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_token"
+}
+
+// CreatedPersonalAccessToken carries the token itself, which is shown only once at creation.
+type CreatedPersonalAccessToken struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// PersonalAccessTokenPayloadTemplate represents mandatory fields in personal access token creation payload
+var PersonalAccessTokenPayloadTemplate = utils.FieldTypeBinder{
+	AttributeName:          utils.String,
+	AttributeScopes:        utils.List,
+	AttributeExpiresInDays: utils.Number,
+}
+
+// AccessTokenAuthenticator...
+type AccessTokenAuthenticator interface {
+	AuthenticateAccessToken(string) (*PersonalAccessToken, *User, error)
+}
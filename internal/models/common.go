@@ -9,6 +9,7 @@ import (
 const (
 	RoleAdmin       = "admin"
 	RoleAdvanced    = "advanced"
+	RoleBasic       = "basic"
 	DefaultPageSize = "10"
 	QueryParamID    = "id"
 )
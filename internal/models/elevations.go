@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeJustification     = "justification"
+	AttributeDurationInMinutes = "duration_in_minutes"
+	QueryParamStatus           = "status"
+
+	ElevationStatusPending  = "pending"
+	ElevationStatusApproved = "approved"
+	ElevationStatusDenied   = "denied"
+	ElevationStatusRevoked  = "revoked"
+	// ElevationStatusExpired is reported for approved elevation past its window, and pending elevation not reviewed
+	// in time, it isn't persisted
+	ElevationStatusExpired = "expired"
+)
+
+// ElevationStatuses lists the statuses elevations are reported with, which they can be filtered by.
+var ElevationStatuses = []string{ElevationStatusPending, ElevationStatusApproved, ElevationStatusDenied,
+	ElevationStatusRevoked, ElevationStatusExpired}
+
+// RoleElevation represent request of user to hold another role temporarily, with GORM field representation.
+// Once approved by another user, tokens issued to the requester till the elevation expires carry the role
+// elevated to. Elevations are retained once reviewed, as the history of who held which role and why.
+type RoleElevation struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	// UserID is the user requesting the elevation
+	UserID            uint   `json:"user_id" gorm:"column:user_id;index;not null"`
+	Role              string `json:"role" gorm:"column:role;not null"`
+	Justification     string `json:"justification" gorm:"column:justification;not null"`
+	DurationInMinutes int64  `json:"duration_in_minutes" gorm:"column:duration_in_minutes;not null"`
+	Status            string `json:"status" gorm:"column:status;index;not null"`
+	// ReviewedBy is the user who approved or denied the elevation, nil while pending
+	ReviewedBy *uint      `json:"reviewed_by" gorm:"column:reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at" gorm:"column:reviewed_at"`
+	// ExpiresAt is the end of the window pending elevation can be reviewed in, and once approved, the end of
+	// the window the role is held for. It is moved up upon revocation.
+	ExpiresAt *time.Time `json:"expires_at" gorm:"column:expires_at"`
+	// RevokedBy is the user who ended the window of the elevation ahead of time, nil unless revoked by a user
+	RevokedBy *uint      `json:"revoked_by" gorm:"column:revoked_by"`
+	RevokedAt *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+}
+
+// TableName...
+func (RoleElevation) TableName() string {
+	return "role_elevation"
+}
+
+// IsActive reports whether the elevation is approved and its window hasn't ended.
+func (e *RoleElevation) IsActive(now time.Time) bool {
+	return e.Status == ElevationStatusApproved && e.ExpiresAt != nil && e.ExpiresAt.After(now)
+}
+
+// IsPending reports whether the elevation awaits review and its window to be reviewed in hasn't ended.
+func (e *RoleElevation) IsPending(now time.Time) bool {
+	return e.Status == ElevationStatusPending && e.ExpiresAt != nil && e.ExpiresAt.After(now)
+}
+
+// MarkExpired reports approved elevation past its window, and pending elevation not reviewed in time, as expired.
+func (e *RoleElevation) MarkExpired(now time.Time) {
+	if (e.Status == ElevationStatusApproved && !e.IsActive(now)) ||
+		(e.Status == ElevationStatusPending && !e.IsPending(now)) {
+		e.Status = ElevationStatusExpired
+	}
+}
+
+// IsElevationStatus reports whether elevations are reported with the status.
+func IsElevationStatus(status string) bool {
+	for _, elevationStatus := range ElevationStatuses {
+		if elevationStatus == status {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleElevationPayloadTemplate represents mandatory fields in role elevation request payload
+var RoleElevationPayloadTemplate = utils.FieldTypeBinder{
+	AttributeRole:              utils.String,
+	AttributeJustification:     utils.String,
+	AttributeDurationInMinutes: utils.Number,
+}
+
+// PaginatedRoleElevationList...
+type PaginatedRoleElevationList struct {
+	Data        []RoleElevation
+	TotalItems  int64
+	PageSize    int
+	CurrentPage int
+}
+
+// RoleElevationOperations...
+type RoleElevationOperations interface {
+	CreateElevation(*RoleElevation) error
+	GetElevation(uint) (*RoleElevation, error)
+	FetchElevationsWithPagination(uint, string, time.Time, int, int) (*PaginatedRoleElevationList, error)
+	ReviewElevation(*RoleElevation) error
+	RevokeElevation(*RoleElevation) error
+	GetUserRole(uint) (string, error)
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// ImpersonationAudit represent request made by admin while impersonating a user, with GORM field representation.
+// Every request made with impersonation token is recorded ahead of being served.
+type ImpersonationAudit struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;index"`
+	// ActorID is the admin user impersonating
+	ActorID uint `json:"actor_id" gorm:"column:actor_id;index;not null"`
+	// UserID is the impersonated user
+	UserID  uint   `json:"user_id" gorm:"column:user_id;index;not null"`
+	TokenID string `json:"token_id" gorm:"column:token_id;not null"`
+	Method  string `json:"method" gorm:"column:method;not null"`
+	Path    string `json:"path" gorm:"column:path;not null"`
+	IP      string `json:"ip" gorm:"column:ip"`
+}
+
+// TableName...
+func (ImpersonationAudit) TableName() string {
+	return "impersonation_audit"
+}
+
+// ImpersonationAuditor...
+type ImpersonationAuditor interface {
+	RecordImpersonatedRequest(*ImpersonationAudit) error
+}
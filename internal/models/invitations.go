@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeInvitationToken = "token"
+)
+
+// Invitation represent pending invitation of a user with GORM field representation. User is added only once the
+// invitee accepts the invitation and chooses the password, upon which the invitation is deleted.
+// Only the hash of the invitation token is persisted.
+type Invitation struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+	Name      string    `json:"name" gorm:"column:name"`
+	Email     string    `json:"email" gorm:"column:email;unique;not null"`
+	Role      string    `json:"role" gorm:"column:role;not null"`
+	// InvitedBy is the admin user who invited, nil if invited through service account
+	InvitedBy *uint     `json:"invited_by" gorm:"column:invited_by"`
+	TokenHash string    `json:"-" gorm:"column:token_hash;unique;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;not null"`
+}
+
+// TableName...
+func (Invitation) TableName() string {
+	return "invitation"
+}
+
+// IsExpired reports whether the invitation can no longer be accepted.
+func (i *Invitation) IsExpired(now time.Time) bool {
+	return !i.ExpiresAt.After(now)
+}
+
+// InvitationPayloadTemplate represents mandatory fields in invitation payload
+var InvitationPayloadTemplate = utils.FieldTypeBinder{
+	AttributeName:  utils.String,
+	AttributeEmail: utils.String,
+	AttributeRole:  utils.String,
+}
+
+// InvitationAcceptPayloadTemplate represents mandatory fields in payload accepting invitation
+var InvitationAcceptPayloadTemplate = utils.FieldTypeBinder{
+	AttributeInvitationToken: utils.String,
+	AttributePassword:        utils.String,
+}
+
+// PaginatedInvitationList...
+type PaginatedInvitationList struct {
+	Data        []Invitation
+	TotalItems  int64
+	PageSize    int
+	CurrentPage int
+}
+
+// InvitationOperations...
+type InvitationOperations interface {
+	CreateInvitation(*Invitation) error
+	GetInvitation(uint) (*Invitation, error)
+	GetInvitationByToken(string) (*Invitation, error)
+	FetchInvitationsWithPagination(int, int) (*PaginatedInvitationList, error)
+	RenewInvitation(uint, string, time.Time) (*Invitation, error)
+	DeleteInvitation(uint) error
+	AcceptInvitation(string, string) (*User, error)
+	DeleteExpiredInvitations(time.Time) (int64, error)
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// LoginFailure represent failed login attempts from a client IP with GORM field representation.
+// Failures are tracked regardless of the account targeted, such that spraying passwords
+// across accounts from a client gets locked out too.
+type LoginFailure struct {
+	IP             string     `gorm:"column:ip;primaryKey"`
+	FailedAttempts int        `gorm:"column:failed_attempts;not null"`
+	LastFailedAt   time.Time  `gorm:"column:last_failed_at;not null"`
+	LockedUntil    *time.Time `gorm:"column:locked_until"`
+}
+
+// TableName...
+func (LoginFailure) TableName() string {
+	return "login_failure"
+}
+
+// IsLocked...
+func (f *LoginFailure) IsLocked(now time.Time) bool {
+	return f.LockedUntil != nil && f.LockedUntil.After(now)
+}
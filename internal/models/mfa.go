@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeMFAToken = "mfa_token"
+	AttributeMFACode  = "code"
+)
+
+// MFARecoveryCode represent single use recovery code of user with GORM field representation.
+// Only the hash of the code is persisted, and the code is deleted once used.
+type MFARecoveryCode struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UserID    uint      `gorm:"column:user_id;index;not null"`
+	CodeHash  string    `gorm:"column:code_hash;not null"`
+}
+
+// TableName...
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_code"
+}
+
+// MFAEnrollment represents TOTP secret pending confirmation, to be enrolled in user's authenticator app.
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// MFARecoveryCodes represents recovery codes, shown only once to the user.
+type MFARecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFACodePayloadTemplate represents mandatory fields in payload presenting TOTP or recovery code
+var MFACodePayloadTemplate = utils.FieldTypeBinder{
+	AttributeMFACode: utils.String,
+}
+
+// MFALoginPayloadTemplate represents mandatory fields in payload completing login with second factor
+var MFALoginPayloadTemplate = utils.FieldTypeBinder{
+	AttributeMFAToken: utils.String,
+	AttributeMFACode:  utils.String,
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeOIDCCode  = "code"
+	AttributeOIDCState = "state"
+)
+
+// OIDCAuthorization represent single sign-on login awaiting the user to return from identity provider, with GORM
+// field representation. Only the hash of the state is persisted, which is the one passing through the browser.
+// Code verifier and nonce never leave the service, and the authorization is consumed along with the state.
+// UserID is set when signed in user links the identity provider, and nil for login.
+type OIDCAuthorization struct {
+	ID           uint      `gorm:"primarykey"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+	StateHash    string    `gorm:"column:state_hash;unique;not null"`
+	CodeVerifier string    `gorm:"column:code_verifier;not null"`
+	Nonce        string    `gorm:"column:nonce;not null"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;index;not null"`
+	UserID       *uint     `gorm:"column:user_id"`
+}
+
+// TableName...
+func (OIDCAuthorization) TableName() string {
+	return "oidc_authorization"
+}
+
+// OIDCLinkPayloadTemplate represents mandatory fields in payload starting to link identity provider to the user
+var OIDCLinkPayloadTemplate = utils.FieldTypeBinder{
+	AttributeCurrentPassword: utils.String,
+}
+
+// OIDCCallbackPayloadTemplate represents mandatory fields in payload completing single sign-on login, as received
+// by the redirect URL from identity provider
+var OIDCCallbackPayloadTemplate = utils.FieldTypeBinder{
+	AttributeOIDCCode:  utils.String,
+	AttributeOIDCState: utils.String,
+}
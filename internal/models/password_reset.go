@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeResetToken = "token"
+)
+
+// PasswordResetToken represent single use token of self-service password reset with GORM field representation.
+// Only the hash of the token is persisted. Token is valid till it expires or is used, whichever happens first.
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primarykey"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+	UserID    uint       `gorm:"column:user_id;index;not null"`
+	TokenHash string     `gorm:"column:token_hash;unique;not null"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+}
+
+// TableName...
+func (PasswordResetToken) TableName() string {
+	return "password_reset_token"
+}
+
+// IsValid reports whether the token is neither used nor expired.
+func (t *PasswordResetToken) IsValid(now time.Time) bool {
+	return t.UsedAt == nil && t.ExpiresAt.After(now)
+}
+
+// PasswordForgotPayloadTemplate represents mandatory fields in payload requesting password reset
+var PasswordForgotPayloadTemplate = utils.FieldTypeBinder{
+	AttributeEmail: utils.String,
+}
+
+// PasswordResetPayloadTemplate represents mandatory fields in payload resetting password with reset token
+var PasswordResetPayloadTemplate = utils.FieldTypeBinder{
+	AttributeResetToken: utils.String,
+	AttributePassword:   utils.String,
+}
@@ -1,9 +1,46 @@
 package models
 
-// UserRole kept simple as we are not doing UserRole management
+import "userservice/internal/utils"
+
+const (
+	AttributePermissions = "permissions"
+	AttributeParent      = "parent"
+	QueryParamRoleName   = "name"
+
+	PermissionServiceRead         = "service:read"
+	PermissionServiceWrite        = "service:write"
+	PermissionServiceAdmin        = "service:admin"
+	PermissionUserRead            = "user:read"
+	PermissionUserWrite           = "user:write"
+	PermissionRoleRead            = "role:read"
+	PermissionRoleWrite           = "role:write"
+	PermissionServiceAccountRead  = "service-account:read"
+	PermissionServiceAccountWrite = "service-account:write"
+	PermissionInvitationRead      = "invitation:read"
+	PermissionInvitationWrite     = "invitation:write"
+)
+
+// Permissions lists every permission the routes are authorized for, which roles can be granted.
+// Personal access tokens are scoped to these permissions as well.
+var Permissions = []string{
+	PermissionServiceRead, PermissionServiceWrite, PermissionServiceAdmin,
+	PermissionUserRead, PermissionUserWrite,
+	PermissionRoleRead, PermissionRoleWrite,
+	PermissionServiceAccountRead, PermissionServiceAccountWrite,
+	PermissionInvitationRead, PermissionInvitationWrite,
+}
+
+// BuiltInRoles are the roles seeded by migration, which routes are authorized for, hence they can't be deleted.
+var BuiltInRoles = []string{RoleBasic, RoleAdvanced, RoleAdmin}
+
+// UserRole represent role which users and service accounts are assigned with GORM field representation.
+// Role is granted the permissions, which the routes are authorized for, along with every permission
+// of its parent role, if any.
 type UserRole struct {
-	Name        string `json:"name" gorm:"column:name;unique;not null"`
-	Description string `json:"description" gorm:"column:description;unique;not null"`
+	Name        string   `json:"name" gorm:"column:name;unique;not null"`
+	Description string   `json:"description" gorm:"column:description;not null"`
+	Permissions []string `json:"permissions" gorm:"column:permissions;serializer:json"`
+	Parent      string   `json:"parent" gorm:"column:parent;not null;default:''"`
 }
 
 // TableName...
@@ -11,7 +48,53 @@ func (UserRole) TableName() string {
 	return "user_role"
 }
 
+// EffectivePermissions represent permissions granted to role, including the ones inherited from its ancestors.
+type EffectivePermissions struct {
+	Name        string   `json:"name"`
+	Inherits    []string `json:"inherits"`
+	Permissions []string `json:"permissions"`
+}
+
+// IsPermission reports whether the permission is one the routes are authorized for.
+func IsPermission(permission string) bool {
+	for _, knownPermission := range Permissions {
+		if knownPermission == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBuiltInRole reports whether the role is one of the roles seeded by migration.
+func IsBuiltInRole(role string) bool {
+	for _, builtInRole := range BuiltInRoles {
+		if builtInRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateRolePayloadTemplate represents mandatory fields in role creation payload
+var CreateRolePayloadTemplate = utils.FieldTypeBinder{
+	AttributeName:        utils.String,
+	AttributeDescription: utils.String,
+	AttributePermissions: utils.List,
+	AttributeParent:      utils.String,
+}
+
+// UpdateRolePayloadTemplate represents mandatory fields in role update payload.
+// Role name is immutable, as users and service accounts refer the role by its name.
+var UpdateRolePayloadTemplate = utils.FieldTypeBinder{
+	AttributeDescription: utils.String,
+	AttributePermissions: utils.List,
+	AttributeParent:      utils.String,
+}
+
 // RoleOperations...
 type RoleOperations interface {
 	FetchRoles() ([]UserRole, error)
+	CreateRole(*UserRole) error
+	UpdateRole(string, string, []string, string) (*UserRole, error)
+	DeleteRole(string) error
 }
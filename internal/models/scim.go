@@ -0,0 +1,26 @@
+package models
+
+// Operators of user filter, as in SCIM filter.
+const (
+	FilterOperatorEqual      = "eq"
+	FilterOperatorNotEqual   = "ne"
+	FilterOperatorContains   = "co"
+	FilterOperatorStartsWith = "sw"
+	FilterOperatorEndsWith   = "ew"
+	FilterOperatorPresent    = "pr"
+)
+
+// UserFilter is a case-insensitive condition on column of user, the value is ignored by present operator.
+type UserFilter struct {
+	Column   string
+	Operator string
+	Value    string
+}
+
+// SCIMOperations...
+type SCIMOperations interface {
+	FetchUsers([]UserFilter, int, int) ([]User, int64, error)
+	GetUser(uint) (*User, error)
+	CreateUser(*User) error
+	ReplaceUser(uint, string, string, string, bool) (*User, error)
+}
@@ -21,11 +21,14 @@ var (
 
 // Service represent service metadata with GORM field representation.
 // VersionCount is precomputed and maintain, so we support reads from high scalable users.
+// OwnerTeamID is the team whose members can modify the service; service registered by earlier releases
+// may be unowned, which can be modified by anyone permitted to manage services.
 type Service struct {
 	DBModel
 	Name         string `json:"name" gorm:"column:name;unique;not null" validate:"required"`
 	Description  string `json:"description" gorm:"column:description"`
 	VersionCount int    `json:"versionCount" gorm:"column:version_count"`
+	OwnerTeamID  *uint  `json:"ownerTeamId" gorm:"column:owner_team_id;index"`
 }
 
 // TableName...
@@ -58,8 +61,16 @@ func (ServiceVersion) TableName() string {
 // we have only concurrent reads.
 // even in worst case, we are ok with delayed point of initialization during package init.
 
-// RegisterOrUpdateServicePayloadTemplate represents mandatory fields in service register/update request payload
-var RegisterOrUpdateServicePayloadTemplate = utils.FieldTypeBinder{
+// RegisterServicePayloadTemplate represents mandatory fields in service register request payload.
+// Service is owned by the team since its registration.
+var RegisterServicePayloadTemplate = utils.FieldTypeBinder{
+	AttributeServiceName:        utils.String,
+	AttributeServiceDescription: utils.String,
+	AttributeTeamID:             utils.Number,
+}
+
+// UpdateServicePayloadTemplate represents mandatory fields in service update request payload
+var UpdateServicePayloadTemplate = utils.FieldTypeBinder{
 	AttributeServiceName:        utils.String,
 	AttributeServiceDescription: utils.String,
 }
@@ -97,10 +108,12 @@ type ServiceOperations interface {
 	CheckIfServiceExist(uint) (bool, error)
 	CheckIfVersionForServiceExist(uint, string) (bool, error)
 	GetService(uint) (*Service, error)
-	CreateService(string, string) (*Service, error)
+	CreateService(string, string, uint) (*Service, error)
 	UpdateService(uint, string, string) (*Service, error)
 	DeleteService(uint) error
-	FetchServices(int, int, string, bool, bool) ([]Service, int64, error)
+	UpdateServiceOwner(uint, uint) (*Service, error)
+	IsTeamMember(uint, uint) (bool, error)
+	FetchServices(int, int, string, bool, bool, uint) ([]Service, int64, error)
 	FormatServiceDetailsWithPageDetails([]Service, int64, int, int) PaginatedServiceList
 	GetServiceVersion(uint, string) (*ServiceVersion, error)
 	CreateServiceVersion(uint, string, string) (*ServiceVersion, error)
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeDescription  = "description"
+	AttributeGrantType    = "grant_type"
+	AttributeClientID     = "client_id"
+	AttributeClientSecret = "client_secret"
+
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// ServiceAccount represent non-human identity used by automation with GORM field representation.
+// Service accounts authenticate through client credentials grant, and only the hash of client secret is persisted.
+type ServiceAccount struct {
+	DBModel
+	Name              string     `json:"name" gorm:"column:name;unique;not null"`
+	Description       string     `json:"description" gorm:"column:description"`
+	Role              string     `json:"role" gorm:"column:role;not null"`
+	ClientID          string     `json:"client_id" gorm:"column:client_id;unique;not null"`
+	ClientSecretHash  string     `json:"-" gorm:"column:client_secret_hash;not null"`
+	LastTokenIssuedAt *time.Time `json:"last_token_issued_at" gorm:"column:last_token_issued_at"`
+}
+
+// TableName...
+func (ServiceAccount) TableName() string {
+	return "service_account"
+}
+
+// ServiceAccountCredentials carries client secret, which is shown only once at creation or rotation.
+type ServiceAccountCredentials struct {
+	ServiceAccount
+	ClientSecret string `json:"client_secret"`
+}
+
+// ServiceAccountPayloadTemplate represents mandatory fields in service account creation/update payload
+var ServiceAccountPayloadTemplate = utils.FieldTypeBinder{
+	AttributeName:        utils.String,
+	AttributeDescription: utils.String,
+	AttributeRole:        utils.String,
+}
+
+// ClientCredentialsPayloadTemplate represents mandatory fields in client credentials grant payload
+var ClientCredentialsPayloadTemplate = utils.FieldTypeBinder{
+	AttributeGrantType:    utils.String,
+	AttributeClientID:     utils.String,
+	AttributeClientSecret: utils.String,
+}
+
+// PaginatedServiceAccountList...
+type PaginatedServiceAccountList struct {
+	Data        []ServiceAccount
+	TotalItems  int64
+	PageSize    int
+	CurrentPage int
+}
+
+// ServiceAccountOperations...
+type ServiceAccountOperations interface {
+	CreateServiceAccount(*ServiceAccount) error
+	GetServiceAccount(uint) (*ServiceAccount, error)
+	GetServiceAccountByClientID(string) (*ServiceAccount, error)
+	FetchServiceAccountsWithPagination(int, int) (*PaginatedServiceAccountList, error)
+	UpdateServiceAccount(uint, string, string, string) (*ServiceAccount, error)
+	RotateServiceAccountSecret(uint, string) error
+	DeleteServiceAccount(uint) error
+	RecordTokenIssued(uint) error
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	QueryParamSessionID = "sid"
+)
+
+// Session represent a login of user with GORM field representation, i.e. the device it is made from.
+// Session is tied to the refresh token family started by the login, and is active as long as the family
+// has a usable refresh token. Access tokens issued within the session carry the session ID, such that
+// revoking the session revokes them as well.
+type Session struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"issued_at" gorm:"column:created_at"`
+	UserID     uint      `json:"-" gorm:"column:user_id;index;not null"`
+	FamilyID   string    `json:"-" gorm:"column:family_id;unique;not null"`
+	UserAgent  string    `json:"user_agent" gorm:"column:user_agent"`
+	IP         string    `json:"ip" gorm:"column:ip"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"column:last_seen_at;not null"`
+	// Current marks the session the request is made from
+	Current bool `json:"current" gorm:"-"`
+}
+
+// TableName...
+func (Session) TableName() string {
+	return "session"
+}
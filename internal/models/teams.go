@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeUserID  = "user_id"
+	AttributeTeamID  = "team_id"
+	QueryParamUserID = "user_id"
+	QueryParamOwner  = "owner"
+)
+
+// Team represent group of users owning services with GORM field representation.
+type Team struct {
+	DBModel
+	Name        string `json:"name" gorm:"column:name;unique;not null"`
+	Description string `json:"description" gorm:"column:description"`
+}
+
+// TableName...
+func (Team) TableName() string {
+	return "team"
+}
+
+// TeamMember represent membership of user in a team with GORM field representation.
+// Memberships are removed along with the team or the user.
+type TeamMember struct {
+	TeamID    uint      `json:"team_id" gorm:"primaryKey;column:team_id"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;column:user_id"`
+	CreatedAt time.Time `json:"-"`
+	Team      Team      `json:"-" gorm:"foreignKey:TeamID;references:ID;constraint:OnDelete:CASCADE"`
+	User      User      `json:"-" gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName...
+func (TeamMember) TableName() string {
+	return "team_member"
+}
+
+// CreateOrUpdateTeamPayloadTemplate represents mandatory fields in team creation/update payload
+var CreateOrUpdateTeamPayloadTemplate = utils.FieldTypeBinder{
+	AttributeName:        utils.String,
+	AttributeDescription: utils.String,
+}
+
+// AddTeamMemberPayloadTemplate represents mandatory fields in payload adding user to a team
+var AddTeamMemberPayloadTemplate = utils.FieldTypeBinder{
+	AttributeUserID: utils.Number,
+}
+
+// ServiceOwnerPayloadTemplate represents mandatory fields in payload assigning the team owning a service
+var ServiceOwnerPayloadTemplate = utils.FieldTypeBinder{
+	AttributeTeamID: utils.Number,
+}
+
+// TeamOperations...
+type TeamOperations interface {
+	FetchTeams() ([]Team, error)
+	GetTeam(uint) (*Team, error)
+	CreateTeam(string, string) (*Team, error)
+	UpdateTeam(uint, string, string) (*Team, error)
+	DeleteTeam(uint) error
+	FetchTeamMembers(uint) ([]User, error)
+	AddTeamMember(uint, uint) error
+	RemoveTeamMember(uint, uint) error
+}
+
+// ServiceOwnership resolves whether user is a member of the team owning the service.
+type ServiceOwnership interface {
+	IsServiceOwner(uint, uint) (bool, error)
+}
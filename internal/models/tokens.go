@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+	"userservice/internal/utils"
+)
+
+const (
+	AttributeRefreshToken = "refresh_token"
+)
+
+// RefreshToken represent opaque refresh token metadata with GORM field representation.
+// Only the hash of the token is persisted. Tokens issued from a single login share the FamilyID,
+// such that a replay of an already rotated token can revoke every token descended from that login.
+type RefreshToken struct {
+	DBModel
+	UserID    uint      `json:"-" gorm:"column:user_id;index;not null"`
+	FamilyID  string    `json:"-" gorm:"column:family_id;index;not null"`
+	TokenHash string    `json:"-" gorm:"column:token_hash;unique;not null"`
+	ExpiresAt time.Time `json:"-" gorm:"column:expires_at;not null"`
+	Used      bool      `json:"-" gorm:"type:boolean;column:used"`
+	Revoked   bool      `json:"-" gorm:"type:boolean;column:revoked"`
+}
+
+// TableName...
+func (RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// RefreshTokenPayloadTemplate represents mandatory fields in token refresh payload
+var RefreshTokenPayloadTemplate = utils.FieldTypeBinder{
+	AttributeRefreshToken: utils.String,
+}
+
+// RevokedToken represent access token revoked ahead of its expiry, identified by its jti claim.
+// Record is only relevant until the token expires naturally.
+type RevokedToken struct {
+	JTI       string    `gorm:"column:jti;primaryKey"`
+	ExpiresAt time.Time `gorm:"column:expires_at;index;not null"`
+}
+
+// TableName...
+func (RevokedToken) TableName() string {
+	return "revoked_token"
+}
+
+// SubjectRevocation represent revocation of every access token issued to a subject before RevokedBefore.
+type SubjectRevocation struct {
+	Subject       string    `gorm:"column:subject;primaryKey"`
+	RevokedBefore time.Time `gorm:"column:revoked_before;index;not null"`
+}
+
+// TableName...
+func (SubjectRevocation) TableName() string {
+	return "subject_revocation"
+}
+
+// TokenRevocationOperations...
+type TokenRevocationOperations interface {
+	RevokeToken(string, time.Time) error
+	RevokeSubjectTokens(string) error
+	IsRevoked(string, string, time.Time) bool
+}
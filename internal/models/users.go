@@ -1,12 +1,16 @@
 package models
 
-import "userservice/internal/utils"
+import (
+	"time"
+	"userservice/internal/utils"
+)
 
 const (
-	AttributeName     = "name"
-	AttributeEmail    = "email"
-	AttributePassword = "password"
-	AttributeRole     = "role"
+	AttributeName            = "name"
+	AttributeEmail           = "email"
+	AttributePassword        = "password"
+	AttributeCurrentPassword = "current_password"
+	AttributeRole            = "role"
 )
 
 // User represent user metadata with GORM field representation
@@ -17,6 +21,24 @@ type User struct {
 	Role                string `json:"role" gorm:"column:role;not null"`
 	PasswordHash        string `json:"-" gorm:"column:password_hash"`
 	IsTemporaryPassword bool   `json:"-" gorm:"type:boolean;column:temp_password"`
+	// TemporaryPasswordExpiresAt is set along temporary password of added user, nil never expires it
+	TemporaryPasswordExpiresAt *time.Time `json:"-" gorm:"column:temp_password_expires_at"`
+	// consecutive failed login attempts, reset upon successful login or lockout
+	FailedLoginAttempts int        `json:"failed_login_attempts" gorm:"column:failed_login_attempts;not null"`
+	LockedUntil         *time.Time `json:"locked_until" gorm:"column:locked_until"`
+	MFAEnabled          bool       `json:"mfa_enabled" gorm:"type:boolean;column:mfa_enabled"`
+	// MFASecret is the TOTP secret of confirmed enrollment, whereas MFAPendingSecret awaits confirmation
+	MFASecret        string `json:"-" gorm:"column:mfa_secret"`
+	MFAPendingSecret string `json:"-" gorm:"column:mfa_pending_secret"`
+	// MFALastUsedStep is the TOTP time step last signed in with, codes of the step or prior are rejected as replay
+	MFALastUsedStep int64 `json:"-" gorm:"column:mfa_last_used_step;not null"`
+	// OIDCIssuer and OIDCSubject identify the user at identity provider, once provisioned through or linked to
+	// single sign-on. OIDCProvisioned user is added by single sign-on, whose role is kept in sync with the provider.
+	OIDCIssuer      *string `json:"-" gorm:"column:oidc_issuer;uniqueIndex:idx_user_oidc_identity"`
+	OIDCSubject     *string `json:"-" gorm:"column:oidc_subject;uniqueIndex:idx_user_oidc_identity"`
+	OIDCProvisioned bool    `json:"-" gorm:"type:boolean;column:oidc_provisioned"`
+	// Disabled user is deactivated through SCIM, who can't login nor refresh tokens till reactivated
+	Disabled bool `json:"disabled" gorm:"type:boolean;column:disabled"`
 }
 
 // TableName...
@@ -24,6 +46,16 @@ func (User) TableName() string {
 	return "user"
 }
 
+// IsTemporaryPasswordExpired reports whether temporary password is yet to be changed past its expiry.
+func (u *User) IsTemporaryPasswordExpired(now time.Time) bool {
+	return u.IsTemporaryPassword && u.TemporaryPasswordExpiresAt != nil && !u.TemporaryPasswordExpiresAt.After(now)
+}
+
+// IsLocked reports whether the account is locked out due to failed login attempts.
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(now)
+}
+
 // We consider global variables for payload templates, since
 // there is not dependant variables getting initialized based on its value,
 // we have only concurrent reads.
@@ -42,8 +74,15 @@ var RegisterOrUpdateUserPayloadTemplate = utils.FieldTypeBinder{
 	AttributeRole:  utils.String,
 }
 
-// LoginPayloadTemplate represents mandatory fields in password change payload
+// PasswordChangePayloadTemplate represents mandatory fields in password change payload
 var PasswordChangePayloadTemplate = utils.FieldTypeBinder{
+	AttributePassword:        utils.String,
+	AttributeCurrentPassword: utils.String,
+}
+
+// TemporaryPasswordChangePayloadTemplate represents mandatory fields in payload replacing temporary password,
+// which doesn't require the current password.
+var TemporaryPasswordChangePayloadTemplate = utils.FieldTypeBinder{
 	AttributePassword: utils.String,
 }
 
@@ -59,10 +98,61 @@ type PaginatedUserList struct {
 type UserOperations interface {
 	GetUserByEmail(string) (*User, error)
 	GetUser(uint) (*User, error)
-	CreateUser(string, string, string, string) error
+	CreateUser(string, string, string, string, *time.Time) error
 	UpdateUser(uint, string, string, string) (*User, error)
 	DeleteUser(uint) error
 	FetchUsersWithPagination(int, int) ([]User, int64, error)
 	FormatUserDetailsWithPageDetails([]User, int64, int, int) PaginatedUserList
 	ChangePassword(string, string) error
+	CreateRefreshToken(uint, string, string, time.Time) error
+	RotateRefreshToken(string, string, time.Time) (*RefreshToken, error)
+	RevokeRefreshTokenFamily(uint, string) error
+	RevokeUserRefreshTokens(uint) error
+	CreateAccessToken(*PersonalAccessToken) error
+	FetchAccessTokens(uint) ([]PersonalAccessToken, error)
+	RevokeAccessToken(uint, uint) error
+	RecordLoginFailure(uint) (int, error)
+	LockUser(uint, time.Time) error
+	ResetLoginFailures(uint) error
+	RehashPassword(uint, string, string) error
+	GetLoginFailure(string) (*LoginFailure, error)
+	RecordIPLoginFailure(string, time.Time, time.Time) (int, error)
+	LockIP(string, time.Time) error
+	FetchPasswordHistory(uint, int) ([]string, error)
+	RecordPasswordHistory(uint, string, int) error
+	SetPendingMFASecret(uint, string) error
+	EnableMFA(uint, string, []string) error
+	DisableMFA(uint) error
+	RecordMFAStep(uint, int64) (bool, error)
+	ConsumeRecoveryCode(uint, string) (bool, error)
+	ReplaceRecoveryCodes(uint, []string) error
+	CreatePasswordResetToken(uint, string, time.Time) error
+	GetPasswordResetToken(string) (*PasswordResetToken, error)
+	ConsumePasswordResetToken(string) error
+	ProvisionUser(string, string, string, string, string) (*User, error)
+	GetUserByOIDCIdentity(string, string) (*User, error)
+	LinkOIDCIdentity(uint, string, string) error
+	CreateOIDCAuthorization(*OIDCAuthorization) error
+	ConsumeOIDCAuthorization(string, time.Time) (*OIDCAuthorization, error)
+	CreateSession(*Session) error
+	TouchSession(string, time.Time) (*Session, error)
+	FetchSessions(uint) ([]Session, error)
+	RevokeSession(uint, uint) error
+	FetchImpersonationAudits(uint) ([]ImpersonationAudit, error)
+	GetActiveElevation(uint, time.Time) (*RoleElevation, error)
+	EndElevations(uint, time.Time) error
+}
+
+// PasswordHistory represent password hash formerly chosen by user with GORM field representation.
+// Only last few hashes per user are retained, to deny reuse of recent passwords.
+type PasswordHistory struct {
+	ID           uint      `gorm:"primarykey"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+	UserID       uint      `gorm:"column:user_id;index;not null"`
+	PasswordHash string    `gorm:"column:password_hash;not null"`
+}
+
+// TableName...
+func (PasswordHistory) TableName() string {
+	return "password_history"
 }
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileNotifier drops every notification as an e-mail file into the outbox directory.
+// Outbox can be inspected in local setups, or be picked up by an external mail relay.
+type FileNotifier struct {
+	outboxDir string
+	from      string
+}
+
+// NewFileNotifier initializes notifier writing to the outbox directory, creating it if it doesn't exist.
+func NewFileNotifier(outboxDir string, from string) (*FileNotifier, error) {
+	if err := os.MkdirAll(outboxDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create notification outbox %s: %v", outboxDir, err)
+	}
+	return &FileNotifier{outboxDir: outboxDir, from: from}, nil
+}
+
+// Notify writes the message into a file of its own. File is written under a temporary name and renamed once
+// complete, such that a relay watching the outbox never picks up a partially written message.
+func (n *FileNotifier) Notify(message Message) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	tempPath := filepath.Join(n.outboxDir, "."+name+".tmp")
+	// messages carry secrets meant only for the recipient, hence readable only by the owner
+	if err := os.WriteFile(tempPath, message.Format(n.from, now), 0o600); err != nil {
+		return fmt.Errorf("failed to write notification to outbox: %v", err)
+	}
+	if err := os.Rename(tempPath, filepath.Join(n.outboxDir, name)); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to write notification to outbox: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File Notifier Tests", func() {
+	var outboxDir string
+
+	BeforeEach(func() {
+		var err error
+		outboxDir, err = os.MkdirTemp("", "outbox")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(outboxDir)
+	})
+
+	It("creates outbox directory", func() {
+		dir := filepath.Join(outboxDir, "nested", "outbox")
+		_, err := NewFileNotifier(dir, "noreply@mgmtportal.com")
+		Expect(err).To(BeNil())
+		info, err := os.Stat(dir)
+		Expect(err).To(BeNil())
+		Expect(info.IsDir()).To(BeTrue())
+	})
+
+	It("writes each message to a file of its own", func() {
+		notifier, err := NewFileNotifier(outboxDir, "noreply@mgmtportal.com")
+		Expect(err).To(BeNil())
+		Expect(notifier.Notify(Message{To: "admin@mgmtportal.com", Subject: "first", Body: "body"})).To(Succeed())
+		Expect(notifier.Notify(Message{To: "user@mgmtportal.com", Subject: "second", Body: "body"})).To(Succeed())
+
+		files, err := filepath.Glob(filepath.Join(outboxDir, "*.eml"))
+		Expect(err).To(BeNil())
+		Expect(files).To(HaveLen(2))
+		entries, _ := os.ReadDir(outboxDir)
+		Expect(entries).To(HaveLen(2))
+
+		content, err := os.ReadFile(files[0])
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(ContainSubstring("From: noreply@mgmtportal.com\r\n"))
+		Expect(string(content)).To(ContainSubstring("To: admin@mgmtportal.com\r\n"))
+		Expect(string(content)).To(ContainSubstring("Subject: first\r\n"))
+		info, _ := os.Stat(files[0])
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+	})
+
+	It("fails if outbox directory is gone", func() {
+		notifier, err := NewFileNotifier(outboxDir, "noreply@mgmtportal.com")
+		Expect(err).To(BeNil())
+		Expect(os.RemoveAll(outboxDir)).To(Succeed())
+		Expect(notifier.Notify(Message{To: "admin@mgmtportal.com"})).NotTo(Succeed())
+	})
+})
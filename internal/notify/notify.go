@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Supported notifier drivers.
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// Message represents notification to be delivered to a user.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications to users out-of-band, i.e. not through the API response.
+type Notifier interface {
+	Notify(message Message) error
+}
+
+// LoadNotifier initializes notifier of the configured driver.
+func LoadNotifier(driver string, from string, outboxDir string, smtpConfig SMTPConfig,
+	log *zap.SugaredLogger) (Notifier, error) {
+	switch driver {
+	case DriverLog:
+		return NewLogNotifier(log), nil
+	case DriverFile:
+		return NewFileNotifier(outboxDir, from)
+	case DriverSMTP:
+		if smtpConfig.Host == "" {
+			return nil, fmt.Errorf("smtp host is required for %s notifier", DriverSMTP)
+		}
+		if smtpConfig.TLSMode != "" && !isSMTPTLSMode(smtpConfig.TLSMode) {
+			return nil, fmt.Errorf("unsupported smtp tls mode %q, expected one of %s, %s, %s", smtpConfig.TLSMode,
+				SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSOpportunistic)
+		}
+		return NewSMTPNotifier(smtpConfig, from), nil
+	default:
+		return nil, fmt.Errorf("unsupported notifier driver %q, expected one of %s, %s, %s",
+			driver, DriverLog, DriverFile, DriverSMTP)
+	}
+}
+
+// Format formats the message as plain text e-mail (RFC 5322).
+// Line breaks are stripped off the header values, such that user provided values can't inject headers.
+func (m Message) Format(from string, date time.Time) []byte {
+	var buffer bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", m.To},
+		{"Subject", m.Subject},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=UTF-8"},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buffer, "%s: %s\r\n", header[0], sanitizeHeader(header[1]))
+	}
+	buffer.WriteString("\r\n")
+	buffer.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buffer.Bytes()
+}
+
+// sanitizeHeader strips line breaks off header value.
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// LogNotifier writes notifications to the application log.
+// Notifications carry secrets meant only for the recipient, hence it is only meant for local testing.
+type LogNotifier struct {
+	log *zap.SugaredLogger
+}
+
+// NewLogNotifier initializes notifier writing to the given logger.
+func NewLogNotifier(log *zap.SugaredLogger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+// Notify logs the message, including its body.
+func (n *LogNotifier) Notify(message Message) error {
+	n.log.Infow("Notification", "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var _ = Describe("Notifier Tests", func() {
+	It("log notifier writes the message to the log", func() {
+		var buffer bytes.Buffer
+		core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+			zapcore.AddSync(&buffer), zapcore.InfoLevel)
+		notifier := NewLogNotifier(zap.New(core).Sugar())
+		err := notifier.Notify(Message{To: "admin@mgmtportal.com", Subject: "Password reset", Body: "token"})
+		Expect(err).To(BeNil())
+		Expect(buffer.String()).To(ContainSubstring(`"to":"admin@mgmtportal.com"`))
+		Expect(buffer.String()).To(ContainSubstring(`"subject":"Password reset"`))
+		Expect(buffer.String()).To(ContainSubstring(`"body":"token"`))
+	})
+
+	It("loads notifier of the configured driver", func() {
+		notifier, err := LoadNotifier(DriverLog, "", "", SMTPConfig{}, zap.NewNop().Sugar())
+		Expect(err).To(BeNil())
+		Expect(notifier).To(BeAssignableToTypeOf(&LogNotifier{}))
+		notifier, err = LoadNotifier(DriverSMTP, "noreply@mgmtportal.com", "", SMTPConfig{Host: "localhost", Port: 25},
+			zap.NewNop().Sugar())
+		Expect(err).To(BeNil())
+		Expect(notifier).To(BeAssignableToTypeOf(&SMTPNotifier{}))
+	})
+
+	It("fails to load notifier of unknown driver or incomplete config", func() {
+		_, err := LoadNotifier("pigeon", "", "", SMTPConfig{}, zap.NewNop().Sugar())
+		Expect(err).To(HaveOccurred())
+		_, err = LoadNotifier(DriverSMTP, "", "", SMTPConfig{}, zap.NewNop().Sugar())
+		Expect(err).To(HaveOccurred())
+		_, err = LoadNotifier(DriverSMTP, "", "", SMTPConfig{Host: "localhost", Port: 25, TLSMode: "ssl"},
+			zap.NewNop().Sugar())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("formats message as e-mail without injectable headers", func() {
+		message := Message{To: "admin@mgmtportal.com", Subject: "Hi\r\nBcc: eve@mgmtportal.com", Body: "line1\nline2\n"}
+		formatted := string(message.Format("noreply@mgmtportal.com", time.Unix(0, 0).UTC()))
+		headers, body, found := strings.Cut(formatted, "\r\n\r\n")
+		Expect(found).To(BeTrue())
+		Expect(headers).To(ContainSubstring("From: noreply@mgmtportal.com\r\n"))
+		Expect(headers).To(ContainSubstring("To: admin@mgmtportal.com\r\n"))
+		Expect(headers).To(ContainSubstring("Subject: HiBcc: eve@mgmtportal.com\r\n"))
+		Expect(headers).To(ContainSubstring("Date: Thu, 01 Jan 1970 00:00:00 +0000"))
+		Expect(headers).NotTo(ContainSubstring("\r\nBcc:"))
+		Expect(body).To(Equal("line1\r\nline2\r\n"))
+	})
+})
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// smtpTimeout bounds the whole SMTP conversation, such that an unresponsive server doesn't hold the request.
+const smtpTimeout = 10 * time.Second
+
+// Supported modes of securing the connection to SMTP server with TLS.
+const (
+	// SMTPTLSStartTLS upgrades the connection through STARTTLS, failing the send if server doesn't offer it.
+	SMTPTLSStartTLS = "starttls"
+	// SMTPTLSImplicit establishes TLS right away on connecting, like SMTP servers listening on port 465.
+	SMTPTLSImplicit = "implicit"
+	// SMTPTLSOpportunistic upgrades the connection only if server offers STARTTLS, otherwise messages are sent
+	// in plain text. It is only meant for relays on trusted network, e.g. localhost.
+	SMTPTLSOpportunistic = "opportunistic"
+)
+
+// SMTPConfig represents SMTP server notifications are relayed through.
+// Credentials are optional, and are only sent over TLS or to localhost.
+// TLSMode defaults to SMTPTLSStartTLS, if unset.
+type SMTPConfig struct {
+	Host     string
+	Port     int64
+	Username string
+	Password string
+	TLSMode  string
+}
+
+// SMTPNotifier delivers notifications as e-mail through SMTP server.
+type SMTPNotifier struct {
+	host    string
+	addr    string
+	auth    smtp.Auth
+	from    string
+	tlsMode string
+}
+
+// isSMTPTLSMode reports whether the connection to SMTP server can be secured in the mode.
+func isSMTPTLSMode(mode string) bool {
+	return mode == SMTPTLSStartTLS || mode == SMTPTLSImplicit || mode == SMTPTLSOpportunistic
+}
+
+// NewSMTPNotifier initializes notifier sending e-mails from the given address.
+func NewSMTPNotifier(config SMTPConfig, from string) *SMTPNotifier {
+	notifier := &SMTPNotifier{
+		host:    config.Host,
+		addr:    net.JoinHostPort(config.Host, strconv.FormatInt(config.Port, 10)),
+		from:    from,
+		tlsMode: config.TLSMode,
+	}
+	if notifier.tlsMode == "" {
+		notifier.tlsMode = SMTPTLSStartTLS
+	}
+	if config.Username != "" {
+		notifier.auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	return notifier
+}
+
+// Notify sends the message over the connection secured with TLS as per the mode. Message isn't sent,
+// if TLS can't be established, unless the mode is opportunistic.
+func (n *SMTPNotifier) Notify(message Message) error {
+	var (
+		conn   net.Conn
+		err    error
+		dialer = &net.Dialer{Timeout: smtpTimeout}
+	)
+	if n.tlsMode == SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", n.addr, &tls.Config{ServerName: n.host})
+	} else {
+		conn, err = dialer.Dial("tcp", n.addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect smtp server %s: %v", n.addr, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to initiate smtp session with %s: %v", n.addr, err)
+	}
+	defer client.Close()
+
+	if n.tlsMode != SMTPTLSImplicit {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+				return fmt.Errorf("failed to start tls with smtp server %s: %v", n.addr, err)
+			}
+		} else if n.tlsMode == SMTPTLSStartTLS {
+			return fmt.Errorf("smtp server %s doesn't offer STARTTLS, whereas tls is required", n.addr)
+		}
+	}
+	if n.auth != nil {
+		if err := client.Auth(n.auth); err != nil {
+			return fmt.Errorf("failed to authenticate with smtp server %s: %v", n.addr, err)
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return fmt.Errorf("smtp server rejected sender: %v", err)
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return fmt.Errorf("smtp server rejected recipient: %v", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp server rejected message: %v", err)
+	}
+	if _, err := writer.Write(message.Format(n.from, time.Now())); err != nil {
+		return fmt.Errorf("failed to send message to smtp server: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected message: %v", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"bufio"
+	"net"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeSMTPServer accepts a single SMTP session, recording envelope and data of the message.
+// rejectRecipient makes it refuse RCPT command, like a server not relaying to the domain.
+type fakeSMTPServer struct {
+	listener        net.Listener
+	rejectRecipient bool
+	from            string
+	recipients      []string
+	data            string
+	done            chan struct{}
+}
+
+func startFakeSMTPServer(rejectRecipient bool) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	server := &fakeSMTPServer{listener: listener, rejectRecipient: rejectRecipient, done: make(chan struct{})}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) config() SMTPConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	// fake server doesn't offer STARTTLS
+	return SMTPConfig{Host: addr.IP.String(), Port: int64(addr.Port), TLSMode: SMTPTLSOpportunistic}
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	defer s.listener.Close()
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			s.from = command
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRecipient {
+				reply("550 relay not permitted")
+				continue
+			}
+			s.recipients = append(s.recipients, command)
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+var _ = Describe("SMTP Notifier Tests", func() {
+	It("sends message through the smtp server", func() {
+		server := startFakeSMTPServer(false)
+		notifier := NewSMTPNotifier(server.config(), "noreply@mgmtportal.com")
+		err := notifier.Notify(Message{To: "admin@mgmtportal.com", Subject: "Your account is ready",
+			Body: "temporary password\n.\n"})
+		Expect(err).To(BeNil())
+		<-server.done
+		Expect(server.from).To(Equal("MAIL FROM:<noreply@mgmtportal.com>"))
+		Expect(server.recipients).To(Equal([]string{"RCPT TO:<admin@mgmtportal.com>"}))
+		Expect(server.data).To(ContainSubstring("To: admin@mgmtportal.com\r\n"))
+		Expect(server.data).To(ContainSubstring("Subject: Your account is ready\r\n"))
+		// lone dot of the body is escaped, such that it doesn't end the message early
+		Expect(server.data).To(HaveSuffix("\r\ntemporary password\r\n..\r\n"))
+	})
+
+	It("fails if server rejects the recipient", func() {
+		server := startFakeSMTPServer(true)
+		notifier := NewSMTPNotifier(server.config(), "noreply@mgmtportal.com")
+		err := notifier.Notify(Message{To: "admin@elsewhere.com", Subject: "subject", Body: "body"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("rejected recipient"))
+		<-server.done
+	})
+
+	It("fails if server doesn't offer STARTTLS, unless the mode is opportunistic", func() {
+		server := startFakeSMTPServer(false)
+		config := server.config()
+		config.TLSMode = ""
+		err := NewSMTPNotifier(config, "noreply@mgmtportal.com").Notify(Message{To: "admin@mgmtportal.com"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("doesn't offer STARTTLS"))
+		<-server.done
+		Expect(server.recipients).To(BeEmpty())
+	})
+
+	It("fails if server doesn't establish implicit tls", func() {
+		server := startFakeSMTPServer(false)
+		config := server.config()
+		config.TLSMode = SMTPTLSImplicit
+		err := NewSMTPNotifier(config, "noreply@mgmtportal.com").Notify(Message{To: "admin@mgmtportal.com"})
+		Expect(err).To(HaveOccurred())
+		<-server.done
+		Expect(server.recipients).To(BeEmpty())
+	})
+
+	It("fails if server is unreachable", func() {
+		server := startFakeSMTPServer(false)
+		config := server.config()
+		// close the listener without accepting a session
+		_ = server.listener.Close()
+		<-server.done
+		err := NewSMTPNotifier(config, "noreply@mgmtportal.com").Notify(Message{To: "admin@mgmtportal.com"})
+		Expect(err).To(HaveOccurred())
+	})
+})
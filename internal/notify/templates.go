@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Templates of the messages, each defining "subject" and "body".
+const (
+	TemplateTemporaryPassword = "temporary_password"
+	TemplatePasswordReset     = "password_reset"
+	TemplateInvitation        = "invitation"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templates are parsed once at package init, such that a malformed template fails fast.
+var templates = func() map[string]*template.Template {
+	parsed := make(map[string]*template.Template)
+	for _, name := range []string{TemplateTemporaryPassword, TemplatePasswordReset, TemplateInvitation} {
+		parsed[name] = template.Must(template.ParseFS(templateFiles, "templates/"+name+".tmpl"))
+	}
+	return parsed
+}()
+
+// TemporaryPasswordData is rendered by TemplateTemporaryPassword.
+type TemporaryPasswordData struct {
+	Name     string
+	Email    string
+	Password string
+}
+
+// PasswordResetData is rendered by TemplatePasswordReset. Link is sent if reset page is configured,
+// token otherwise.
+type PasswordResetData struct {
+	Name             string
+	Token            string
+	Link             string
+	ExpiresInMinutes int64
+}
+
+// InvitationData is rendered by TemplateInvitation. Link is sent if invitation page is configured, token otherwise.
+type InvitationData struct {
+	Name      string
+	Role      string
+	Token     string
+	Link      string
+	ExpiresAt time.Time
+}
+
+// NewMessage renders the template with the data, into message addressed to the recipient.
+func NewMessage(templateName string, to string, data interface{}) (Message, error) {
+	tmpl, ok := templates[templateName]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown message template %q", templateName)
+	}
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render subject of %s: %v", templateName, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render body of %s: %v", templateName, err)
+	}
+	return Message{To: to, Subject: strings.TrimSpace(subject.String()), Body: body.String()}, nil
+}
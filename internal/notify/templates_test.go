@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Template Tests", func() {
+	It("renders temporary password message", func() {
+		message, err := NewMessage(TemplateTemporaryPassword, "admin@mgmtportal.com",
+			TemporaryPasswordData{Name: "Admin", Email: "admin@mgmtportal.com", Password: "Xy7#temporary"})
+		Expect(err).To(BeNil())
+		Expect(message.To).To(Equal("admin@mgmtportal.com"))
+		Expect(message.Subject).To(Equal("Your account is ready"))
+		Expect(message.Body).To(HavePrefix("Hi Admin,"))
+		Expect(message.Body).To(ContainSubstring("\nXy7#temporary\n"))
+	})
+
+	It("renders password reset message with link or token", func() {
+		message, err := NewMessage(TemplatePasswordReset, "admin@mgmtportal.com",
+			PasswordResetData{Name: "Admin", Token: "token", Link: "https://mgmtportal.com/reset?token=token",
+				ExpiresInMinutes: 30})
+		Expect(err).To(BeNil())
+		Expect(message.Subject).To(Equal("Password reset"))
+		Expect(message.Body).To(ContainSubstring("within 30 minutes"))
+		Expect(message.Body).To(ContainSubstring("link:\n\nhttps://mgmtportal.com/reset?token=token\n"))
+
+		message, err = NewMessage(TemplatePasswordReset, "admin@mgmtportal.com",
+			PasswordResetData{Name: "Admin", Token: "token", ExpiresInMinutes: 30})
+		Expect(err).To(BeNil())
+		Expect(message.Body).To(ContainSubstring("reset token:\n\ntoken\n"))
+	})
+
+	It("renders invitation message", func() {
+		message, err := NewMessage(TemplateInvitation, "user@mgmtportal.com", InvitationData{Name: "User",
+			Role: "basic", Token: "token", ExpiresAt: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)})
+		Expect(err).To(BeNil())
+		Expect(message.Subject).To(Equal("You are invited"))
+		Expect(message.Body).To(ContainSubstring("join as basic user"))
+		Expect(message.Body).To(ContainSubstring("before 2024-05-01 10:30 UTC"))
+		Expect(message.Body).To(ContainSubstring("invitation token:\n\ntoken\n"))
+	})
+
+	It("fails to render unknown template or mismatching data", func() {
+		_, err := NewMessage("unknown", "admin@mgmtportal.com", nil)
+		Expect(err).To(HaveOccurred())
+		_, err = NewMessage(TemplateTemporaryPassword, "admin@mgmtportal.com", PasswordResetData{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,328 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"userservice/internal/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// discoveryPath is where the provider publishes its metadata, relative to the issuer (OpenID Connect Discovery 1.0).
+	discoveryPath = "/.well-known/openid-configuration"
+	// httpTimeout bounds every request to the provider, such that an unresponsive provider doesn't hold the login.
+	httpTimeout = 10 * time.Second
+	// keysRefreshInterval throttles fetching the keys again upon token signed with unknown key,
+	// such that forged tokens can't make us hammer the provider.
+	keysRefreshInterval = time.Minute
+	// maxResponseSize bounds the responses read from the provider.
+	maxResponseSize = 1 << 20
+
+	codeChallengeMethodS256 = "S256"
+)
+
+// Config represents the registration of this service as a client(relying party) at the identity provider.
+// Client secret is optional for public clients, PKCE protects the authorization code regardless.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim is the ID token claim listing the groups user belongs to
+	GroupsClaim string
+	// RoleMapping grants role to the user based on the groups
+	RoleMapping *RoleMapping
+	// Leeway tolerates clock skew with the provider while validating time based claims
+	Leeway time.Duration
+}
+
+// Metadata is the subset of provider metadata needed for the authorization code flow.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Identity is the user authenticated by the provider, as asserted by the ID token.
+// Issuer along with Subject identify the user, whereas email might be reassigned at the provider.
+type Identity struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+// tokenResponse is the response of token endpoint, only ID token is of interest.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Provider authenticates users through authorization code flow with PKCE (RFC 7636) of an OpenID Connect provider.
+// Metadata and keys of the provider are fetched on first use, such that the provider being unreachable doesn't
+// prevent the service from starting.
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+	log        *zap.SugaredLogger
+
+	mutex         sync.Mutex
+	metadata      *Metadata
+	keys          *auth.KeySet
+	keysFetchedAt time.Time
+}
+
+// NewProvider...
+func NewProvider(config Config, log *zap.SugaredLogger) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	if config.RoleMapping == nil {
+		config.RoleMapping = &RoleMapping{}
+	}
+	return &Provider{config: config, httpClient: &http.Client{Timeout: httpTimeout}, log: log}
+}
+
+// CodeChallenge derives PKCE code challenge of the verifier, with S256 method.
+func CodeChallenge(codeVerifier string) string {
+	digest := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// AuthorizationURL builds the URL user is redirected to for signing in at the provider. Provider redirects back to
+// the configured redirect URL along with the state, and authorization code to be exchanged through Exchange.
+func (p *Provider) AuthorizationURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		p.log.Errorf("Failed to build authorization URL: %v", err)
+		return "", err
+	}
+	authorizationURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint %s: %v", metadata.AuthorizationEndpoint, err)
+	}
+	params := authorizationURL.Query()
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", codeChallengeMethodS256)
+	authorizationURL.RawQuery = params.Encode()
+	return authorizationURL.String(), nil
+}
+
+// Exchange redeems the authorization code along with the PKCE code verifier, and returns the identity asserted by
+// the ID token. ID token has to carry the nonce sent along with the authorization request.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	identity, err := p.exchange(ctx, code, codeVerifier, nonce)
+	if err != nil {
+		p.log.Warnf("Single sign-on login failed: %v", err)
+		return nil, err
+	}
+	return identity, nil
+}
+
+// Role maps groups of the identity to a role, reporting false if none of the groups is granted a role.
+func (p *Provider) Role(identity *Identity) (string, bool) {
+	return p.config.RoleMapping.Role(identity.Groups)
+}
+
+// exchange...
+func (p *Provider) exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		// client credentials are form encoded before being sent as basic auth (RFC 6749 section 2.3.1)
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem authorization code: %v", err)
+	}
+	defer response.Body.Close()
+	var tokens tokenResponse
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("invalid token response with status %d: %v", response.StatusCode, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("authorization code is rejected with status %d: %s %s", response.StatusCode,
+			tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response lacks id_token")
+	}
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// VerifyIDToken validates signature, issuer, audience, expiry and nonce of the ID token (OpenID Connect Core 1.0
+// section 3.1.3.7), and extracts the identity. Email has to be verified by the provider.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := p.verificationKeys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	keyfunc := func(token *jwt.Token) (interface{}, error) {
+		key, err := keys.Keyfunc(token)
+		if err == nil {
+			return key, nil
+		}
+		// provider might have rotated its keys since they are fetched
+		if keys, err = p.verificationKeys(ctx, true); err != nil {
+			return nil, err
+		}
+		return keys.Keyfunc(token)
+	}
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, keyfunc,
+		jwt.WithValidMethods([]string{auth.AlgorithmRS256, auth.AlgorithmES256, auth.AlgorithmEdDSA}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(p.config.Leeway))
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %v", err)
+	}
+
+	tokenNonce, _ := claims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("invalid id token: nonce mismatch")
+	}
+	// token issued to multiple audiences has to be authorized for us
+	if audience, _ := claims.GetAudience(); len(audience) > 1 {
+		if authorizedParty, _ := claims["azp"].(string); authorizedParty != p.config.ClientID {
+			return nil, fmt.Errorf("invalid id token: authorized party %q isn't the client", authorizedParty)
+		}
+	}
+
+	identity := &Identity{Issuer: metadata.Issuer}
+	identity.Subject, _ = claims.GetSubject()
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	if identity.Subject == "" || identity.Email == "" {
+		return nil, fmt.Errorf("invalid id token: sub or email claim is missing")
+	}
+	if verified, _ := claims["email_verified"].(bool); !verified {
+		return nil, fmt.Errorf("invalid id token: email %s isn't verified", identity.Email)
+	}
+	if p.config.GroupsClaim != "" {
+		identity.Groups = stringList(claims[p.config.GroupsClaim])
+	}
+	return identity, nil
+}
+
+// discover fetches provider metadata once, retrying on subsequent calls if it failed.
+func (p *Provider) discover(ctx context.Context) (*Metadata, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	var metadata Metadata
+	if err := p.fetchJSON(ctx, strings.TrimSuffix(p.config.IssuerURL, "/")+discoveryPath, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to discover provider metadata: %v", err)
+	}
+	// issuer has to be the one we are configured with, otherwise tokens of other issuer might get accepted
+	if metadata.Issuer != p.config.IssuerURL {
+		return nil, fmt.Errorf("provider metadata is of issuer %s instead of %s", metadata.Issuer,
+			p.config.IssuerURL)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("provider metadata lacks authorization_endpoint, token_endpoint or jwks_uri")
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// verificationKeys fetches the keys provider signs ID tokens with, unless they are fetched already.
+// Keys are fetched again on refresh, though not more often than keysRefreshInterval.
+func (p *Provider) verificationKeys(ctx context.Context, refresh bool) (*auth.KeySet, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.keys != nil && (!refresh || time.Since(p.keysFetchedAt) < keysRefreshInterval) {
+		return p.keys, nil
+	}
+	var jwks auth.JWKSet
+	if err := p.fetchJSON(ctx, p.metadata.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %v", err)
+	}
+	p.keys = auth.NewJWKSKeySet(jwks)
+	p.keysFetchedAt = time.Now()
+	return p.keys, nil
+}
+
+// fetchJSON...
+func (p *Provider) fetchJSON(ctx context.Context, url string, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", url, response.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(v)
+}
+
+// stringList reads claim which is either a list of strings or a single string.
+func stringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
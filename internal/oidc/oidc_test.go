@@ -0,0 +1,186 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"time"
+	. "userservice/internal/oidc"
+	"userservice/internal/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("OIDC Tests", func() {
+
+	var (
+		idp      *oidctest.FakeIdP
+		provider *Provider
+		ctx      = context.Background()
+		claims   = jwt.MapClaims{"sub": "idp-user-1", "email": "john@mgmtportal.com", "email_verified": true,
+			"name": "John", "groups": []string{"developers", "everyone"}}
+	)
+	BeforeEach(func() {
+		var err error
+		idp, err = oidctest.NewFakeIdP("userservice", "secret")
+		Expect(err).To(BeNil())
+		provider = NewProvider(Config{IssuerURL: idp.Issuer(), ClientID: "userservice", ClientSecret: "secret",
+			RedirectURL: "https://portal/sso/callback", GroupsClaim: "groups"}, zap.NewNop().Sugar())
+	})
+	AfterEach(func() {
+		idp.Close()
+	})
+
+	It("PKCE code challenge is unpadded base64url SHA-256 of verifier", func() {
+		Expect(CodeChallenge("dBjftJeZ4CVP-mJ92K27uhbUJU1p1r_wW1gFWFOEjXk")).
+			To(Equal("ngF5GsXcbwljx6u133FFr3Xht9xooA_DuaX_3QwODtc"))
+	})
+	It("role of the identity", func() {
+		roleMapping, _ := ParseRoleMapping([]string{"developers=advanced"}, "")
+		provider = NewProvider(Config{IssuerURL: idp.Issuer(), RoleMapping: roleMapping}, zap.NewNop().Sugar())
+		role, ok := provider.Role(&Identity{Groups: []string{"developers"}})
+		Expect(ok).To(BeTrue())
+		Expect(role).To(Equal("advanced"))
+		_, ok = NewProvider(Config{}, zap.NewNop().Sugar()).Role(&Identity{Groups: []string{"developers"}})
+		Expect(ok).To(BeFalse())
+	})
+	It("authorization URL carries PKCE code challenge", func() {
+		authorizationURL, err := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+		Expect(err).To(BeNil())
+		parsedURL, _ := url.Parse(authorizationURL)
+		Expect(parsedURL.Path).To(Equal("/authorize"))
+		params := parsedURL.Query()
+		Expect(params.Get("response_type")).To(Equal("code"))
+		Expect(params.Get("client_id")).To(Equal("userservice"))
+		Expect(params.Get("redirect_uri")).To(Equal("https://portal/sso/callback"))
+		Expect(params.Get("scope")).To(Equal("openid email profile"))
+		Expect(params.Get("state")).To(Equal("state"))
+		Expect(params.Get("nonce")).To(Equal("nonce"))
+		Expect(params.Get("code_challenge")).To(Equal(CodeChallenge("verifier")))
+		Expect(params.Get("code_challenge_method")).To(Equal("S256"))
+	})
+	Context("authorization code flow", func() {
+		It("exchanges code for identity", func() {
+			authorizationURL, _ := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			code, state, err := idp.Authorize(authorizationURL, claims)
+			Expect(err).To(BeNil())
+			Expect(state).To(Equal("state"))
+			identity, err := provider.Exchange(ctx, code, "verifier", "nonce")
+			Expect(err).To(BeNil())
+			Expect(*identity).To(Equal(Identity{Issuer: idp.Issuer(), Subject: "idp-user-1",
+				Email: "john@mgmtportal.com", Name: "John", Groups: []string{"developers", "everyone"}}))
+		})
+		It("public client exchanges code without secret", func() {
+			idp.Close()
+			idp, _ = oidctest.NewFakeIdP("userservice", "")
+			provider = NewProvider(Config{IssuerURL: idp.Issuer(), ClientID: "userservice",
+				RedirectURL: "https://portal/sso/callback"}, zap.NewNop().Sugar())
+			authorizationURL, _ := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			code, _, _ := idp.Authorize(authorizationURL, claims)
+			_, err := provider.Exchange(ctx, code, "verifier", "nonce")
+			Expect(err).To(BeNil())
+		})
+		It("code is rejected with wrong code verifier", func() {
+			authorizationURL, _ := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			code, _, _ := idp.Authorize(authorizationURL, claims)
+			_, err := provider.Exchange(ctx, code, "other-verifier", "nonce")
+			Expect(err).To(MatchError(ContainSubstring("invalid_grant")))
+		})
+		It("code is redeemed only once", func() {
+			authorizationURL, _ := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			code, _, _ := idp.Authorize(authorizationURL, claims)
+			_, err := provider.Exchange(ctx, code, "verifier", "nonce")
+			Expect(err).To(BeNil())
+			_, err = provider.Exchange(ctx, code, "verifier", "nonce")
+			Expect(err).To(MatchError(ContainSubstring("invalid_grant")))
+		})
+		It("wrong client secret is rejected", func() {
+			provider = NewProvider(Config{IssuerURL: idp.Issuer(), ClientID: "userservice", ClientSecret: "wrong",
+				RedirectURL: "https://portal/sso/callback"}, zap.NewNop().Sugar())
+			authorizationURL, _ := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			code, _, _ := idp.Authorize(authorizationURL, claims)
+			_, err := provider.Exchange(ctx, code, "verifier", "nonce")
+			Expect(err).To(MatchError(ContainSubstring("invalid_client")))
+		})
+		It("ID token of other nonce is rejected", func() {
+			authorizationURL, _ := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			code, _, _ := idp.Authorize(authorizationURL, claims)
+			_, err := provider.Exchange(ctx, code, "verifier", "other-nonce")
+			Expect(err).To(MatchError(ContainSubstring("nonce mismatch")))
+		})
+	})
+	Context("ID token validation", func() {
+		It("valid ID token", func() {
+			idToken, _ := idp.SignIDToken(jwt.MapClaims{"sub": "1", "email": "john@mgmtportal.com",
+				"email_verified": true, "nonce": "nonce", "groups": "developers"})
+			identity, err := provider.VerifyIDToken(ctx, idToken, "nonce")
+			Expect(err).To(BeNil())
+			Expect(identity.Groups).To(Equal([]string{"developers"}))
+		})
+		for _, tc := range []struct {
+			name   string
+			claims jwt.MapClaims
+			err    string
+		}{
+			{"other issuer", jwt.MapClaims{"iss": "https://evil"}, "token has invalid issuer"},
+			{"other audience", jwt.MapClaims{"aud": "other-client"}, "token has invalid audience"},
+			{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, "token is expired"},
+			{"issued in future", jwt.MapClaims{"iat": time.Now().Add(time.Hour).Unix()}, "token used before issued"},
+			{"multiple audiences without being authorized party", jwt.MapClaims{"aud": []string{"userservice", "other"},
+				"azp": "other"}, "authorized party"},
+			{"missing email", jwt.MapClaims{"email": nil}, "sub or email claim is missing"},
+			{"unverified email", jwt.MapClaims{"email_verified": false}, "isn't verified"},
+			{"email not asserted as verified", jwt.MapClaims{"email_verified": nil}, "isn't verified"},
+			{"email verified asserted as string", jwt.MapClaims{"email_verified": "true"}, "isn't verified"},
+		} {
+			tc := tc
+			It("rejects token of "+tc.name, func() {
+				claims := jwt.MapClaims{"sub": "1", "email": "john@mgmtportal.com", "email_verified": true,
+					"nonce": "nonce"}
+				for name, value := range tc.claims {
+					if value == nil {
+						delete(claims, name)
+					} else {
+						claims[name] = value
+					}
+				}
+				idToken, _ := idp.SignIDToken(claims)
+				_, err := provider.VerifyIDToken(ctx, idToken, "nonce")
+				Expect(err).To(MatchError(ContainSubstring(tc.err)))
+			})
+		}
+		It("rejects token signed with other key", func() {
+			otherIdP, _ := oidctest.NewFakeIdP("userservice", "secret")
+			defer otherIdP.Close()
+			idToken, _ := otherIdP.SignIDToken(jwt.MapClaims{"iss": idp.Issuer(), "sub": "1",
+				"email": "john@mgmtportal.com", "nonce": "nonce"})
+			_, err := provider.VerifyIDToken(ctx, idToken, "nonce")
+			Expect(err).To(MatchError(ContainSubstring("invalid id token")))
+		})
+		It("rejects unsigned token", func() {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"iss": idp.Issuer(), "aud": "userservice",
+				"sub": "1", "email": "john@mgmtportal.com", "nonce": "nonce", "exp": time.Now().Add(time.Minute).Unix()})
+			idToken, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			_, err := provider.VerifyIDToken(ctx, idToken, "nonce")
+			Expect(err).To(MatchError(ContainSubstring("invalid id token")))
+		})
+	})
+	Context("discovery", func() {
+		It("metadata of other issuer is rejected", func() {
+			provider = NewProvider(Config{IssuerURL: idp.Issuer() + "/", ClientID: "userservice"}, zap.NewNop().Sugar())
+			_, err := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			Expect(err).To(MatchError(ContainSubstring("provider metadata is of issuer")))
+		})
+		It("unreachable provider is retried", func() {
+			idp.SetUnavailable(true)
+			provider = NewProvider(Config{IssuerURL: idp.Issuer(), ClientID: "userservice"}, zap.NewNop().Sugar())
+			_, err := provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			Expect(err).To(MatchError(ContainSubstring("failed to discover provider metadata")))
+			idp.SetUnavailable(false)
+			_, err = provider.AuthorizationURL(ctx, "state", "nonce", "verifier")
+			Expect(err).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,201 @@
+// Package oidctest provides fake OpenID Connect identity provider for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+	"userservice/internal/auth"
+	"userservice/internal/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// fakeIdPKeyID identifies the key fake identity provider signs ID tokens with.
+	fakeIdPKeyID = "fake-idp-key"
+	// discoveryPath is where the provider publishes its metadata, relative to the issuer.
+	discoveryPath = "/.well-known/openid-configuration"
+	// codeChallengeMethodS256 is the only PKCE method clients are expected to use.
+	codeChallengeMethodS256 = "S256"
+)
+
+// tokenResponse is the response of token endpoint.
+type tokenResponse struct {
+	IDToken string `json:"id_token,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// fakeAuthorization is the pending authorization of an issued code.
+type fakeAuthorization struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	claims        jwt.MapClaims
+}
+
+// FakeIdP is a minimal in-process OpenID Connect provider for testing the login flow without a real provider.
+// It serves discovery, keys and token endpoints, and sign in at the provider is simulated through Authorize.
+type FakeIdP struct {
+	server       *httptest.Server
+	key          *rsa.PrivateKey
+	clientID     string
+	clientSecret string
+
+	mutex          sync.Mutex
+	authorizations map[string]fakeAuthorization
+	unavailable    bool
+}
+
+// NewFakeIdP starts fake provider for the client, which has to be closed once done.
+func NewFakeIdP(clientID, clientSecret string) (*FakeIdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	idp := &FakeIdP{key: key, clientID: clientID, clientSecret: clientSecret,
+		authorizations: make(map[string]fakeAuthorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, idp.serveDiscovery)
+	mux.HandleFunc("/keys", idp.serveKeys)
+	mux.HandleFunc("/token", idp.serveToken)
+	idp.server = httptest.NewServer(idp.withAvailability(mux))
+	return idp, nil
+}
+
+// Issuer...
+func (f *FakeIdP) Issuer() string {
+	return f.server.URL
+}
+
+// Close...
+func (f *FakeIdP) Close() {
+	f.server.Close()
+}
+
+// SetUnavailable simulates outage of the provider, every endpoint responding with 503 Service Unavailable.
+func (f *FakeIdP) SetUnavailable(unavailable bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.unavailable = unavailable
+}
+
+// Authorize simulates the user with given claims signing in through the authorization URL, responding with the
+// authorization code and state which provider redirects back with.
+func (f *FakeIdP) Authorize(authorizationURL string, claims jwt.MapClaims) (string, string, error) {
+	parsedURL, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", "", err
+	}
+	params := parsedURL.Query()
+	if params.Get("response_type") != "code" || params.Get("client_id") != f.clientID {
+		return "", "", fmt.Errorf("unexpected response_type or client_id")
+	}
+	if params.Get("code_challenge_method") != codeChallengeMethodS256 || params.Get("code_challenge") == "" {
+		return "", "", fmt.Errorf("PKCE code challenge is missing")
+	}
+	code, err := auth.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.authorizations[code] = fakeAuthorization{redirectURI: params.Get("redirect_uri"),
+		codeChallenge: params.Get("code_challenge"), nonce: params.Get("nonce"), claims: claims}
+	return code, params.Get("state"), nil
+}
+
+// SignIDToken signs ID token with the claims, adding the registered claims which are missing.
+func (f *FakeIdP) SignIDToken(claims jwt.MapClaims) (string, error) {
+	now := time.Now()
+	idTokenClaims := jwt.MapClaims{"iss": f.Issuer(), "aud": f.clientID, "iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix()}
+	for name, value := range claims {
+		idTokenClaims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, idTokenClaims)
+	token.Header[auth.JWTHeaderKeyID] = fakeIdPKeyID
+	return token.SignedString(f.key)
+}
+
+// serveDiscovery...
+func (f *FakeIdP) serveDiscovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Metadata{Issuer: f.Issuer(), AuthorizationEndpoint: f.Issuer() + "/authorize",
+		TokenEndpoint: f.Issuer() + "/token", JWKSURI: f.Issuer() + "/keys"})
+}
+
+// serveKeys...
+func (f *FakeIdP) serveKeys(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, auth.JWKSet{Keys: []auth.JWK{{KeyType: "RSA", KeyID: fakeIdPKeyID, Use: "sig",
+		Algorithm: auth.AlgorithmRS256, N: base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes())}}})
+}
+
+// serveToken redeems authorization code, once, for the client who presents the matching code verifier.
+func (f *FakeIdP) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "unsupported_grant_type"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if clientID != f.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(f.clientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, tokenResponse{Error: "invalid_client"})
+		return
+	}
+
+	f.mutex.Lock()
+	authorization, ok := f.authorizations[r.PostForm.Get("code")]
+	delete(f.authorizations, r.PostForm.Get("code"))
+	f.mutex.Unlock()
+	if !ok || authorization.redirectURI != r.PostForm.Get("redirect_uri") ||
+		oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != authorization.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, tokenResponse{Error: "invalid_grant"})
+		return
+	}
+	claims := jwt.MapClaims{"nonce": authorization.nonce}
+	for name, value := range authorization.claims {
+		claims[name] = value
+	}
+	idToken, err := f.SignIDToken(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, tokenResponse{Error: "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, tokenResponse{IDToken: idToken})
+}
+
+// withAvailability...
+func (f *FakeIdP) withAvailability(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		unavailable := f.unavailable
+		f.mutex.Unlock()
+		if unavailable {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON...
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,58 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupRole maps members of the provider group to the role.
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// RoleMapping maps groups of the user to a role. Mappings are evaluated in order, and the first one matching any of
+// the groups wins, hence the mappings are expected to be listed from the most privileged role to the least.
+// Users who match none of the mappings are given the default role, if any.
+type RoleMapping struct {
+	mappings    []GroupRole
+	defaultRole string
+}
+
+// ParseRoleMapping parses mappings formatted as group=role.
+func ParseRoleMapping(mappings []string, defaultRole string) (*RoleMapping, error) {
+	roleMapping := &RoleMapping{defaultRole: defaultRole}
+	for _, mapping := range mappings {
+		group, role, ok := strings.Cut(mapping, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid group role mapping %q; expected group=role", mapping)
+		}
+		roleMapping.mappings = append(roleMapping.mappings, GroupRole{Group: group, Role: role})
+	}
+	return roleMapping, nil
+}
+
+// Role maps the groups to a role, reporting false if none matches and there is no default role.
+func (m *RoleMapping) Role(groups []string) (string, bool) {
+	for _, mapping := range m.mappings {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role, true
+			}
+		}
+	}
+	return m.defaultRole, m.defaultRole != ""
+}
+
+// Roles lists every role groups are mapped to, including the default role.
+func (m *RoleMapping) Roles() []string {
+	var roles []string
+	for _, mapping := range m.mappings {
+		roles = append(roles, mapping.Role)
+	}
+	if m.defaultRole != "" {
+		roles = append(roles, m.defaultRole)
+	}
+	return roles
+}
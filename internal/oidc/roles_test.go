@@ -0,0 +1,39 @@
+package oidc
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Role Mapping Tests", func() {
+
+	It("first matching mapping wins", func() {
+		mapping, err := ParseRoleMapping([]string{"platform-admins=admin", " developers = advanced"}, "")
+		Expect(err).To(BeNil())
+		role, ok := mapping.Role([]string{"developers", "platform-admins"})
+		Expect(ok).To(BeTrue())
+		Expect(role).To(Equal("admin"))
+		role, ok = mapping.Role([]string{"developers"})
+		Expect(ok).To(BeTrue())
+		Expect(role).To(Equal("advanced"))
+		Expect(mapping.Roles()).To(Equal([]string{"admin", "advanced"}))
+	})
+	It("unmatched groups get default role", func() {
+		mapping, _ := ParseRoleMapping([]string{"developers=advanced"}, "basic")
+		role, ok := mapping.Role([]string{"sales"})
+		Expect(ok).To(BeTrue())
+		Expect(role).To(Equal("basic"))
+		Expect(mapping.Roles()).To(Equal([]string{"advanced", "basic"}))
+	})
+	It("unmatched groups are rejected without default role", func() {
+		mapping, _ := ParseRoleMapping([]string{"developers=advanced"}, "")
+		_, ok := mapping.Role(nil)
+		Expect(ok).To(BeFalse())
+	})
+	It("malformed mapping", func() {
+		for _, malformed := range []string{"developers", "=admin", "developers="} {
+			_, err := ParseRoleMapping([]string{malformed}, "")
+			Expect(err).To(MatchError(ContainSubstring("expected group=role")))
+		}
+	})
+})
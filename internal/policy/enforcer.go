@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	appErrors "userservice/internal/errors"
+	"userservice/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Enforcer authorizes requests against the policy file, which can be reloaded at runtime.
+type Enforcer struct {
+	path             string
+	ownership        models.ServiceOwnership
+	isRoleConfigured func(string) bool
+	log              *zap.SugaredLogger
+	lock             sync.RWMutex
+	policy           *Policy
+}
+
+// NewEnforcer loads the policy file, ownership resolves the owner condition.
+// Roles the policy refers to are checked against isRoleConfigured, and the unknown ones are warned about.
+func NewEnforcer(path string, ownership models.ServiceOwnership, isRoleConfigured func(string) bool,
+	log *zap.SugaredLogger) (*Enforcer, error) {
+	enforcer := &Enforcer{path: path, ownership: ownership, isRoleConfigured: isRoleConfigured, log: log}
+	policy, err := enforcer.load()
+	if err != nil {
+		return nil, err
+	}
+	enforcer.policy = policy
+	return enforcer, nil
+}
+
+// Reload reads the policy file again. Policy in effect is retained, if the file is invalid.
+func (e *Enforcer) Reload() (int, error) {
+	policy, err := e.load()
+	if err != nil {
+		return 0, err
+	}
+	e.lock.Lock()
+	e.policy = policy
+	e.lock.Unlock()
+	return policy.Rules(), nil
+}
+
+// load reads the policy file. Roles which aren't configured don't invalidate the policy, as roles are managed
+// at runtime, whereas they are likely misspelt, hence warned about.
+func (e *Enforcer) load() (*Policy, error) {
+	policy, err := LoadFile(e.path)
+	if err != nil {
+		return nil, err
+	}
+	if unknownRoles := policy.UnknownRoles(e.isRoleConfigured); len(unknownRoles) != 0 {
+		e.log.Warnf("Policy file %s refers to roles which aren't configured, rules never apply to them: %s",
+			e.path, strings.Join(unknownRoles, ", "))
+	}
+	return policy, nil
+}
+
+// Authorize reports whether the policy in effect permits the request.
+func (e *Enforcer) Authorize(request Request) (bool, error) {
+	e.lock.RLock()
+	policy := e.policy
+	e.lock.RUnlock()
+	return policy.Authorize(request, e.evaluateCondition)
+}
+
+// evaluateCondition...
+func (e *Enforcer) evaluateCondition(condition string, request Request) (bool, error) {
+	switch condition {
+	case ConditionOwner:
+		serviceID, ok := serviceIDOfResource(request.Resource)
+		// service accounts can't be members of a team
+		if !ok || request.UserID == 0 {
+			return false, nil
+		}
+		return e.ownership.IsServiceOwner(serviceID, request.UserID)
+	}
+	return false, nil
+}
+
+// OwnershipStore resolves team owning services from DB.
+type OwnershipStore struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewOwnershipStore...
+func NewOwnershipStore(db *gorm.DB, log *zap.SugaredLogger) *OwnershipStore {
+	return &OwnershipStore{db: db, log: log}
+}
+
+// IsServiceOwner checks if user is a member of the team owning the service.
+// Service yet to be owned, or which doesn't exist, is considered owned, as the routes handle them.
+func (s *OwnershipStore) IsServiceOwner(serviceID uint, userID uint) (bool, error) {
+	var service models.Service
+	if err := s.db.Select("owner_team_id").Where("id = ?", serviceID).First(&service).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		s.log.Errorf("Failed to fetch owner of service [ID:%d]: %v", serviceID, err)
+		return false, appErrors.ErrInternal
+	}
+	if service.OwnerTeamID == nil {
+		return true, nil
+	}
+	var memberCount int64
+	if err := s.db.Model(&models.TeamMember{}).Where("team_id = ? and user_id = ?", *service.OwnerTeamID, userID).
+		Count(&memberCount).Error; err != nil {
+		s.log.Errorf("Failed to determine if user [ID:%d] is a member of team [ID:%d]: %v", userID,
+			*service.OwnerTeamID, err)
+		return false, appErrors.ErrInternal
+	}
+	return memberCount != 0, nil
+}
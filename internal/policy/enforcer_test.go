@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	appErrors "userservice/internal/errors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// ownershipMock...
+type ownershipMock struct {
+	isOwner bool
+	err     error
+}
+
+// IsServiceOwner...
+func (m *ownershipMock) IsServiceOwner(uint, uint) (bool, error) {
+	return m.isOwner, m.err
+}
+
+// isRoleConfigured considers the default roles configured.
+func isRoleConfigured(role string) bool {
+	return role == "basic" || role == "advanced" || role == "admin"
+}
+
+var _ = Describe("Enforcer", func() {
+	var (
+		policyDir string
+		path      string
+		ownership *ownershipMock
+		enforcer  *Enforcer
+	)
+	BeforeEach(func() {
+		var err error
+		policyDir, err = os.MkdirTemp("", "policy")
+		Expect(err).To(BeNil())
+		path = filepath.Join(policyDir, "policy.yaml")
+		Expect(os.WriteFile(path, []byte(validPolicy), 0600)).To(Succeed())
+		ownership = &ownershipMock{isOwner: true}
+		enforcer, err = NewEnforcer(path, ownership, isRoleConfigured, zap.NewExample().Sugar())
+		Expect(err).To(BeNil())
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(policyDir)
+	})
+
+	It("Invalid policy file at startup", func() {
+		Expect(os.WriteFile(path, []byte("version: 1\n"), 0600)).To(Succeed())
+		_, err := NewEnforcer(path, ownership, isRoleConfigured, zap.NewExample().Sugar())
+		Expect(err).To(Not(BeNil()))
+	})
+	It("Owner condition holds for members of the team owning the service", func() {
+		request := Request{Role: "advanced", Action: ActionUpdate, Resource: "service/1", UserID: 3}
+		allowed, err := enforcer.Authorize(request)
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeTrue())
+
+		ownership.isOwner = false
+		allowed, err = enforcer.Authorize(request)
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeFalse())
+
+		ownership.err = appErrors.ErrInternal
+		_, err = enforcer.Authorize(request)
+		Expect(err).To(MatchError(appErrors.ErrInternal))
+	})
+	It("Owner condition never holds for service accounts", func() {
+		allowed, err := enforcer.Authorize(Request{Role: "advanced", Action: ActionUpdate, Resource: "service/1"})
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeFalse())
+	})
+	It("Reload applies the updated policy", func() {
+		request := Request{Role: "basic", Action: ActionCreate, Resource: "service"}
+		allowed, _ := enforcer.Authorize(request)
+		Expect(allowed).To(BeFalse())
+
+		Expect(os.WriteFile(path, []byte("version: 1\nrules:\n  - roles: [basic]\n    actions: [create]\n"+
+			"    resources: [service]\n"), 0600)).To(Succeed())
+		rules, err := enforcer.Reload()
+		Expect(err).To(BeNil())
+		Expect(rules).To(Equal(1))
+		allowed, _ = enforcer.Authorize(request)
+		Expect(allowed).To(BeTrue())
+	})
+	It("Policy referring to roles which aren't configured is loaded", func() {
+		Expect(os.WriteFile(path, []byte("version: 1\nrules:\n  - roles: [basic, auditor]\n    actions: [read]\n"+
+			"    resources: [user]\n"), 0600)).To(Succeed())
+		rules, err := enforcer.Reload()
+		Expect(err).To(BeNil())
+		Expect(rules).To(Equal(1))
+	})
+	It("Reload retains the policy in effect, if updated policy is invalid", func() {
+		Expect(os.WriteFile(path, []byte("version: 1\nrules:\n  - roles: [basic]\n"), 0600)).To(Succeed())
+		_, err := enforcer.Reload()
+		Expect(err).To(Not(BeNil()))
+		allowed, err := enforcer.Authorize(Request{Role: "admin", Action: ActionDelete, Resource: "user/2"})
+		Expect(err).To(BeNil())
+		Expect(allowed).To(BeTrue())
+	})
+})
+
+var _ = Describe("Ownership store", func() {
+	var (
+		mock   sqlmock.Sqlmock
+		mockDb *sql.DB
+		store  *OwnershipStore
+	)
+	BeforeEach(func() {
+		mockDb, mock, _ = sqlmock.New()
+		db, _ := gorm.Open(postgres.New(postgres.Config{Conn: mockDb, DriverName: "postgres"}))
+		store = NewOwnershipStore(db, zap.NewExample().Sugar())
+	})
+
+	It("Service which doesn't exist is left to the routes", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "owner_team_id" FROM "service" WHERE id = $1`)).
+			WillReturnError(gorm.ErrRecordNotFound)
+		isOwner, err := store.IsServiceOwner(1, 3)
+		Expect(err).To(BeNil())
+		Expect(isOwner).To(BeTrue())
+	})
+	It("Service yet to be owned by a team", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "owner_team_id" FROM "service" WHERE id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_team_id"}).AddRow(nil))
+		isOwner, err := store.IsServiceOwner(1, 3)
+		Expect(err).To(BeNil())
+		Expect(isOwner).To(BeTrue())
+	})
+	It("User isn't a member of the team owning the service", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "owner_team_id" FROM "service" WHERE id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_team_id"}).AddRow(7))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "team_member" WHERE team_id = $1 and user_id = $2`)).
+			WithArgs(7, 3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		isOwner, err := store.IsServiceOwner(1, 3)
+		Expect(err).To(BeNil())
+		Expect(isOwner).To(BeFalse())
+	})
+	It("DB error while fetching owner of service", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "owner_team_id" FROM "service" WHERE id = $1`)).
+			WillReturnError(errors.New("connection error"))
+		_, err := store.IsServiceOwner(1, 3)
+		Expect(err).To(MatchError(appErrors.ErrInternal))
+	})
+})
@@ -0,0 +1,338 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SupportedVersion is the version of policy document understood by this release.
+const SupportedVersion = 1
+
+const (
+	ActionRead   = "read"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionAny    = "*"
+
+	// AnyRole matches the role of every request.
+	AnyRole = "*"
+
+	// ConditionOwner holds when requester is a member of the team owning the service the resource belongs to.
+	// Service yet to be owned by a team is considered owned by everyone, alike service management.
+	ConditionOwner = "owner"
+
+	// wildcardSegment matches exactly one segment of the resource, whereas
+	// wildcardRemainder matches any number of trailing segments.
+	wildcardSegment   = "*"
+	wildcardRemainder = "**"
+
+	serviceResource = "service"
+)
+
+var (
+	knownActions    = []string{ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionAny}
+	knownConditions = []string{ConditionOwner}
+)
+
+// Rule permits the roles to perform the actions on resources matching any of the patterns,
+// provided every condition holds.
+type Rule struct {
+	Description string   `json:"description" yaml:"description"`
+	Roles       []string `json:"roles" yaml:"roles"`
+	Actions     []string `json:"actions" yaml:"actions"`
+	Resources   []string `json:"resources" yaml:"resources"`
+	Conditions  []string `json:"conditions" yaml:"conditions"`
+}
+
+// Document represents the policy file. It is versioned, such that its format can evolve across releases.
+type Document struct {
+	Version int    `json:"version" yaml:"version"`
+	Rules   []Rule `json:"rules" yaml:"rules"`
+}
+
+// Policy is the validated policy document, which requests are authorized against.
+// Requests are denied, unless a rule permits them.
+type Policy struct {
+	rules []Rule
+}
+
+// Request is the authenticated request to be authorized.
+type Request struct {
+	Role string
+	// InheritedRoles are the ancestors of the role, rules of which apply to the request as well
+	InheritedRoles []string
+	Action         string
+	Resource       string
+	// UserID is the requester, 0 for service accounts
+	UserID uint
+}
+
+// ConditionEvaluator reports whether the condition holds for the request.
+type ConditionEvaluator func(condition string, request Request) (bool, error)
+
+// LoadFile reads and validates the policy file. Files with .json extension are decoded as JSON, others as YAML.
+func LoadFile(path string) (*Policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %v", err)
+	}
+	policy, err := Parse(content, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Parse decodes the policy document and validates it. Unknown fields are rejected, as they are likely misspelt.
+func Parse(content []byte, isJSON bool) (*Policy, error) {
+	var document Document
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, fmt.Errorf("malformed JSON: %v", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&document); err != nil {
+			return nil, fmt.Errorf("malformed YAML: %v", err)
+		}
+	}
+	if err := document.Validate(); err != nil {
+		return nil, err
+	}
+	return &Policy{rules: document.Rules}, nil
+}
+
+// Validate reports every problem of the document at once, such that the file can be fixed in one go.
+func (d *Document) Validate() error {
+	if d.Version != SupportedVersion {
+		return fmt.Errorf("unsupported version %d; supported version is %d", d.Version, SupportedVersion)
+	}
+	if len(d.Rules) == 0 {
+		return errors.New("no rules are defined; every request would be denied")
+	}
+	var problems []error
+	for i, rule := range d.Rules {
+		for _, problem := range rule.validate() {
+			problems = append(problems, fmt.Errorf("rule %d: %s", i+1, problem))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// validate...
+func (r *Rule) validate() (problems []string) {
+	if len(r.Roles) == 0 {
+		problems = append(problems, "roles are empty")
+	}
+	for _, role := range r.Roles {
+		if strings.TrimSpace(role) == "" {
+			problems = append(problems, "role is empty")
+		}
+	}
+	if len(r.Actions) == 0 {
+		problems = append(problems, "actions are empty")
+	}
+	for _, action := range r.Actions {
+		if !contains(knownActions, action) {
+			problems = append(problems, fmt.Sprintf("unknown action %q; choose %s", action,
+				strings.Join(knownActions, ", ")))
+		}
+	}
+	if len(r.Resources) == 0 {
+		problems = append(problems, "resources are empty")
+	}
+	for _, pattern := range r.Resources {
+		if problem := validatePattern(pattern); problem != "" {
+			problems = append(problems, fmt.Sprintf("resource %q %s", pattern, problem))
+		}
+	}
+	for _, condition := range r.Conditions {
+		if !contains(knownConditions, condition) {
+			problems = append(problems, fmt.Sprintf("unknown condition %q; choose %s", condition,
+				strings.Join(knownConditions, ", ")))
+			continue
+		}
+		if condition == ConditionOwner {
+			for _, pattern := range r.Resources {
+				segments := strings.Split(pattern, "/")
+				if len(segments) < 2 || segments[0] != serviceResource || segments[1] == wildcardRemainder {
+					problems = append(problems, fmt.Sprintf(
+						"condition %q applies only to resources of a service, e.g. service/*, but not to %q",
+						condition, pattern))
+				}
+			}
+		}
+	}
+	return
+}
+
+// validatePattern...
+func validatePattern(pattern string) string {
+	if pattern == "" {
+		return "is empty"
+	}
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+			return "has an empty segment; segments are separated by a single '/' without leading or trailing '/'"
+		case segment == wildcardRemainder && i != len(segments)-1:
+			return "has '**' ahead of the last segment"
+		case segment != wildcardSegment && segment != wildcardRemainder && strings.Contains(segment, "*"):
+			return "has a partial wildcard; '*' and '**' must be whole segments"
+		}
+	}
+	return ""
+}
+
+// ActionFromMethod maps the HTTP method of the request to the action it performs.
+func ActionFromMethod(method string) string {
+	switch method {
+	case http.MethodPost:
+		return ActionCreate
+	case http.MethodPut, http.MethodPatch:
+		return ActionUpdate
+	case http.MethodDelete:
+		return ActionDelete
+	default:
+		return ActionRead
+	}
+}
+
+// Authorize reports whether any rule permits the request.
+// Conditions are evaluated only for rules matching the request otherwise.
+func (p *Policy) Authorize(request Request, evaluate ConditionEvaluator) (bool, error) {
+	for _, rule := range p.rules {
+		if !rule.matches(request) {
+			continue
+		}
+		conditionsHold := true
+		for _, condition := range rule.Conditions {
+			holds, err := evaluate(condition, request)
+			if err != nil {
+				return false, err
+			}
+			if !holds {
+				conditionsHold = false
+				break
+			}
+		}
+		if conditionsHold {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Rules responds with the number of rules in policy.
+func (p *Policy) Rules() int {
+	return len(p.rules)
+}
+
+// Roles responds with the distinct roles the rules refer to, in order of their appearance, except AnyRole.
+func (p *Policy) Roles() []string {
+	var roles []string
+	for _, rule := range p.rules {
+		for _, role := range rule.Roles {
+			if role != AnyRole && !contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// UnknownRoles responds with the roles the rules refer to, which aren't configured.
+// Rules never apply to such roles, unless they are created afterwards.
+func (p *Policy) UnknownRoles(isRoleConfigured func(string) bool) []string {
+	var unknownRoles []string
+	for _, role := range p.Roles() {
+		if !isRoleConfigured(role) {
+			unknownRoles = append(unknownRoles, role)
+		}
+	}
+	return unknownRoles
+}
+
+// matchesRole reports whether the rule applies to the role of the request, or any role it inherits.
+func (r *Rule) matchesRole(request Request) bool {
+	if contains(r.Roles, AnyRole) || contains(r.Roles, request.Role) {
+		return true
+	}
+	for _, inheritedRole := range request.InheritedRoles {
+		if contains(r.Roles, inheritedRole) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches...
+func (r *Rule) matches(request Request) bool {
+	if !r.matchesRole(request) {
+		return false
+	}
+	if !contains(r.Actions, request.Action) && !contains(r.Actions, ActionAny) {
+		return false
+	}
+	for _, pattern := range r.Resources {
+		if matchResource(pattern, request.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchResource matches resource against the pattern segment by segment.
+func matchResource(pattern string, resource string) bool {
+	patternSegments := strings.Split(pattern, "/")
+	resourceSegments := strings.Split(resource, "/")
+	for i, patternSegment := range patternSegments {
+		if patternSegment == wildcardRemainder {
+			return true
+		}
+		if i >= len(resourceSegments) {
+			return false
+		}
+		if patternSegment != wildcardSegment && patternSegment != resourceSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(resourceSegments)
+}
+
+// serviceIDOfResource responds with ID of the service the resource belongs to, false if it doesn't belong to one.
+func serviceIDOfResource(resource string) (uint, bool) {
+	segments := strings.Split(resource, "/")
+	if len(segments) < 2 || segments[0] != serviceResource {
+		return 0, false
+	}
+	serviceID, err := strconv.ParseUint(segments[1], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(serviceID), true
+}
+
+// contains...
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const validPolicy = `
+version: 1
+rules:
+  - description: everyone views services
+    roles: ["*"]
+    actions: [read]
+    resources: ["service/**", "services"]
+  - description: team members manage their services and versions
+    roles: [advanced]
+    actions: [update, delete]
+    resources: ["service/*", "service/*/version/*"]
+    conditions: [owner]
+  - roles: [admin]
+    actions: ["*"]
+    resources: ["**"]
+`
+
+var _ = Describe("Policy", func() {
+
+	noConditions := func(string, Request) (bool, error) {
+		return false, errors.New("unexpected condition evaluation")
+	}
+
+	Context("Parse", func() {
+		It("Valid YAML policy", func() {
+			policy, err := Parse([]byte(validPolicy), false)
+			Expect(err).To(BeNil())
+			Expect(policy.Rules()).To(Equal(3))
+		})
+		It("Valid JSON policy", func() {
+			policy, err := Parse([]byte(`{"version": 1, "rules": [
+				{"roles": ["basic"], "actions": ["read"], "resources": ["service/*"]}]}`), true)
+			Expect(err).To(BeNil())
+			Expect(policy.Rules()).To(Equal(1))
+		})
+		It("Unknown fields are rejected", func() {
+			_, err := Parse([]byte("version: 1\nrules:\n  - role: [basic]\n"), false)
+			Expect(err).To(MatchError(ContainSubstring("malformed YAML")))
+			_, err = Parse([]byte(`{"version": 1, "rule": []}`), true)
+			Expect(err).To(MatchError(ContainSubstring("malformed JSON")))
+		})
+		It("Unsupported version", func() {
+			_, err := Parse([]byte("version: 2\nrules: []\n"), false)
+			Expect(err).To(MatchError("unsupported version 2; supported version is 1"))
+		})
+		It("Policy without rules", func() {
+			_, err := Parse([]byte("version: 1\n"), false)
+			Expect(err).To(MatchError(ContainSubstring("no rules are defined")))
+		})
+		It("Every problem of the rules is reported", func() {
+			_, err := Parse([]byte(`
+version: 1
+rules:
+  - roles: []
+    actions: [modify]
+    resources: ["service/**/version", "/services", "service/v*"]
+  - roles: [advanced]
+    actions: [update]
+    resources: ["services"]
+    conditions: [owner, weekday]
+`), false)
+			Expect(err).To(Not(BeNil()))
+			Expect(err.Error()).To(ContainSubstring("rule 1: roles are empty"))
+			Expect(err.Error()).To(ContainSubstring(`rule 1: unknown action "modify"`))
+			Expect(err.Error()).To(ContainSubstring(`rule 1: resource "service/**/version" has '**' ahead of the last segment`))
+			Expect(err.Error()).To(ContainSubstring(`rule 1: resource "/services" has an empty segment`))
+			Expect(err.Error()).To(ContainSubstring(`rule 1: resource "service/v*" has a partial wildcard`))
+			Expect(err.Error()).To(ContainSubstring(`rule 2: condition "owner" applies only to resources of a service`))
+			Expect(err.Error()).To(ContainSubstring(`rule 2: unknown condition "weekday"`))
+		})
+	})
+
+	Context("Roles", func() {
+		It("Distinct roles the rules refer to are listed", func() {
+			policy, err := Parse([]byte(validPolicy+`  - roles: [basic, advanced]
+    actions: [read]
+    resources: [user]
+`), false)
+			Expect(err).To(BeNil())
+			Expect(policy.Roles()).To(Equal([]string{"advanced", "admin", "basic"}))
+		})
+		It("Roles which aren't configured are reported", func() {
+			policy, err := Parse([]byte(validPolicy), false)
+			Expect(err).To(BeNil())
+			Expect(policy.UnknownRoles(func(role string) bool { return role == "admin" })).
+				To(Equal([]string{"advanced"}))
+			Expect(policy.UnknownRoles(func(string) bool { return true })).To(BeEmpty())
+		})
+	})
+	Context("LoadFile", func() {
+		var policyDir string
+		BeforeEach(func() {
+			var err error
+			policyDir, err = os.MkdirTemp("", "policy")
+			Expect(err).To(BeNil())
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(policyDir)
+		})
+		It("Policy file doesn't exist", func() {
+			_, err := LoadFile(filepath.Join(policyDir, "policy.yaml"))
+			Expect(err).To(MatchError(ContainSubstring("failed to read policy file")))
+		})
+		It("Invalid policy file is reported along its path", func() {
+			path := filepath.Join(policyDir, "policy.json")
+			Expect(os.WriteFile(path, []byte(`{"version": 0}`), 0600)).To(Succeed())
+			_, err := LoadFile(path)
+			Expect(err).To(MatchError(ContainSubstring("invalid policy file " + path)))
+		})
+	})
+
+	Context("Authorize", func() {
+		var policy *Policy
+		BeforeEach(func() {
+			var err error
+			policy, err = Parse([]byte(validPolicy), false)
+			Expect(err).To(BeNil())
+		})
+		It("Rule for any role permits the request", func() {
+			allowed, err := policy.Authorize(Request{Role: "basic", Action: ActionRead,
+				Resource: "service/1/version/v1"}, noConditions)
+			Expect(err).To(BeNil())
+			Expect(allowed).To(BeTrue())
+		})
+		It("Rule for inherited role permits the request", func() {
+			request := Request{Role: "release-manager", InheritedRoles: []string{"advanced"}, Action: ActionDelete,
+				Resource: "service/1/version/v1"}
+			allowed, err := policy.Authorize(request, func(string, Request) (bool, error) { return true, nil })
+			Expect(err).To(BeNil())
+			Expect(allowed).To(BeTrue())
+		})
+		It("Request isn't permitted by any rule", func() {
+			allowed, err := policy.Authorize(Request{Role: "basic", Action: ActionCreate, Resource: "service"},
+				noConditions)
+			Expect(err).To(BeNil())
+			Expect(allowed).To(BeFalse())
+		})
+		It("Rule permits the request once its conditions hold", func() {
+			request := Request{Role: "advanced", Action: ActionDelete, Resource: "service/1/version/v1", UserID: 3}
+			allowed, err := policy.Authorize(request, func(condition string, r Request) (bool, error) {
+				Expect(condition).To(Equal(ConditionOwner))
+				Expect(r).To(Equal(request))
+				return false, nil
+			})
+			Expect(err).To(BeNil())
+			Expect(allowed).To(BeFalse())
+
+			allowed, err = policy.Authorize(request, func(string, Request) (bool, error) { return true, nil })
+			Expect(err).To(BeNil())
+			Expect(allowed).To(BeTrue())
+		})
+		It("Failure to evaluate condition", func() {
+			_, err := policy.Authorize(Request{Role: "advanced", Action: ActionUpdate, Resource: "service/1"},
+				noConditions)
+			Expect(err).To(MatchError("unexpected condition evaluation"))
+		})
+		It("Wildcards of the resource", func() {
+			Expect(matchResource("service/*", "service/1")).To(BeTrue())
+			Expect(matchResource("service/*", "service/1/versions")).To(BeFalse())
+			Expect(matchResource("service/*/version/*", "service/1/version/v1")).To(BeTrue())
+			Expect(matchResource("service/**", "service")).To(BeTrue())
+			Expect(matchResource("service/**", "services")).To(BeFalse())
+			Expect(matchResource("**", "user/self/password")).To(BeTrue())
+		})
+	})
+
+	It("Action of the request method", func() {
+		Expect(ActionFromMethod(http.MethodGet)).To(Equal(ActionRead))
+		Expect(ActionFromMethod(http.MethodPost)).To(Equal(ActionCreate))
+		Expect(ActionFromMethod(http.MethodPut)).To(Equal(ActionUpdate))
+		Expect(ActionFromMethod(http.MethodPatch)).To(Equal(ActionUpdate))
+		Expect(ActionFromMethod(http.MethodDelete)).To(Equal(ActionDelete))
+	})
+})
@@ -18,7 +18,28 @@ type ErrorResponse struct {
 // TokenResponse...
 type TokenResponse struct {
 	AccessToken        string `json:"access_token"`
+	RefreshToken       string `json:"refresh_token"`
 	PassChangeRequired bool   `json:"password_change_required"`
+	// MFAEnrollmentRequired token is restricted to MFA enrollment, as user's role demands MFA
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+}
+
+// MFAChallengeResponse...
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// ClientCredentialsTokenResponse...
+type ClientCredentialsTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// AuthorizationURLResponse...
+type AuthorizationURLResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
 }
 
 // TempPassResponse...
@@ -37,8 +58,23 @@ func FormatErrorResponse(data string) ErrorResponse {
 }
 
 // FormatGenericResponse formats token and password state
-func FormatTokenResponse(token string, passChangeRequired bool) TokenResponse {
-	return TokenResponse{AccessToken: token, PassChangeRequired: passChangeRequired}
+func FormatTokenResponse(token string, refreshToken string, passChangeRequired bool) TokenResponse {
+	return TokenResponse{AccessToken: token, RefreshToken: refreshToken, PassChangeRequired: passChangeRequired}
+}
+
+// FormatMFAChallengeResponse formats MFA challenge token, which is to be presented along with the second factor
+func FormatMFAChallengeResponse(token string) MFAChallengeResponse {
+	return MFAChallengeResponse{MFARequired: true, MFAToken: token}
+}
+
+// FormatClientCredentialsTokenResponse formats token issued through client credentials grant
+func FormatClientCredentialsTokenResponse(token string, expiresInSec int64) ClientCredentialsTokenResponse {
+	return ClientCredentialsTokenResponse{AccessToken: token, TokenType: "Bearer", ExpiresIn: expiresInSec}
+}
+
+// FormatAuthorizationURLResponse formats URL of identity provider, which user is to be redirected to for sign in
+func FormatAuthorizationURLResponse(authorizationURL string) AuthorizationURLResponse {
+	return AuthorizationURLResponse{AuthorizationURL: authorizationURL}
 }
 
 // FormatTempPassResponse formats temporary password
@@ -52,6 +88,12 @@ type FieldTypeBinder map[string]reflect.Type
 // Reflect Type of string
 var String = reflect.TypeOf("")
 
+// Reflect Type of JSON number
+var Number = reflect.TypeOf(float64(0))
+
+// Reflect Type of JSON array
+var List = reflect.TypeOf([]interface{}{})
+
 // EnsureFieldsStrictlyExists check if input have same set and equal fields mentioned in FieldTypeBinder
 func EnsureFieldsStrictlyExists(input map[string]interface{}, fieldTypeMap FieldTypeBinder) bool {
 	if len(input) != len(fieldTypeMap) {
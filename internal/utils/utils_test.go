@@ -84,12 +84,14 @@ var _ = Describe("Utils Suite Test", func() {
 		})
 		It("Format Token Response", func() {
 			token := "sample-token"
+			refreshToken := "sample-refresh-token"
 			passChangeRequired := true
 			expected := TokenResponse{
 				AccessToken:        token,
+				RefreshToken:       refreshToken,
 				PassChangeRequired: passChangeRequired,
 			}
-			result := FormatTokenResponse(token, passChangeRequired)
+			result := FormatTokenResponse(token, refreshToken, passChangeRequired)
 			Expect(result).To(Equal(expected))
 		})
 		It("Format Temp Pass Response", func() {
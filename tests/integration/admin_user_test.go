@@ -3,6 +3,7 @@ package integration
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -16,6 +17,7 @@ var (
 	token    string
 	router   *gin.Engine
 	tempPass string
+	teamID   float64
 )
 
 // truncateTables...
@@ -24,6 +26,10 @@ func truncateTables(db *gorm.DB) (err error) {
 	if err != nil {
 		return
 	}
+	err = db.Exec("TRUNCATE TABLE team CASCADE").Error
+	if err != nil {
+		return
+	}
 	err = db.Exec(`TRUNCATE TABLE "user"`).Error
 	if err != nil {
 		return
@@ -74,7 +80,7 @@ func TestAdminResetPassword(t *testing.T) {
 	url := "/api/v1/user/self/password"
 	method := "PUT"
 
-	payload := []byte(`{"password": "admin123"}`)
+	payload := []byte(`{"password": "Mgmt-Portal-2024"}`)
 	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
 	if err != nil {
 		t.Fatalf("Failed to read response body: %v", err)
@@ -87,6 +93,16 @@ func TestAdminResetPassword(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200 OK; got %v", w.Code)
 	}
+	// tokens issued with former password are revoked, continue with the freshly issued one
+	responseBody, err := io.ReadAll(w.Result().Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	var resultMap map[string]interface{}
+	if err := json.Unmarshal(responseBody, &resultMap); err != nil {
+		t.Fatalf("Failed to unmarshal response body: %v", err)
+	}
+	token = resultMap["access_token"].(string)
 }
 
 // TestAdminAddUser...
@@ -146,3 +162,73 @@ func TestAdminListUsers(t *testing.T) {
 		t.Errorf("Admin User is not being listed")
 	}
 }
+
+// TestAdminAddTeam...
+func TestAdminAddTeam(t *testing.T) {
+	url := "/api/v1/team"
+	method := "POST"
+
+	payload := []byte(`{"name":"platform","description":"platform services"}`)
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 Created; got %v", w.Code)
+	}
+	responseBody, err := io.ReadAll(w.Result().Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	var resultMap map[string]interface{}
+	if err := json.Unmarshal(responseBody, &resultMap); err != nil {
+		t.Fatalf("Failed to unmarshal response body: %v", err)
+	}
+	teamID = resultMap["id"].(float64)
+}
+
+// TestAdminAddTeamMember adds the new user to the team, such that the user can add services owned by the team.
+func TestAdminAddTeamMember(t *testing.T) {
+	req, err := http.NewRequest("GET", "/api/v1/users", nil)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var users struct {
+		Data []struct {
+			ID    uint   `json:"id"`
+			Email string `json:"email"`
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil {
+		t.Fatalf("Failed to unmarshal response body: %v", err)
+	}
+	var userID uint
+	for _, user := range users.Data {
+		if user.Email == "khalid@gmail.com" {
+			userID = user.ID
+		}
+	}
+
+	url := fmt.Sprintf("/api/v1/team/%d/member", int(teamID))
+	payload := []byte(fmt.Sprintf(`{"user_id": %d}`, userID))
+	req, err = http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 Created; got %v", w.Code)
+	}
+}
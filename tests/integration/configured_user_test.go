@@ -46,7 +46,7 @@ func TestConfiguredUserResetPassword(t *testing.T) {
 	url := "/api/v1/user/self/password"
 	method := "PUT"
 
-	payload := []byte(`{"password": "khalid123"}`)
+	payload := []byte(`{"password": "Khalid-Portal-2024"}`)
 	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
 	if err != nil {
 		t.Fatalf("Failed to read response body: %v", err)
@@ -59,4 +59,14 @@ func TestConfiguredUserResetPassword(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status 200 OK; got %v", w.Code)
 	}
+	// tokens issued with former password are revoked, continue with the freshly issued one
+	responseBody, err := io.ReadAll(w.Result().Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	var resultMap map[string]interface{}
+	if err := json.Unmarshal(responseBody, &resultMap); err != nil {
+		t.Fatalf("Failed to unmarshal response body: %v", err)
+	}
+	token = resultMap["access_token"].(string)
 }
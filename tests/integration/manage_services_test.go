@@ -3,6 +3,7 @@ package integration
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -19,7 +20,7 @@ func TestAdvancedUserAddService(t *testing.T) {
 	url := "/api/v1/service"
 	method := "POST"
 
-	payload := []byte(`{"name":"postman-4","description":"postman  sdf"}`)
+	payload := []byte(fmt.Sprintf(`{"name":"postman-4","description":"postman  sdf","team_id":%d}`, int(teamID)))
 	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
 	if err != nil {
 		t.Fatalf("Failed to read response body: %v", err)
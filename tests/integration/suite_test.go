@@ -5,7 +5,9 @@ import (
 	"log"
 	"sync"
 	"testing"
+	"time"
 	"userservice/cmd/migration"
+	"userservice/internal/auth"
 	"userservice/internal/components/service"
 	"userservice/internal/components/user"
 	"userservice/internal/configs"
@@ -14,6 +16,7 @@ import (
 
 	"userservice/internal/middleware"
 	"userservice/internal/misc"
+	"userservice/internal/notify"
 
 	"github.com/gin-gonic/gin"
 	pginit "github.com/sabariarunkumar/go-postgresql-init"
@@ -64,11 +67,25 @@ func initPrerequisites() error {
 	gin.SetMode(gin.ReleaseMode)
 	router = gin.Default()
 	v1Apis := router.Group("/api/v1")
-	v1Apis.Use(middleware.Authenticate("/api/v1", logger, []byte(config.JWTSecret)))
-	userHandler := user.NewHandler(logger, config, db)
-	userHandler.RegisterRoutes(v1Apis)
 	ctx := context.Background()
+	revocationStore := auth.NewRevocationStore(ctx, db, logger, time.Second*time.Duration(config.JWTExpirationInSeconds),
+		time.Second*time.Duration(config.JWTLeewayInSeconds))
+	if err = revocationStore.Load(); err != nil {
+		return err
+	}
+	tokens := auth.NewTokenIssuer(auth.NewHMACKeySet([]byte(config.JWTSecret)), config.JWTIssuer, config.JWTAudience,
+		config.JWTExpirationInSeconds, config.JWTLeewayInSeconds)
+	v1Apis.Use(middleware.Authenticate("/api/v1", logger, tokens, revocationStore,
+		auth.NewAccessTokenStore(db, logger)))
+	passwordPolicy, err := auth.LoadPasswordPolicy(int(config.PasswordMinLength), int(config.PasswordMaxLength),
+		config.PasswordRequiredCharacterClasses)
+	if err != nil {
+		return err
+	}
 	var wg sync.WaitGroup
+	userHandler := user.NewHandler(&wg, logger, config, db, tokens, revocationStore, passwordPolicy,
+		auth.NewArgon2idHasher(auth.DefaultArgon2idParams), notify.NewLogNotifier(logger), nil)
+	userHandler.RegisterRoutes(v1Apis)
 	serviceHandler := service.NewHandler(ctx, &wg, logger, config, db)
 	serviceHandler.RegisterRoutes(v1Apis)
 